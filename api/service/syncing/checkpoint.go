@@ -0,0 +1,78 @@
+package syncing
+
+import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// CheckpointSync brings a new beacon chain to the block of the given trusted
+// checkpoint without downloading nor executing the blocks before it. It
+// downloads the checkpoint block, checked against the hash of the checkpoint,
+// then its state trie as in FastSync, and makes it the head block along with
+// the committees and validator data of the checkpoint. The blocks after it are
+// left to SyncLoop to verify and execute.
+//
+// It is a no-op when the chain is not new.
+func (ss *StateSync) CheckpointSync(bc *core.BlockChain, cp *core.Checkpoint) error {
+	if bc.ShardID() != shard.BeaconChainShardID {
+		return ErrCheckpointSyncShardChain
+	}
+	if bc.CurrentBlock().NumberU64() != 0 {
+		return nil
+	}
+	utils.Logger().Info().
+		Uint64("blockNum", cp.BlockNumber).
+		Str("blockHash", cp.BlockHash.Hex()).
+		Str("epoch", cp.Epoch.String()).
+		Msg("[SYNC] CheckpointSync: started")
+
+	checkpointBlock, err := ss.downloadCheckpointBlock(cp)
+	if err != nil {
+		return err
+	}
+	if err := ss.downloadState(bc, checkpointBlock.Root()); err != nil {
+		return err
+	}
+	if err := bc.ResetWithCheckpoint(cp, checkpointBlock); err != nil {
+		return err
+	}
+	utils.Logger().Info().
+		Uint64("blockNum", cp.BlockNumber).
+		Msg("[SYNC] CheckpointSync: finished, checkpoint block is the new head")
+	return nil
+}
+
+// downloadCheckpointBlock downloads the block of the given checkpoint from the
+// first peer which has it.
+func (ss *StateSync) downloadCheckpointBlock(cp *core.Checkpoint) (*types.Block, error) {
+	for i := 0; i < fastSyncRetryLimit; i++ {
+		peerConfig := ss.syncConfig.peerAt(i)
+		if peerConfig == nil {
+			break
+		}
+		payload, err := peerConfig.GetBlocks([][]byte{cp.BlockHash[:]})
+		if err != nil || len(payload) != 1 {
+			continue
+		}
+		blockObj := new(types.Block)
+		if err := rlp.DecodeBytes(payload[0], blockObj); err != nil || blockObj.Hash() != cp.BlockHash {
+			ss.banPeer(peerConfig, errors.Wrapf(errInvalidSyncData, "not the checkpoint block %x", cp.BlockHash))
+			continue
+		}
+		if hash := types.DeriveSha(
+			blockObj.Transactions(), blockObj.StakingTransactions(),
+		); hash != blockObj.Header().TxHash() {
+			ss.banPeer(peerConfig, errors.Wrapf(
+				errInvalidSyncData, "transaction root hash mismatch of checkpoint block: have %x, want %x",
+				hash, blockObj.Header().TxHash(),
+			))
+			continue
+		}
+		return blockObj, nil
+	}
+	return nil, errors.Wrapf(ErrGetBlock, "checkpoint block %x", cp.BlockHash)
+}
@@ -15,6 +15,7 @@ type Client struct {
 	dlClient pb.DownloaderClient
 	opts     []grpc.DialOption
 	conn     *grpc.ClientConn
+	target   string // address of the peer, for logging
 }
 
 // ClientSetup setups a Client given ip and port.
@@ -28,12 +29,17 @@ func ClientSetup(ip, port string) *Client {
 		return nil
 	}
 	utils.Logger().Info().Str("ip", ip).Msg("[SYNC] grpc connect successfully")
+	client.target = client.conn.Target()
 	client.dlClient = pb.NewDownloaderClient(client.conn)
 	return &client
 }
 
 // Close closes the Client.
 func (client *Client) Close() {
+	if client.conn == nil {
+		// streams are closed after each query
+		return
+	}
 	err := client.conn.Close()
 	if err != nil {
 		utils.Logger().Info().Msg("[SYNC] unable to close connection")
@@ -49,7 +55,7 @@ func (client *Client) GetBlockHashes(startHash []byte, size uint32, ip, port str
 	request.Port = port
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
-		utils.Logger().Error().Err(err).Str("target", client.conn.Target()).Msg("[SYNC] GetBlockHashes query failed")
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] GetBlockHashes query failed")
 	}
 	return response
 }
@@ -66,7 +72,7 @@ func (client *Client) GetBlockHeaders(hashes [][]byte) *pb.DownloaderResponse {
 	}
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
-		utils.Logger().Error().Err(err).Str("target", client.conn.Target()).Msg("[SYNC] downloader/client.go:GetBlockHeaders query failed")
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] downloader/client.go:GetBlockHeaders query failed")
 	}
 	return response
 }
@@ -83,7 +89,63 @@ func (client *Client) GetBlocks(hashes [][]byte) *pb.DownloaderResponse {
 	}
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
-		utils.Logger().Error().Err(err).Str("target", client.conn.Target()).Msg("[SYNC] downloader/client.go:GetBlocks query failed")
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] downloader/client.go:GetBlocks query failed")
+	}
+	return response
+}
+
+// GetReceipts gets the receipts of blocks in serialization byte array by calling a grpc request.
+func (client *Client) GetReceipts(hashes [][]byte) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_RECEIPT}
+	request.Hashes = make([][]byte, len(hashes))
+	for i := range hashes {
+		request.Hashes[i] = make([]byte, len(hashes[i]))
+		copy(request.Hashes[i], hashes[i])
+	}
+	response, err := client.dlClient.Query(ctx, request)
+	if err != nil {
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] downloader/client.go:GetReceipts query failed")
+	}
+	return response
+}
+
+// GetTrieNodes gets the state trie nodes (or contract codes) of the given hashes by calling a grpc request.
+func (client *Client) GetTrieNodes(hashes [][]byte) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_STATETRIE}
+	request.Hashes = make([][]byte, len(hashes))
+	for i := range hashes {
+		request.Hashes[i] = make([]byte, len(hashes[i]))
+		copy(request.Hashes[i], hashes[i])
+	}
+	response, err := client.dlClient.Query(ctx, request)
+	if err != nil {
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] downloader/client.go:GetTrieNodes query failed")
+	}
+	return response
+}
+
+// GetStateProof gets the Merkle proofs of the account of the given address, and
+// of the given storage keys of it, in the state of the given block by calling
+// a grpc request. The first payload is the account proof, followed by one
+// storage proof per key.
+func (client *Client) GetStateProof(blockHash []byte, address []byte, keys [][]byte) *pb.DownloaderResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	request := &pb.DownloaderRequest{Type: pb.DownloaderRequest_STATEPROOF}
+	request.BlockHash = make([]byte, len(blockHash))
+	copy(request.BlockHash, blockHash)
+	request.Hashes = make([][]byte, 0, len(keys)+1)
+	request.Hashes = append(request.Hashes, append([]byte{}, address...))
+	for i := range keys {
+		request.Hashes = append(request.Hashes, append([]byte{}, keys[i]...))
+	}
+	response, err := client.dlClient.Query(ctx, request)
+	if err != nil {
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] downloader/client.go:GetStateProof query failed")
 	}
 	return response
 }
@@ -100,7 +162,7 @@ func (client *Client) Register(hash []byte, ip, port string) *pb.DownloaderRespo
 	request.Port = port
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil || response == nil {
-		utils.Logger().Error().Err(err).Str("target", client.conn.Target()).Interface("response", response).Msg("[SYNC] client.go:Register failed")
+		utils.Logger().Error().Err(err).Str("target", client.target).Interface("response", response).Msg("[SYNC] client.go:Register failed")
 	}
 	return response
 }
@@ -122,7 +184,7 @@ func (client *Client) PushNewBlock(selfPeerHash [20]byte, blockHash []byte, time
 
 	response, err := client.dlClient.Query(ctx, request)
 	if err != nil {
-		utils.Logger().Error().Err(err).Str("target", client.conn.Target()).Msg("[SYNC] unable to send new block to unsync node")
+		utils.Logger().Error().Err(err).Str("target", client.target).Msg("[SYNC] unable to send new block to unsync node")
 	}
 	return response, err
 }
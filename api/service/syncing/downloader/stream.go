@@ -0,0 +1,156 @@
+package downloader
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+	"github.com/harmony-one/harmony/internal/utils"
+	libp2p_host "github.com/libp2p/go-libp2p-core/host"
+	libp2p_network "github.com/libp2p/go-libp2p-core/network"
+	libp2p_peer "github.com/libp2p/go-libp2p-core/peer"
+	libp2p_protocol "github.com/libp2p/go-libp2p-core/protocol"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// Constants for the syncing stream protocol.
+const (
+	// StreamProtocolPrefix is the prefix of the libp2p protocols serving the
+	// downloader requests, which are suffixed by the shard ID of the chain served
+	StreamProtocolPrefix = "/harmony/sync/1.0.0"
+	// maxStreamRequestSize is the maximum size of a request on a syncing stream
+	maxStreamRequestSize = 4 << 20
+	// maxStreamRequestHashes is the maximum number of hashes of a request on a
+	// syncing stream, which is no less than the largest query batch of syncing
+	maxStreamRequestHashes = 1000
+	// maxStreamResponseSize is the maximum size of a response on a syncing stream
+	maxStreamResponseSize = 64 << 20
+	streamTimeout         = 30 * time.Second
+)
+
+// StreamProtocolID returns the syncing stream protocol for the chain of the given shard.
+func StreamProtocolID(shardID uint32) libp2p_protocol.ID {
+	return libp2p_protocol.ID(fmt.Sprintf("%s/%d", StreamProtocolPrefix, shardID))
+}
+
+// writeStreamMessage writes the message prefixed by its length.
+func writeStreamMessage(w io.Writer, msg proto.Message) error {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	if _, err := w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// readStreamMessage reads a message prefixed by its length, of at most maxSize bytes.
+func readStreamMessage(r io.Reader, msg proto.Message, maxSize uint32) error {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+	length := binary.BigEndian.Uint32(size[:])
+	if length > maxSize {
+		return errors.Errorf("stream message too large: %d bytes", length)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// StartStream serves the downloader requests for the chain of the given shard
+// over libp2p streams of the given host, on StreamProtocolID(shardID).
+func (s *Server) StartStream(host libp2p_host.Host, shardID uint32) {
+	host.SetStreamHandler(StreamProtocolID(shardID), s.handleStream)
+}
+
+// handleStream answers the single request of an incoming syncing stream.
+func (s *Server) handleStream(stream libp2p_network.Stream) {
+	defer stream.Close()
+	if err := stream.SetDeadline(time.Now().Add(streamTimeout)); err != nil {
+		utils.Logger().Warn().Err(err).Msg("[SYNC] cannot set syncing stream deadline")
+	}
+	remote := stream.Conn().RemotePeer()
+	request := &pb.DownloaderRequest{}
+	if err := readStreamMessage(stream, request, maxStreamRequestSize); err != nil {
+		utils.Logger().Warn().Err(err).Str("peer", remote.Pretty()).
+			Msg("[SYNC] cannot read syncing stream request")
+		stream.Reset()
+		return
+	}
+	if len(request.Hashes) > maxStreamRequestHashes {
+		utils.Logger().Warn().Int("hashes", len(request.Hashes)).Str("peer", remote.Pretty()).
+			Msg("[SYNC] too many hashes in syncing stream request")
+		stream.Reset()
+		return
+	}
+	response, err := s.downloadInterface.CalculateResponse(request, remote.Pretty())
+	if err != nil {
+		utils.Logger().Warn().Err(err).Str("peer", remote.Pretty()).
+			Msg("[SYNC] cannot answer syncing stream request")
+		stream.Reset()
+		return
+	}
+	if err := writeStreamMessage(stream, response); err != nil {
+		utils.Logger().Warn().Err(err).Str("peer", remote.Pretty()).
+			Msg("[SYNC] cannot write syncing stream response")
+		stream.Reset()
+	}
+}
+
+// streamClient is a DownloaderClient sending each query on a new libp2p
+// stream to the peer.
+type streamClient struct {
+	host     libp2p_host.Host
+	peerID   libp2p_peer.ID
+	protocol libp2p_protocol.ID
+}
+
+// Query sends the request to the peer and reads its response.
+func (c *streamClient) Query(
+	ctx context.Context, request *pb.DownloaderRequest, opts ...grpc.CallOption,
+) (*pb.DownloaderResponse, error) {
+	stream, err := c.host.NewStream(ctx, c.peerID, c.protocol)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open syncing stream to %s", c.peerID.Pretty())
+	}
+	defer stream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := stream.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeStreamMessage(stream, request); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	response := &pb.DownloaderResponse{}
+	if err := readStreamMessage(stream, response, maxStreamResponseSize); err != nil {
+		stream.Reset()
+		return nil, err
+	}
+	return response, nil
+}
+
+// ClientSetupStream setups a Client querying the given peer over libp2p
+// streams of the given host, for the chain of the given shard.
+func ClientSetupStream(host libp2p_host.Host, peerID libp2p_peer.ID, shardID uint32) *Client {
+	client := Client{
+		dlClient: &streamClient{host: host, peerID: peerID, protocol: StreamProtocolID(shardID)},
+		target:   peerID.Pretty(),
+	}
+	utils.Logger().Info().Str("peer", client.target).Msg("[SYNC] syncing stream client set up")
+	return &client
+}
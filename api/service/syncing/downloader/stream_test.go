@@ -0,0 +1,133 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+	"github.com/libp2p/go-libp2p"
+	libp2p_host "github.com/libp2p/go-libp2p-core/host"
+	libp2p_peerstore "github.com/libp2p/go-libp2p-core/peerstore"
+)
+
+func TestStreamMessageRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	request := &pb.DownloaderRequest{
+		Type:   pb.DownloaderRequest_BLOCKHEADER,
+		Hashes: [][]byte{{1, 2, 3}, {4, 5, 6}},
+	}
+	if err := writeStreamMessage(&buf, request); err != nil {
+		t.Fatal(err)
+	}
+	got := &pb.DownloaderRequest{}
+	if err := readStreamMessage(&buf, got, maxStreamRequestSize); err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != request.Type || len(got.Hashes) != 2 || !bytes.Equal(got.Hashes[1], request.Hashes[1]) {
+		t.Errorf("request mismatch: have %v, want %v", got, request)
+	}
+}
+
+func TestStreamMessageTooLarge(t *testing.T) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], maxStreamResponseSize+1)
+	if err := readStreamMessage(
+		bytes.NewReader(size[:]), &pb.DownloaderResponse{}, maxStreamResponseSize,
+	); err == nil {
+		t.Error("oversized stream response accepted")
+	}
+	binary.BigEndian.PutUint32(size[:], maxStreamRequestSize+1)
+	if err := readStreamMessage(
+		bytes.NewReader(size[:]), &pb.DownloaderRequest{}, maxStreamRequestSize,
+	); err == nil {
+		t.Error("oversized stream request accepted")
+	}
+}
+
+func TestStreamProtocolID(t *testing.T) {
+	if id := StreamProtocolID(2); id != "/harmony/sync/1.0.0/2" {
+		t.Errorf("unexpected protocol ID %s", id)
+	}
+}
+
+// headerServer serves the hashes it is asked for as headers, the given height,
+// and fails the requests of the other types
+type headerServer struct {
+	height  uint64
+	queries chan string
+}
+
+func (s *headerServer) CalculateResponse(
+	request *pb.DownloaderRequest, incomingPeer string,
+) (*pb.DownloaderResponse, error) {
+	s.queries <- incomingPeer
+	switch request.Type {
+	case pb.DownloaderRequest_BLOCKHEADER:
+		return &pb.DownloaderResponse{Payload: request.Hashes}, nil
+	case pb.DownloaderRequest_BLOCKHEIGHT:
+		return &pb.DownloaderResponse{BlockHeight: s.height}, nil
+	}
+	return nil, errors.New("unsupported request")
+}
+
+// newStreamHost creates a libp2p host listening on a local port
+func newStreamHost(t *testing.T) libp2p_host.Host {
+	host, err := libp2p.New(
+		context.Background(), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestStreamQuery(t *testing.T) {
+	const shardID = 1
+	server := &headerServer{height: 42, queries: make(chan string, 10)}
+	serverHost := newStreamHost(t)
+	defer serverHost.Close()
+	NewServer(server).StartStream(serverHost, shardID)
+	clientHost := newStreamHost(t)
+	defer clientHost.Close()
+	clientHost.Peerstore().AddAddrs(
+		serverHost.ID(), serverHost.Addrs(), libp2p_peerstore.PermanentAddrTTL,
+	)
+
+	client := ClientSetupStream(clientHost, serverHost.ID(), shardID)
+	hashes := [][]byte{{1, 2, 3}, {4, 5, 6}}
+	response := client.GetBlockHeaders(hashes)
+	if response == nil || len(response.Payload) != 2 ||
+		!bytes.Equal(response.Payload[1], hashes[1]) {
+		t.Fatalf("unexpected headers response %v", response)
+	}
+	if peer := <-server.queries; peer != clientHost.ID().Pretty() {
+		t.Errorf("query from %s, expected the client host %s", peer, clientHost.ID().Pretty())
+	}
+	height, err := client.GetBlockChainHeight()
+	if err != nil || height.BlockHeight != 42 {
+		t.Errorf("unexpected height response %v, %v", height, err)
+	}
+	<-server.queries
+
+	// a failed query resets the stream, the client gets no response
+	if response := client.GetBlocks(hashes); response != nil {
+		t.Errorf("response %v to a failed query", response)
+	}
+	<-server.queries
+
+	// requests of more hashes than any syncing batch are not answered
+	if response := client.GetBlockHeaders(
+		make([][]byte, maxStreamRequestHashes+1),
+	); response != nil {
+		t.Errorf("response %v to a request of too many hashes", response)
+	}
+
+	// the chains of other shards are not served
+	other := ClientSetupStream(clientHost, serverHost.ID(), shardID+1)
+	if _, err := other.GetBlockChainHeight(); err == nil {
+		t.Error("queried the chain of a shard not served")
+	}
+}
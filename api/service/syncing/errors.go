@@ -4,13 +4,19 @@ import "errors"
 
 // Errors ...
 var (
-	ErrRegistrationFail      = errors.New("[SYNC]: registration failed")
-	ErrGetBlock              = errors.New("[SYNC]: get block failed")
-	ErrGetBlockHash          = errors.New("[SYNC]: get blockhash failed")
-	ErrProcessStateSync      = errors.New("[SYNC]: get blockhash failed")
-	ErrGetConsensusHashes    = errors.New("[SYNC]: get consensus hashes failed")
-	ErrGenStateSyncTaskQueue = errors.New("[SYNC]: generate state sync task queue failed")
-	ErrDownloadBlocks        = errors.New("[SYNC]: get download blocks failed")
-	ErrUpdateBlockAndStatus  = errors.New("[SYNC]: update block and status failed")
-	ErrGenerateNewState      = errors.New("[SYNC]: get generate new state failed")
+	ErrRegistrationFail         = errors.New("[SYNC]: registration failed")
+	ErrGetBlock                 = errors.New("[SYNC]: get block failed")
+	ErrGetBlockHash             = errors.New("[SYNC]: get blockhash failed")
+	ErrProcessStateSync         = errors.New("[SYNC]: get blockhash failed")
+	ErrGetConsensusHashes       = errors.New("[SYNC]: get consensus hashes failed")
+	ErrGenStateSyncTaskQueue    = errors.New("[SYNC]: generate state sync task queue failed")
+	ErrDownloadBlocks           = errors.New("[SYNC]: get download blocks failed")
+	ErrUpdateBlockAndStatus     = errors.New("[SYNC]: update block and status failed")
+	ErrGenerateNewState         = errors.New("[SYNC]: get generate new state failed")
+	ErrGetBlockHeaders          = errors.New("[SYNC]: get block headers failed")
+	ErrGetReceipts              = errors.New("[SYNC]: get receipts failed")
+	ErrGetTrieNodes             = errors.New("[SYNC]: get state trie nodes failed")
+	ErrFastSyncBeaconChain      = errors.New("[SYNC]: beacon chain cannot be fast synced")
+	ErrCheckpointSyncShardChain = errors.New("[SYNC]: only the beacon chain can be synced from a checkpoint")
+	ErrGetStateProof            = errors.New("[SYNC]: get state proof failed")
 )
@@ -0,0 +1,279 @@
+package syncing
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// Constants for fast syncing.
+const (
+	// FastSyncPivotDistance is how many blocks behind the highest peer the pivot
+	// block is, the blocks after the pivot being executed as in a full sync
+	FastSyncPivotDistance uint64 = 64
+	StateSyncBatchSize           = 384 // maximum number of state trie nodes for one query
+	fastSyncRetryLimit           = 5   // consecutive failed queries before giving up
+)
+
+// peerAt returns the i-th peer, wrapping around, or nil if there is no peer.
+func (sc *SyncConfig) peerAt(i int) *SyncPeerConfig {
+	sc.mtx.RLock()
+	defer sc.mtx.RUnlock()
+	if len(sc.peers) == 0 {
+		return nil
+	}
+	return sc.peers[i%len(sc.peers)]
+}
+
+// GetReceipts gets the receipts of the given blocks by calling grpc request to
+// the corresponding peer, and checks them against the receipt roots of the blocks.
+func (peerConfig *SyncPeerConfig) GetReceipts(blocks []*types.Block) ([]types.Receipts, error) {
+	hashes := make([][]byte, len(blocks))
+	for i := range blocks {
+		hashes[i] = blocks[i].Hash().Bytes()
+	}
+	response := peerConfig.client.GetReceipts(hashes)
+	if response == nil || len(response.Payload) != len(blocks) {
+		return nil, ErrGetReceipts
+	}
+	receipts := make([]types.Receipts, len(blocks))
+	for i, payload := range response.Payload {
+		if err := rlp.DecodeBytes(payload, &receipts[i]); err != nil {
+			return nil, errors.Wrapf(
+				errInvalidSyncData, "cannot decode receipts of block %d: %v", blocks[i].NumberU64(), err,
+			)
+		}
+		if hash := types.DeriveSha(receipts[i]); hash != blocks[i].Header().ReceiptHash() {
+			return nil, errors.Wrapf(
+				errInvalidSyncData, "receipt root hash mismatch of block %d: have %x, want %x",
+				blocks[i].NumberU64(), hash, blocks[i].Header().ReceiptHash(),
+			)
+		}
+	}
+	return receipts, nil
+}
+
+// GetTrieNodes gets the state trie nodes of the given hashes by calling grpc
+// request to the corresponding peer. Only the nodes whose hash is one of the
+// requested ones are returned, so that a peer cannot feed forged nodes.
+func (peerConfig *SyncPeerConfig) GetTrieNodes(hashes []common.Hash) ([]trie.SyncResult, error) {
+	requested := make(map[common.Hash]struct{}, len(hashes))
+	request := make([][]byte, len(hashes))
+	for i := range hashes {
+		requested[hashes[i]] = struct{}{}
+		request[i] = hashes[i].Bytes()
+	}
+	response := peerConfig.client.GetTrieNodes(request)
+	if response == nil {
+		return nil, ErrGetTrieNodes
+	}
+	results := []trie.SyncResult{}
+	for _, data := range response.Payload {
+		hash := crypto.Keccak256Hash(data)
+		if _, ok := requested[hash]; !ok {
+			continue
+		}
+		delete(requested, hash)
+		results = append(results, trie.SyncResult{Hash: hash, Data: data})
+	}
+	if len(results) == 0 {
+		return nil, ErrGetTrieNodes
+	}
+	return results, nil
+}
+
+// FastSync brings a new shard chain close to the head of the peers without
+// executing its blocks. It downloads the blocks up to a pivot block
+// FastSyncPivotDistance blocks behind the highest peer, verifying their headers
+// and bodies, and their receipts, then the state trie at the pivot, verified
+// node by node from the state root of the pivot header, and makes the pivot the
+// head block. The blocks after the pivot are left to SyncLoop to execute.
+// The off chain data of the blocks up to the pivot which does not need their
+// execution is written as well: their outgoing cross shard receipts, the first
+// block of each epoch, and the last commit signature, the one of the pivot.
+//
+// It is a no-op when the chain is already close to the head of the peers, or
+// when the chain is not new and no previous fast sync was interrupted.
+// The beacon chain cannot be fast synced, as executing its blocks needs the
+// staking data kept off the state of each block.
+func (ss *StateSync) FastSync(bc *core.BlockChain) error {
+	if bc.ShardID() == shard.BeaconChainShardID {
+		return ErrFastSyncBeaconChain
+	}
+	currentHeight := bc.CurrentBlock().NumberU64()
+	if currentHeight != 0 && bc.CurrentFastBlock().NumberU64() <= currentHeight {
+		return nil
+	}
+	otherHeight := ss.getMaxPeerHeight(false)
+	if otherHeight <= currentHeight+FastSyncPivotDistance {
+		return nil
+	}
+	pivot := otherHeight - FastSyncPivotDistance
+	utils.Logger().Info().
+		Uint64("otherHeight", otherHeight).
+		Uint64("currentHeight", currentHeight).
+		Uint64("pivot", pivot).
+		Msg("[SYNC] FastSync: started")
+
+	var lastCommits []byte
+	for bc.CurrentFastBlock().NumberU64() < pivot {
+		commits, err := ss.fastSyncBlocks(bc, pivot)
+		if err != nil {
+			return err
+		}
+		lastCommits = commits
+	}
+	pivotBlock := bc.CurrentFastBlock()
+	if err := ss.downloadState(bc, pivotBlock.Root()); err != nil {
+		return err
+	}
+	if err := bc.FastSyncCommitHead(pivotBlock.Hash()); err != nil {
+		return err
+	}
+	if len(lastCommits) > 0 {
+		if err := bc.WriteLastCommits(lastCommits); err != nil {
+			return err
+		}
+	}
+	utils.Logger().Info().
+		Uint64("blockHeight", pivotBlock.NumberU64()).
+		Str("blockHex", pivotBlock.Hash().Hex()).
+		Msg("[SYNC] FastSync: finished, pivot block is the new head")
+	return nil
+}
+
+// fastSyncBlocks downloads the next batch of blocks after the fast sync head,
+// up to the pivot, and writes them with their receipts but without state.
+// The headers are verified as a skeleton before the bodies and receipts are
+// downloaded from all the peers. When the batch reaches the pivot, it returns
+// the commit signature and bitmap of the pivot, carried by the header after it.
+func (ss *StateSync) fastSyncBlocks(bc *core.BlockChain, pivot uint64) ([]byte, error) {
+	head := bc.CurrentFastBlock()
+	// one more block than needed, as the last header is not verified by signature
+	size := uint32(pivot - head.NumberU64() + 1)
+	if size > SyncLoopBatchSize {
+		size = SyncLoopBatchSize
+	}
+	startHash := head.Hash()
+	ss.getConsensusHashes(startHash[:], size)
+	headers, err := ss.downloadHeaders(bc, startHash[:])
+	if err != nil {
+		return nil, err
+	}
+	var lastCommits []byte
+	for i := 0; i+1 < len(headers); i++ {
+		if headers[i].Number().Uint64() == pivot {
+			sig := headers[i+1].LastCommitSignature()
+			lastCommits = append(sig[:], headers[i+1].LastCommitBitmap()...)
+		}
+	}
+	if len(headers) > 0 {
+		headers = headers[:len(headers)-1]
+	}
+	for len(headers) > 0 && headers[len(headers)-1].Number().Uint64() > pivot {
+		headers = headers[:len(headers)-1]
+	}
+	if len(headers) == 0 {
+		return nil, ErrDownloadBlocks
+	}
+
+	if _, err := bc.InsertHeaderChain(headers, 1); err != nil {
+		return nil, err
+	}
+	blocks, receipts, err := ss.downloadBodies(headers, true)
+	if len(blocks) > 0 {
+		if _, err := bc.InsertReceiptChain(blocks, receipts); err != nil {
+			return nil, err
+		}
+	}
+	return lastCommits, err
+}
+
+// downloadState downloads the state trie of the given root, with the storage
+// tries and the codes of its accounts, from the peers into the database.
+func (ss *StateSync) downloadState(bc *core.BlockChain, root common.Hash) error {
+	db := bc.ChainDb()
+	sched := state.NewStateSync(root, db)
+	batch := db.NewBatch()
+
+	pending := []common.Hash{}
+	failures, round, nodes := 0, 0, 0
+	for {
+		if len(pending) < StateSyncBatchSize {
+			pending = append(pending, sched.Missing(StateSyncBatchSize-len(pending))...)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		peerConfig := ss.syncConfig.peerAt(round)
+		if peerConfig == nil {
+			return ErrGetTrieNodes
+		}
+		round++
+		results, err := peerConfig.GetTrieNodes(pending)
+		if err != nil {
+			failures++
+			utils.Logger().Warn().Err(err).
+				Str("peerIP", peerConfig.ip).
+				Str("peerPort", peerConfig.port).
+				Int("failNumber", failures).
+				Msg("[SYNC] downloadState: GetTrieNodes failed")
+			if failures > fastSyncRetryLimit {
+				return err
+			}
+			continue
+		}
+		failures = 0
+		if _, index, err := sched.Process(results); err != nil {
+			return errors.Wrapf(err, "[SYNC] downloadState: cannot process trie node %x", results[index].Hash)
+		}
+		if _, err := sched.Commit(batch); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+
+		delivered := make(map[common.Hash]struct{}, len(results))
+		for _, result := range results {
+			delivered[result.Hash] = struct{}{}
+		}
+		remaining := []common.Hash{}
+		for _, hash := range pending {
+			if _, ok := delivered[hash]; !ok {
+				remaining = append(remaining, hash)
+			}
+		}
+		pending = remaining
+
+		nodes += len(results)
+		if round%100 == 0 {
+			utils.Logger().Info().
+				Int("nodes", nodes).
+				Int("pending", sched.Pending()).
+				Msg("[SYNC] downloadState: in progress")
+		}
+	}
+	if pending := sched.Pending(); pending > 0 {
+		return errors.Errorf("[SYNC] downloadState: %d trie nodes still pending", pending)
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	utils.Logger().Info().
+		Int("nodes", nodes).
+		Str("root", root.Hex()).
+		Msg("[SYNC] downloadState: finished")
+	return nil
+}
@@ -0,0 +1,320 @@
+package syncing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/service/syncing/downloader"
+	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
+	"github.com/harmony-one/harmony/block"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	chain2 "github.com/harmony-one/harmony/internal/chain"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/libp2p/go-libp2p"
+	libp2p_host "github.com/libp2p/go-libp2p-core/host"
+	libp2p_peerstore "github.com/libp2p/go-libp2p-core/peerstore"
+	"github.com/pkg/errors"
+)
+
+const fastSyncTestShardID = 1
+
+var (
+	fastSyncTestKey, _   = crypto.GenerateKey()
+	fastSyncTestAddress  = crypto.PubkeyToAddress(fastSyncTestKey.PublicKey)
+	fastSyncTestBLSKey   = bls_cosi.RandPrivateKey()
+	fastSyncTestReceiver = common.HexToAddress("0x1234")
+)
+
+// fastSyncTestConfig is the test chain config before staking, whose
+// committees are not weighted by stake
+func fastSyncTestConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.PreStakingEpoch = big.NewInt(100)
+	config.StakingEpoch = big.NewInt(100)
+	return &config
+}
+
+// fastSyncTestCommittee is the committee of the test chain, the test BLS key alone
+func fastSyncTestCommittee(epoch *big.Int) shard.State {
+	var pubKey shard.BLSPublicKey
+	pubKey.FromLibBLSPublicKey(fastSyncTestBLSKey.GetPublicKey())
+	return shard.State{Epoch: epoch, Shards: []shard.Committee{{
+		ShardID: fastSyncTestShardID,
+		Slots:   shard.SlotList{{EcdsaAddress: fastSyncTestAddress, BLSPublicKey: pubKey}},
+	}}}
+}
+
+// newFastSyncTestChain creates a chain of the test shard at genesis
+func newFastSyncTestChain(t *testing.T) *core.BlockChain {
+	config := fastSyncTestConfig()
+	committee := fastSyncTestCommittee(nil)
+	db := ethdb.NewMemDatabase()
+	gspec := core.Genesis{
+		Config:         config,
+		Factory:        blockfactory.NewFactory(config),
+		Alloc:          core.GenesisAlloc{fastSyncTestAddress: {Balance: big.NewInt(1e18)}},
+		ShardID:        fastSyncTestShardID,
+		GasLimit:       params.TestGenesisGasLimit,
+		ShardStateHash: committee.Hash(),
+		ShardState:     committee,
+	}
+	gspec.MustCommit(db)
+	bc, err := core.NewBlockChain(db, nil, config, chain2.Engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
+
+// makeFastSyncTestBlocks makes n blocks after the genesis, each one carrying
+// the commit signature of its parent. The block lastOfEpoch ends epoch 0 with
+// the committee of epoch 1, and the block cxBlock sends a cross shard
+// transaction. The blocks do not change the state, as they are not executed.
+func makeFastSyncTestBlocks(
+	t *testing.T, genesis *types.Block, n, lastOfEpoch, cxBlock uint64,
+) ([]*types.Block, []types.Receipts) {
+	config := fastSyncTestConfig()
+	factory := blockfactory.NewFactory(config)
+	nextShardState, err := shard.EncodeWrapper(fastSyncTestCommittee(big.NewInt(1)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask, err := bls_cosi.NewMask([]*bls.PublicKey{fastSyncTestBLSKey.GetPublicKey()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mask.SetKey(fastSyncTestBLSKey.GetPublicKey(), true); err != nil {
+		t.Fatal(err)
+	}
+
+	blocks, receipts := []*types.Block{}, []types.Receipts{}
+	parent := genesis
+	var lastCommitSig [96]byte
+	for num := uint64(1); num <= n; num++ {
+		epoch := big.NewInt(0)
+		if num > lastOfEpoch {
+			epoch = big.NewInt(1)
+		}
+		fields := factory.NewHeader(epoch).With().
+			ParentHash(parent.Hash()).
+			Number(new(big.Int).SetUint64(num)).
+			Epoch(epoch).
+			ShardID(fastSyncTestShardID).
+			Root(genesis.Root()).
+			GasLimit(params.TestGenesisGasLimit).
+			Time(new(big.Int).SetUint64(num))
+		if num > 1 {
+			fields = fields.LastCommitSignature(lastCommitSig).LastCommitBitmap(mask.Bitmap)
+		}
+		if num == lastOfEpoch {
+			fields = fields.ShardState(nextShardState)
+		}
+		header := fields.Header()
+
+		txs, blockReceipts, cxReceipts := []*types.Transaction{}, types.Receipts{}, []*types.CXReceipt{}
+		if num == cxBlock {
+			tx, err := types.SignTx(
+				types.NewCrossShardTransaction(
+					0, &fastSyncTestReceiver, fastSyncTestShardID, shard.BeaconChainShardID,
+					big.NewInt(1000), params.TxGas, big.NewInt(1), nil,
+				),
+				types.NewEIP155Signer(config.ChainID), fastSyncTestKey,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			txs = append(txs, tx)
+			blockReceipts = append(blockReceipts, types.NewReceipt(nil, false, params.TxGas))
+			cxReceipts = append(cxReceipts, &types.CXReceipt{
+				TxHash:    tx.Hash(),
+				From:      fastSyncTestAddress,
+				To:        &fastSyncTestReceiver,
+				ShardID:   fastSyncTestShardID,
+				ToShardID: shard.BeaconChainShardID,
+				Amount:    big.NewInt(1000),
+			})
+		}
+		blk := types.NewBlock(header, txs, blockReceipts, cxReceipts, nil, nil)
+		blocks = append(blocks, blk)
+		receipts = append(receipts, blockReceipts)
+
+		commitPayload := make([]byte, 8)
+		binary.LittleEndian.PutUint64(commitPayload, num)
+		hash := blk.Hash()
+		commitPayload = append(commitPayload, hash[:]...)
+		copy(lastCommitSig[:], fastSyncTestBLSKey.SignHash(commitPayload).Serialize())
+		parent = blk
+	}
+	return blocks, receipts
+}
+
+// chainServer answers the downloader requests of a fast sync from a chain,
+// as a node does
+type chainServer struct {
+	chain *core.BlockChain
+}
+
+func (s *chainServer) CalculateResponse(
+	request *pb.DownloaderRequest, incomingPeer string,
+) (*pb.DownloaderResponse, error) {
+	response := &pb.DownloaderResponse{}
+	switch request.Type {
+	case pb.DownloaderRequest_BLOCKHASH:
+		start := s.chain.GetHeaderByHash(common.BytesToHash(request.BlockHash))
+		if start == nil {
+			return response, errors.New("unknown start block")
+		}
+		for num := start.Number().Uint64(); num <= start.Number().Uint64()+uint64(request.Size); num++ {
+			header := s.chain.GetHeaderByNumber(num)
+			if header == nil {
+				break
+			}
+			hash := header.Hash()
+			response.Payload = append(response.Payload, hash[:])
+		}
+	case pb.DownloaderRequest_BLOCKHEADER:
+		for _, hash := range request.Hashes {
+			if header := s.chain.GetHeaderByHash(common.BytesToHash(hash)); header != nil {
+				encoded, err := rlp.EncodeToBytes(header)
+				if err != nil {
+					return response, err
+				}
+				response.Payload = append(response.Payload, encoded)
+			}
+		}
+	case pb.DownloaderRequest_BLOCK:
+		for _, hash := range request.Hashes {
+			if blk := s.chain.GetBlockByHash(common.BytesToHash(hash)); blk != nil {
+				encoded, err := rlp.EncodeToBytes(blk)
+				if err != nil {
+					return response, err
+				}
+				response.Payload = append(response.Payload, encoded)
+			}
+		}
+	case pb.DownloaderRequest_RECEIPT:
+		for _, hash := range request.Hashes {
+			receipts := s.chain.GetReceiptsByHash(common.BytesToHash(hash))
+			if receipts == nil {
+				receipts = types.Receipts{}
+			}
+			encoded, err := rlp.EncodeToBytes(receipts)
+			if err != nil {
+				return response, err
+			}
+			response.Payload = append(response.Payload, encoded)
+		}
+	case pb.DownloaderRequest_STATETRIE:
+		for _, hash := range request.Hashes {
+			if data, err := s.chain.TrieNode(common.BytesToHash(hash)); err == nil && len(data) > 0 {
+				response.Payload = append(response.Payload, data)
+			}
+		}
+	case pb.DownloaderRequest_BLOCKHEIGHT:
+		response.BlockHeight = s.chain.CurrentBlock().NumberU64()
+	}
+	return response, nil
+}
+
+// newStreamHost creates a libp2p host listening on a local port
+func newStreamHost(t *testing.T) libp2p_host.Host {
+	host, err := libp2p.New(
+		context.Background(), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host
+}
+
+func TestFastSync(t *testing.T) {
+	const (
+		numBlocks   = FastSyncPivotDistance + 10
+		pivot       = numBlocks - FastSyncPivotDistance
+		lastOfEpoch = 4
+		cxBlock     = 3
+	)
+	source := newFastSyncTestChain(t)
+	blocks, receipts := makeFastSyncTestBlocks(t, source.Genesis(), numBlocks, lastOfEpoch, cxBlock)
+	headers := make([]*block.Header, len(blocks))
+	for i := range blocks {
+		headers[i] = blocks[i].Header()
+	}
+	if _, err := source.InsertHeaderChain(headers, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.InsertReceiptChain(blocks, receipts); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.FastSyncCommitHead(blocks[len(blocks)-1].Hash()); err != nil {
+		t.Fatal(err)
+	}
+
+	serverHost := newStreamHost(t)
+	defer serverHost.Close()
+	downloader.NewServer(&chainServer{chain: source}).StartStream(serverHost, fastSyncTestShardID)
+	clientHost := newStreamHost(t)
+	defer clientHost.Close()
+	clientHost.Peerstore().AddAddrs(
+		serverHost.ID(), serverHost.Addrs(), libp2p_peerstore.PermanentAddrTTL,
+	)
+
+	stateSync := CreateStateSync("127.0.0.1", "8000", [20]byte{})
+	stateSync.EnableStreams(clientHost, fastSyncTestShardID)
+	if err := stateSync.CreateSyncConfig(
+		[]p2p.Peer{{IP: "127.0.0.1", Port: "9000", PeerID: serverHost.ID()}}, false,
+	); err != nil {
+		t.Fatal(err)
+	}
+	bc := newFastSyncTestChain(t)
+	if err := stateSync.FastSync(bc); err != nil {
+		t.Fatalf("fast sync failed: %v", err)
+	}
+
+	if head := bc.CurrentBlock(); head.Hash() != blocks[pivot-1].Hash() {
+		t.Fatalf("expected the pivot %d as head, got block %d", pivot, head.NumberU64())
+	}
+	stateDB, err := bc.State()
+	if err != nil {
+		t.Fatalf("no state at the pivot: %v", err)
+	}
+	if balance := stateDB.GetBalance(fastSyncTestAddress); balance.Cmp(big.NewInt(1e18)) != 0 {
+		t.Errorf("unexpected balance %v at the pivot", balance)
+	}
+
+	cxReceipts, err := rawdb.ReadCXReceipts(
+		bc.ChainDb(), shard.BeaconChainShardID, cxBlock, blocks[cxBlock-1].Hash(),
+	)
+	if err != nil || len(cxReceipts) != 1 ||
+		cxReceipts[0].TxHash != blocks[cxBlock-1].Transactions()[0].Hash() {
+		t.Errorf("cross shard receipts of block %d not written: %v, %v", cxBlock, cxReceipts, err)
+	}
+	if num, err := rawdb.ReadEpochBlockNumber(bc.ChainDb(), big.NewInt(1)); err != nil ||
+		num.Uint64() != lastOfEpoch+1 {
+		t.Errorf("expected block %d as first of epoch 1, got %v, %v", lastOfEpoch+1, num, err)
+	}
+	if _, err := bc.ReadShardState(big.NewInt(1)); err != nil {
+		t.Errorf("no committee of epoch 1: %v", err)
+	}
+	child := blocks[pivot].Header()
+	sig := child.LastCommitSignature()
+	if lastCommits, err := bc.ReadLastCommits(); err != nil ||
+		!bytes.Equal(lastCommits, append(sig[:], child.LastCommitBitmap()...)) {
+		t.Errorf("last commits are not the ones of the pivot: %x, %v", lastCommits, err)
+	}
+}
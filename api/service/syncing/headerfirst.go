@@ -0,0 +1,456 @@
+package syncing
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Workiva/go-datastructures/queue"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	libp2p_peer "github.com/libp2p/go-libp2p-core/peer"
+	"github.com/pkg/errors"
+)
+
+// Constants for header-first syncing.
+const (
+	headersBatchSize     = 500             // maximum number of headers for one query
+	bodiesBatchSize      = 64              // maximum number of blocks for one query
+	bodiesTargetRTT      = 2 * time.Second // how long a query of blocks should take given the peer throughput
+	bodiesPollTimeout    = 100 * time.Millisecond
+	peerBanDuration      = 30 * time.Minute // how long a peer which served invalid data is not synced from
+	initialBodiesPerPeer = 8                // blocks asked for at once to a peer of unknown throughput
+)
+
+// errInvalidSyncData is the cause of the errors of peers which served data not
+// matching what was asked for, which get them banned.
+var errInvalidSyncData = errors.New("[SYNC]: peer served invalid data")
+
+// syncPeerKey identifies a peer by its peer ID when it is queried over libp2p
+// streams, and by its ip:port otherwise.
+func syncPeerKey(ip, port string, peerID libp2p_peer.ID) string {
+	if peerID != "" {
+		return peerID.Pretty()
+	}
+	return net.JoinHostPort(ip, port)
+}
+
+// isBanned tells whether the peer of the given key is banned.
+func (ss *StateSync) isBanned(key string) bool {
+	ss.banMux.Lock()
+	defer ss.banMux.Unlock()
+	until, ok := ss.bannedPeers[key]
+	if ok && time.Now().After(until) {
+		delete(ss.bannedPeers, key)
+		return false
+	}
+	return ok
+}
+
+// banPeer stops syncing from a peer which served invalid data, for peerBanDuration.
+func (ss *StateSync) banPeer(peerConfig *SyncPeerConfig, reason error) {
+	utils.Logger().Warn().Err(reason).
+		Str("peerIP", peerConfig.ip).
+		Str("peerPort", peerConfig.port).
+		Str("peerID", peerConfig.peerID.Pretty()).
+		Msg("[SYNC] banning peer")
+	key := syncPeerKey(peerConfig.ip, peerConfig.port, peerConfig.peerID)
+	ss.banMux.Lock()
+	ss.bannedPeers[key] = time.Now().Add(peerBanDuration)
+	ss.banMux.Unlock()
+	ss.syncConfig.RemovePeer(peerConfig)
+}
+
+// RemovePeer removes the given peer and closes its connection.
+func (sc *SyncConfig) RemovePeer(peer *SyncPeerConfig) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	for i := range sc.peers {
+		if sc.peers[i] == peer {
+			peer.client.Close()
+			copy(sc.peers[i:], sc.peers[i+1:])
+			sc.peers[len(sc.peers)-1] = nil
+			sc.peers = sc.peers[:len(sc.peers)-1]
+			return
+		}
+	}
+}
+
+// consensusBlockHashes returns the block hashes agreed by the peers left
+// after GetBlockHashesConsensusAndCleanUp.
+func (sc *SyncConfig) consensusBlockHashes() [][]byte {
+	sc.mtx.RLock()
+	defer sc.mtx.RUnlock()
+	if len(sc.peers) == 0 {
+		return nil
+	}
+	return sc.peers[0].blockHashes
+}
+
+// recordDelivery accounts for count blocks served by the peer in elapsed.
+func (peerConfig *SyncPeerConfig) recordDelivery(count int, elapsed time.Duration) {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	peerConfig.delivered += count
+	peerConfig.elapsed += elapsed
+}
+
+// bodiesCapacity is how many blocks to ask the peer for at once, so that the
+// query takes about bodiesTargetRTT given the throughput of the peer so far.
+func (peerConfig *SyncPeerConfig) bodiesCapacity() int {
+	peerConfig.mux.Lock()
+	defer peerConfig.mux.Unlock()
+	if peerConfig.delivered == 0 || peerConfig.elapsed == 0 {
+		return initialBodiesPerPeer
+	}
+	throughput := float64(peerConfig.delivered) / peerConfig.elapsed.Seconds()
+	capacity := int(throughput * bodiesTargetRTT.Seconds())
+	if capacity < 1 {
+		return 1
+	}
+	if capacity > bodiesBatchSize {
+		return bodiesBatchSize
+	}
+	return capacity
+}
+
+// GetBlockHeaders gets the headers of the given block hashes by calling grpc
+// request to the corresponding peer, in the order of the hashes.
+func (peerConfig *SyncPeerConfig) GetBlockHeaders(hashes [][]byte) ([]*block.Header, error) {
+	headers := make([]*block.Header, 0, len(hashes))
+	for len(headers) < len(hashes) {
+		batch := hashes[len(headers):]
+		if len(batch) > headersBatchSize {
+			batch = batch[:headersBatchSize]
+		}
+		response := peerConfig.client.GetBlockHeaders(batch)
+		if response == nil || len(response.Payload) != len(batch) {
+			return nil, ErrGetBlockHeaders
+		}
+		for i, payload := range response.Payload {
+			header := new(block.Header)
+			if err := rlp.DecodeBytes(payload, header); err != nil {
+				return nil, errors.Wrapf(errInvalidSyncData, "cannot decode header: %v", err)
+			}
+			if hash := header.Hash(); !bytes.Equal(hash[:], batch[i]) {
+				return nil, errors.Wrapf(
+					errInvalidSyncData, "header hash mismatch: have %x, want %x", hash, batch[i],
+				)
+			}
+			headers = append(headers, header)
+		}
+	}
+	return headers, nil
+}
+
+// skeletonChain is the chain with the shard states carried by the headers of a
+// skeleton being verified, which are not written before their blocks are.
+type skeletonChain struct {
+	*core.BlockChain
+	shardStates map[uint64]*shard.State
+}
+
+// ReadShardState retrieves sharding state given the epoch number,
+// from the skeleton first.
+func (c *skeletonChain) ReadShardState(epoch *big.Int) (*shard.State, error) {
+	if shardState, ok := c.shardStates[epoch.Uint64()]; ok {
+		return shardState, nil
+	}
+	return c.BlockChain.ReadShardState(epoch)
+}
+
+// addShardStateOf keeps the shard state carried by the last header of an epoch,
+// under the epoch it is for.
+func (c *skeletonChain) addShardStateOf(header *block.Header) error {
+	shardState, err := shard.DecodeWrapper(header.ShardState())
+	if err != nil {
+		return err
+	}
+	epoch := new(big.Int).Add(header.Epoch(), common.Big1)
+	if shardState.Epoch != nil && c.Config().IsStaking(shardState.Epoch) {
+		// After staking, the epoch will be decided by the epoch in the shard state.
+		epoch = shardState.Epoch
+	}
+	c.shardStates[epoch.Uint64()] = shardState
+	return nil
+}
+
+// verifySkeleton checks that the headers are linked from the start header, and
+// verifies the commit signature over each header, which is in its child header.
+// The last header is only checked against the hash agreed by the peers, as its
+// commit signature is not known yet.
+func verifySkeleton(bc *core.BlockChain, start *block.Header, headers []*block.Header) error {
+	parent := start
+	for _, header := range headers {
+		if header.ParentHash() != parent.Hash() ||
+			header.Number().Uint64() != parent.Number().Uint64()+1 {
+			return errors.Wrapf(
+				errInvalidSyncData, "header %d is not linked to its parent", header.Number().Uint64(),
+			)
+		}
+		parent = header
+	}
+
+	chain := &skeletonChain{BlockChain: bc, shardStates: map[uint64]*shard.State{}}
+	all := append([]*block.Header{start}, headers...)
+	for i := 0; i < len(all)-1; i++ {
+		header, child := all[i], all[i+1]
+		// the genesis block is not signed
+		if header.Number().Sign() > 0 {
+			sig := child.LastCommitSignature()
+			if err := bc.Engine().VerifyHeaderWithSignature(
+				chain, header, sig[:], child.LastCommitBitmap(), false,
+			); err != nil {
+				return errors.Wrapf(
+					errInvalidSyncData, "header %d: %v", header.Number().Uint64(), err,
+				)
+			}
+		}
+		if len(header.ShardState()) > 0 {
+			if err := chain.addShardStateOf(header); err != nil {
+				return errors.Wrapf(
+					errInvalidSyncData, "shard state of header %d: %v", header.Number().Uint64(), err,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// downloadHeaders downloads the headers of the block hashes agreed by the peers
+// after the start block from one peer, and verifies them as a skeleton. Peers
+// serving invalid headers are banned and the next peer is asked.
+func (ss *StateSync) downloadHeaders(bc *core.BlockChain, startHash []byte) ([]*block.Header, error) {
+	start := bc.GetHeaderByHash(common.BytesToHash(startHash))
+	if start == nil {
+		return nil, errors.Errorf("[SYNC] downloadHeaders: unknown start block %x", startHash)
+	}
+	hashes := ss.syncConfig.consensusBlockHashes()
+	// the first hash is the one of the start block
+	if len(hashes) > 0 && bytes.Equal(hashes[0], startHash) {
+		hashes = hashes[1:]
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	var err error
+	for attempt := 0; attempt <= downloadBlocksRetryLimit; attempt++ {
+		peerConfig := ss.syncConfig.peerAt(attempt)
+		if peerConfig == nil {
+			break
+		}
+		var headers []*block.Header
+		headers, err = peerConfig.GetBlockHeaders(hashes)
+		if err == nil {
+			err = verifySkeleton(bc, start, headers)
+		}
+		if err == nil {
+			utils.Logger().Info().
+				Int("count", len(headers)).
+				Str("peerIP", peerConfig.ip).
+				Str("peerPort", peerConfig.port).
+				Msg("[SYNC] downloadHeaders: verified headers")
+			return headers, nil
+		}
+		if errors.Cause(err) == errInvalidSyncData {
+			ss.banPeer(peerConfig, err)
+		} else {
+			utils.Logger().Warn().Err(err).
+				Str("peerIP", peerConfig.ip).
+				Str("peerPort", peerConfig.port).
+				Msg("[SYNC] downloadHeaders: GetBlockHeaders failed")
+		}
+	}
+	if err == nil {
+		err = ErrGetBlockHeaders
+	}
+	return nil, err
+}
+
+// bodiesFetch is the download of the blocks, and optionally the receipts, of
+// verified headers, shared by the peers serving them.
+type bodiesFetch struct {
+	headers      []*block.Header
+	withReceipts bool
+	tasks        *queue.Queue
+	remaining    int64 // number of blocks not delivered yet, accessed atomically
+	mux          sync.Mutex
+	blocks       []*types.Block
+	receipts     []types.Receipts
+}
+
+// deliver asks the peer for the blocks of the tasks, and their receipts if
+// needed, and keeps the ones matching their headers. It returns the tasks
+// which are not delivered.
+func (fetch *bodiesFetch) deliver(
+	peerConfig *SyncPeerConfig, tasks []SyncBlockTask,
+) ([]SyncBlockTask, error) {
+	hashes := make([][]byte, len(tasks))
+	for i := range tasks {
+		hashes[i] = tasks[i].blockHash
+	}
+	payload, err := peerConfig.GetBlocks(hashes)
+	if err != nil || len(payload) == 0 {
+		return tasks, ErrGetBlock
+	}
+	// the peer skips the blocks it does not have, so match them by hash
+	byHash := make(map[common.Hash]*types.Block, len(payload))
+	for i := range payload {
+		blockObj := new(types.Block)
+		if err := rlp.DecodeBytes(payload[i], blockObj); err != nil {
+			return tasks, errors.Wrapf(errInvalidSyncData, "cannot decode block: %v", err)
+		}
+		byHash[blockObj.Hash()] = blockObj
+	}
+
+	blocks, delivered, missing := []*types.Block{}, []SyncBlockTask{}, []SyncBlockTask{}
+	for _, task := range tasks {
+		blockObj, ok := byHash[common.BytesToHash(task.blockHash)]
+		if !ok {
+			missing = append(missing, task)
+			continue
+		}
+		header := fetch.headers[task.index]
+		if hash := types.DeriveSha(
+			blockObj.Transactions(), blockObj.StakingTransactions(),
+		); hash != header.TxHash() {
+			return tasks, errors.Wrapf(
+				errInvalidSyncData, "transaction root hash mismatch of block %d: have %x, want %x",
+				blockObj.NumberU64(), hash, header.TxHash(),
+			)
+		}
+		blocks = append(blocks, blockObj)
+		delivered = append(delivered, task)
+	}
+	if len(blocks) == 0 {
+		return tasks, ErrGetBlock
+	}
+
+	var receipts []types.Receipts
+	if fetch.withReceipts {
+		if receipts, err = peerConfig.GetReceipts(blocks); err != nil {
+			return tasks, err
+		}
+	}
+
+	fetch.mux.Lock()
+	for i, task := range delivered {
+		fetch.blocks[task.index] = blocks[i]
+		if fetch.withReceipts {
+			fetch.receipts[task.index] = receipts[i]
+		}
+	}
+	fetch.mux.Unlock()
+	atomic.AddInt64(&fetch.remaining, -int64(len(delivered)))
+	return missing, nil
+}
+
+// fetchBodies serves the tasks of the fetch from one peer until all the blocks
+// are delivered, the peer fails too many times, or it gets banned.
+func (ss *StateSync) fetchBodies(peerConfig *SyncPeerConfig, fetch *bodiesFetch) {
+	failures := 0
+	for atomic.LoadInt64(&fetch.remaining) > 0 {
+		items, err := fetch.tasks.Poll(int64(peerConfig.bodiesCapacity()), bodiesPollTimeout)
+		if err == queue.ErrTimeout {
+			// the other peers may still give back the tasks they fail
+			continue
+		}
+		if err != nil || len(items) == 0 {
+			return
+		}
+		tasks := make([]SyncBlockTask, len(items))
+		for i := range items {
+			tasks[i] = items[i].(SyncBlockTask)
+		}
+
+		start := time.Now()
+		undelivered, err := fetch.deliver(peerConfig, tasks)
+		peerConfig.recordDelivery(len(tasks)-len(undelivered), time.Since(start))
+		for _, task := range undelivered {
+			if err := fetch.tasks.Put(task); err != nil {
+				return
+			}
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+		if errors.Cause(err) == errInvalidSyncData {
+			ss.banPeer(peerConfig, err)
+			return
+		}
+		failures++
+		utils.Logger().Warn().Err(err).
+			Str("peerIP", peerConfig.ip).
+			Str("peerPort", peerConfig.port).
+			Int("failNumber", failures).
+			Msg("[SYNC] fetchBodies: query failed")
+		if failures > downloadBlocksRetryLimit {
+			return
+		}
+	}
+}
+
+// downloadBodies downloads the blocks of the verified headers from all the
+// peers at once, and their receipts if withReceipts, each peer being asked for
+// as many blocks at a time as its throughput allows. It returns the blocks,
+// and receipts, delivered in a row from the first header, with an error if
+// not all of them could be.
+func (ss *StateSync) downloadBodies(
+	headers []*block.Header, withReceipts bool,
+) ([]*types.Block, []types.Receipts, error) {
+	fetch := &bodiesFetch{
+		headers:      headers,
+		withReceipts: withReceipts,
+		tasks:        queue.New(int64(len(headers))),
+		remaining:    int64(len(headers)),
+		blocks:       make([]*types.Block, len(headers)),
+	}
+	if withReceipts {
+		fetch.receipts = make([]types.Receipts, len(headers))
+	}
+	for i, header := range headers {
+		hash := header.Hash()
+		if err := fetch.tasks.Put(SyncBlockTask{index: i, blockHash: hash[:]}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var wg sync.WaitGroup
+	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ss.fetchBodies(peerConfig, fetch)
+		}()
+		return
+	})
+	wg.Wait()
+	fetch.tasks.Dispose()
+
+	count := 0
+	for count < len(headers) && fetch.blocks[count] != nil {
+		count++
+	}
+	utils.Logger().Info().
+		Int("count", count).
+		Int("total", len(headers)).
+		Msg("[SYNC] downloadBodies: finished")
+	var receipts []types.Receipts
+	if withReceipts {
+		receipts = fetch.receipts[:count]
+	}
+	if count < len(headers) {
+		return fetch.blocks[:count], receipts, ErrDownloadBlocks
+	}
+	return fetch.blocks, receipts, nil
+}
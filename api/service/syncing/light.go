@@ -0,0 +1,194 @@
+package syncing
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/light"
+	"github.com/pkg/errors"
+)
+
+// StateProofKeysLimit is the maximum number of storage keys for one state
+// proof query.
+const StateProofKeysLimit = 64
+
+// GetStateProof gets the Merkle proofs of the account of the given address,
+// and of the given storage keys of it, in the state of the given block by
+// calling grpc request to the corresponding peer.
+func (peerConfig *SyncPeerConfig) GetStateProof(
+	blockHash common.Hash, address common.Address, keys []common.Hash,
+) (accountProof [][]byte, storageProofs [][][]byte, err error) {
+	keyBytes := make([][]byte, len(keys))
+	for i := range keys {
+		keyBytes[i] = keys[i][:]
+	}
+	response := peerConfig.client.GetStateProof(blockHash[:], address[:], keyBytes)
+	if response == nil || len(response.Payload) == 0 {
+		return nil, nil, ErrGetStateProof
+	}
+	if len(response.Payload) != len(keys)+1 {
+		return nil, nil, errors.Wrapf(
+			errInvalidSyncData, "%d state proofs for %d keys", len(response.Payload), len(keys),
+		)
+	}
+	if err := rlp.DecodeBytes(response.Payload[0], &accountProof); err != nil {
+		return nil, nil, errors.Wrapf(errInvalidSyncData, "cannot decode account proof: %v", err)
+	}
+	storageProofs = make([][][]byte, len(keys))
+	for i, payload := range response.Payload[1:] {
+		if err := rlp.DecodeBytes(payload, &storageProofs[i]); err != nil {
+			return nil, nil, errors.Wrapf(errInvalidSyncData, "cannot decode storage proof: %v", err)
+		}
+	}
+	return accountProof, storageProofs, nil
+}
+
+// RetrieveState implements light.StateRetriever, asking the peers in turn for
+// the proofs of the state of the account, checked against the state root of the
+// given verified header. Peers serving invalid proofs are banned.
+func (ss *StateSync) RetrieveState(
+	header *block.Header, address common.Address, keys []common.Hash,
+) (*light.AccountState, error) {
+	if len(keys) > StateProofKeysLimit {
+		return nil, errors.Errorf("cannot prove more than %d storage keys at once", StateProofKeysLimit)
+	}
+	syncConfig := ss.syncConfig
+	if syncConfig == nil {
+		return nil, errors.Wrap(ErrGetStateProof, "no peers")
+	}
+	for i := 0; i < fastSyncRetryLimit; i++ {
+		peerConfig := syncConfig.peerAt(i)
+		if peerConfig == nil {
+			break
+		}
+		accountProof, storageProofs, err := peerConfig.GetStateProof(header.Hash(), address, keys)
+		if err == nil {
+			var account *light.AccountState
+			account, err = light.VerifyStateProof(header.Root(), address, keys, accountProof, storageProofs)
+			if err == nil {
+				return account, nil
+			}
+			err = errors.Wrapf(errInvalidSyncData, "%v", err)
+		}
+		if errors.Cause(err) == errInvalidSyncData {
+			ss.banPeer(peerConfig, err)
+		} else {
+			// the peer may not have the state of the block anymore
+			utils.Logger().Debug().Err(err).
+				Str("peerIP", peerConfig.ip).
+				Str("peerPort", peerConfig.port).
+				Msg("[SYNC] RetrieveState: GetStateProof failed")
+		}
+	}
+	return nil, errors.Wrapf(ErrGetStateProof, "account %s at block %d", address.Hex(), header.Number())
+}
+
+// LightSync brings the given light chain up to the height of the peers,
+// downloading and verifying the headers only. A new light chain starts from the
+// given checkpoint, if any, instead of from genesis. As the commit signature
+// over a header is carried by its child, the light chain stays one header
+// behind the peers.
+func (ss *StateSync) LightSync(lc *light.LightChain, cp *core.Checkpoint) error {
+	if cp != nil && lc.CurrentHeader().Number().Uint64() == 0 {
+		header, err := ss.downloadCheckpointHeader(cp)
+		if err != nil {
+			return err
+		}
+		if err := lc.ResetWithCheckpoint(cp, header); err != nil {
+			return err
+		}
+	}
+	for {
+		otherHeight := ss.getMaxPeerHeight(true)
+		current := lc.CurrentHeader()
+		currentHeight := current.Number().Uint64()
+		if currentHeight+1 >= otherHeight {
+			utils.Logger().Info().
+				Uint64("otherHeight", otherHeight).
+				Uint64("currentHeight", currentHeight).
+				Msg("[SYNC] LightSync: light chain is in sync")
+			return nil
+		}
+		size := uint32(otherHeight - currentHeight)
+		if size > SyncLoopBatchSize {
+			size = SyncLoopBatchSize
+		}
+		startHash := current.Hash()
+		ss.getConsensusHashes(startHash[:], size)
+		if err := ss.downloadLightHeaders(lc, startHash[:]); err != nil {
+			return err
+		}
+		if lc.CurrentHeader().Number().Uint64() == currentHeight {
+			return errors.Wrap(ErrGetBlockHeaders, "no header verified")
+		}
+	}
+}
+
+// downloadLightHeaders downloads the headers of the block hashes agreed by the
+// peers after the start block, and inserts them into the light chain. Peers
+// serving headers which fail the verification are banned and the next peer is
+// asked.
+func (ss *StateSync) downloadLightHeaders(lc *light.LightChain, startHash []byte) error {
+	hashes := ss.syncConfig.consensusBlockHashes()
+	// the first hash is the one of the start block
+	if len(hashes) > 0 && bytes.Equal(hashes[0], startHash) {
+		hashes = hashes[1:]
+	}
+	if len(hashes) < 2 {
+		return nil
+	}
+	var err error
+	for attempt := 0; attempt <= downloadBlocksRetryLimit; attempt++ {
+		peerConfig := ss.syncConfig.peerAt(attempt)
+		if peerConfig == nil {
+			break
+		}
+		var headers []*block.Header
+		headers, err = peerConfig.GetBlockHeaders(hashes)
+		if err == nil {
+			if _, err = lc.InsertHeaders(headers); err != nil {
+				err = errors.Wrapf(errInvalidSyncData, "%v", err)
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if errors.Cause(err) == errInvalidSyncData {
+			ss.banPeer(peerConfig, err)
+			// the headers verified before the invalid one are kept
+			return err
+		}
+		utils.Logger().Warn().Err(err).
+			Str("peerIP", peerConfig.ip).
+			Str("peerPort", peerConfig.port).
+			Msg("[SYNC] downloadLightHeaders: GetBlockHeaders failed")
+	}
+	if err == nil {
+		err = ErrGetBlockHeaders
+	}
+	return err
+}
+
+// downloadCheckpointHeader downloads the header of the block of the given
+// checkpoint from the first peer which has it.
+func (ss *StateSync) downloadCheckpointHeader(cp *core.Checkpoint) (*block.Header, error) {
+	for i := 0; i < fastSyncRetryLimit; i++ {
+		peerConfig := ss.syncConfig.peerAt(i)
+		if peerConfig == nil {
+			break
+		}
+		headers, err := peerConfig.GetBlockHeaders([][]byte{cp.BlockHash[:]})
+		if err != nil {
+			if errors.Cause(err) == errInvalidSyncData {
+				ss.banPeer(peerConfig, err)
+			}
+			continue
+		}
+		return headers[0], nil
+	}
+	return nil, errors.Wrapf(ErrGetBlockHeaders, "checkpoint header %x", cp.BlockHash)
+}
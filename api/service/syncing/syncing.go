@@ -2,7 +2,6 @@ package syncing
 
 import (
 	"bytes"
-	"encoding/hex"
 	"fmt"
 	"reflect"
 	"sort"
@@ -10,9 +9,7 @@ import (
 	"sync"
 	"time"
 
-	"github.com/Workiva/go-datastructures/queue"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/harmony/api/service/syncing/downloader"
 	pb "github.com/harmony-one/harmony/api/service/syncing/downloader/proto"
 	"github.com/harmony-one/harmony/consensus"
@@ -22,6 +19,8 @@ import (
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/node/worker"
 	"github.com/harmony-one/harmony/p2p"
+	libp2p_host "github.com/libp2p/go-libp2p-core/host"
+	libp2p_peer "github.com/libp2p/go-libp2p-core/peer"
 	"github.com/pkg/errors"
 )
 
@@ -42,11 +41,16 @@ const (
 type SyncPeerConfig struct {
 	ip          string
 	port        string
+	peerID      libp2p_peer.ID // set when the peer is queried over libp2p streams
 	peerHash    []byte
 	client      *downloader.Client
 	blockHashes [][]byte       // block hashes before node doing sync
 	newBlocks   []*types.Block // blocks after node doing sync
 	mux         sync.Mutex
+	// blocks served by the peer and the time spent on them, to size the
+	// requests to the peer after its throughput
+	delivered int
+	elapsed   time.Duration
 }
 
 // GetClient returns client pointer of downloader.Client
@@ -96,20 +100,35 @@ func CreateStateSync(ip string, port string, peerHash [20]byte) *StateSync {
 	stateSync.selfPeerHash = peerHash
 	stateSync.commonBlocks = make(map[int]*types.Block)
 	stateSync.lastMileBlocks = []*types.Block{}
+	stateSync.bannedPeers = make(map[string]time.Time)
 	return stateSync
 }
 
 // StateSync is the struct that implements StateSyncInterface.
 type StateSync struct {
-	selfip             string
-	selfport           string
-	selfPeerHash       [20]byte // hash of ip and address combination
-	commonBlocks       map[int]*types.Block
-	lastMileBlocks     []*types.Block // last mile blocks to catch up with the consensus
-	syncConfig         *SyncConfig
-	stateSyncTaskQueue *queue.Queue
-	syncMux            sync.Mutex
-	lastMileMux        sync.Mutex
+	selfip         string
+	selfport       string
+	selfPeerHash   [20]byte // hash of ip and address combination
+	commonBlocks   map[int]*types.Block
+	lastMileBlocks []*types.Block // last mile blocks to catch up with the consensus
+	syncConfig     *SyncConfig
+	syncMux        sync.Mutex
+	lastMileMux    sync.Mutex
+	// bannedPeers are the keys (see syncPeerKey) of the peers which served
+	// invalid data, with when they are banned until
+	bannedPeers map[string]time.Time
+	banMux      sync.Mutex
+	// streamHost, when set, is the host whose libp2p streams are used to query
+	// the peers with a peer ID, for the chain of streamShardID
+	streamHost    libp2p_host.Host
+	streamShardID uint32
+}
+
+// EnableStreams makes the peers with a peer ID be queried over libp2p streams
+// of the given host, for the chain of the given shard, instead of gRPC.
+func (ss *StateSync) EnableStreams(host libp2p_host.Host, shardID uint32) {
+	ss.streamHost = host
+	ss.streamShardID = shardID
 }
 
 func (ss *StateSync) purgeAllBlocksFromCache() {
@@ -239,16 +258,29 @@ func (ss *StateSync) CreateSyncConfig(peers []p2p.Peer, isBeacon bool) error {
 	ss.syncConfig = &SyncConfig{}
 	var wg sync.WaitGroup
 	for _, peer := range peers {
+		var peerID libp2p_peer.ID
+		if ss.streamHost != nil {
+			peerID = peer.PeerID
+		}
+		if ss.isBanned(syncPeerKey(peer.IP, peer.Port, peerID)) {
+			continue
+		}
 		wg.Add(1)
 		go func(peer p2p.Peer) {
 			defer wg.Done()
-			client := downloader.ClientSetup(peer.IP, peer.Port)
+			var client *downloader.Client
+			if peerID != "" {
+				client = downloader.ClientSetupStream(ss.streamHost, peerID, ss.streamShardID)
+			} else {
+				client = downloader.ClientSetup(peer.IP, peer.Port)
+			}
 			if client == nil {
 				return
 			}
 			peerConfig := &SyncPeerConfig{
 				ip:     peer.IP,
 				port:   peer.Port,
+				peerID: peerID,
 				client: client,
 			}
 			ss.syncConfig.AddPeer(peerConfig)
@@ -375,88 +407,6 @@ func (ss *StateSync) getConsensusHashes(startHash []byte, size uint32) {
 	utils.Logger().Info().Msg("[SYNC] Finished getting consensus block hashes")
 }
 
-func (ss *StateSync) generateStateSyncTaskQueue(bc *core.BlockChain) {
-	ss.stateSyncTaskQueue = queue.New(0)
-	ss.syncConfig.ForEachPeer(func(configPeer *SyncPeerConfig) (brk bool) {
-		for id, blockHash := range configPeer.blockHashes {
-			if err := ss.stateSyncTaskQueue.Put(SyncBlockTask{index: id, blockHash: blockHash}); err != nil {
-				utils.Logger().Warn().
-					Err(err).
-					Int("taskIndex", id).
-					Str("taskBlock", hex.EncodeToString(blockHash)).
-					Msg("[SYNC] generateStateSyncTaskQueue: cannot add task")
-			}
-		}
-		brk = true
-		return
-	})
-	utils.Logger().Info().Int64("length", ss.stateSyncTaskQueue.Len()).Msg("[SYNC] generateStateSyncTaskQueue: finished")
-}
-
-// downloadBlocks downloads blocks from state sync task queue.
-func (ss *StateSync) downloadBlocks(bc *core.BlockChain) {
-	// Initialize blockchain
-	var wg sync.WaitGroup
-	count := 0
-	ss.syncConfig.ForEachPeer(func(peerConfig *SyncPeerConfig) (brk bool) {
-		wg.Add(1)
-		go func(stateSyncTaskQueue *queue.Queue, bc *core.BlockChain) {
-			defer wg.Done()
-			for !stateSyncTaskQueue.Empty() {
-				task, err := ss.stateSyncTaskQueue.Poll(1, time.Millisecond)
-				if err == queue.ErrTimeout || len(task) == 0 {
-					utils.Logger().Error().Err(err).Msg("[SYNC] downloadBlocks: ss.stateSyncTaskQueue poll timeout")
-					break
-				}
-				syncTask := task[0].(SyncBlockTask)
-				//id := syncTask.index
-				payload, err := peerConfig.GetBlocks([][]byte{syncTask.blockHash})
-				if err != nil || len(payload) == 0 {
-					count++
-					utils.Logger().Error().Err(err).Int("failNumber", count).Msg("[SYNC] downloadBlocks: GetBlocks failed")
-					if count > downloadBlocksRetryLimit {
-						break
-					}
-					if err := ss.stateSyncTaskQueue.Put(syncTask); err != nil {
-						utils.Logger().Warn().
-							Err(err).
-							Int("taskIndex", syncTask.index).
-							Str("taskBlock", hex.EncodeToString(syncTask.blockHash)).
-							Msg("downloadBlocks: cannot add task")
-					}
-					continue
-				}
-
-				var blockObj types.Block
-				// currently only send one block a time
-				err = rlp.DecodeBytes(payload[0], &blockObj)
-
-				if err != nil {
-					count++
-					utils.Logger().Error().Err(err).Msg("[SYNC] downloadBlocks: failed to DecodeBytes from received new block")
-					if count > downloadBlocksRetryLimit {
-						break
-					}
-					if err := ss.stateSyncTaskQueue.Put(syncTask); err != nil {
-						utils.Logger().Warn().
-							Err(err).
-							Int("taskIndex", syncTask.index).
-							Str("taskBlock", hex.EncodeToString(syncTask.blockHash)).
-							Msg("cannot add task")
-					}
-					continue
-				}
-				ss.syncMux.Lock()
-				ss.commonBlocks[syncTask.index] = &blockObj
-				ss.syncMux.Unlock()
-			}
-		}(ss.stateSyncTaskQueue, bc)
-		return
-	})
-	wg.Wait()
-	utils.Logger().Info().Msg("[SYNC] downloadBlocks: finished")
-}
-
 // CompareBlockByHash compares two block by hash, it will be used in sort the blocks
 func CompareBlockByHash(a *types.Block, b *types.Block) int {
 	ha := a.Hash()
@@ -663,10 +613,21 @@ func (ss *StateSync) generateNewState(bc *core.BlockChain, worker *worker.Worker
 func (ss *StateSync) ProcessStateSync(startHash []byte, size uint32, bc *core.BlockChain, worker *worker.Worker) error {
 	// Gets consensus hashes.
 	ss.getConsensusHashes(startHash, size)
-	ss.generateStateSyncTaskQueue(bc)
-	// Download blocks.
-	if ss.stateSyncTaskQueue.Len() > 0 {
-		ss.downloadBlocks(bc)
+	// Download and verify the headers, then the blocks.
+	headers, err := ss.downloadHeaders(bc, startHash)
+	if err != nil {
+		utils.Logger().Warn().Err(err).Msg("[SYNC] ProcessStateSync: downloadHeaders failed")
+	}
+	if len(headers) > 0 {
+		blocks, _, err := ss.downloadBodies(headers, false)
+		if err != nil {
+			utils.Logger().Warn().Err(err).Msg("[SYNC] ProcessStateSync: downloadBodies failed")
+		}
+		ss.syncMux.Lock()
+		for i := range blocks {
+			ss.commonBlocks[i] = blocks[i]
+		}
+		ss.syncMux.Unlock()
 	}
 	return ss.generateNewState(bc, worker)
 }
@@ -697,6 +658,11 @@ func (ss *StateSync) RegisterNodeInfo() int {
 			brk = true
 			return
 		}
+		if peerConfig.peerID != "" {
+			// the peer pushes new blocks to a gRPC port, which a node syncing
+			// over streams may not have reachable
+			return
+		}
 		if peerConfig.ip == ss.selfip && peerConfig.port == GetSyncingPort(ss.selfport) {
 			logger.Debug().
 				Str("selfport", ss.selfport).
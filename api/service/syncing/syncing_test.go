@@ -2,6 +2,7 @@ package syncing
 
 import (
 	"testing"
+	"time"
 
 	"github.com/harmony-one/harmony/api/service/syncing/downloader"
 	"github.com/stretchr/testify/assert"
@@ -49,3 +50,31 @@ func TestCreateStateSync(t *testing.T) {
 		t.Error("Unable to create stateSync")
 	}
 }
+
+func TestBodiesCapacity(t *testing.T) {
+	peerConfig := CreateTestSyncPeerConfig(&downloader.Client{}, nil)
+	assert.Equal(t, initialBodiesPerPeer, peerConfig.bodiesCapacity(), "unknown throughput")
+
+	peerConfig.recordDelivery(10, time.Second)
+	assert.Equal(t, 20, peerConfig.bodiesCapacity(), "10 blocks per second")
+
+	peerConfig.recordDelivery(0, time.Minute)
+	assert.Equal(t, 1, peerConfig.bodiesCapacity(), "slow peer")
+
+	fast := CreateTestSyncPeerConfig(&downloader.Client{}, nil)
+	fast.recordDelivery(1000, time.Second)
+	assert.Equal(t, bodiesBatchSize, fast.bodiesCapacity(), "fast peer")
+}
+
+func TestBanPeer(t *testing.T) {
+	stateSync := CreateStateSync("127.0.0.1", "8000", [20]byte{})
+	assert.Equal(t, "127.0.0.1:9000", syncPeerKey("127.0.0.1", "9000", ""))
+	assert.False(t, stateSync.isBanned("127.0.0.1:9000"))
+
+	stateSync.bannedPeers["127.0.0.1:9000"] = time.Now().Add(peerBanDuration)
+	assert.True(t, stateSync.isBanned("127.0.0.1:9000"))
+	assert.False(t, stateSync.isBanned("127.0.0.1:9001"))
+
+	stateSync.bannedPeers["127.0.0.1:9000"] = time.Now().Add(-time.Second)
+	assert.False(t, stateSync.isBanned("127.0.0.1:9000"), "ban expired")
+}
@@ -0,0 +1,96 @@
+// election runs the election of the next committee against the current
+// state of a beacon chain node, optionally with extra stake for a validator,
+// and prints the slots, effective stake and stake needed to win of each candidate
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/harmony-one/harmony/common/denominations"
+	"github.com/harmony-one/harmony/numeric"
+)
+
+var (
+	version string
+	builtBy string
+	builtAt string
+	commit  string
+)
+
+func printVersion(me string) {
+	fmt.Fprintf(os.Stderr, "Harmony (C) 2019. %v, version %v-%v (%v %v)\n", path.Base(me), version, commit, builtBy, builtAt)
+	os.Exit(0)
+}
+
+// electionDryRun asks the node for the election with extra stake added to
+// validator, extra is sent as hex quantity so it can not be negative
+func electionDryRun(
+	client *rpc.Client, validator *string, extra *big.Int,
+) (json.RawMessage, error) {
+	result := json.RawMessage{}
+	if err := client.Call(
+		&result, "hmy_getElectionDryRun", validator, (*hexutil.Big)(extra),
+	); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func main() {
+	node := flag.String("node", "http://localhost:9500", "rpc endpoint of a beacon chain node")
+	validator := flag.String("validator", "", "bech32 address of the validator to add stake to")
+	extraStake := flag.String("extra_stake", "0", "stake in ONE to add to the validator")
+	versionFlag := flag.Bool("version", false, "Output version info")
+
+	flag.Parse()
+
+	if *versionFlag {
+		printVersion(os.Args[0])
+	}
+
+	stake, err := numeric.NewDecFromStr(*extraStake)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid extra stake %s: %v\n", *extraStake, err)
+		os.Exit(1)
+	}
+	extra := stake.MulInt(big.NewInt(denominations.One)).TruncateInt()
+	if extra.Sign() < 0 {
+		fmt.Fprintf(os.Stderr, "extra stake %s can not be negative\n", *extraStake)
+		os.Exit(1)
+	}
+	var validatorArg *string
+	if *validator != "" {
+		validatorArg = validator
+	} else if extra.Sign() != 0 {
+		fmt.Fprintln(os.Stderr, "extra stake needs a validator")
+		os.Exit(1)
+	}
+
+	client, err := rpc.Dial(*node)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot connect to %s: %v\n", *node, err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	result, err := electionDryRun(client, validatorArg, extra)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "election dry run failed: %v\n", err)
+		os.Exit(1)
+	}
+	out := bytes.Buffer{}
+	if err := json.Indent(&out, result, "", "  "); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot print the result: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(out.String())
+}
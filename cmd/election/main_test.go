@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	internal_common "github.com/harmony-one/harmony/internal/common"
+	"github.com/harmony-one/harmony/internal/hmyapi/apiv1"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/shard/committee"
+)
+
+// dryRunBackend is a beacon chain backend only running election dry runs
+type dryRunBackend struct {
+	apiv1.Backend
+	validator  *common.Address
+	extraStake *big.Int
+}
+
+func (b *dryRunBackend) GetShardID() uint32 {
+	return shard.BeaconChainShardID
+}
+
+func (b *dryRunBackend) GetElectionDryRun(
+	validator *common.Address, extraStake *big.Int,
+) (*committee.DryRunEPoSRound, error) {
+	b.validator, b.extraStake = validator, extraStake
+	return &committee.DryRunEPoSRound{
+		CompletedEPoSRound: &committee.CompletedEPoSRound{
+			MedianStake: numeric.ZeroDec(),
+		},
+		LowestWinningStake: numeric.OneDec(),
+	}, nil
+}
+
+func TestElectionDryRun(t *testing.T) {
+	backend := &dryRunBackend{}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName(
+		"hmy", apiv1.NewPublicBlockChainAPI(backend),
+	); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	validator := "one1pdv9lrdwl0rg5vglh4xtyrv3wjk3wsqket7zxy"
+	extra, _ := new(big.Int).SetString("1500000000000000000000", 10)
+	result, err := electionDryRun(client, &validator, extra)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if backend.extraStake == nil || backend.extraStake.Cmp(extra) != 0 {
+		t.Errorf("expected extra stake %v, got %v", extra, backend.extraStake)
+	}
+	if backend.validator == nil ||
+		*backend.validator != internal_common.ParseAddr(validator) {
+		t.Errorf("expected validator %s, got %v", validator, backend.validator)
+	}
+	round := map[string]interface{}{}
+	if err := json.Unmarshal(result, &round); err != nil {
+		t.Fatalf("cannot decode the result: %v", err)
+	}
+	if _, ok := round["lowest-winning-stake"]; !ok {
+		t.Errorf("expected the dry run round, got %s", result)
+	}
+
+	// without a validator the stake is still sent
+	if _, err := electionDryRun(client, nil, big.NewInt(0)); err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if backend.validator != nil || backend.extraStake.Sign() != 0 {
+		t.Errorf("expected no validator and no stake, got %v %v",
+			backend.validator, backend.extraStake)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/chain"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/node"
+	"github.com/pkg/errors"
+)
+
+// importBatchSize is how many blocks are inserted at once when importing
+// without verifying the signatures.
+const importBatchSize = 2500
+
+// Defaults of prune-state.
+const (
+	defaultPruneRetain    = 128
+	defaultPruneBloomSize = 2048 // in MB
+)
+
+const chainCommandUsage = `chain commands, working on the database of the chain of -shard_id in -db_dir kept in -db_backend:
+  harmony [flags] export <file> [first] [last]
+        write the blocks first..last (default: the whole chain) in RLP to file
+  harmony [flags] import [-verify_signatures=false] <file>
+        insert the blocks of a file written by export
+  harmony [flags] prune-state [-retain N] [-bloom_size MB]
+        delete the state not reachable from the latest N blocks, with the node stopped
+  harmony [flags] checkpoint <file> [block]
+        write the checkpoint of the block (default: the head block) of the beacon chain, for -checkpoint
+  harmony [flags] db convert <backend> <dir>
+        copy the database into a new one kept in backend (one of -db_backend) in dir
+  harmony [flags] db inspect
+        print the number and size of the keys of each kind of data
+  harmony [flags] db check
+        check the canonical chain is complete and consistent with its indices
+  harmony [flags] db repair
+        rebuild the transaction lookups and epoch block numbers from the canonical chain
+  harmony [flags] db get <hex key>
+  harmony [flags] db put <hex key> <hex value>
+        read or write a single key, with the node stopped
+  harmony [flags] replay [-as_recorder] <recording>
+        feed a recording written with -record_consensus to consensus on a chain in memory
+Files ending with .gz are gzip compressed.`
+
+// runChainCommand runs the chain command given after the flags instead of a node.
+func runChainCommand(args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "prune-state":
+		return runPruneState(args[1:])
+	case "checkpoint":
+		return runCheckpoint(args[1:])
+	case "db":
+		return runDBCommand(args[1:])
+	case "replay":
+		return runReplay(args[1:])
+	default:
+		return errors.Errorf("unknown command %#v\n%s", args[0], chainCommandUsage)
+	}
+}
+
+// openShardChain opens the chain of -shard_id in -db_dir, with its genesis
+// block written if the database is new.
+func openShardChain() (*core.BlockChain, error) {
+	if *shardID < 0 {
+		return nil, errors.New("-shard_id is required")
+	}
+	nodeconfig.SetNetworkType(nodeconfig.NetworkType(*networkType))
+	nodeconfig.GetDefaultConfig().ShardID = uint32(*shardID)
+	chainDBFactory, err := shardchain.NewDBFactory(*dbBackend, *dbDir, *ancientDir)
+	if err != nil {
+		return nil, err
+	}
+	chainNode := node.New(nil, nil, chainDBFactory, nil, *isArchival)
+	bc := chainNode.Blockchain()
+	if bc == nil {
+		return nil, errors.Errorf("cannot open the chain of shard %d in %#v", *shardID, *dbDir)
+	}
+	// inserted blocks are finalized, and their rewards read the beacon chain
+	chain.Engine.SetBeaconchain(chainNode.Beaconchain())
+	return bc, nil
+}
+
+func runExport(args []string) error {
+	if len(args) < 1 || len(args) > 3 {
+		return errors.Errorf("usage: export <file> [first] [last]\n%s", chainCommandUsage)
+	}
+	bc, err := openShardChain()
+	if err != nil {
+		return err
+	}
+	defer bc.Stop()
+
+	first, last := uint64(0), bc.CurrentBlock().NumberU64()
+	if len(args) > 1 {
+		if first, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+			return errors.Wrapf(err, "invalid first block %#v", args[1])
+		}
+	}
+	if len(args) > 2 {
+		if last, err = strconv.ParseUint(args[2], 10, 64); err != nil {
+			return errors.Wrapf(err, "invalid last block %#v", args[2])
+		}
+	}
+	return exportChain(bc, args[0], first, last)
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	verify := fs.Bool(
+		"verify_signatures", true,
+		"verify the commit signatures of the imported blocks; disable only for trusted files",
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.Errorf("usage: import [-verify_signatures=false] <file>\n%s", chainCommandUsage)
+	}
+	bc, err := openShardChain()
+	if err != nil {
+		return err
+	}
+	defer bc.Stop()
+	return importChain(bc, fs.Arg(0), *verify)
+}
+
+func runPruneState(args []string) error {
+	fs := flag.NewFlagSet("prune-state", flag.ContinueOnError)
+	retain := fs.Uint64("retain", defaultPruneRetain, "number of latest blocks whose state is kept")
+	bloomSize := fs.Uint64(
+		"bloom_size", defaultPruneBloomSize,
+		"size in MB of the bloom filter of the state to keep; a larger one leaves less garbage",
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || *retain == 0 {
+		return errors.Errorf(
+			"usage: prune-state [-retain N] [-bloom_size MB], N > 0\n%s", chainCommandUsage,
+		)
+	}
+	bc, err := openShardChain()
+	if err != nil {
+		return err
+	}
+	defer bc.Stop()
+
+	start := time.Now()
+	if err := core.PruneState(bc, *retain, *bloomSize<<20); err != nil {
+		return err
+	}
+	fmt.Printf("Pruned the state of shard %d in %v\n", bc.ShardID(), time.Since(start))
+	return nil
+}
+
+func runCheckpoint(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.Errorf("usage: checkpoint <file> [block]\n%s", chainCommandUsage)
+	}
+	bc, err := openShardChain()
+	if err != nil {
+		return err
+	}
+	defer bc.Stop()
+
+	number := bc.CurrentBlock().NumberU64()
+	if len(args) > 1 {
+		if number, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+			return errors.Wrapf(err, "invalid block %#v", args[1])
+		}
+	}
+	cp, err := core.NewCheckpoint(bc, number)
+	if err != nil {
+		return err
+	}
+	if err := core.WriteCheckpointFile(cp, args[0]); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the checkpoint of block %d %s of epoch %v with %d validator snapshots to %s\n",
+		cp.BlockNumber, cp.BlockHash.Hex(), cp.Epoch, len(cp.ValidatorSnapshots), args[0])
+	return nil
+}
+
+// exportChain writes the blocks first..last of the chain to the given file,
+// gzip compressed if its name ends with .gz.
+func exportChain(bc *core.BlockChain, fn string, first, last uint64) error {
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var writer io.Writer = fh
+	var gzWriter *gzip.Writer
+	if strings.HasSuffix(fn, ".gz") {
+		gzWriter = gzip.NewWriter(fh)
+		writer = gzWriter
+	}
+	start := time.Now()
+	if err := bc.ExportN(writer, first, last); err != nil {
+		return err
+	}
+	// closing flushes the end of the compressed stream
+	if gzWriter != nil {
+		if err := gzWriter.Close(); err != nil {
+			return err
+		}
+	}
+	if err := fh.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported blocks %d..%d of shard %d to %s in %v\n",
+		first, last, bc.ShardID(), fn, time.Since(start))
+	return nil
+}
+
+// importChain inserts the blocks of the given file, as written by exportChain,
+// skipping the ones already in the chain. When verify is set, the commit
+// signature over each block is verified, which needs its parent to be
+// inserted before, so the blocks are then inserted one by one.
+func importChain(bc *core.BlockChain, fn string, verify bool) error {
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = fh
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	stream := rlp.NewStream(reader, 0)
+
+	batchSize := importBatchSize
+	if verify {
+		batchSize = 1
+	}
+	start, imported := time.Now(), 0
+	blocks := make(types.Blocks, 0, batchSize)
+	insert := func() error {
+		if len(blocks) == 0 {
+			return nil
+		}
+		if _, err := bc.InsertChain(blocks, verify); err != nil {
+			return errors.Wrapf(err, "cannot insert block %d", blocks[0].NumberU64())
+		}
+		imported += len(blocks)
+		if imported%importBatchSize < len(blocks) {
+			utils.Logger().Info().
+				Int("imported", imported).
+				Uint64("blockNum", blocks[len(blocks)-1].NumberU64()).
+				Msg("Importing blocks")
+		}
+		blocks = blocks[:0]
+		return nil
+	}
+
+	for n := 0; ; n++ {
+		var blk types.Block
+		if err := stream.Decode(&blk); err == io.EOF {
+			break
+		} else if err != nil {
+			return errors.Wrapf(err, "cannot decode block #%d of %s", n, fn)
+		}
+		// the genesis block and the blocks already imported are skipped
+		if blk.NumberU64() == 0 || bc.HasBlock(blk.Hash(), blk.NumberU64()) {
+			continue
+		}
+		blocks = append(blocks, &blk)
+		if len(blocks) == batchSize {
+			if err := insert(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := insert(); err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d blocks into shard %d from %s in %v, head is now block %d\n",
+		imported, bc.ShardID(), fn, time.Since(start), bc.CurrentBlock().NumberU64())
+	return nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/chain"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	shardingconfig "github.com/harmony-one/harmony/internal/configs/sharding"
+	"github.com/harmony-one/harmony/internal/genesis"
+	"github.com/harmony-one/harmony/node/worker"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard"
+)
+
+// testSchedule is the localnet schedule with a genesis committee of generated keys
+type testSchedule struct {
+	shardingconfig.Schedule
+	instance shardingconfig.Instance
+}
+
+func (s testSchedule) InstanceForEpoch(epoch *big.Int) shardingconfig.Instance {
+	return s.instance
+}
+
+// useTestCommittee makes the given key the genesis committee of each of two
+// shards, on the mainnet genesis
+func useTestCommittee(t *testing.T, key *bls.SecretKey) {
+	accounts := []genesis.DeployAccount{}
+	for i := 0; i < 2; i++ {
+		accounts = append(accounts, genesis.DeployAccount{
+			Index:        strconv.Itoa(i),
+			Address:      common.BytesToAddress(key.GetPublicKey().Serialize()).Hex(),
+			BLSPublicKey: key.GetPublicKey().SerializeToHexStr(),
+		})
+	}
+	schedule := shardingconfig.LocalnetSchedule
+	instance, err := shardingconfig.NewInstance(
+		2, 1, 1, numeric.OneDec(), accounts, nil,
+		[]*big.Int{big.NewInt(0)}, schedule.BlocksPerEpoch(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousSchedule := shard.Schedule
+	shard.Schedule = testSchedule{Schedule: schedule, instance: instance}
+	t.Cleanup(func() { shard.Schedule = previousSchedule })
+}
+
+// useChainFlags sets the flags openShardChain reads, for a chain of the given
+// shard in dir
+func useChainFlags(t *testing.T, shard int, dir string) {
+	previousShardID, previousDBDir, previousNetworkType := *shardID, *dbDir, *networkType
+	previousNodeShardID := nodeconfig.GetDefaultConfig().ShardID
+	previousNodeNetworkType := nodeconfig.GetDefaultConfig().GetNetworkType()
+	*shardID, *dbDir, *networkType = shard, dir, string(nodeconfig.Mainnet)
+	t.Cleanup(func() {
+		*shardID, *dbDir, *networkType = previousShardID, previousDBDir, previousNetworkType
+		nodeconfig.GetDefaultConfig().ShardID = previousNodeShardID
+		nodeconfig.SetNetworkType(previousNodeNetworkType)
+	})
+}
+
+// addSignedBlocks proposes n empty blocks on the chain, each one carrying the
+// commit signature of its parent by the committee key. On the beacon chain,
+// the last block of an epoch carries the committees of the next one.
+func addSignedBlocks(t *testing.T, bc *core.BlockChain, key *bls.SecretKey, n int) {
+	mask, err := bls_cosi.NewMask([]*bls.PublicKey{key.GetPublicKey()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mask.SetKey(key.GetPublicKey(), true); err != nil {
+		t.Fatal(err)
+	}
+	proposer := worker.New(bc.Config(), bc, chain.Engine)
+	var sig []byte
+	for i := 0; i < n; i++ {
+		if err := proposer.UpdateCurrent(); err != nil {
+			t.Fatal(err)
+		}
+		signers := mask.Bitmap
+		if sig == nil {
+			// the genesis block is not signed
+			signers = nil
+		}
+		var shardState *shard.State
+		if header := proposer.GetCurrentHeader(); bc.ShardID() == shard.BeaconChainShardID &&
+			shard.Schedule.IsLastBlock(header.Number().Uint64()) {
+			if shardState, err = bc.SuperCommitteeForNextEpoch(bc, header, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		blk, err := proposer.FinalizeNewBlock(sig, signers, uint64(i+1), common.Address{}, nil, shardState)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := bc.InsertChain(types.Blocks{blk}, true); err != nil {
+			t.Fatalf("cannot insert block %d: %v", blk.NumberU64(), err)
+		}
+		commitPayload := make([]byte, 8)
+		binary.LittleEndian.PutUint64(commitPayload, blk.NumberU64())
+		hash := blk.Hash()
+		sig = key.SignHash(append(commitPayload, hash[:]...)).Serialize()
+	}
+}
+
+func TestExportImportShardChain(t *testing.T) {
+	const numBlocks = 4
+	key := bls_cosi.RandPrivateKey()
+	useTestCommittee(t, key)
+	dir, err := ioutil.TempDir("", "chaincmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	useChainFlags(t, 1, filepath.Join(dir, "source"))
+	source, err := openShardChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addSignedBlocks(t, source, key, numBlocks)
+	head := source.CurrentBlock()
+	fn := filepath.Join(dir, "blocks.rlp.gz")
+	if err := exportChain(source, fn, 0, head.NumberU64()); err != nil {
+		t.Fatalf("cannot export: %v", err)
+	}
+	source.Stop()
+
+	useChainFlags(t, 1, filepath.Join(dir, "target"))
+	target, err := openShardChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Stop()
+	if err := importChain(target, fn, true); err != nil {
+		t.Fatalf("cannot import: %v", err)
+	}
+	if imported := target.CurrentBlock(); imported.Hash() != head.Hash() {
+		t.Errorf("imported head %d is not the exported one %d", imported.NumberU64(), head.NumberU64())
+	}
+	// importing again skips the blocks already in the chain
+	if err := importChain(target, fn, true); err != nil {
+		t.Errorf("cannot import again: %v", err)
+	}
+}
+
+// copyState writes the state trie of the given root in src to dst.
+func copyState(t *testing.T, dst, src ethdb.Database, root common.Hash) {
+	sched := state.NewStateSync(root, dst)
+	for missing := sched.Missing(0); len(missing) > 0; missing = sched.Missing(0) {
+		results := make([]trie.SyncResult, len(missing))
+		for i, hash := range missing {
+			data, err := src.Get(hash[:])
+			if err != nil {
+				t.Fatalf("state node %x missing: %v", hash, err)
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("cannot process state node %x: %v", results[index].Hash, err)
+		}
+		if _, err := sched.Commit(dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestResetWithCheckpoint(t *testing.T) {
+	key := bls_cosi.RandPrivateKey()
+	useTestCommittee(t, key)
+	dir, err := ioutil.TempDir("", "chaincmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// keep the state of every block, the one of the checkpoint is copied
+	previousArchival := *isArchival
+	*isArchival = true
+	defer func() { *isArchival = previousArchival }()
+
+	useChainFlags(t, int(shard.BeaconChainShardID), filepath.Join(dir, "source"))
+	source, err := openShardChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the checkpoint ends epoch 1, and the blocks after it start epoch 2
+	number := shard.Schedule.EpochLastBlock(1)
+	addSignedBlocks(t, source, key, int(number)+3)
+	cp, err := core.NewCheckpoint(source, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join(dir, "checkpoint")
+	if err := core.WriteCheckpointFile(cp, fn); err != nil {
+		t.Fatal(err)
+	}
+	if cp, err = core.ReadCheckpointFile(fn); err != nil {
+		t.Fatal(err)
+	}
+	checkpointBlock := source.GetBlockByNumber(number)
+	nextBlocks := []*types.Block{}
+	for n := number + 1; n <= source.CurrentBlock().NumberU64(); n++ {
+		nextBlocks = append(nextBlocks, source.GetBlockByNumber(n))
+	}
+	sourceDB := source.ChainDb()
+	source.Stop()
+
+	useChainFlags(t, int(shard.BeaconChainShardID), filepath.Join(dir, "target"))
+	target, err := openShardChain()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Stop()
+	copyState(t, target.ChainDb(), sourceDB, checkpointBlock.Root())
+	if err := target.ResetWithCheckpoint(cp, checkpointBlock); err != nil {
+		t.Fatal(err)
+	}
+	// one at a time, as the headers of a batch are verified against the chain
+	for _, blk := range nextBlocks {
+		if _, err := target.InsertChain(types.Blocks{blk}, true); err != nil {
+			t.Fatalf("cannot insert block %d after the checkpoint: %v", blk.NumberU64(), err)
+		}
+	}
+	if head := target.CurrentBlock(); head.Hash() != nextBlocks[len(nextBlocks)-1].Hash() {
+		t.Errorf("head block %d is not the last inserted one", head.NumberU64())
+	}
+	if epochBlock, err := rawdb.ReadEpochBlockNumber(target.ChainDb(), big.NewInt(1)); err != nil ||
+		epochBlock.Uint64() != shard.Schedule.EpochLastBlock(0)+1 {
+		t.Errorf("first block of epoch 1 mismatch: have %v (%v)", epochBlock, err)
+	}
+	if _, err := target.ReadShardState(big.NewInt(0)); err != nil {
+		t.Errorf("shard state of the epoch before the checkpoint missing: %v", err)
+	}
+	if _, err := rawdb.ReadBlockRewardAccumulator(target.ChainDb(), number); err != nil {
+		t.Errorf("block reward accumulator of the checkpoint missing: %v", err)
+	}
+}
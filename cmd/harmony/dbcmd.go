@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// maxCheckDetails is how many of the inconsistencies found by db check are
+// described.
+const maxCheckDetails = 100
+
+// runDBCommand runs the db command given after the flags, which works on the
+// database of the chain without opening the chain, so that a corrupted
+// database can be looked into.
+func runDBCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.Errorf("missing db command\n%s", chainCommandUsage)
+	}
+	switch args[0] {
+	case "convert":
+		return runConvertDB(args[1:])
+	case "inspect":
+		return runInspectDB(args[1:])
+	case "check":
+		return runCheckDB(args[1:])
+	case "repair":
+		return runRepairDB(args[1:])
+	case "get":
+		return runGetDB(args[1:])
+	case "put":
+		return runPutDB(args[1:])
+	default:
+		return errors.Errorf("unknown db command %#v\n%s", args[0], chainCommandUsage)
+	}
+}
+
+// openShardDB opens the database of the chain of -shard_id in -db_dir, kept in
+// -db_backend, with its ancient store in -ancient_dir if withAncient is set.
+// No blocks are moved to the ancient store while the database is open.
+func openShardDB(withAncient bool) (ethdb.Database, error) {
+	if *shardID < 0 {
+		return nil, errors.New("-shard_id is required")
+	}
+	name := shardchain.ChainDBName(uint32(*shardID))
+	if _, err := os.Stat(path.Join(*dbDir, name)); err != nil {
+		return nil, errors.Wrapf(err, "no database of shard %d in %#v", *shardID, *dbDir)
+	}
+	factory, err := shardchain.NewDBFactory(*dbBackend, *dbDir, "")
+	if err != nil {
+		return nil, err
+	}
+	db, err := factory.NewChainDB(uint32(*shardID))
+	if err != nil || !withAncient || *ancientDir == "" {
+		return db, err
+	}
+	ancientDB, err := rawdb.NewDatabaseWithFreezer(db, path.Join(*ancientDir, name), false)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ancientDB, nil
+}
+
+// keyValueStore returns the key-value database of db, which must be iterable.
+func keyValueStore(db ethdb.Database) (rawdb.IterableDatabase, error) {
+	idb, ok := rawdb.KeyValueStore(db).(rawdb.IterableDatabase)
+	if !ok {
+		return nil, errors.Errorf("%s database cannot be iterated over", *dbBackend)
+	}
+	return idb, nil
+}
+
+func runConvertDB(args []string) error {
+	if len(args) != 2 {
+		return errors.Errorf("usage: db convert <backend> <dir>\n%s", chainCommandUsage)
+	}
+	backend, rootDir := args[0], args[1]
+	dstFactory, err := shardchain.NewDBFactory(backend, rootDir, "")
+	if err != nil {
+		return err
+	}
+	dstDir := path.Join(rootDir, shardchain.ChainDBName(uint32(*shardID)))
+	if files, err := ioutil.ReadDir(dstDir); err == nil && len(files) > 0 {
+		return errors.Errorf("%s is not empty", dstDir)
+	}
+
+	// without the ancient store, which would move blocks out of it meanwhile
+	srcDB, err := openShardDB(false)
+	if err != nil {
+		return err
+	}
+	defer srcDB.Close()
+	src, err := keyValueStore(srcDB)
+	if err != nil {
+		return err
+	}
+	dst, err := dstFactory.NewChainDB(uint32(*shardID))
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	start := time.Now()
+	keys, size, err := copyDB(dst, src)
+	if err != nil {
+		return errors.Wrapf(err, "cannot copy the database to %s", dstDir)
+	}
+	fmt.Printf(
+		"Converted the database of shard %d to %s in %s: %d keys, %s in %v\n",
+		*shardID, backend, dstDir, keys, size, time.Since(start),
+	)
+	if *ancientDir != "" {
+		fmt.Printf("The ancient store in %s is used as is with the new database\n", *ancientDir)
+	}
+	return nil
+}
+
+// copyDB writes all the keys of src to dst, returning how many keys and how
+// much data were copied.
+func copyDB(dst ethdb.Database, src rawdb.IterableDatabase) (int, common.StorageSize, error) {
+	keys, size := 0, common.StorageSize(0)
+	batch := dst.NewBatch()
+	it := src.NewIterator()
+	defer it.Release()
+	lastLog := time.Now()
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return keys, size, err
+		}
+		keys++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+		if batch.ValueSize() < ethdb.IdealBatchSize {
+			continue
+		}
+		if err := batch.Write(); err != nil {
+			return keys, size, err
+		}
+		batch.Reset()
+		if time.Since(lastLog) > 8*time.Second {
+			utils.Logger().Info().
+				Int("keys", keys).
+				Str("size", size.String()).
+				Msg("Copying the database")
+			lastLog = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return keys, size, err
+	}
+	return keys, size, batch.Write()
+}
+
+func runInspectDB(args []string) error {
+	if len(args) != 0 {
+		return errors.Errorf("usage: db inspect\n%s", chainCommandUsage)
+	}
+	db, err := openShardDB(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	kvStore, err := keyValueStore(db)
+	if err != nil {
+		return err
+	}
+	stats, err := rawdb.InspectDatabase(kvStore, func(keys int) {
+		utils.Logger().Info().Int("keys", keys).Msg("Inspecting the database")
+	})
+	if err != nil {
+		return err
+	}
+	count, size := 0, common.StorageSize(0)
+	fmt.Printf("%-30s %12s %12s\n", "Data", "Keys", "Size")
+	for _, stat := range stats {
+		fmt.Printf("%-30s %12d %12s\n", stat.Name, stat.Count, stat.Size)
+		count += stat.Count
+		size += stat.Size
+	}
+	fmt.Printf("%-30s %12d %12s\n", "Total", count, size)
+	if ancients, ok := db.(rawdb.AncientReader); ok {
+		frozen, _ := ancients.Ancients()
+		fmt.Printf("Ancient store: %d blocks\n", frozen)
+	}
+	return nil
+}
+
+func runCheckDB(args []string) error {
+	if len(args) != 0 {
+		return errors.Errorf("usage: db check\n%s", chainCommandUsage)
+	}
+	db, err := openShardDB(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := core.CheckChainDB(db, uint32(*shardID), maxCheckDetails)
+	if err != nil {
+		return err
+	}
+	for _, detail := range report.Details {
+		fmt.Println(detail)
+	}
+	if report.Problems > len(report.Details) {
+		fmt.Printf("... and %d more\n", report.Problems-len(report.Details))
+	}
+	fmt.Printf("Checked blocks %d..%d of shard %d: %d problems\n", report.First, report.Head, *shardID, report.Problems)
+	if report.UnindexedEpochs > 0 {
+		fmt.Printf("The first blocks of %d epochs are not indexed, db repair indexes them\n", report.UnindexedEpochs)
+	}
+	if report.Problems > 0 {
+		return errors.Errorf("the database of shard %d is inconsistent", *shardID)
+	}
+	return nil
+}
+
+func runRepairDB(args []string) error {
+	if len(args) != 0 {
+		return errors.Errorf("usage: db repair\n%s", chainCommandUsage)
+	}
+	db, err := openShardDB(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	start := time.Now()
+	blocks, err := core.RepairChainIndices(db)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Rebuilt the transaction lookups and epoch block numbers of %d blocks of shard %d in %v\n",
+		blocks, *shardID, time.Since(start))
+	return nil
+}
+
+func runGetDB(args []string) error {
+	if len(args) != 1 {
+		return errors.Errorf("usage: db get <hex key>\n%s", chainCommandUsage)
+	}
+	key, err := hexutil.Decode(args[0])
+	if err != nil {
+		return errors.Wrapf(err, "invalid key %#v", args[0])
+	}
+	db, err := openShardDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	value, err := db.Get(key)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get %s", args[0])
+	}
+	fmt.Println(hexutil.Encode(value))
+	return nil
+}
+
+func runPutDB(args []string) error {
+	if len(args) != 2 {
+		return errors.Errorf("usage: db put <hex key> <hex value>\n%s", chainCommandUsage)
+	}
+	key, err := hexutil.Decode(args[0])
+	if err != nil {
+		return errors.Wrapf(err, "invalid key %#v", args[0])
+	}
+	value, err := hexutil.Decode(args[1])
+	if err != nil {
+		return errors.Wrapf(err, "invalid value %#v", args[1])
+	}
+	db, err := openShardDB(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if old, err := db.Get(key); err == nil {
+		fmt.Printf("Previous value: %s\n", hexutil.Encode(old))
+	}
+	return db.Put(key, value)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/harmony-one/harmony/internal/shardchain"
+)
+
+func TestConvertDB(t *testing.T) {
+	// more keys than a single transaction of the target takes
+	const numKeys = 300000
+	dir, err := ioutil.TempDir("", "dbcmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	previousBackend, previousAncientDir := *dbBackend, *ancientDir
+	*dbBackend, *ancientDir = shardchain.LDBBackend, ""
+	defer func() { *dbBackend, *ancientDir = previousBackend, previousAncientDir }()
+	useChainFlags(t, 1, filepath.Join(dir, "leveldb"))
+
+	factory, err := shardchain.NewDBFactory(shardchain.LDBBackend, *dbDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := factory.NewChainDB(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := src.NewBatch()
+	for i := 0; i < numKeys; i++ {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, uint32(i))
+		batch.Put(key, key[3:])
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	src.Close()
+
+	badgerDir := filepath.Join(dir, "badger")
+	if err := runConvertDB([]string{shardchain.BadgerBackend, badgerDir}); err != nil {
+		t.Fatalf("cannot convert: %v", err)
+	}
+	if err := runConvertDB([]string{shardchain.BadgerBackend, badgerDir}); err == nil {
+		t.Error("converted into a database not empty")
+	}
+
+	factory, err = shardchain.NewDBFactory(shardchain.BadgerBackend, badgerDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := factory.NewChainDB(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	it := dst.(*shardchain.BadgerDatabase).NewIterator()
+	defer it.Release()
+	count := 0
+	for ; it.Next(); count++ {
+		key := make([]byte, 4)
+		binary.BigEndian.PutUint32(key, uint32(count))
+		if !bytes.Equal(it.Key(), key) || !bytes.Equal(it.Value(), key[3:]) {
+			t.Fatalf("key %d mismatch: have %x=%x", count, it.Key(), it.Value())
+		}
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if count != numKeys {
+		t.Errorf("converted keys mismatch: have %d, want %d", count, numKeys)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"syscall"
+
+	"github.com/harmony-one/harmony/core"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/node"
+)
+
+// lightDBDir is the directory of the database of a light node in -db_dir, apart
+// from the database of a full node.
+const lightDBDir = "light"
+
+// runLightNode runs a light node of the beacon chain until it is shut down.
+func runLightNode(nodeConfig *nodeconfig.ConfigType) {
+	chainDBFactory, err := shardchain.NewDBFactory(*dbBackend, path.Join(nodeConfig.DBDir, lightDBDir), "")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot set up the light chain database: %v\n", err)
+		os.Exit(1)
+	}
+	lightNode, err := node.NewLightNode(myHost, chainDBFactory)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot set up the light node: %v\n", err)
+		os.Exit(1)
+	}
+	lightNode.NodeConfig.StreamSync = *streamSync
+	lightNode.SyncingPeerProvider = newSyncingPeerProvider()
+	if lightNode.SyncingPeerProvider == nil {
+		// light nodes have no neighbors, so they sync over streams by default
+		lightNode.SyncingPeerProvider = node.NewStreamSyncingPeerProvider(myHost)
+		lightNode.NodeConfig.StreamSync = true
+	}
+	if *checkpointFile != "" {
+		cp, err := core.ReadCheckpointFile(*checkpointFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot read the beacon chain checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+		lightNode.Checkpoint = cp
+	}
+
+	// Prepare for graceful shutdown from os signals
+	osSignal := make(chan os.Signal, 1)
+	signal.Notify(osSignal, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range osSignal {
+			if sig == syscall.SIGTERM || sig == os.Interrupt {
+				const msg = "Got %s signal. Gracefully shutting down...\n"
+				utils.Logger().Printf(msg, sig)
+				fmt.Printf(msg, sig)
+				lightNode.ShutDown()
+			}
+		}
+	}()
+
+	head := lightNode.Chain().CurrentHeader()
+	utils.Logger().Info().
+		Uint64("blockNum", head.Number().Uint64()).
+		Str("blockHash", head.Hash().Hex()).
+		Bool("streamSync", lightNode.NodeConfig.StreamSync).
+		Str("multiaddress",
+			fmt.Sprintf("/ip4/%s/tcp/%s/p2p/%s", *ip, *port, myHost.GetID().Pretty()),
+		).
+		Msg("==== New Light Node ====")
+
+	if err := lightNode.Start(*port); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot start the light node: %v\n", err)
+		os.Exit(1)
+	}
+	select {}
+}
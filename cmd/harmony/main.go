@@ -81,10 +81,20 @@ var (
 	keyFile = flag.String("key", "./.hmykey", "the p2p key file of the harmony node")
 	// isArchival indicates this node is an archival node that will save and archive current blockchain
 	isArchival = flag.Bool("is_archival", false, "false will enable cached state pruning")
+	// fastSync makes a new shard node download the state of a recent block instead of executing all blocks
+	fastSync = flag.Bool("fast_sync", false, "sync a new non-beacon shard node from the state of a recent block downloaded from peers instead of executing all blocks from genesis; ignored by archival nodes")
+	// checkpointFile is the trusted beacon chain checkpoint a new node syncs the beacon chain from
+	checkpointFile = flag.String("checkpoint", "", "if not empty, sync a new beacon chain forward from the trusted checkpoint in this file, written by the checkpoint command, instead of from genesis; ignored by archival nodes")
+	// streamSync makes the node sync from the peers found through libp2p over streams instead of gRPC
+	streamSync = flag.Bool("stream_sync", false, "sync from the peers found through libp2p peer discovery over libp2p streams instead of from the gRPC syncing port of peers")
 	// delayCommit is the commit-delay timer, used by Harmony nodes
 	delayCommit = flag.String("delay_commit", "0ms", "how long to delay sending commit messages in consensus, ex: 500ms, 1s")
-	// nodeType indicates the type of the node: validator, explorer
-	nodeType = flag.String("node_type", "validator", "node type: validator, explorer")
+	// voteAggregationFanout is the group size of the consensus vote aggregation overlay
+	voteAggregationFanout = flag.Int("vote_aggregation_fanout", 0, "group size for aggregating votes before they reach the leader, must be the same on all nodes of the shard; 0 disables (default: 0)")
+	// recordConsensus is the file recording the inputs of consensus for replay
+	recordConsensus = flag.String("record_consensus", "", "file to append consensus messages and timeouts to, for replay with the replay command; empty disables (default: \"\")")
+	// nodeType indicates the type of the node: validator, explorer, light
+	nodeType = flag.String("node_type", "validator", "node type: validator, explorer, light")
 	// networkType indicates the type of the network
 	networkType = flag.String("network_type", "mainnet", "type of the network: mainnet, testnet, pangaea, partner, stressnet, devnet, localnet")
 	// blockPeriod indicates the how long the leader waits to propose a new block.
@@ -99,6 +109,7 @@ var (
 	blsPass            = flag.String("blspass", "", "The file containing passphrase to decrypt the encrypted bls file.")
 	blsPassphrase      string
 	maxBLSKeysPerNode  = flag.Int("max_bls_keys_per_node", 4, "maximum number of bls keys allowed per node (default 4)")
+	blsReloadInterval  = flag.String("bls_reload_interval", "1m", "how often to load the bls keys newly added to blsfolder, such as a rotated key, without restarting; 0 disables")
 	// Sharding configuration parameters for devnet
 	devnetNumShards   = flag.Uint("dn_num_shards", 2, "number of shards for -network_type=devnet (default: 2)")
 	devnetShardSize   = flag.Int("dn_shard_size", 10, "number of nodes per shard for -network_type=devnet (default 10)")
@@ -109,7 +120,11 @@ var (
 	// logging verbosity
 	verbosity = flag.Int("verbosity", 5, "Logging verbosity: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail (default: 5)")
 	// dbDir is the database directory.
-	dbDir     = flag.String("db_dir", "", "blockchain database directory")
+	dbDir = flag.String("db_dir", "", "blockchain database directory")
+	// ancientDir is the directory of the ancient stores of the blockchain databases.
+	ancientDir = flag.String("ancient_dir", "", "if not empty, keep the data of the blocks final for long in flat files in this directory instead of in the blockchain database, moving the old blocks of an existing database there in the background")
+	// dbBackend is the storage engine of the blockchain databases.
+	dbBackend = flag.String("db_backend", shardchain.LDBBackend, "storage engine of the blockchain databases: "+strings.Join(shardchain.DBBackends, ", "))
 	publicRPC = flag.Bool("public_rpc", false, "Enable Public RPC Access (default: false)")
 	// Bad block revert
 	doRevertBefore = flag.Int("do_revert_before", 0, "If the current block is less than do_revert_before, revert all blocks until (including) revert_to block")
@@ -291,12 +306,7 @@ func readMultiBLSKeys(consensusMultiBLSPriKey *multibls.PrivateKey, consensusMul
 		}
 		return nil
 	}); err != nil {
-		fmt.Fprintf(os.Stderr,
-			"[Multi-BLS] ERROR when reading blskey file under %s: %v\n",
-			*blsFolder,
-			err,
-		)
-		os.Exit(100)
+		return errors.Wrapf(err, "cannot read blskey file under %s", *blsFolder)
 	}
 
 	keyFiles := []os.FileInfo{}
@@ -310,12 +320,11 @@ func readMultiBLSKeys(consensusMultiBLSPriKey *multibls.PrivateKey, consensusMul
 	}
 
 	if len(keyFiles) > *maxBLSKeysPerNode {
-		fmt.Fprintf(os.Stderr,
-			"[Multi-BLS] maximum number of bls keys per node is %d, found: %d\n",
+		return errors.Errorf(
+			"maximum number of bls keys per node is %d, found: %d",
 			*maxBLSKeysPerNode,
 			len(keyFiles),
 		)
-		os.Exit(100)
 	}
 
 	for _, blsKeyFile := range keyFiles {
@@ -326,10 +335,11 @@ func readMultiBLSKeys(consensusMultiBLSPriKey *multibls.PrivateKey, consensusMul
 			fullName := blsKeyFile.Name()
 			ext := filepath.Ext(fullName)
 			name := fullName[:len(fullName)-len(ext)]
+			passphrase := blsPassphrase
 			if val, ok := keyPasses[name]; ok {
-				blsPassphrase = val
+				passphrase = val
 			}
-			consensusPriKey, err = blsgen.LoadBLSKeyWithPassPhrase(blsKeyFilePath, blsPassphrase)
+			consensusPriKey, err = blsgen.LoadBLSKeyWithPassPhrase(blsKeyFilePath, passphrase)
 		} else {
 			consensusPriKey, err = blsgen.LoadAwsCMKEncryptedBLSKey(blsKeyFilePath, awsSettingString)
 		}
@@ -344,6 +354,68 @@ func readMultiBLSKeys(consensusMultiBLSPriKey *multibls.PrivateKey, consensusMul
 	return nil
 }
 
+// blsFolderFingerprint changes whenever a file of blsfolder is added, removed or modified
+func blsFolderFingerprint() (string, error) {
+	var builder strings.Builder
+	err := filepath.Walk(*blsFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&builder, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return builder.String(), err
+}
+
+// watchBLSFolder reloads the bls keys of blsfolder whenever it changes while
+// the node runs, so that a validator rotating its slot key needs no restart
+func watchBLSFolder(currentNode *node.Node, interval time.Duration) {
+	currentConsensus := currentNode.Consensus
+	lastFingerprint, _ := blsFolderFingerprint()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		fingerprint, err := blsFolderFingerprint()
+		if err != nil || fingerprint == lastFingerprint {
+			continue
+		}
+		consensusMultiPriKey := &multibls.PrivateKey{}
+		consensusMultiPubKey := &multibls.PublicKey{}
+		if err := readMultiBLSKeys(consensusMultiPriKey, consensusMultiPubKey); err != nil {
+			utils.Logger().Warn().Err(err).Msg("[Multi-BLS] cannot reload bls keys")
+			continue
+		}
+		if n := len(consensusMultiPriKey.PrivateKey); n == 0 || n > *maxBLSKeysPerNode {
+			utils.Logger().Warn().
+				Int("keys", n).
+				Int("maxBLSKeysPerNode", *maxBLSKeysPerNode).
+				Msg("[Multi-BLS] cannot reload bls keys, keeping the current ones")
+			continue
+		}
+		if err := nodeconfig.GetDefaultConfig().ValidateConsensusKeysForSameShard(
+			consensusMultiPubKey.PublicKey, currentConsensus.ShardID,
+		); err != nil {
+			utils.Logger().Warn().Err(err).Msg("[Multi-BLS] cannot reload bls keys")
+			continue
+		}
+		lastFingerprint = fingerprint
+		added, removed := currentConsensus.SetPrivateKeys(consensusMultiPriKey)
+		for _, key := range added {
+			utils.Logger().Info().
+				Str("publicKey", key.SerializeToHexStr()).
+				Msg("[Multi-BLS] loaded new bls key")
+		}
+		for _, key := range removed {
+			utils.Logger().Info().
+				Str("publicKey", key.SerializeToHexStr()).
+				Msg("[Multi-BLS] dropped bls key")
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			currentNode.ReceiveAggregatorGroups()
+		}
+	}
+}
+
 func setupConsensusKey(nodeConfig *nodeconfig.ConfigType) multibls.PublicKey {
 	consensusMultiPriKey := &multibls.PrivateKey{}
 	consensusMultiPubKey := &multibls.PublicKey{}
@@ -448,7 +520,7 @@ func setupConsensusAndNode(nodeConfig *nodeconfig.ConfigType) *node.Node {
 		myHost, nodeConfig.ShardID, p2p.Peer{}, nodeConfig.ConsensusPriKey, decider,
 	)
 	currentConsensus.Decider.SetMyPublicKeyProvider(func() (*multibls.PublicKey, error) {
-		return currentConsensus.PubKey, nil
+		return currentConsensus.GetPublicKeys(), nil
 	})
 
 	// staking validator doesn't have to specify ECDSA address
@@ -467,6 +539,15 @@ func setupConsensusAndNode(nodeConfig *nodeconfig.ConfigType) *node.Node {
 		os.Exit(1)
 	}
 	currentConsensus.SetCommitDelay(commitDelay)
+	currentConsensus.SetVoteAggregation(*voteAggregationFanout)
+	if *recordConsensus != "" {
+		recorder, err := consensus.NewEventRecorder(*recordConsensus)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot record consensus: %v\n", err)
+			os.Exit(1)
+		}
+		currentConsensus.SetEventRecorder(recorder)
+	}
 	currentConsensus.MinPeers = *minPeers
 
 	blacklist, err := setupBlacklist()
@@ -475,34 +556,32 @@ func setupConsensusAndNode(nodeConfig *nodeconfig.ConfigType) *node.Node {
 	}
 
 	// Current node.
-	chainDBFactory := &shardchain.LDBFactory{RootDir: nodeConfig.DBDir}
+	chainDBFactory, err := shardchain.NewDBFactory(*dbBackend, nodeConfig.DBDir, *ancientDir)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot set up the blockchain database: %v\n", err)
+		os.Exit(1)
+	}
 
 	currentNode := node.New(myHost, currentConsensus, chainDBFactory, blacklist, *isArchival)
 
-	switch {
-	case *networkType == nodeconfig.Localnet:
-		epochConfig := shard.Schedule.InstanceForEpoch(ethCommon.Big0)
-		selfPort, err := strconv.ParseUint(*port, 10, 16)
-		if err != nil {
-			utils.Logger().Fatal().
-				Err(err).
-				Str("self_port_string", *port).
-				Msg("cannot convert self port string into port number")
-		}
-		currentNode.SyncingPeerProvider = node.NewLocalSyncingPeerProvider(
-			6000, uint16(selfPort), epochConfig.NumShards(), uint32(epochConfig.NumNodesPerShard()))
-	case *dnsZone != "":
-		currentNode.SyncingPeerProvider = node.NewDNSSyncingPeerProvider(*dnsZone, syncing.GetSyncingPort(*port))
-	case *dnsFlag:
-		currentNode.SyncingPeerProvider = node.NewDNSSyncingPeerProvider("t.hmny.io", syncing.GetSyncingPort(*port))
-	default:
+	currentNode.SyncingPeerProvider = newSyncingPeerProvider()
+	if currentNode.SyncingPeerProvider == nil {
 		currentNode.SyncingPeerProvider = node.NewLegacySyncingPeerProvider(currentNode)
-
 	}
 
 	// TODO: refactor the creation of blockchain out of node.New()
 	currentConsensus.ChainReader = currentNode.Blockchain()
 	currentNode.NodeConfig.DNSZone = *dnsZone
+	currentNode.NodeConfig.FastSync = *fastSync && !*isArchival
+	currentNode.NodeConfig.StreamSync = *streamSync
+	if *checkpointFile != "" && !*isArchival {
+		cp, err := core.ReadCheckpointFile(*checkpointFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "ERROR cannot read the beacon chain checkpoint: %v\n", err)
+			os.Exit(1)
+		}
+		currentNode.BeaconCheckpoint = cp
+	}
 
 	currentNode.NodeConfig.SetBeaconGroupID(
 		nodeconfig.NewGroupIDByShardID(shard.BeaconChainShardID),
@@ -562,6 +641,32 @@ func setupConsensusAndNode(nodeConfig *nodeconfig.ConfigType) *node.Node {
 	return currentNode
 }
 
+// newSyncingPeerProvider returns the provider of the syncing peers set by the
+// flags, or nil if the neighbors of the node are synced from.
+func newSyncingPeerProvider() node.SyncingPeerProvider {
+	switch {
+	case *networkType == nodeconfig.Localnet:
+		epochConfig := shard.Schedule.InstanceForEpoch(ethCommon.Big0)
+		selfPort, err := strconv.ParseUint(*port, 10, 16)
+		if err != nil {
+			utils.Logger().Fatal().
+				Err(err).
+				Str("self_port_string", *port).
+				Msg("cannot convert self port string into port number")
+		}
+		return node.NewLocalSyncingPeerProvider(
+			6000, uint16(selfPort), epochConfig.NumShards(), uint32(epochConfig.NumNodesPerShard()))
+	case *streamSync:
+		return node.NewStreamSyncingPeerProvider(myHost)
+	case *dnsZone != "":
+		return node.NewDNSSyncingPeerProvider(*dnsZone, syncing.GetSyncingPort(*port))
+	case *dnsFlag:
+		return node.NewDNSSyncingPeerProvider("t.hmny.io", syncing.GetSyncingPort(*port))
+	default:
+		return nil
+	}
+}
+
 func setupBlacklist() (map[ethCommon.Address]struct{}, error) {
 	utils.Logger().Debug().Msgf("Using blacklist file at `%s`", *blacklistPath)
 	dat, err := ioutil.ReadFile(*blacklistPath)
@@ -600,7 +705,12 @@ func setupViperConfig() {
 	viperconfig.ResetConfInt(minPeers, envViper, configFileViper, "", "min_peers")
 	viperconfig.ResetConfString(keyFile, envViper, configFileViper, "", "key")
 	viperconfig.ResetConfBool(isArchival, envViper, configFileViper, "", "is_archival")
+	viperconfig.ResetConfBool(fastSync, envViper, configFileViper, "", "fast_sync")
+	viperconfig.ResetConfString(checkpointFile, envViper, configFileViper, "", "checkpoint")
+	viperconfig.ResetConfBool(streamSync, envViper, configFileViper, "", "stream_sync")
 	viperconfig.ResetConfString(delayCommit, envViper, configFileViper, "", "delay_commit")
+	viperconfig.ResetConfInt(voteAggregationFanout, envViper, configFileViper, "", "vote_aggregation_fanout")
+	viperconfig.ResetConfString(recordConsensus, envViper, configFileViper, "", "record_consensus")
 	viperconfig.ResetConfString(nodeType, envViper, configFileViper, "", "node_type")
 	viperconfig.ResetConfString(networkType, envViper, configFileViper, "", "network_type")
 	viperconfig.ResetConfInt(blockPeriod, envViper, configFileViper, "", "block_period")
@@ -609,6 +719,7 @@ func setupViperConfig() {
 	viperconfig.ResetConfString(blsKeyFile, envViper, configFileViper, "", "blskey_file")
 	viperconfig.ResetConfString(blsFolder, envViper, configFileViper, "", "blsfolder")
 	viperconfig.ResetConfString(blsPass, envViper, configFileViper, "", "blsPass")
+	viperconfig.ResetConfString(blsReloadInterval, envViper, configFileViper, "", "bls_reload_interval")
 	viperconfig.ResetConfUInt(devnetNumShards, envViper, configFileViper, "", "dn_num_shards")
 	viperconfig.ResetConfInt(devnetShardSize, envViper, configFileViper, "", "dn_shard_size")
 	viperconfig.ResetConfInt(devnetHarmonySize, envViper, configFileViper, "", "dn_hmy_size")
@@ -616,6 +727,8 @@ func setupViperConfig() {
 	viperconfig.ResetConfString(keystoreDir, envViper, configFileViper, "", "keystore")
 	viperconfig.ResetConfInt(verbosity, envViper, configFileViper, "", "verbosity")
 	viperconfig.ResetConfString(dbDir, envViper, configFileViper, "", "db_dir")
+	viperconfig.ResetConfString(ancientDir, envViper, configFileViper, "", "ancient_dir")
+	viperconfig.ResetConfString(dbBackend, envViper, configFileViper, "", "db_backend")
 	viperconfig.ResetConfBool(publicRPC, envViper, configFileViper, "", "public_rpc")
 	viperconfig.ResetConfInt(doRevertBefore, envViper, configFileViper, "", "do_revert_before")
 	viperconfig.ResetConfInt(revertTo, envViper, configFileViper, "", "revert_to")
@@ -636,6 +749,7 @@ func main() {
 	switch *nodeType {
 	case "validator":
 	case "explorer":
+	case "light":
 		break
 	default:
 		_, _ = fmt.Fprintf(os.Stderr, "Unknown node type: %s\n", *nodeType)
@@ -684,6 +798,19 @@ func main() {
 
 	setupViperConfig()
 
+	if flag.NArg() > 0 {
+		if err := runChainCommand(flag.Args()); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR %s: %s\n", flag.Arg(0), err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *nodeType == "light" {
+		// light nodes sync the beacon chain only
+		*shardID = int(shard.BeaconChainShardID)
+	}
+
 	initSetup()
 
 	if *nodeType == "validator" {
@@ -719,6 +846,9 @@ func main() {
 		fmt.Fprintf(os.Stderr, "ERROR cannot configure node: %s\n", err)
 		os.Exit(1)
 	}
+	if *nodeType == "light" {
+		runLightNode(nodeConfig)
+	}
 	currentNode := setupConsensusAndNode(nodeConfig)
 	nodeconfig.GetDefaultConfig().ShardID = nodeConfig.ShardID
 
@@ -780,6 +910,17 @@ func main() {
 		).
 		Msg(startMsg)
 
+	if *nodeType == "validator" && *blsKeyFile == "" && *cmkEncryptedBLSKey == "" {
+		interval, err := time.ParseDuration(*blsReloadInterval)
+		if err != nil || interval < 0 {
+			fmt.Fprintf(os.Stderr, "ERROR invalid bls reload interval %#v\n", *blsReloadInterval)
+			os.Exit(1)
+		}
+		if interval > 0 {
+			go watchBLSFolder(currentNode, interval)
+		}
+	}
+
 	go currentNode.SupportSyncing()
 	currentNode.ServiceManagerSetup()
 	currentNode.RunServices()
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/harmony-one/harmony/consensus/replay"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/memhost"
+	"github.com/pkg/errors"
+)
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	asRecorder := fs.Bool(
+		"as_recorder", false,
+		"replay with the bls keys of -blskey_file or -blsfolder, reproducing the votes of the recording node; "+
+			"otherwise replay as a node listening to the shard",
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.Errorf("usage: replay [-as_recorder] <recording>\n%s", chainCommandUsage)
+	}
+	key := multibls.GetPrivateKey(bls_cosi.RandPrivateKey())
+	if *asRecorder {
+		var err error
+		if key, err = replayKeys(); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	harness, err := replayRecording(fs.Arg(0), key)
+	if err != nil {
+		return err
+	}
+	defer harness.Close()
+	head := harness.Node.Blockchain().CurrentBlock()
+	fmt.Printf("Replayed %s on shard %d in %v: head is block %d %s, view %d in %s mode, leader %s\n",
+		fs.Arg(0), harness.Consensus.ShardID, time.Since(start), head.NumberU64(), head.Hash().Hex(),
+		harness.Consensus.GetViewID(), harness.Consensus.Mode(),
+		harness.Consensus.LeaderPubKey.SerializeToHexStr())
+	return nil
+}
+
+// replayKeys loads the bls keys of -blskey_file or -blsfolder, decrypted
+// with the passphrase of -blspass or of the .pass files of the folder
+func replayKeys() (*multibls.PrivateKey, error) {
+	if *blsPass != "" {
+		passphrase, err := utils.GetPassphraseFromSource(*blsPass)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot read the bls passphrase")
+		}
+		blsPassphrase = passphrase
+	}
+	nodeConfig := nodeconfig.GetDefaultConfig()
+	setupConsensusKey(nodeConfig)
+	return nodeConfig.ConsensusPriKey, nil
+}
+
+// replayRecording feeds the events of the recording file, written by a node of
+// -shard_id started with -record_consensus, to a harness with the given keys
+// over a network of its own
+func replayRecording(fn string, key *multibls.PrivateKey) (*replay.Harness, error) {
+	if *shardID < 0 {
+		return nil, errors.New("-shard_id is required")
+	}
+	nodeconfig.SetNetworkType(nodeconfig.NetworkType(*networkType))
+	nodeconfig.GetDefaultConfig().ShardID = uint32(*shardID)
+	host := memhost.NewNetwork(time.Now().UnixNano()).NewHost(p2p.Peer{IP: "127.0.0.1", Port: "0"})
+	harness, err := replay.New(host, uint32(*shardID), key)
+	if err != nil {
+		return nil, err
+	}
+	// a recording of votes aggregated in groups replays with the same groups
+	harness.Consensus.SetVoteAggregation(*voteAggregationFanout)
+	if err := harness.ReplayFile(fn); err != nil {
+		harness.Close()
+		return nil, err
+	}
+	return harness, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/consensus/replay"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/memhost"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestReplayRecording(t *testing.T) {
+	key := bls_cosi.RandPrivateKey()
+	useTestCommittee(t, key)
+	dir, err := ioutil.TempDir("", "replaycmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	useChainFlags(t, int(shard.BeaconChainShardID), dir)
+	nodeconfig.SetNetworkType(nodeconfig.Mainnet)
+
+	// the recording node starts at genesis, as the leader of the committee
+	recorder, err := replay.New(
+		memhost.NewNetwork(1).NewHost(p2p.Peer{IP: "127.0.0.1", Port: "0"}),
+		shard.BeaconChainShardID, multibls.GetPrivateKey(key),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := recorder.Node.Blockchain().Genesis()
+	shardState, err := recorder.Node.Blockchain().ReadShardState(big.NewInt(0))
+	recorder.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedShardState, err := shard.EncodeWrapper(*shardState, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := rlp.EncodeToBytes(&consensus.RecordedStartState{
+		Head:         genesis,
+		ShardState:   encodedShardState,
+		BlockNum:     1,
+		ViewID:       1,
+		Mode:         consensus.Normal,
+		LeaderPubKey: key.GetPublicKey().Serialize(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := filepath.Join(dir, "consensus.rlp")
+	events, err := consensus.NewEventRecorder(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Record(consensus.RecordedStart, 1, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Record(consensus.RecordedTimeout, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runReplay([]string{}); err == nil {
+		t.Error("replayed without a recording")
+	}
+	harness, err := replayRecording(fn, multibls.GetPrivateKey(key))
+	if err != nil {
+		t.Fatalf("cannot replay: %v", err)
+	}
+	defer harness.Close()
+	if head := harness.Node.Blockchain().CurrentBlock(); head.Hash() != genesis.Hash() {
+		t.Errorf("expected the recorded head, got block %d", head.NumberU64())
+	}
+	if mode := harness.Consensus.Mode(); mode != consensus.ViewChanging {
+		t.Errorf("expected view changing after the replayed timeout, got %s", mode)
+	}
+	if err := runReplay([]string{fn}); err != nil {
+		t.Errorf("replay command failed: %v", err)
+	}
+}
@@ -0,0 +1,479 @@
+package consensus
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/p2p/host"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// Vote aggregation overlay
+//
+// When enabled, the committee (in Decider participant order) is split into
+// groups of voteAggregationFanout keys. The first key of every group is the
+// aggregator of that group: it collects the prepare/commit votes of its
+// members, partially aggregates signatures and bitmaps and forwards a single
+// vote to the leader, whose payload is |aggregated sig|bitmap| instead of a
+// single signature. The leader then only verifies one signature per group.
+// All nodes of a shard must agree on the fanout, since the leader holds back
+// the votes of group members that bypass their aggregator. Those are counted
+// only when the aggregates did not come within voteAggregationFallback, so
+// that an offline aggregator does not cost the votes of its whole group.
+
+var (
+	errEmptyAggregate        = errors.New("aggregated vote has no signers")
+	errNotAggregatorOfSigner = errors.New("sender is not the aggregator of every signer")
+	errInvalidAggregateSig   = errors.New("invalid aggregated signature")
+	errAggregateHashMismatch = errors.New("aggregated vote is not on the block in consensus")
+	errAggregatorKeyMissing  = errors.New("aggregator key is not one of my keys")
+)
+
+// aggregateID identifies the partial aggregate of one of my aggregator keys in a phase
+type aggregateID struct {
+	phase    quorum.Phase
+	keyIndex int
+}
+
+// partialAggregate is the aggregate an aggregator builds for one phase
+type partialAggregate struct {
+	blockNum  uint64
+	viewID    uint64
+	blockHash common.Hash
+	sig       *bls.Sign
+	mask      *bls_cosi.Mask
+	// index of my key which acts as the aggregator
+	keyIndex int
+	sent     bool
+}
+
+// SetVoteAggregation sets the group size of the vote aggregation overlay.
+// A fanout less than 2 disables vote aggregation.
+func (consensus *Consensus) SetVoteAggregation(fanout int) {
+	consensus.voteAggregationFanout = fanout
+}
+
+func (consensus *Consensus) isVoteAggregationEnabled() bool {
+	return consensus.voteAggregationFanout > 1
+}
+
+// aggregatorIndex returns the participant index of the aggregator
+// of the group the participant at idx belongs to
+func aggregatorIndex(idx, fanout int) int {
+	return idx - idx%fanout
+}
+
+// aggregatorOf returns the key aggregating the votes of the given key,
+// nil if the key is not a participant
+func (consensus *Consensus) aggregatorOf(pubKey *bls.PublicKey) *bls.PublicKey {
+	idx := consensus.Decider.IndexOf(pubKey)
+	if idx == -1 {
+		return nil
+	}
+	return consensus.Decider.Participants()[aggregatorIndex(
+		idx, consensus.voteAggregationFanout,
+	)]
+}
+
+// myKeyIndex returns the index of the given key among my keys, -1 if not mine
+func (consensus *Consensus) myKeyIndex(pubKey *bls.PublicKey) int {
+	if pubKey == nil {
+		return -1
+	}
+	for i, key := range consensus.GetPublicKeys().PublicKey {
+		if key.IsEqual(pubKey) {
+			return i
+		}
+	}
+	return -1
+}
+
+// acceptsDirectVote tells the leader whether a single signature vote
+// of the given key in the phase is counted, or should come through its aggregator
+func (consensus *Consensus) acceptsDirectVote(p quorum.Phase, pubKey *bls.PublicKey) bool {
+	if !consensus.isVoteAggregationEnabled() || consensus.directVoteFallback[p] {
+		return true
+	}
+	aggregator := consensus.aggregatorOf(pubKey)
+	if aggregator == nil {
+		return false
+	}
+	return aggregator.IsEqual(pubKey) || consensus.myKeyIndex(aggregator) != -1
+}
+
+// aggregatorGroupID returns the group the votes aggregated by the given key are sent to
+func (consensus *Consensus) aggregatorGroupID(aggregator *bls.PublicKey) nodeconfig.GroupID {
+	return nodeconfig.NewAggregatorGroupID(
+		nodeconfig.ShardID(consensus.ShardID), aggregator.SerializeToHexStr(),
+	)
+}
+
+// AggregatorGroups returns the groups of the votes any of my keys would
+// aggregate, none if vote aggregation is disabled
+func (consensus *Consensus) AggregatorGroups() []nodeconfig.GroupID {
+	if !consensus.isVoteAggregationEnabled() {
+		return nil
+	}
+	groups := []nodeconfig.GroupID{}
+	for _, key := range consensus.GetPublicKeys().PublicKey {
+		groups = append(groups, consensus.aggregatorGroupID(key))
+	}
+	return groups
+}
+
+// voteAggregator returns the aggregator the votes of my given key are sent to,
+// nil if they go to the leader directly: without vote aggregation, for the
+// aggregators themselves, and for the groups aggregated by another of my keys,
+// which never receives a message sent by its own node
+func (consensus *Consensus) voteAggregator(pubKey *bls.PublicKey) *bls.PublicKey {
+	if !consensus.isVoteAggregationEnabled() {
+		return nil
+	}
+	aggregator := consensus.aggregatorOf(pubKey)
+	if aggregator == nil || aggregator.IsEqual(pubKey) || consensus.myKeyIndex(aggregator) != -1 {
+		return nil
+	}
+	return aggregator
+}
+
+// sendVote sends the vote of my key in the phase to its aggregator, or to the
+// shard group when it has none. A vote sent to an aggregator is sent to the
+// shard group once more if the phase did not end within voteAggregationFallback,
+// for the leader to count it when the aggregate does not come.
+// caller holds the consensus mutex
+func (consensus *Consensus) sendVote(p quorum.Phase, pubKey *bls.PublicKey, p2pMsg []byte) error {
+	shardGroup := []nodeconfig.GroupID{nodeconfig.NewGroupIDByShardID(nodeconfig.ShardID(consensus.ShardID))}
+	aggregator := consensus.voteAggregator(pubKey)
+	if aggregator == nil {
+		return consensus.msgSender.SendWithoutRetry(shardGroup, p2pMsg)
+	}
+	blockNum, viewID := consensus.blockNum, consensus.viewID
+	phase := FBFTPrepare
+	if p == quorum.Commit {
+		phase = FBFTCommit
+	}
+	time.AfterFunc(voteAggregationFallback, func() {
+		consensus.mutex.Lock()
+		pending := consensus.blockNum == blockNum && consensus.viewID == viewID &&
+			consensus.phase == phase && consensus.current.Mode() != Listening
+		consensus.mutex.Unlock()
+		if !pending {
+			return
+		}
+		if err := consensus.msgSender.SendWithoutRetry(shardGroup, p2pMsg); err != nil {
+			consensus.getLogger().Warn().Err(err).
+				Str("phase", p.String()).
+				Msg("[SendVote] Cannot send vote to the leader")
+		} else {
+			consensus.getLogger().Info().
+				Str("phase", p.String()).
+				Uint64("blockNum", blockNum).
+				Msg("[SendVote] Phase not done, sent vote to the leader")
+		}
+	})
+	return consensus.msgSender.SendWithoutRetry(
+		[]nodeconfig.GroupID{consensus.aggregatorGroupID(aggregator)}, p2pMsg,
+	)
+}
+
+// holdDirectVote keeps the direct vote of a group member, which is counted
+// only if the aggregates of the phase do not come in time,
+// caller holds the consensus mutex
+func (consensus *Consensus) holdDirectVote(p quorum.Phase, msg *msg_pb.Message) {
+	if len(consensus.heldVotes[p]) == 0 {
+		blockNum, viewID := consensus.blockNum, consensus.viewID
+		time.AfterFunc(voteAggregationFallback, func() {
+			consensus.releaseHeldVotes(p, blockNum, viewID)
+		})
+	}
+	consensus.heldVotes[p] = append(consensus.heldVotes[p], msg)
+}
+
+// releaseHeldVotes counts the held direct votes of the phase, and from then on
+// any direct vote of the phase, as some aggregators did not forward in time
+func (consensus *Consensus) releaseHeldVotes(p quorum.Phase, blockNum, viewID uint64) {
+	consensus.mutex.Lock()
+	if consensus.blockNum != blockNum || consensus.viewID != viewID {
+		consensus.mutex.Unlock()
+		return
+	}
+	// the timer is an input of consensus as much as a view change timeout
+	consensus.recordVoteFallback(p, blockNum, viewID)
+	consensus.directVoteFallback[p] = true
+	held := consensus.heldVotes[p]
+	consensus.heldVotes[p] = nil
+	consensus.mutex.Unlock()
+
+	consensus.getLogger().Info().
+		Str("phase", p.String()).
+		Int("heldVotes", len(held)).
+		Msg("[ReleaseHeldVotes] Aggregates missing, counting direct votes of group members")
+	for _, msg := range held {
+		if p == quorum.Prepare {
+			consensus.onPrepare(msg)
+		} else {
+			consensus.onCommit(msg)
+		}
+	}
+}
+
+// isAggregatedVote tells whether the prepare/commit payload carries
+// an aggregated signature and bitmap instead of a single signature
+func isAggregatedVote(recvMsg *FBFTMessage) bool {
+	return len(recvMsg.Payload) > shard.BLSSignatureSizeInBytes
+}
+
+// shouldAggregateVote is a cheap check before any signature verification
+// whether the given prepare/commit message is to be aggregated by one of my keys
+func (consensus *Consensus) shouldAggregateVote(msg *msg_pb.Message) bool {
+	if !consensus.isVoteAggregationEnabled() || msg.GetConsensus() == nil {
+		return false
+	}
+	senderKey, err := bls_cosi.BytesToBLSPublicKey(msg.GetConsensus().SenderPubkey)
+	if err != nil {
+		return false
+	}
+	aggregator := consensus.aggregatorOf(senderKey)
+	return aggregator != nil &&
+		!aggregator.IsEqual(senderKey) &&
+		consensus.myKeyIndex(aggregator) != -1
+}
+
+// groupSize returns how many votes the given aggregator waits for before
+// forwarding, which excludes itself and the keys of the leader
+func (consensus *Consensus) groupSize(aggregator *bls.PublicKey) int {
+	members := consensus.Decider.Participants()
+	start := consensus.Decider.IndexOf(aggregator)
+	size := 0
+	for i := start + 1; i < start+consensus.voteAggregationFanout && i < len(members); i++ {
+		if !members[i].IsEqual(consensus.LeaderPubKey) {
+			size++
+		}
+	}
+	return size
+}
+
+// votePayload returns what a vote of the given phase signs
+func votePayload(p quorum.Phase, blockNum uint64, blockHash common.Hash) []byte {
+	if p == quorum.Prepare {
+		return blockHash[:]
+	}
+	// TODO(audit): sign signature on hash+blockNum+viewID (add a hard fork)
+	blockNumBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(blockNumBytes, blockNum)
+	return append(blockNumBytes, blockHash[:]...)
+}
+
+// onVoteToAggregate is run by an aggregator receiving the vote of a group member
+func (consensus *Consensus) onVoteToAggregate(msg *msg_pb.Message) {
+	recvMsg, err := ParseFBFTMessage(msg)
+	if err != nil {
+		consensus.getLogger().Debug().Err(err).Msg("[OnVoteToAggregate] Unparseable vote")
+		return
+	}
+	if isAggregatedVote(recvMsg) {
+		// only single votes are aggregated, no nested aggregates
+		return
+	}
+
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+
+	if recvMsg.ViewID != consensus.viewID || recvMsg.BlockNum != consensus.blockNum {
+		consensus.getLogger().Debug().
+			Uint64("MsgViewID", recvMsg.ViewID).
+			Uint64("MsgBlockNum", recvMsg.BlockNum).
+			Msg("[OnVoteToAggregate] BlockNum/viewID not match")
+		return
+	}
+	if !bytes.Equal(recvMsg.BlockHash[:], consensus.blockHash[:]) {
+		consensus.getLogger().Debug().
+			Str("MsgBlockHash", recvMsg.BlockHash.Hex()).
+			Msg("[OnVoteToAggregate] Vote is not on the block in consensus")
+		return
+	}
+
+	p := quorum.Prepare
+	if recvMsg.MessageType == msg_pb.MessageType_COMMIT {
+		p = quorum.Commit
+	}
+	var sign bls.Sign
+	if err := sign.Deserialize(recvMsg.Payload); err != nil {
+		consensus.getLogger().Debug().Err(err).
+			Msg("[OnVoteToAggregate] Failed to deserialize bls signature")
+		return
+	}
+	if !sign.VerifyHash(
+		recvMsg.SenderPubkey, votePayload(p, recvMsg.BlockNum, recvMsg.BlockHash),
+	) {
+		consensus.getLogger().Error().Msg("[OnVoteToAggregate] Received invalid BLS signature")
+		return
+	}
+
+	aggregator := consensus.aggregatorOf(recvMsg.SenderPubkey)
+	id := aggregateID{p, consensus.myKeyIndex(aggregator)}
+	agg, ok := consensus.voteAggregates[id]
+	if !ok || agg.blockNum != recvMsg.BlockNum ||
+		agg.viewID != recvMsg.ViewID || agg.blockHash != recvMsg.BlockHash {
+		mask, err := bls_cosi.NewMask(consensus.Decider.Participants(), nil)
+		if err != nil {
+			consensus.getLogger().Warn().Err(err).Msg("[OnVoteToAggregate] Unable to setup mask")
+			return
+		}
+		agg = &partialAggregate{
+			blockNum:  recvMsg.BlockNum,
+			viewID:    recvMsg.ViewID,
+			blockHash: recvMsg.BlockHash,
+			sig:       &bls.Sign{},
+			mask:      mask,
+			keyIndex:  id.keyIndex,
+		}
+		consensus.voteAggregates[id] = agg
+		time.AfterFunc(voteAggregationWait, func() {
+			consensus.mutex.Lock()
+			defer consensus.mutex.Unlock()
+			consensus.forwardVoteAggregate(id, agg)
+		})
+	}
+	if agg.sent {
+		consensus.getLogger().Debug().
+			Str("phase", p.String()).
+			Msg("[OnVoteToAggregate] Vote arrived after the aggregate was forwarded")
+		return
+	}
+	if enabled, _ := agg.mask.KeyEnabled(recvMsg.SenderPubkey); enabled {
+		return
+	}
+	if err := agg.mask.SetKey(recvMsg.SenderPubkey, true); err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[OnVoteToAggregate] mask.SetKey failed")
+		return
+	}
+	agg.sig.Add(&sign)
+
+	if agg.mask.CountEnabled() >= consensus.groupSize(aggregator) {
+		consensus.forwardVoteAggregate(id, agg)
+	}
+}
+
+// forwardVoteAggregate sends the partial aggregate of the phase to the leader,
+// caller holds the consensus mutex
+func (consensus *Consensus) forwardVoteAggregate(id aggregateID, agg *partialAggregate) {
+	if agg.sent || consensus.voteAggregates[id] != agg {
+		return
+	}
+	agg.sent = true
+	msgType := msg_pb.MessageType_PREPARE
+	if id.phase == quorum.Commit {
+		msgType = msg_pb.MessageType_COMMIT
+	}
+	msgToSend, err := consensus.constructAggregatedVote(msgType, agg)
+	if err != nil {
+		consensus.getLogger().Err(err).
+			Str("message-type", msgType.String()).
+			Msg("could not construct aggregated vote")
+		return
+	}
+	if consensus.current.Mode() == Listening {
+		return
+	}
+	if err := consensus.msgSender.SendWithoutRetry(
+		[]nodeconfig.GroupID{nodeconfig.NewGroupIDByShardID(nodeconfig.ShardID(consensus.ShardID))},
+		host.ConstructP2pMessage(byte(17), msgToSend),
+	); err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[ForwardVoteAggregate] Cannot send aggregated vote")
+	} else {
+		consensus.getLogger().Info().
+			Str("phase", id.phase.String()).
+			Int("signers", agg.mask.CountEnabled()).
+			Uint64("blockNum", agg.blockNum).
+			Msg("[ForwardVoteAggregate] Sent aggregated vote")
+	}
+}
+
+// constructAggregatedVote creates the wire message of a partial aggregate,
+// signed by the aggregator key
+func (consensus *Consensus) constructAggregatedVote(
+	p msg_pb.MessageType, agg *partialAggregate,
+) ([]byte, error) {
+	keys := consensus.getSigningKeys()
+	if agg.keyIndex < 0 || agg.keyIndex >= len(keys.pubKey.PublicKey) {
+		return nil, errAggregatorKeyMissing
+	}
+	message := &msg_pb.Message{
+		ServiceType: msg_pb.ServiceType_CONSENSUS,
+		Type:        p,
+		Request: &msg_pb.Message_Consensus{
+			Consensus: &msg_pb.ConsensusRequest{
+				ViewId:       agg.viewID,
+				BlockNum:     agg.blockNum,
+				ShardId:      consensus.ShardID,
+				BlockHash:    agg.blockHash[:],
+				SenderPubkey: keys.pubKey.PublicKey[agg.keyIndex].Serialize(),
+			},
+		},
+	}
+	buffer := bytes.Buffer{}
+	// 96 bytes aggregated signature
+	buffer.Write(agg.sig.Serialize())
+	// Bitmap
+	buffer.Write(agg.mask.Bitmap)
+	message.GetConsensus().Payload = buffer.Bytes()
+
+	marshaledMessage, err := consensus.signAndMarshalConsensusMessage(
+		message, keys.priKey.PrivateKey[agg.keyIndex],
+	)
+	if err != nil {
+		return nil, err
+	}
+	return proto.ConstructConsensusMessage(marshaledMessage), nil
+}
+
+// submitAggregatedVote verifies a partial aggregate received by the leader
+// and counts its signers, caller holds the consensus mutex
+func (consensus *Consensus) submitAggregatedVote(
+	p quorum.Phase, recvMsg *FBFTMessage, bitmap *bls_cosi.Mask,
+) error {
+	if !bytes.Equal(recvMsg.BlockHash[:], consensus.blockHash[:]) {
+		return errAggregateHashMismatch
+	}
+	aggSig, mask, err := consensus.ReadSignatureBitmapPayload(recvMsg.Payload, 0)
+	if err != nil {
+		return err
+	}
+	signers := mask.GetPubKeyFromMask(true)
+	if len(signers) == 0 {
+		return errEmptyAggregate
+	}
+	for _, signer := range signers {
+		aggregator := consensus.aggregatorOf(signer)
+		if aggregator == nil || signer.IsEqual(recvMsg.SenderPubkey) ||
+			!aggregator.IsEqual(recvMsg.SenderPubkey) {
+			return errNotAggregatorOfSigner
+		}
+	}
+	if !aggSig.VerifyHash(
+		mask.AggregatePublic, votePayload(p, recvMsg.BlockNum, recvMsg.BlockHash),
+	) {
+		return errInvalidAggregateSig
+	}
+	if _, err := consensus.Decider.SubmitAggregateVote(
+		p, signers, aggSig, recvMsg.BlockHash, recvMsg.BlockNum, recvMsg.ViewID,
+	); err != nil {
+		return err
+	}
+	for _, signer := range signers {
+		if err := bitmap.SetKey(signer, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package consensus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/api/proto"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/p2pimpl"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestAggregatorIndex(t *testing.T) {
+	tests := []struct {
+		idx, fanout, expected int
+	}{
+		{0, 4, 0},
+		{3, 4, 0},
+		{4, 4, 4},
+		{9, 4, 8},
+		{9, 10, 0},
+	}
+	for _, test := range tests {
+		if got := aggregatorIndex(test.idx, test.fanout); got != test.expected {
+			t.Errorf("aggregatorIndex(%d, %d) = %d, expected %d",
+				test.idx, test.fanout, got, test.expected)
+		}
+	}
+}
+
+// newAggregationTestConsensus returns a consensus with the first of ten keys,
+// aggregating votes in groups of four
+func newAggregationTestConsensus(t *testing.T) (*Consensus, []*bls.SecretKey, []*bls.PublicKey) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9902"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9902")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	secretKeys := []*bls.SecretKey{}
+	pubKeys := []*bls.PublicKey{}
+	for i := 0; i < 10; i++ {
+		secretKeys = append(secretKeys, bls_cosi.RandPrivateKey())
+		pubKeys = append(pubKeys, secretKeys[i].GetPublicKey())
+	}
+	decider := quorum.NewDecider(
+		quorum.SuperMajorityVote, shard.BeaconChainShardID,
+	)
+	consensus, err := New(
+		host, shard.BeaconChainShardID, leader, multibls.GetPrivateKey(secretKeys[0]), decider,
+	)
+	if err != nil {
+		t.Fatalf("Cannot create consensus: %v", err)
+	}
+	consensus.UpdatePublicKeys(pubKeys)
+	consensus.SetVoteAggregation(4)
+	return consensus, secretKeys, pubKeys
+}
+
+// voteMessage returns the prepare vote of the given key on the block hash
+func voteMessage(
+	t *testing.T, consensus *Consensus, blockHash common.Hash, secretKey *bls.SecretKey,
+) *msg_pb.Message {
+	consensusHash := consensus.blockHash
+	copy(consensus.blockHash[:], blockHash[:])
+	defer func() { consensus.blockHash = consensusHash }()
+	networkMessage, err := consensus.construct(
+		msg_pb.MessageType_PREPARE, nil, secretKey.GetPublicKey(), secretKey,
+	)
+	if err != nil {
+		t.Fatalf("could not construct prepare: %v", err)
+	}
+	payload, _ := proto.GetConsensusMessagePayload(networkMessage.Bytes)
+	msg := &msg_pb.Message{}
+	if err := protobuf.Unmarshal(payload, msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSubmitAggregatedVote(t *testing.T) {
+	consensus, secretKeys, pubKeys := newAggregationTestConsensus(t)
+	blockHash := common.BytesToHash([]byte("block"))
+	copy(consensus.blockHash[:], blockHash[:])
+
+	if !consensus.aggregatorOf(pubKeys[6]).IsEqual(pubKeys[4]) {
+		t.Error("expected key 4 to aggregate the votes of key 6")
+	}
+	if !consensus.acceptsDirectVote(quorum.Prepare, pubKeys[2]) ||
+		!consensus.acceptsDirectVote(quorum.Prepare, pubKeys[4]) {
+		t.Error("leader group members and aggregators should vote directly")
+	}
+	if consensus.acceptsDirectVote(quorum.Prepare, pubKeys[6]) {
+		t.Error("key 6 should vote through its aggregator")
+	}
+
+	mask, _ := bls_cosi.NewMask(pubKeys, nil)
+	sigs := []*bls.Sign{}
+	for _, i := range []int{5, 6} {
+		sigs = append(sigs, secretKeys[i].SignHash(blockHash[:]))
+		mask.SetKey(pubKeys[i], true)
+	}
+	payload := bytes.Buffer{}
+	payload.Write(bls_cosi.AggregateSig(sigs).Serialize())
+	payload.Write(mask.Bitmap)
+
+	recvMsg := &FBFTMessage{
+		BlockHash:    blockHash,
+		SenderPubkey: pubKeys[8],
+		Payload:      payload.Bytes(),
+	}
+	if err := consensus.submitAggregatedVote(
+		quorum.Prepare, recvMsg, consensus.prepareBitmap,
+	); err != errNotAggregatorOfSigner {
+		t.Errorf("expected %v, got %v", errNotAggregatorOfSigner, err)
+	}
+
+	recvMsg.SenderPubkey = pubKeys[4]
+	if err := consensus.submitAggregatedVote(
+		quorum.Prepare, recvMsg, consensus.prepareBitmap,
+	); err != nil {
+		t.Fatalf("aggregated vote rejected: %v", err)
+	}
+	if count := consensus.Decider.SignersCount(quorum.Prepare); count != 2 {
+		t.Errorf("expected 2 signers, got %d", count)
+	}
+	if consensus.prepareBitmap.CountEnabled() != 2 {
+		t.Error("prepare bitmap not updated with the aggregated signers")
+	}
+	if err := consensus.submitAggregatedVote(
+		quorum.Prepare, recvMsg, consensus.prepareBitmap,
+	); err == nil {
+		t.Error("expected overlapping aggregate to be rejected")
+	}
+}
+
+func TestHeldDirectVotes(t *testing.T) {
+	consensus, secretKeys, pubKeys := newAggregationTestConsensus(t)
+	blockHash := common.BytesToHash([]byte("block"))
+	copy(consensus.blockHash[:], blockHash[:])
+
+	consensus.mutex.Lock()
+	consensus.holdDirectVote(quorum.Prepare, voteMessage(t, consensus, blockHash, secretKeys[6]))
+	consensus.mutex.Unlock()
+	// a late timer of another block does not release the votes
+	consensus.releaseHeldVotes(quorum.Prepare, consensus.blockNum+1, consensus.viewID)
+	if consensus.Decider.SignersCount(quorum.Prepare) != 0 ||
+		consensus.acceptsDirectVote(quorum.Prepare, pubKeys[6]) {
+		t.Fatal("held vote released by the timer of another block")
+	}
+
+	consensus.releaseHeldVotes(quorum.Prepare, consensus.blockNum, consensus.viewID)
+	if count := consensus.Decider.SignersCount(quorum.Prepare); count != 1 {
+		t.Errorf("expected the held vote to be counted, got %d signers", count)
+	}
+	if len(consensus.heldVotes[quorum.Prepare]) != 0 {
+		t.Error("released votes still held")
+	}
+	if !consensus.acceptsDirectVote(quorum.Prepare, pubKeys[7]) {
+		t.Error("direct votes should be counted after the fallback")
+	}
+	if consensus.acceptsDirectVote(quorum.Commit, pubKeys[7]) {
+		t.Error("fallback of the prepare phase should not apply to commits")
+	}
+
+	consensus.ResetState()
+	if consensus.acceptsDirectVote(quorum.Prepare, pubKeys[7]) {
+		t.Error("fallback should not outlive the round")
+	}
+}
+
+func TestRecordedVoteFallback(t *testing.T) {
+	consensus, secretKeys, _ := newAggregationTestConsensus(t)
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consensus.rlp")
+	recorder, err := NewEventRecorder(path)
+	if err != nil {
+		t.Fatalf("Cannot create recorder: %v", err)
+	}
+	consensus.SetEventRecorder(recorder)
+	blockHash := common.BytesToHash([]byte("block"))
+	holdVote := func() {
+		copy(consensus.blockHash[:], blockHash[:])
+		consensus.mutex.Lock()
+		consensus.holdDirectVote(quorum.Prepare, voteMessage(t, consensus, blockHash, secretKeys[6]))
+		consensus.mutex.Unlock()
+	}
+
+	holdVote()
+	consensus.releaseHeldVotes(quorum.Prepare, consensus.blockNum+1, consensus.viewID)
+	consensus.releaseHeldVotes(quorum.Prepare, consensus.blockNum, consensus.viewID)
+	consensus.CloseEventRecorder()
+	consensus.SetEventRecorder(nil)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ReadRecordedEvents(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Cannot read recording: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != RecordedTimeout || events[0].ViewID != consensus.viewID {
+		t.Fatalf("expected the fallback of the block in consensus recorded as timeout, got %+v", events)
+	}
+
+	// replaying the fallback releases the held votes instead of changing view
+	consensus.ResetState()
+	holdVote()
+	if err := consensus.ReplayEvent(events[0]); err != nil {
+		t.Fatalf("Cannot replay fallback: %v", err)
+	}
+	if count := consensus.Decider.SignersCount(quorum.Prepare); count != 1 {
+		t.Errorf("expected the held vote to be counted on replay, got %d signers", count)
+	}
+	if consensus.current.Mode() == ViewChanging {
+		t.Error("replayed fallback started a view change")
+	}
+}
+
+func TestVoteAggregator(t *testing.T) {
+	consensus, _, pubKeys := newAggregationTestConsensus(t)
+	if aggregator := consensus.voteAggregator(pubKeys[6]); aggregator == nil ||
+		!aggregator.IsEqual(pubKeys[4]) {
+		t.Error("expected the votes of key 6 to go to key 4")
+	}
+	if consensus.voteAggregator(pubKeys[4]) != nil {
+		t.Error("aggregators should vote to the leader directly")
+	}
+	if consensus.voteAggregator(pubKeys[2]) != nil {
+		t.Error("a group aggregated by one of my keys should vote to the leader directly")
+	}
+	groups := consensus.AggregatorGroups()
+	if len(groups) != 1 || groups[0] != consensus.aggregatorGroupID(pubKeys[0]) {
+		t.Errorf("expected the aggregator group of my key only, got %v", groups)
+	}
+
+	consensus.SetVoteAggregation(0)
+	if consensus.voteAggregator(pubKeys[6]) != nil || len(consensus.AggregatorGroups()) != 0 {
+		t.Error("votes sent to aggregators with vote aggregation disabled")
+	}
+}
+
+func TestVoteToAggregateHash(t *testing.T) {
+	consensus, secretKeys, _ := newAggregationTestConsensus(t)
+	blockHash := common.BytesToHash([]byte("block"))
+	copy(consensus.blockHash[:], blockHash[:])
+
+	otherHash := common.BytesToHash([]byte("other block"))
+	consensus.onVoteToAggregate(voteMessage(t, consensus, otherHash, secretKeys[1]))
+	if len(consensus.voteAggregates) != 0 {
+		t.Fatal("vote on another block aggregated")
+	}
+
+	consensus.onVoteToAggregate(voteMessage(t, consensus, blockHash, secretKeys[2]))
+	agg := consensus.voteAggregates[aggregateID{quorum.Prepare, 0}]
+	if agg == nil || agg.blockHash != blockHash || agg.mask.CountEnabled() != 1 {
+		t.Fatal("vote on the block in consensus not aggregated")
+	}
+	// the vote with the wrong hash does not reset the partial aggregate
+	consensus.onVoteToAggregate(voteMessage(t, consensus, otherHash, secretKeys[3]))
+	if consensus.voteAggregates[aggregateID{quorum.Prepare, 0}] != agg ||
+		agg.mask.CountEnabled() != 1 {
+		t.Error("partial aggregate reset by a vote on another block")
+	}
+}
+
+func TestAggregatedDoubleSign(t *testing.T) {
+	consensus, secretKeys, pubKeys := newAggregationTestConsensus(t)
+	blockHash := common.BytesToHash([]byte("block"))
+	copy(consensus.blockHash[:], blockHash[:])
+
+	mask, _ := bls_cosi.NewMask(pubKeys, nil)
+	mask.SetKey(pubKeys[5], true)
+	payload := bytes.Buffer{}
+	payload.Write(secretKeys[5].SignHash(
+		votePayload(quorum.Commit, consensus.blockNum, blockHash),
+	).Serialize())
+	payload.Write(mask.Bitmap)
+	recvMsg := &FBFTMessage{
+		MessageType:  msg_pb.MessageType_COMMIT,
+		BlockNum:     consensus.blockNum,
+		BlockHash:    blockHash,
+		SenderPubkey: pubKeys[4],
+		Payload:      payload.Bytes(),
+	}
+	if !consensus.checkAggregatedDoubleSign(recvMsg) {
+		t.Error("aggregated commit on a block not announced should be suspicious")
+	}
+
+	for _, typ := range []msg_pb.MessageType{
+		msg_pb.MessageType_ANNOUNCE, msg_pb.MessageType_PREPARED,
+	} {
+		consensus.FBFTLog.AddMessage(&FBFTMessage{
+			MessageType: typ,
+			BlockNum:    consensus.blockNum,
+			BlockHash:   blockHash,
+		})
+	}
+	if consensus.checkAggregatedDoubleSign(recvMsg) {
+		t.Error("aggregated commit on the prepared block reported as double sign")
+	}
+}
@@ -128,6 +128,14 @@ func (consensus *Consensus) onAnnounceSanityChecks(recvMsg *FBFTMessage) bool {
 			Str("leaderKey", consensus.LeaderPubKey.SerializeToHexStr()).
 			Msg("[OnAnnounce] Announce message received again")
 	}
+	if !consensus.isScheduledLeader(recvMsg) {
+		consensus.getLogger().Warn().
+			Str("recvMsg.SenderPubkey", recvMsg.SenderPubkey.SerializeToHexStr()).
+			Uint64("recvMsg.BlockNum", recvMsg.BlockNum).
+			Uint64("recvMsg.ViewID", recvMsg.ViewID).
+			Msg("[OnAnnounce] Announce not from the scheduled rotation leader")
+		return false
+	}
 	return consensus.isRightBlockNumCheck(recvMsg)
 }
 
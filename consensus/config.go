@@ -15,6 +15,14 @@ const (
 	maxLogSize        uint32        = 1000
 	// threshold between received consensus message blockNum and my blockNum
 	consensusBlockNumBuffer uint64 = 2
+	// how long a vote aggregator waits for the votes of its group
+	// before forwarding the partial aggregate to the leader
+	voteAggregationWait time.Duration = 2 * time.Second
+	// how long the leader waits for the partial aggregates of a phase
+	// before counting the direct votes of group members instead
+	voteAggregationFallback time.Duration = 2 * voteAggregationWait
+	// number of blocks a leader proposes before the scheduled rotation
+	leaderRotationBlocks uint64 = 16
 )
 
 // TimeoutType is the type of timeout in view change protocol
@@ -3,10 +3,12 @@ package consensus
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	"github.com/harmony-one/harmony/consensus/quorum"
 	"github.com/harmony-one/harmony/core"
 	"github.com/harmony-one/harmony/core/types"
@@ -68,6 +70,20 @@ type Consensus struct {
 	viewIDBitmap map[uint64]*bls_cosi.Mask
 	m1Payload    []byte     // message payload for type m1 := |vcBlockHash|prepared_agg_sigs|prepared_bitmap|, new leader only need one
 	vcLock       sync.Mutex // mutex for view change
+	// group size of the vote aggregation overlay, disabled if less than 2
+	voteAggregationFanout int
+	// partial aggregates of group votes when this node is an aggregator
+	voteAggregates map[aggregateID]*partialAggregate
+	// direct votes of group members held by the leader in case
+	// their aggregators do not forward in time
+	heldVotes map[quorum.Phase][]*msg_pb.Message
+	// phases in which the leader counts direct votes of group members
+	directVoteFallback map[quorum.Phase]bool
+	// records the inputs of the main loop for replay, if set
+	recorder *EventRecorder
+	// first prepare and view change votes of each signer, to catch double signs
+	signedVotes     map[signedVoteKey]*signedVote
+	signedVotesLock sync.Mutex
 	// The chain reader for the blockchain this consensus is working on
 	ChainReader *core.BlockChain
 	// map of nodeID to validator Peer object
@@ -76,14 +92,16 @@ type Consensus struct {
 	// If the number of validators is less than minPeers, the consensus won't start
 	MinPeers   int
 	pubKeyLock sync.Mutex
-	// private/public keys of current node
-	priKey *multibls.PrivateKey
-	PubKey *multibls.PublicKey
+	// private/public keys of current node, a *signingKeys replaced as a
+	// whole when keys are added, so readers need no lock
+	keys atomic.Value
 	// TODO(audit): SelfAddresses doesn't have the ECDSA address for external validators. Don't use it that way.
 	SelfAddresses map[string]common.Address
 	// the publickey of leader
 	LeaderPubKey *bls.PublicKey
 	viewID       uint64
+	// viewID at which the current scheduled leader took over
+	rotationViewID uint64
 	// Blockhash - 32 byte
 	blockHash [32]byte
 	// Block to run consensus on
@@ -132,6 +150,22 @@ type Consensus struct {
 	BlockPeriod time.Duration
 	// The time due for next block proposal
 	NextBlockDue time.Time
+	// clock of the timeouts, the wall clock unless set otherwise
+	now func() time.Time
+}
+
+// SetClock makes the consensus and view change timeouts run on the given clock
+// instead of the wall clock, as tests on a network with a manual clock do
+func (consensus *Consensus) SetClock(now func() time.Time) {
+	consensus.now = now
+	for _, timeout := range consensus.consensusTimeout {
+		timeout.SetClock(now)
+	}
+}
+
+// Now returns the current time on the clock of the timeouts
+func (consensus *Consensus) Now() time.Time {
+	return consensus.now()
 }
 
 // SetCommitDelay sets the commit message delay.  If set to non-zero,
@@ -158,9 +192,10 @@ func (consensus *Consensus) VdfSeedSize() int {
 
 // GetLeaderPrivateKey returns leader private key if node is the leader
 func (consensus *Consensus) GetLeaderPrivateKey(leaderKey *bls.PublicKey) (*bls.SecretKey, error) {
-	for i, key := range consensus.PubKey.PublicKey {
+	keys := consensus.getSigningKeys()
+	for i, key := range keys.pubKey.PublicKey {
 		if key.IsEqual(leaderKey) {
-			return consensus.priKey.PrivateKey[i], nil
+			return keys.priKey.PrivateKey[i], nil
 		}
 	}
 	return nil, errors.Wrapf(errLeaderPriKeyNotFound, leaderKey.SerializeToHexStr())
@@ -171,6 +206,53 @@ func (consensus *Consensus) GetConsensusLeaderPrivateKey() (*bls.SecretKey, erro
 	return consensus.GetLeaderPrivateKey(consensus.LeaderPubKey)
 }
 
+// signingKeys are the private keys of the node
+// with their public keys at the same indexes
+type signingKeys struct {
+	priKey *multibls.PrivateKey
+	pubKey *multibls.PublicKey
+}
+
+// getSigningKeys returns the keys of the node, the private and public keys
+// of one call always match by index
+func (consensus *Consensus) getSigningKeys() *signingKeys {
+	return consensus.keys.Load().(*signingKeys)
+}
+
+// GetPublicKeys returns the public keys of the node
+func (consensus *Consensus) GetPublicKeys() *multibls.PublicKey {
+	return consensus.getSigningKeys().pubKey
+}
+
+// SetPrivateKeys replaces the keys the node signs consensus messages with,
+// such as on a rotated slot key, returning the public keys added and removed
+func (consensus *Consensus) SetPrivateKeys(
+	keys *multibls.PrivateKey,
+) (added, removed []*bls.PublicKey) {
+	consensus.mutex.Lock()
+	defer consensus.mutex.Unlock()
+	current := consensus.getSigningKeys()
+	priKey := &multibls.PrivateKey{
+		PrivateKey: append([]*bls.SecretKey{}, keys.PrivateKey...),
+	}
+	pubKey := priKey.GetPublicKey()
+	for _, key := range pubKey.PublicKey {
+		if !current.pubKey.Contains(key) {
+			added = append(added, key)
+		}
+	}
+	for _, key := range current.pubKey.PublicKey {
+		if !pubKey.Contains(key) {
+			removed = append(removed, key)
+		}
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		return nil, nil
+	}
+	consensus.keys.Store(&signingKeys{priKey: priKey, pubKey: pubKey})
+	return added, removed
+}
+
 // TODO: put shardId into chain reader's chain config
 
 // New create a new Consensus record
@@ -190,13 +272,15 @@ func New(
 	consensus.current = State{mode: Normal}
 	// FBFT timeout
 	consensus.consensusTimeout = createTimeout()
+	consensus.now = time.Now
 	consensus.validators.Store(leader.ConsensusPubKey.SerializeToHexStr(), leader)
 
 	if multiBLSPriKey != nil {
-		consensus.priKey = multiBLSPriKey
-		consensus.PubKey = multiBLSPriKey.GetPublicKey()
+		consensus.keys.Store(&signingKeys{
+			priKey: multiBLSPriKey, pubKey: multiBLSPriKey.GetPublicKey(),
+		})
 		utils.Logger().Info().
-			Str("publicKey", consensus.PubKey.SerializeToHexStr()).Msg("My Public Key")
+			Str("publicKey", consensus.GetPublicKeys().SerializeToHexStr()).Msg("My Public Key")
 	} else {
 		utils.Logger().Error().Msg("the bls key is nil")
 		return nil, fmt.Errorf("nil bls key, aborting")
@@ -212,6 +296,10 @@ func New(
 	consensus.syncNotReadyChan = make(chan struct{})
 	consensus.SlashChan = make(chan slash.Record)
 	consensus.commitFinishChan = make(chan uint64)
+	consensus.voteAggregates = map[aggregateID]*partialAggregate{}
+	consensus.heldVotes = map[quorum.Phase][]*msg_pb.Message{}
+	consensus.directVoteFallback = map[quorum.Phase]bool{}
+	consensus.signedVotes = map[signedVoteKey]*signedVote{}
 	consensus.ReadySignal = make(chan struct{})
 	// channel for receiving newly generated VDF
 	consensus.RndChannel = make(chan [vdfAndSeedSize]byte)
@@ -187,6 +187,10 @@ func (consensus *Consensus) ResetState() {
 	consensus.commitBitmap = commitBitmap
 	consensus.aggregatedPrepareSig = nil
 	consensus.aggregatedCommitSig = nil
+	consensus.voteAggregates = map[aggregateID]*partialAggregate{}
+	consensus.heldVotes = map[quorum.Phase][]*msg_pb.Message{}
+	consensus.directVoteFallback = map[quorum.Phase]bool{}
+	consensus.pruneSignedVotes()
 }
 
 // Returns a string representation of this consensus
@@ -201,7 +205,7 @@ func (consensus *Consensus) String() string {
 	return fmt.Sprintf(
 		"[Duty:%s Pub:%s Header:%s Num:%d View:%d Shard:%d Epoch:%d]",
 		duty,
-		consensus.PubKey.SerializeToHexStr(),
+		consensus.GetPublicKeys().SerializeToHexStr(),
 		hex.EncodeToString(consensus.blockHeader),
 		consensus.blockNum,
 		consensus.viewID,
@@ -432,7 +436,7 @@ func (consensus *Consensus) UpdateConsensusInformation() Mode {
 	if isFirstTimeStaking || haventUpdatedDecider {
 		decider := quorum.NewDecider(quorum.SuperMajorityStake, consensus.ShardID)
 		decider.SetMyPublicKeyProvider(func() (*multibls.PublicKey, error) {
-			return consensus.PubKey, nil
+			return consensus.GetPublicKeys(), nil
 		})
 		consensus.Decider = decider
 	}
@@ -543,10 +547,17 @@ func (consensus *Consensus) UpdateConsensusInformation() Mode {
 			consensus.LeaderPubKey = leaderPubKey
 		}
 	}
+	// the next block may start a new turn of the leader rotation
+	if nextBlockNum := curHeader.Number().Uint64() + 1; consensus.isLeaderRotationBlock(nextBlockNum) {
+		if leaderPubKey := consensus.scheduledLeader(nextBlockNum); leaderPubKey != nil {
+			consensus.LeaderPubKey = leaderPubKey
+			consensus.rotationViewID = curHeader.ViewID().Uint64() + 1
+		}
+	}
 
 	for _, key := range pubKeys {
 		// in committee
-		if consensus.PubKey.Contains(key) {
+		if consensus.GetPublicKeys().Contains(key) {
 			if hasError {
 				return Syncing
 			}
@@ -555,7 +566,7 @@ func (consensus *Consensus) UpdateConsensusInformation() Mode {
 			if !consensus.LeaderPubKey.IsEqual(oldLeader) && consensus.IsLeader() {
 				go func() {
 					utils.Logger().Debug().
-						Str("myKey", consensus.PubKey.SerializeToHexStr()).
+						Str("myKey", consensus.GetPublicKeys().SerializeToHexStr()).
 						Uint64("viewID", consensus.viewID).
 						Uint64("block", consensus.blockNum).
 						Msg("[UpdateConsensusInformation] I am the New Leader")
@@ -572,7 +583,7 @@ func (consensus *Consensus) UpdateConsensusInformation() Mode {
 // IsLeader check if the node is a leader or not by comparing the public key of
 // the node with the leader public key
 func (consensus *Consensus) IsLeader() bool {
-	for _, key := range consensus.PubKey.PublicKey {
+	for _, key := range consensus.GetPublicKeys().PublicKey {
 		if key.IsEqual(consensus.LeaderPubKey) {
 			return true
 		}
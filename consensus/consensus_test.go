@@ -3,6 +3,8 @@ package consensus
 import (
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	"github.com/harmony-one/harmony/consensus/quorum"
 	"github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
@@ -36,3 +38,81 @@ func TestNew(test *testing.T) {
 		test.Error("Consensus ReadySignal should be initialized")
 	}
 }
+
+func TestCheckConflictingVoteNilViewChange(test *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9903"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9903")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		test.Fatalf("newhost failure: %v", err)
+	}
+	decider := quorum.NewDecider(
+		quorum.SuperMajorityVote, shard.BeaconChainShardID,
+	)
+	consensus, err := New(
+		host, shard.BeaconChainShardID, leader, multibls.GetPrivateKey(bls.RandPrivateKey()), decider,
+	)
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+
+	signer := bls.RandPrivateKey().GetPublicKey()
+	msg := &msg_pb.Message{Type: msg_pb.MessageType_VIEWCHANGE}
+	recvMsg := &FBFTMessage{BlockNum: 3, ViewID: 7, SenderPubkey: signer}
+	prepared := common.BytesToHash([]byte("prepared block"))
+
+	// M1 vote then NIL vote in the same view
+	if consensus.checkConflictingVote(msg, recvMsg, prepared, nil) {
+		test.Error("first vote reported as double sign")
+	}
+	if consensus.checkConflictingVote(msg, recvMsg, common.Hash{}, nil) {
+		test.Error("NIL view change after M1 vote reported as double sign")
+	}
+	// NIL vote then M1 vote in the next view
+	recvMsg.ViewID++
+	if consensus.checkConflictingVote(msg, recvMsg, common.Hash{}, nil) {
+		test.Error("first NIL vote reported as double sign")
+	}
+	if consensus.checkConflictingVote(msg, recvMsg, prepared, nil) {
+		test.Error("M1 view change after NIL vote reported as double sign")
+	}
+}
+
+func TestSetPrivateKeys(test *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9904"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9904")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		test.Fatalf("newhost failure: %v", err)
+	}
+	decider := quorum.NewDecider(
+		quorum.SuperMajorityVote, shard.BeaconChainShardID,
+	)
+	oldKey, keptKey, newKey := bls.RandPrivateKey(), bls.RandPrivateKey(), bls.RandPrivateKey()
+	keys := multibls.GetPrivateKey(oldKey)
+	multibls.AppendPriKey(keys, keptKey)
+	consensus, err := New(host, shard.BeaconChainShardID, leader, keys, decider)
+	if err != nil {
+		test.Fatalf("Cannot craeate consensus: %v", err)
+	}
+
+	keys = multibls.GetPrivateKey(keptKey)
+	multibls.AppendPriKey(keys, newKey)
+	added, removed := consensus.SetPrivateKeys(keys)
+	if len(added) != 1 || !added[0].IsEqual(newKey.GetPublicKey()) {
+		test.Errorf("expected the new key to be added, got %v", added)
+	}
+	if len(removed) != 1 || !removed[0].IsEqual(oldKey.GetPublicKey()) {
+		test.Errorf("expected the old key to be removed, got %v", removed)
+	}
+	pubKeys := consensus.GetPublicKeys()
+	if len(pubKeys.PublicKey) != 2 || pubKeys.Contains(oldKey.GetPublicKey()) ||
+		!pubKeys.Contains(newKey.GetPublicKey()) {
+		test.Errorf("expected exactly the set keys, got %v", pubKeys.PublicKey)
+	}
+
+	added, removed = consensus.SetPrivateKeys(keys)
+	if len(added) != 0 || len(removed) != 0 {
+		test.Errorf("expected no change setting the same keys, got %v %v", added, removed)
+	}
+}
@@ -86,6 +86,12 @@ func (consensus *Consensus) handleMessageUpdate(payload []byte) {
 		intendedForLeader &&
 		consensus.leaderSanityChecks(msg):
 		consensus.onCommit(msg)
+	// Votes of group members handled by vote aggregators
+	case (t == msg_pb.MessageType_PREPARE || t == msg_pb.MessageType_COMMIT) &&
+		intendedForValidator &&
+		consensus.shouldAggregateVote(msg) &&
+		consensus.leaderSanityChecks(msg):
+		consensus.onVoteToAggregate(msg)
 	case t == msg_pb.MessageType_VIEWCHANGE &&
 		consensus.viewChangeSanityCheck(msg):
 		consensus.onViewChange(msg)
@@ -289,6 +295,7 @@ func (consensus *Consensus) tryCatchup() {
 		consensus.getLogger().Info().Msg("[TryCatchup] Adding block to chain")
 		consensus.OnConsensusDone(block, msgs[0].Payload)
 		consensus.ResetState()
+		consensus.rotateLeaderIfScheduled()
 
 		select {
 		case consensus.VerifiedNewBlock <- block:
@@ -347,6 +354,7 @@ func (consensus *Consensus) Start(
 			Uint64("blockNum", consensus.blockNum).
 			Msg("[ConsensusMainLoop] Start bootstrap timeout (only once)")
 
+		consensus.recordStart()
 		vdfInProgress := false
 		// Set up next block due time.
 		consensus.NextBlockDue = time.Now().Add(consensus.BlockPeriod)
@@ -367,11 +375,13 @@ func (consensus *Consensus) Start(
 					}
 					if k != timeoutViewChange {
 						consensus.getLogger().Debug().Msg("[ConsensusMainLoop] Ops Consensus Timeout!!!")
+						consensus.record(RecordedTimeout, consensus.viewID+1, nil)
 						consensus.startViewChange(consensus.viewID + 1)
 						break
 					} else {
 						consensus.getLogger().Debug().Msg("[ConsensusMainLoop] Ops View Change Timeout!!!")
 						viewID := consensus.current.ViewID()
+						consensus.record(RecordedTimeout, viewID+1, nil)
 						consensus.startViewChange(viewID + 1)
 						break
 					}
@@ -383,6 +393,7 @@ func (consensus *Consensus) Start(
 				mode := consensus.UpdateConsensusInformation()
 				consensus.current.SetMode(mode)
 				consensus.getLogger().Info().Str("Mode", mode.String()).Msg("Node is IN SYNC")
+				consensus.recordStart()
 
 			case <-consensus.syncNotReadyChan:
 				consensus.getLogger().Debug().Msg("[ConsensusMainLoop] syncNotReadyChan")
@@ -476,13 +487,16 @@ func (consensus *Consensus) Start(
 					Time("startTime", startTime).
 					Int64("publicKeys", consensus.Decider.ParticipantsCount()).
 					Msg("[ConsensusMainLoop] STARTING CONSENSUS")
+				consensus.recordProposal(newBlock)
 				consensus.announce(newBlock)
 
 			case msg := <-consensus.MsgChan:
+				consensus.record(RecordedMessage, 0, msg)
 				consensus.handleMessageUpdate(msg)
 
 			case viewID := <-consensus.commitFinishChan:
 				consensus.getLogger().Debug().Msg("[ConsensusMainLoop] commitFinishChan")
+				consensus.record(RecordedCommitFinish, viewID, nil)
 
 				// Only Leader execute this condition
 				func() {
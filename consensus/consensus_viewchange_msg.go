@@ -47,7 +47,7 @@ func (consensus *Consensus) constructViewChangeMessage(pubKey *bls.PublicKey, pr
 
 	utils.Logger().Debug().
 		Hex("m1Payload", vcMsg.Payload).
-		Str("pubKey", consensus.PubKey.SerializeToHexStr()).
+		Str("pubKey", consensus.GetPublicKeys().SerializeToHexStr()).
 		Msg("[constructViewChangeMessage]")
 
 	sign := priKey.SignHash(msgToSign)
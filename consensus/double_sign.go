@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	protobuf "github.com/golang/protobuf/proto"
 	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
 	"github.com/harmony-one/harmony/consensus/quorum"
 	"github.com/harmony-one/harmony/consensus/votepower"
 	"github.com/harmony-one/harmony/shard"
@@ -16,92 +18,233 @@ import (
 // Returns true when it is a double-sign or there is error, otherwise, false.
 func (consensus *Consensus) checkDoubleSign(recvMsg *FBFTMessage) bool {
 	if consensus.couldThisBeADoubleSigner(recvMsg) {
-		if alreadyCastBallot := consensus.Decider.ReadBallot(
-			quorum.Commit, recvMsg.SenderPubkey,
-		); alreadyCastBallot != nil {
-			firstPubKey := bls.PublicKey{}
-			alreadyCastBallot.SignerPubKey.ToLibBLSPublicKey(&firstPubKey)
-			if recvMsg.SenderPubkey.IsEqual(&firstPubKey) {
-				for _, blk := range consensus.FBFTLog.GetBlocksByNumber(recvMsg.BlockNum) {
-					firstSignedBlock := blk.Header()
-					areHeightsEqual := firstSignedBlock.Number().Uint64() == recvMsg.BlockNum
-					areViewIDsEqual := firstSignedBlock.ViewID().Uint64() == recvMsg.ViewID
-					areHeadersEqual := firstSignedBlock.Hash() == recvMsg.BlockHash
-
-					// If signer already firstSignedBlock, and the block height is the same
-					// and the viewID is the same, then we need to verify the block
-					// hash, and if block hash is different, then that is a clear
-					// case of double signing
-					if areHeightsEqual && areViewIDsEqual && !areHeadersEqual {
-						var doubleSign bls.Sign
-						if err := doubleSign.Deserialize(recvMsg.Payload); err != nil {
-							consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
-								Msg("could not deserialize potential double signer")
-							return true
-						}
-
-						curHeader := consensus.ChainReader.CurrentHeader()
-						committee, err := consensus.ChainReader.ReadShardState(curHeader.Epoch())
-						if err != nil {
-							consensus.getLogger().Err(err).
-								Uint32("shard", consensus.ShardID).
-								Uint64("epoch", curHeader.Epoch().Uint64()).
-								Msg("could not read shard state")
-							return true
-						}
-						offender := *shard.FromLibBLSPublicKeyUnsafe(recvMsg.SenderPubkey)
-						subComm, err := committee.FindCommitteeByID(
-							consensus.ShardID,
-						)
-						if err != nil {
-							consensus.getLogger().Err(err).
-								Str("msg", recvMsg.String()).
-								Msg("could not find subcommittee for bls key")
-							return true
-						}
-
-						addr, err := subComm.AddressForBLSKey(offender)
-
-						if err != nil {
-							consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
-								Msg("could not find address for bls key")
-							return true
-						}
-
-						now := big.NewInt(time.Now().UnixNano())
-
-						go func(reporter common.Address) {
-							evid := slash.Evidence{
-								ConflictingBallots: slash.ConflictingBallots{
-									AlreadyCastBallot: *alreadyCastBallot,
-									DoubleSignedBallot: votepower.Ballot{
-										SignerPubKey:    offender,
-										BlockHeaderHash: recvMsg.BlockHash,
-										Signature:       common.Hex2Bytes(doubleSign.SerializeToHexStr()),
-										Height:          recvMsg.BlockNum,
-										ViewID:          recvMsg.ViewID,
-									}},
-								Moment: slash.Moment{
-									Epoch:        curHeader.Epoch(),
-									ShardID:      consensus.ShardID,
-									TimeUnixNano: now,
-								},
-							}
-							proof := slash.Record{
-								Evidence: evid,
-								Reporter: reporter,
-								Offender: *addr,
-							}
-							consensus.SlashChan <- proof
-						}(consensus.SelfAddresses[consensus.LeaderPubKey.SerializeToHexStr()])
-						return true
-					}
-				}
+		consensus.reportConflictingCommit(recvMsg, recvMsg.SenderPubkey, recvMsg.Payload)
+		return true
+	}
+	return false
+}
+
+// checkAggregatedDoubleSign is checkDoubleSign for an aggregated commit, whose
+// signers are the potential double signers. Only the aggregate of a single vote
+// is the signature of its signer, the signers of larger aggregates are reported
+// from their direct votes, which the leader checks as well.
+func (consensus *Consensus) checkAggregatedDoubleSign(recvMsg *FBFTMessage) bool {
+	if !consensus.couldThisBeADoubleSigner(recvMsg) {
+		return false
+	}
+	aggSig, mask, err := consensus.ReadSignatureBitmapPayload(recvMsg.Payload, 0)
+	if err != nil {
+		consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
+			Msg("could not read aggregated commit of potential double signers")
+		return true
+	}
+	if signers := mask.GetPubKeyFromMask(true); len(signers) == 1 {
+		consensus.reportConflictingCommit(recvMsg, signers[0], aggSig.Serialize())
+	}
+	return true
+}
+
+// reportConflictingCommit reports the commit of signer in recvMsg when the
+// signer already committed to another block at the same height and view
+func (consensus *Consensus) reportConflictingCommit(
+	recvMsg *FBFTMessage, signer *bls.PublicKey, signature []byte,
+) {
+	alreadyCastBallot := consensus.Decider.ReadBallot(quorum.Commit, signer)
+	if alreadyCastBallot == nil {
+		return
+	}
+	firstPubKey := bls.PublicKey{}
+	alreadyCastBallot.SignerPubKey.ToLibBLSPublicKey(&firstPubKey)
+	if !signer.IsEqual(&firstPubKey) {
+		return
+	}
+	for _, blk := range consensus.FBFTLog.GetBlocksByNumber(recvMsg.BlockNum) {
+		firstSignedBlock := blk.Header()
+		areHeightsEqual := firstSignedBlock.Number().Uint64() == recvMsg.BlockNum
+		areViewIDsEqual := firstSignedBlock.ViewID().Uint64() == recvMsg.ViewID
+		areHeadersEqual := firstSignedBlock.Hash() == recvMsg.BlockHash
+
+		// If signer already firstSignedBlock, and the block height is the same
+		// and the viewID is the same, then we need to verify the block
+		// hash, and if block hash is different, then that is a clear
+		// case of double signing
+		if areHeightsEqual && areViewIDsEqual && !areHeadersEqual {
+			var doubleSign bls.Sign
+			if err := doubleSign.Deserialize(signature); err != nil {
+				consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
+					Msg("could not deserialize potential double signer")
+				return
+			}
+			if !isCommitSignatureOf(alreadyCastBallot, &firstPubKey) {
+				// a ballot counted from an aggregate carries the aggregated
+				// signature, which is no evidence against a single signer
+				consensus.getLogger().Warn().Str("msg", recvMsg.String()).
+					Str("signer", signer.SerializeToHexStr()).
+					Msg("double signer first committed through an aggregate, not reported")
+				return
 			}
+			consensus.reportDoubleSign(recvMsg, slash.ConflictingBallots{
+				AlreadyCastBallot: *alreadyCastBallot,
+				DoubleSignedBallot: votepower.Ballot{
+					SignerPubKey:    *shard.FromLibBLSPublicKeyUnsafe(signer),
+					BlockHeaderHash: recvMsg.BlockHash,
+					Signature:       common.Hex2Bytes(doubleSign.SerializeToHexStr()),
+					Height:          recvMsg.BlockNum,
+					ViewID:          recvMsg.ViewID,
+				}}, nil,
+			)
+			return
+		}
+	}
+}
+
+// isCommitSignatureOf tells whether the signature of the commit ballot is the
+// one of the given key alone
+func isCommitSignatureOf(ballot *votepower.Ballot, pubKey *bls.PublicKey) bool {
+	var sig bls.Sign
+	if err := sig.Deserialize(ballot.Signature); err != nil {
+		return false
+	}
+	return sig.VerifyHash(
+		pubKey, votePayload(quorum.Commit, ballot.Height, ballot.BlockHeaderHash),
+	)
+}
+
+// reportDoubleSign sends the slash record of the conflicting ballots found
+// on recvMsg to the node, which broadcasts it to the beacon chain
+func (consensus *Consensus) reportDoubleSign(
+	recvMsg *FBFTMessage, ballots slash.ConflictingBallots, signedMessages [][]byte,
+) {
+	curHeader := consensus.ChainReader.CurrentHeader()
+	committee, err := consensus.ChainReader.ReadShardState(curHeader.Epoch())
+	if err != nil {
+		consensus.getLogger().Err(err).
+			Uint32("shard", consensus.ShardID).
+			Uint64("epoch", curHeader.Epoch().Uint64()).
+			Msg("could not read shard state")
+		return
+	}
+	offender := ballots.DoubleSignedBallot.SignerPubKey
+	subComm, err := committee.FindCommitteeByID(
+		consensus.ShardID,
+	)
+	if err != nil {
+		consensus.getLogger().Err(err).
+			Str("msg", recvMsg.String()).
+			Msg("could not find subcommittee for bls key")
+		return
+	}
+
+	addr, err := subComm.AddressForBLSKey(offender)
+
+	if err != nil {
+		consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
+			Msg("could not find address for bls key")
+		return
+	}
+
+	now := big.NewInt(time.Now().UnixNano())
+
+	go func(reporter common.Address) {
+		evid := slash.Evidence{
+			ConflictingBallots: ballots,
+			Moment: slash.Moment{
+				Epoch:        curHeader.Epoch(),
+				ShardID:      consensus.ShardID,
+				TimeUnixNano: now,
+			},
+			SignedMessages: signedMessages,
 		}
+		proof := slash.Record{
+			Evidence: evid,
+			Reporter: reporter,
+			Offender: *addr,
+		}
+		consensus.SlashChan <- proof
+	}(consensus.SelfAddresses[consensus.LeaderPubKey.SerializeToHexStr()])
+}
+
+// signedVoteKey identifies the vote of a signer in one phase, height and view
+type signedVoteKey struct {
+	msgType  msg_pb.MessageType
+	blockNum uint64
+	viewID   uint64
+	signer   shard.BLSPublicKey
+}
+
+// signedVote is the first vote seen from a signer, with the message carrying it
+type signedVote struct {
+	ballot  votepower.Ballot
+	message []byte
+}
+
+// checkConflictingVote remembers the first prepare or view change message of
+// each signer per height and view, and reports a double sign when the signer
+// later vouches for another block in the same phase, height and view.
+// Returns true when it is a double sign.
+func (consensus *Consensus) checkConflictingVote(
+	msg *msg_pb.Message, recvMsg *FBFTMessage, vouched common.Hash, signature []byte,
+) bool {
+	// a NIL view change vouches for no block, it does not conflict with
+	// the prepared block the same signer vouches for in the same view
+	if vouched == (common.Hash{}) {
+		return false
+	}
+	ballot := votepower.Ballot{
+		SignerPubKey:    *shard.FromLibBLSPublicKeyUnsafe(recvMsg.SenderPubkey),
+		BlockHeaderHash: vouched,
+		Signature:       signature,
+		Height:          recvMsg.BlockNum,
+		ViewID:          recvMsg.ViewID,
+	}
+	key := signedVoteKey{
+		msgType:  msg.GetType(),
+		blockNum: recvMsg.BlockNum,
+		viewID:   recvMsg.ViewID,
+		signer:   ballot.SignerPubKey,
+	}
+
+	consensus.signedVotesLock.Lock()
+	first, seen := consensus.signedVotes[key]
+	if !seen {
+		message, err := protobuf.Marshal(msg)
+		if err == nil {
+			consensus.signedVotes[key] = &signedVote{ballot: ballot, message: message}
+		}
+	}
+	consensus.signedVotesLock.Unlock()
+	if !seen || first.ballot.BlockHeaderHash == vouched {
+		return false
+	}
+
+	message, err := protobuf.Marshal(msg)
+	if err != nil {
+		consensus.getLogger().Err(err).Str("msg", recvMsg.String()).
+			Msg("could not marshal conflicting vote")
 		return true
 	}
-	return false
+	consensus.getLogger().Warn().
+		Str("msgType", msg.GetType().String()).
+		Str("signer", recvMsg.SenderPubkey.SerializeToHexStr()).
+		Uint64("blockNum", recvMsg.BlockNum).
+		Uint64("viewID", recvMsg.ViewID).
+		Msg("conflicting votes from the same signer")
+	consensus.reportDoubleSign(recvMsg, slash.ConflictingBallots{
+		AlreadyCastBallot:  first.ballot,
+		DoubleSignedBallot: ballot,
+	}, [][]byte{first.message, message})
+	return true
+}
+
+// pruneSignedVotes forgets the votes below the height on consensus
+func (consensus *Consensus) pruneSignedVotes() {
+	consensus.signedVotesLock.Lock()
+	defer consensus.signedVotesLock.Unlock()
+	for key := range consensus.signedVotes {
+		if key.blockNum < consensus.blockNum {
+			delete(consensus.signedVotes, key)
+		}
+	}
 }
 
 func (consensus *Consensus) couldThisBeADoubleSigner(
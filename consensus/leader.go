@@ -12,6 +12,7 @@ import (
 	"github.com/harmony-one/harmony/core/types"
 	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
 	"github.com/harmony-one/harmony/p2p/host"
+	"github.com/rs/zerolog"
 )
 
 func (consensus *Consensus) announce(block *types.Block) {
@@ -56,11 +57,12 @@ func (consensus *Consensus) announce(block *types.Block) {
 	consensus.FBFTLog.AddBlock(block)
 
 	// Leader sign the block hash itself
-	for i, key := range consensus.PubKey.PublicKey {
+	keys := consensus.getSigningKeys()
+	for i, key := range keys.pubKey.PublicKey {
 		if _, err := consensus.Decider.SubmitVote(
 			quorum.Prepare,
 			key,
-			consensus.priKey.PrivateKey[i].SignHash(consensus.blockHash[:]),
+			keys.priKey.PrivateKey[i].SignHash(consensus.blockHash[:]),
 			common.BytesToHash(consensus.blockHash[:]),
 			consensus.blockNum,
 			consensus.viewID,
@@ -134,6 +136,20 @@ func (consensus *Consensus) onPrepare(msg *msg_pb.Message) {
 	logger := consensus.getLogger().With().
 		Str("validatorPubKey", validatorPubKey.SerializeToHexStr()).Logger()
 
+	if isAggregatedVote(recvMsg) {
+		consensus.onAggregatedPrepare(recvMsg, logger)
+		return
+	}
+	if consensus.checkConflictingVote(msg, recvMsg, recvMsg.BlockHash, prepareSig) {
+		logger.Warn().Msg("[OnPrepare] Conflicting prepare from the validator")
+		return
+	}
+	if !consensus.acceptsDirectVote(quorum.Prepare, validatorPubKey) {
+		logger.Debug().Msg("[OnPrepare] Vote bypassed its aggregator, held back")
+		consensus.holdDirectVote(quorum.Prepare, msg)
+		return
+	}
+
 	// proceed only when the message is not received before
 	signed := consensus.Decider.ReadBallot(quorum.Prepare, validatorPubKey)
 	if signed != nil {
@@ -178,7 +194,28 @@ func (consensus *Consensus) onPrepare(msg *msg_pb.Message) {
 		consensus.getLogger().Warn().Err(err).Msg("[OnPrepare] prepareBitmap.SetKey failed")
 		return
 	}
+	consensus.checkPrepareQuorum()
+}
 
+func (consensus *Consensus) onAggregatedPrepare(recvMsg *FBFTMessage, logger zerolog.Logger) {
+	if consensus.Decider.IsQuorumAchieved(quorum.Prepare) {
+		// already have enough signatures
+		logger.Debug().Msg("[OnPrepare] Received Additional Aggregated Prepare Message")
+		return
+	}
+	if err := consensus.submitAggregatedVote(
+		quorum.Prepare, recvMsg, consensus.prepareBitmap,
+	); err != nil {
+		logger.Warn().Err(err).Msg("[OnPrepare] Aggregated prepare rejected")
+		return
+	}
+	logger.Info().
+		Int64("NumReceivedSoFar", consensus.Decider.SignersCount(quorum.Prepare)).
+		Msg("[OnPrepare] Received New Aggregated Prepare Signature")
+	consensus.checkPrepareQuorum()
+}
+
+func (consensus *Consensus) checkPrepareQuorum() {
 	if consensus.Decider.IsQuorumAchieved(quorum.Prepare) {
 		// NOTE Let it handle its own logs
 		if err := consensus.didReachPrepareQuorum(); err != nil {
@@ -203,15 +240,26 @@ func (consensus *Consensus) onCommit(msg *msg_pb.Message) {
 	consensus.mutex.Lock()
 	defer consensus.mutex.Unlock()
 
+	validatorPubKey, commitSig, commitBitmap :=
+		recvMsg.SenderPubkey, recvMsg.Payload, consensus.commitBitmap
+	logger := consensus.getLogger().With().
+		Str("validatorPubKey", validatorPubKey.SerializeToHexStr()).Logger()
+
+	if isAggregatedVote(recvMsg) {
+		consensus.onAggregatedCommit(recvMsg, logger)
+		return
+	}
+
 	// Check for potential double signing
 	if consensus.checkDoubleSign(recvMsg) {
 		return
 	}
 
-	validatorPubKey, commitSig, commitBitmap :=
-		recvMsg.SenderPubkey, recvMsg.Payload, consensus.commitBitmap
-	logger := consensus.getLogger().With().
-		Str("validatorPubKey", validatorPubKey.SerializeToHexStr()).Logger()
+	if !consensus.acceptsDirectVote(quorum.Commit, validatorPubKey) {
+		logger.Debug().Msg("[OnCommit] Vote bypassed its aggregator, held back")
+		consensus.holdDirectVote(quorum.Commit, msg)
+		return
+	}
 
 	// has to be called before verifying signature
 	quorumWasMet := consensus.Decider.IsQuorumAchieved(quorum.Commit)
@@ -255,6 +303,30 @@ func (consensus *Consensus) onCommit(msg *msg_pb.Message) {
 		return
 	}
 
+	consensus.checkCommitQuorum(quorumWasMet, logger)
+}
+
+func (consensus *Consensus) onAggregatedCommit(recvMsg *FBFTMessage, logger zerolog.Logger) {
+	// Check for potential double signing of the signers
+	if consensus.checkAggregatedDoubleSign(recvMsg) {
+		return
+	}
+	// has to be called before submitting the aggregate
+	quorumWasMet := consensus.Decider.IsQuorumAchieved(quorum.Commit)
+	if err := consensus.submitAggregatedVote(
+		quorum.Commit, recvMsg, consensus.commitBitmap,
+	); err != nil {
+		logger.Warn().Err(err).Msg("[OnCommit] Aggregated commit rejected")
+		return
+	}
+	logger = logger.With().
+		Int64("numReceivedSoFar", consensus.Decider.SignersCount(quorum.Commit)).
+		Logger()
+	logger.Info().Msg("[OnCommit] Received new aggregated commit message")
+	consensus.checkCommitQuorum(quorumWasMet, logger)
+}
+
+func (consensus *Consensus) checkCommitQuorum(quorumWasMet bool, logger zerolog.Logger) {
 	quorumIsMet := consensus.Decider.IsQuorumAchieved(quorum.Commit)
 	if !quorumWasMet && quorumIsMet {
 		logger.Info().Msg("[OnCommit] 2/3 Enough commits received")
@@ -0,0 +1,199 @@
+package consensus
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	"github.com/harmony-one/harmony/crypto/hash"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard/committee"
+)
+
+// Leader rotation
+//
+// Once the LeaderRotationEpoch fork is active, leadership hands over every
+// leaderRotationBlocks blocks to a leader drawn from the committee with
+// probability proportional to its voting power. The draw is seeded by the shard,
+// the epoch and the hash of the block before the turn, so every validator
+// computes the same leader, while the order of the leaders is not known ahead
+// of the chain. A view change still replaces a scheduled leader which does not
+// make progress, the next rotation then follows the schedule again.
+
+var rotationSeedRange = new(big.Int).Lsh(big.NewInt(1), 64)
+
+// rotationSeed returns the seed of the draw of the leader of the rotation turn
+// starting at a block of the given epoch, whose parent has the given hash
+func rotationSeed(shardID uint32, epoch *big.Int, turn uint64, parentHash common.Hash) []byte {
+	seed := make([]byte, 12)
+	binary.BigEndian.PutUint32(seed[:4], shardID)
+	binary.BigEndian.PutUint64(seed[4:], turn)
+	seed = append(seed, common.LeftPadBytes(epoch.Bytes(), 32)...)
+	return hash.Keccak256(append(seed, parentHash[:]...))
+}
+
+// rotationLeader picks the leader of a rotation turn among participants,
+// weighted by their voting power, with the given seed
+func rotationLeader(
+	participants []*bls.PublicKey,
+	votingPower func(*bls.PublicKey) numeric.Dec,
+	seed []byte,
+) *bls.PublicKey {
+	if len(participants) == 0 {
+		return nil
+	}
+	total := numeric.ZeroDec()
+	powers := make([]numeric.Dec, len(participants))
+	for i := range participants {
+		powers[i] = votingPower(participants[i])
+		total = total.Add(powers[i])
+	}
+	draw := new(big.Int).SetBytes(seed[:8])
+	if !total.IsPositive() {
+		return participants[draw.Uint64()%uint64(len(participants))]
+	}
+
+	// target is uniformly distributed in [0, total)
+	target := total.Mul(numeric.NewDecFromBigInt(draw)).Quo(
+		numeric.NewDecFromBigInt(rotationSeedRange),
+	)
+
+	cumulative := numeric.ZeroDec()
+	for i := range participants {
+		cumulative = cumulative.Add(powers[i])
+		if cumulative.GT(target) {
+			return participants[i]
+		}
+	}
+	return participants[len(participants)-1]
+}
+
+// epochOfChild returns the epoch of the block following the given header
+func epochOfChild(config *params.ChainConfig, parent *block.Header) *big.Int {
+	if shardState, err := parent.GetShardState(); err == nil &&
+		shardState.Epoch != nil && config.IsStaking(shardState.Epoch) {
+		// the shard state of staking epochs decides the next epoch
+		return new(big.Int).Set(shardState.Epoch)
+	}
+	if len(parent.ShardState()) > 0 && parent.Number().Uint64() != 0 {
+		return new(big.Int).Add(parent.Epoch(), common.Big1)
+	}
+	return new(big.Int).Set(parent.Epoch())
+}
+
+// rotationParent returns the parent of the given block when a new leader takes
+// over at the block, nil otherwise
+func (consensus *Consensus) rotationParent(blockNum uint64) *block.Header {
+	if consensus.ChainReader == nil || blockNum == 0 || blockNum%leaderRotationBlocks != 0 {
+		return nil
+	}
+	parent := consensus.ChainReader.GetHeaderByNumber(blockNum - 1)
+	if parent == nil {
+		return nil
+	}
+	config := consensus.ChainReader.Config()
+	if !config.IsLeaderRotation(epochOfChild(config, parent)) {
+		return nil
+	}
+	return parent
+}
+
+// isLeaderRotationBlock tells whether a new leader takes over at the given block
+func (consensus *Consensus) isLeaderRotationBlock(blockNum uint64) bool {
+	return consensus.rotationParent(blockNum) != nil
+}
+
+// rotationDecider returns the committee of the given epoch with the voting
+// power of its members. It is read from the chain rather than taken from the
+// Decider, which may still hold the committee of the previous epoch at the
+// first block of an epoch.
+func (consensus *Consensus) rotationDecider(epoch *big.Int) (quorum.Decider, error) {
+	shardState, err := committee.WithStakingEnabled.ReadFromDB(epoch, consensus.ChainReader)
+	if err != nil {
+		return nil, err
+	}
+	subComm, err := shardState.FindCommitteeByID(consensus.ShardID)
+	if err != nil {
+		return nil, err
+	}
+	pubKeys, err := subComm.BLSPublicKeys()
+	if err != nil {
+		return nil, err
+	}
+	policy := quorum.SuperMajorityVote
+	if consensus.ChainReader.Config().IsStaking(epoch) {
+		policy = quorum.SuperMajorityStake
+	}
+	decider := quorum.NewDecider(policy, consensus.ShardID)
+	decider.UpdateParticipants(pubKeys)
+	if _, err := decider.SetVoters(subComm, epoch); err != nil {
+		return nil, err
+	}
+	return decider, nil
+}
+
+// scheduledLeader returns the leader scheduled to propose the given block,
+// nil if the block does not start a rotation turn
+func (consensus *Consensus) scheduledLeader(blockNum uint64) *bls.PublicKey {
+	parent := consensus.rotationParent(blockNum)
+	if parent == nil {
+		return nil
+	}
+	epoch := epochOfChild(consensus.ChainReader.Config(), parent)
+	decider, err := consensus.rotationDecider(epoch)
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).
+			Uint64("blockNum", blockNum).
+			Uint64("epoch", epoch.Uint64()).
+			Msg("[ScheduledLeader] Cannot read the committee of the rotation turn")
+		return nil
+	}
+	return rotationLeader(
+		decider.Participants(),
+		decider.VotingPower,
+		rotationSeed(consensus.ShardID, epoch, blockNum/leaderRotationBlocks, parent.Hash()),
+	)
+}
+
+// rotateLeaderIfScheduled hands over leadership when the next block
+// starts a new rotation turn, caller holds the consensus mutex
+func (consensus *Consensus) rotateLeaderIfScheduled() {
+	if !consensus.isLeaderRotationBlock(consensus.blockNum) {
+		return
+	}
+	next := consensus.scheduledLeader(consensus.blockNum)
+	consensus.rotationViewID = consensus.viewID
+	if next == nil || next.IsEqual(consensus.LeaderPubKey) {
+		return
+	}
+	wasLeader := consensus.IsLeader()
+	consensus.LeaderPubKey = next
+	consensus.getLogger().Info().
+		Str("leaderKey", next.SerializeToHexStr()).
+		Uint64("blockNum", consensus.blockNum).
+		Msg("[RotateLeader] Leader rotated as scheduled")
+	if !wasLeader && consensus.IsLeader() {
+		go func() {
+			consensus.getLogger().Debug().
+				Str("myKey", consensus.GetPublicKeys().SerializeToHexStr()).
+				Msg("[RotateLeader] I am the New Leader")
+			consensus.ReadySignal <- struct{}{}
+		}()
+	}
+}
+
+// isScheduledLeader checks the sender of an announce starting a rotation turn
+// is the scheduled leader, unless a view change replaced it since
+func (consensus *Consensus) isScheduledLeader(recvMsg *FBFTMessage) bool {
+	if recvMsg.BlockNum != consensus.blockNum ||
+		recvMsg.ViewID != consensus.rotationViewID ||
+		!consensus.isLeaderRotationBlock(recvMsg.BlockNum) {
+		return true
+	}
+	scheduled := consensus.scheduledLeader(recvMsg.BlockNum)
+	return scheduled == nil || recvMsg.SenderPubkey.IsEqual(scheduled)
+}
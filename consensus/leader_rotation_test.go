@@ -0,0 +1,241 @@
+package consensus
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/chain"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/p2pimpl"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestRotationLeader(t *testing.T) {
+	participants := []*bls.PublicKey{}
+	for i := 0; i < 4; i++ {
+		participants = append(participants, bls_cosi.RandPrivateKey().GetPublicKey())
+	}
+	// the last participant holds no voting power and must never lead
+	votingPower := func(key *bls.PublicKey) numeric.Dec {
+		if key.IsEqual(participants[3]) {
+			return numeric.ZeroDec()
+		}
+		return numeric.NewDecWithPrec(1, 1)
+	}
+
+	epoch := big.NewInt(3)
+	picked := map[int]int{}
+	for turn := uint64(0); turn < 300; turn++ {
+		parentHash := common.BigToHash(new(big.Int).SetUint64(turn * 7))
+		seed := rotationSeed(0, epoch, turn, parentHash)
+		leader := rotationLeader(participants, votingPower, seed)
+		if again := rotationLeader(participants, votingPower, seed); !leader.IsEqual(again) {
+			t.Fatalf("rotation leader of turn %d is not deterministic", turn)
+		}
+		for i := range participants {
+			if leader.IsEqual(participants[i]) {
+				picked[i]++
+			}
+		}
+	}
+	if picked[3] != 0 {
+		t.Errorf("participant without voting power picked %d times", picked[3])
+	}
+	for i := 0; i < 3; i++ {
+		if picked[i] == 0 {
+			t.Errorf("participant %d never picked as leader", i)
+		}
+	}
+
+	if rotationLeader(nil, votingPower, rotationSeed(0, epoch, 1, common.Hash{})) != nil {
+		t.Error("expected no leader without participants")
+	}
+}
+
+func TestRotationSeed(t *testing.T) {
+	parentHash := common.BytesToHash([]byte("parent"))
+	seed := rotationSeed(0, big.NewInt(3), 10, parentHash)
+	for _, other := range [][]byte{
+		rotationSeed(1, big.NewInt(3), 10, parentHash),
+		rotationSeed(0, big.NewInt(4), 10, parentHash),
+		rotationSeed(0, big.NewInt(3), 11, parentHash),
+		rotationSeed(0, big.NewInt(3), 10, common.BytesToHash([]byte("other parent"))),
+	} {
+		if bytes.Equal(seed, other) {
+			t.Errorf("rotation seed %x does not depend on all its inputs", seed)
+		}
+	}
+	if !bytes.Equal(seed, rotationSeed(0, big.NewInt(3), 10, parentHash)) {
+		t.Error("rotation seed is not deterministic")
+	}
+}
+
+// rotationTestChain is a beacon chain of unexecuted blocks, with generated
+// committees for epochs 0 and 1
+type rotationTestChain struct {
+	chain      *core.BlockChain
+	committees map[int64][]*bls.PublicKey
+}
+
+// testShardState returns the shard state of the beacon chain committee of the epoch
+func testShardState(epoch int64, keys []*bls.PublicKey) shard.State {
+	committee := shard.Committee{ShardID: shard.BeaconChainShardID}
+	for _, key := range keys {
+		committee.Slots = append(committee.Slots, shard.Slot{
+			BLSPublicKey: *shard.FromLibBLSPublicKeyUnsafe(key),
+		})
+	}
+	return shard.State{Epoch: big.NewInt(epoch), Shards: []shard.Committee{committee}}
+}
+
+func newRotationTestChain(t *testing.T, committeeSize int) *rotationTestChain {
+	c := &rotationTestChain{committees: map[int64][]*bls.PublicKey{}}
+	for epoch := int64(0); epoch < 2; epoch++ {
+		for i := 0; i < committeeSize; i++ {
+			c.committees[epoch] = append(c.committees[epoch], bls_cosi.RandPrivateKey().GetPublicKey())
+		}
+	}
+	// leader rotation without staking, every member holds the same voting power
+	config := *params.TestChainConfig
+	config.StakingEpoch = big.NewInt(100)
+	config.PreStakingEpoch = big.NewInt(100)
+	database := ethdb.NewMemDatabase()
+	gspec := core.Genesis{
+		Config:     &config,
+		Factory:    blockfactory.ForTest,
+		ShardID:    shard.BeaconChainShardID,
+		ShardState: testShardState(0, c.committees[0]),
+	}
+	gspec.MustCommit(database)
+	bc, err := core.NewBlockChain(database, nil, &config, chain.Engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.chain = bc
+	return c
+}
+
+// extend adds blocks up to the given one, the last one carrying the committee
+// of the next epoch if nextEpoch is set
+func (c *rotationTestChain) extend(t *testing.T, last uint64, nextEpoch bool) {
+	for head := c.chain.CurrentHeader(); head.Number().Uint64() < last; head = c.chain.CurrentHeader() {
+		number := head.Number().Uint64() + 1
+		epoch := epochOfChild(c.chain.Config(), head)
+		header := blockfactory.ForTest.NewHeader(epoch).With().
+			Number(new(big.Int).SetUint64(number)).
+			ParentHash(head.Hash()).
+			ViewID(new(big.Int).SetUint64(number)).
+			ShardID(shard.BeaconChainShardID).
+			Header()
+		if number == last && nextEpoch {
+			next := new(big.Int).Add(epoch, common.Big1)
+			shardState, err := shard.EncodeWrapper(
+				testShardState(next.Int64(), c.committees[next.Int64()]), false,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header.SetShardState(shardState)
+		}
+		if err := c.chain.WriteHeadBlockUnexecuted(types.NewBlockWithHeader(header)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScheduledLeaderEnforced(t *testing.T) {
+	leader := p2p.Peer{IP: "127.0.0.1", Port: "9904"}
+	priKey, _, _ := utils.GenKeyP2P("127.0.0.1", "9904")
+	host, err := p2pimpl.NewHost(&leader, priKey)
+	if err != nil {
+		t.Fatalf("newhost failure: %v", err)
+	}
+	c := newRotationTestChain(t, 8)
+	consensus, err := New(
+		host, shard.BeaconChainShardID, leader, multibls.GetPrivateKey(bls_cosi.RandPrivateKey()),
+		quorum.NewDecider(quorum.SuperMajorityVote, shard.BeaconChainShardID),
+	)
+	if err != nil {
+		t.Fatalf("Cannot create consensus: %v", err)
+	}
+	consensus.ChainReader = c.chain
+
+	tests := []struct {
+		name     string
+		last     uint64
+		newEpoch bool
+	}{
+		// a turn within epoch 0, with the committee of the Decider
+		{"within epoch", leaderRotationBlocks - 1, false},
+		// a turn at the first block of epoch 1, with the Decider on epoch 0 still
+		{"first block of epoch", 2*leaderRotationBlocks - 1, true},
+	}
+	for _, test := range tests {
+		c.extend(t, test.last, test.newEpoch)
+		parent := c.chain.CurrentHeader()
+		blockNum := parent.Number().Uint64() + 1
+		epoch := epochOfChild(c.chain.Config(), parent)
+		if test.newEpoch && epoch.Int64() != 1 {
+			t.Fatalf("%s: block %d is in epoch %v", test.name, blockNum, epoch)
+		}
+		if !test.newEpoch {
+			// the chain moves the Decider to the committee of the head block
+			consensus.UpdateConsensusInformation()
+		}
+		participants := c.committees[epoch.Int64()]
+		scheduled := rotationLeader(
+			participants,
+			func(*bls.PublicKey) numeric.Dec { return numeric.OneDec() },
+			rotationSeed(shard.BeaconChainShardID, epoch, blockNum/leaderRotationBlocks, parent.Hash()),
+		)
+		consensus.SetBlockNum(blockNum)
+		consensus.SetViewID(parent.ViewID().Uint64() + 1)
+		consensus.rotationViewID = consensus.viewID
+
+		announce := func(sender *bls.PublicKey, viewID uint64) *FBFTMessage {
+			return &FBFTMessage{
+				MessageType:  msg_pb.MessageType_ANNOUNCE,
+				BlockNum:     blockNum,
+				ViewID:       viewID,
+				BlockHash:    common.BytesToHash([]byte(test.name)),
+				SenderPubkey: sender,
+			}
+		}
+		if !consensus.onAnnounceSanityChecks(announce(scheduled, consensus.viewID)) {
+			t.Errorf("%s: announce of the scheduled leader rejected", test.name)
+		}
+		others := append([]*bls.PublicKey{}, participants...)
+		if test.newEpoch {
+			// the Decider still holds the committee of the previous epoch
+			others = append(others, c.committees[0]...)
+		}
+		for _, other := range others {
+			if other.IsEqual(scheduled) {
+				continue
+			}
+			if consensus.onAnnounceSanityChecks(announce(other, consensus.viewID)) {
+				t.Errorf("%s: announce of %s instead of the scheduled leader accepted",
+					test.name, other.SerializeToHexStr()[:16])
+			}
+			// a view change replaces the scheduled leader
+			if !consensus.onAnnounceSanityChecks(announce(other, consensus.viewID+1)) {
+				t.Errorf("%s: announce of the leader after a view change rejected", test.name)
+			}
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math/big"
 
+	"github.com/harmony-one/bls/ffi/go/bls"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/numeric"
@@ -52,6 +53,14 @@ func (v *uniformVoteWeight) QuorumThreshold() numeric.Dec {
 	return numeric.NewDec(v.TwoThirdsSignersCount())
 }
 
+// VotingPower ..
+func (v *uniformVoteWeight) VotingPower(pubKey *bls.PublicKey) numeric.Dec {
+	if v.IndexOf(pubKey) == -1 {
+		return numeric.ZeroDec()
+	}
+	return numeric.OneDec().QuoInt64(v.ParticipantsCount())
+}
+
 // IsAllSigsCollected ..
 func (v *uniformVoteWeight) IsAllSigsCollected() bool {
 	return v.SignersCount(Commit) == v.ParticipantsCount()
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math/big"
 
+	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/consensus/votepower"
 	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	common2 "github.com/harmony-one/harmony/internal/common"
@@ -136,6 +137,19 @@ func (v *stakedVoteWeight) QuorumThreshold() numeric.Dec {
 	return twoThird
 }
 
+// VotingPower ..
+func (v *stakedVoteWeight) VotingPower(pubKey *bls.PublicKey) numeric.Dec {
+	w := shard.BLSPublicKey{}
+	if err := w.FromLibBLSPublicKey(pubKey); err != nil {
+		return numeric.ZeroDec()
+	}
+	voter, ok := v.roster.Voters[w]
+	if !ok {
+		return numeric.ZeroDec()
+	}
+	return voter.OverallPercent
+}
+
 // IsAllSigsCollected ..
 func (v *stakedVoteWeight) IsAllSigsCollected() bool {
 	return v.SignersCount(Commit) == v.ParticipantsCount()
@@ -32,7 +32,8 @@ var (
 		Commit:     "Commit",
 		ViewChange: "viewChange",
 	}
-	errPhaseUnknown = errors.New("invariant of known phase violated")
+	errPhaseUnknown      = errors.New("invariant of known phase violated")
+	errAggregateOverlaps = errors.New("aggregated vote overlaps already cast ballots")
 )
 
 func (p Phase) String() string {
@@ -82,6 +83,13 @@ type SignatoryTracker interface {
 		sig *bls.Sign, headerHash common.Hash,
 		height, viewID uint64,
 	) (*votepower.Ballot, error)
+	// SubmitAggregateVote records a ballot for each of the given keys,
+	// all of them carrying the same partially aggregated signature
+	SubmitAggregateVote(
+		p Phase, pubKeys []*bls.PublicKey,
+		aggSig *bls.Sign, headerHash common.Hash,
+		height, viewID uint64,
+	) ([]*votepower.Ballot, error)
 	// Caller assumes concurrency protection
 	SignersCount(Phase) int64
 	reset([]Phase)
@@ -117,6 +125,8 @@ type Decider interface {
 	IsQuorumAchieved(Phase) bool
 	IsQuorumAchievedByMask(mask *bls_cosi.Mask) bool
 	QuorumThreshold() numeric.Dec
+	// VotingPower is the share of the total voting power held by the key
+	VotingPower(*bls.PublicKey) numeric.Dec
 	AmIMemberOfCommitee() bool
 	IsAllSigsCollected() bool
 	ResetPrepareAndCommitVotes()
@@ -160,12 +170,20 @@ type depInject struct {
 func (s *cIdentities) AggregateVotes(p Phase) *bls.Sign {
 	ballots := s.ReadAllBallots(p)
 	sigs := make([]*bls.Sign, 0, len(ballots))
+	// ballots submitted through an aggregated vote share the same
+	// signature, which must only be counted once
+	seen := map[string]struct{}{}
 	for _, ballot := range ballots {
 		sig := &bls.Sign{}
 		// NOTE invariant that shouldn't happen by now
 		// but pointers are pointers
 		if ballot != nil {
-			sig.DeserializeHexStr(common.Bytes2Hex(ballot.Signature))
+			hexSig := common.Bytes2Hex(ballot.Signature)
+			if _, ok := seen[hexSig]; ok {
+				continue
+			}
+			seen[hexSig] = struct{}{}
+			sig.DeserializeHexStr(hexSig)
 			sigs = append(sigs, sig)
 		}
 	}
@@ -250,6 +268,31 @@ func (s *cIdentities) SubmitVote(
 	return ballot, nil
 }
 
+func (s *cIdentities) SubmitAggregateVote(
+	p Phase, pubKeys []*bls.PublicKey,
+	aggSig *bls.Sign, headerHash common.Hash,
+	height, viewID uint64,
+) ([]*votepower.Ballot, error) {
+	// An aggregate can not be split apart again, so accept it
+	// only when none of its signers were counted before
+	for _, key := range pubKeys {
+		if s.ReadBallot(p, key) != nil {
+			return nil, errors.Wrapf(
+				errAggregateOverlaps, "key: %s", key.SerializeToHexStr(),
+			)
+		}
+	}
+	ballots := make([]*votepower.Ballot, 0, len(pubKeys))
+	for _, key := range pubKeys {
+		ballot, err := s.SubmitVote(p, key, aggSig, headerHash, height, viewID)
+		if err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, ballot)
+	}
+	return ballots, nil
+}
+
 func (s *cIdentities) reset(ps []Phase) {
 	for i := range ps {
 		switch m := votepower.NewRound(); ps[i] {
@@ -0,0 +1,369 @@
+package consensus
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// recorderQueueSize is the number of events the main loop can record
+// ahead of the writer before it has to wait for it
+const recorderQueueSize = 1024
+
+var errRecorderClosed = errors.New("consensus recorder is closed")
+
+// RecordedEventKind is the kind of input of the consensus main loop
+type RecordedEventKind byte
+
+const (
+	// RecordedMessage is an inbound consensus message
+	RecordedMessage RecordedEventKind = iota
+	// RecordedTimeout is a consensus or view change timeout starting a view change,
+	// or with a RecordedVoteFallback payload the end of the wait of the leader
+	// for the aggregated votes of a phase
+	RecordedTimeout
+	// RecordedCommitFinish is the end of the commit grace period of the leader
+	RecordedCommitFinish
+	// RecordedProposal is a new block proposed by the node as leader
+	RecordedProposal
+	// RecordedStart is the state consensus and its chain start from
+	RecordedStart
+)
+
+var recordedEventKindNames = map[RecordedEventKind]string{
+	RecordedMessage:      "Message",
+	RecordedTimeout:      "Timeout",
+	RecordedCommitFinish: "CommitFinish",
+	RecordedProposal:     "Proposal",
+	RecordedStart:        "Start",
+}
+
+func (k RecordedEventKind) String() string {
+	if name, ok := recordedEventKindNames[k]; ok {
+		return name
+	}
+	return "Unknown"
+}
+
+// RecordedEvent is an input of the consensus main loop, kept for replay
+type RecordedEvent struct {
+	Kind         RecordedEventKind
+	TimeUnixNano uint64
+	// ViewID is the view to change to on timeout, or the view whose commit
+	// finished or whose aggregated votes did not come
+	ViewID uint64
+	// Payload is the message payload, the RLP encoded block of a proposal,
+	// the RLP encoded RecordedStartState of a start
+	// or the RLP encoded RecordedVoteFallback of a vote aggregation timeout
+	Payload []byte
+}
+
+// RecordedVoteFallback is the phase and block whose aggregated votes
+// the leader stopped waiting for
+type RecordedVoteFallback struct {
+	Phase    quorum.Phase
+	BlockNum uint64
+}
+
+// RecordedStartState is the state of consensus and of the head of its chain
+// when the main loop starts, or when the node caught up by syncing,
+// which the events recorded after it apply to
+type RecordedStartState struct {
+	Head *types.Block
+	// ShardState is the encoded shard state of the epoch of Head
+	ShardState   []byte
+	BlockNum     uint64
+	ViewID       uint64
+	Mode         Mode
+	LeaderPubKey []byte
+}
+
+// DecodeRecordedStartState decodes the payload of a RecordedStart event
+func DecodeRecordedStartState(payload []byte) (*RecordedStartState, error) {
+	start := &RecordedStartState{}
+	if err := rlp.DecodeBytes(payload, start); err != nil {
+		return nil, errors.Wrap(err, "cannot decode recorded start")
+	}
+	if start.Head == nil {
+		return nil, errors.New("recorded start has no head block")
+	}
+	return start, nil
+}
+
+// EventRecorder appends the inputs of the consensus main loop to a file,
+// one RLP encoded RecordedEvent after the other. The events are written
+// and flushed in the background, off the main loop.
+type EventRecorder struct {
+	mutex  sync.Mutex
+	closed bool
+	events chan RecordedEvent
+	// done receives the first write error, if any, once the writer stopped
+	done   chan error
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// NewEventRecorder creates a recorder appending to the file at path
+func NewEventRecorder(path string) (*EventRecorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open consensus recording %s", path)
+	}
+	r := &EventRecorder{
+		events: make(chan RecordedEvent, recorderQueueSize),
+		done:   make(chan error, 1),
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}
+	go r.writeLoop()
+	return r, nil
+}
+
+// Record queues the event, stamped with the current time, for writing.
+// It only waits when the writer is recorderQueueSize events behind.
+func (r *EventRecorder) Record(kind RecordedEventKind, viewID uint64, payload []byte) error {
+	event := RecordedEvent{
+		Kind:         kind,
+		TimeUnixNano: uint64(time.Now().UnixNano()),
+		ViewID:       viewID,
+		Payload:      payload,
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return errRecorderClosed
+	}
+	r.events <- event
+	return nil
+}
+
+// writeLoop writes the queued events, flushing whenever the queue is empty so
+// that the recording is complete up to the last quiet moment on a crash
+func (r *EventRecorder) writeLoop() {
+	var err error
+	for event := range r.events {
+		if err != nil {
+			continue
+		}
+		if err = rlp.Encode(r.writer, &event); err == nil && len(r.events) == 0 {
+			err = r.writer.Flush()
+		}
+		if err != nil {
+			utils.Logger().Warn().Err(err).
+				Str("kind", event.Kind.String()).
+				Msg("[Recorder] Cannot write consensus event, recording stopped")
+		}
+	}
+	if err == nil {
+		err = r.writer.Flush()
+	}
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	r.done <- err
+}
+
+// Close writes the queued events and closes the recording
+func (r *EventRecorder) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return errRecorderClosed
+	}
+	r.closed = true
+	close(r.events)
+	r.mutex.Unlock()
+	return <-r.done
+}
+
+// ReadRecordedEvents reads back all the events of a recording
+func ReadRecordedEvents(reader io.Reader) ([]RecordedEvent, error) {
+	stream := rlp.NewStream(reader, 0)
+	events := []RecordedEvent{}
+	for {
+		event := RecordedEvent{}
+		if err := stream.Decode(&event); err != nil {
+			if err == io.EOF {
+				return events, nil
+			}
+			return nil, errors.Wrapf(err, "cannot decode recorded event %d", len(events))
+		}
+		events = append(events, event)
+	}
+}
+
+// SetEventRecorder makes the consensus main loop record all of its inputs
+func (consensus *Consensus) SetEventRecorder(recorder *EventRecorder) {
+	consensus.recorder = recorder
+}
+
+// CloseEventRecorder stops recording and closes the recording, if any
+func (consensus *Consensus) CloseEventRecorder() {
+	if consensus.recorder == nil {
+		return
+	}
+	if err := consensus.recorder.Close(); err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[Recorder] Cannot close consensus recording")
+	}
+}
+
+func (consensus *Consensus) record(kind RecordedEventKind, viewID uint64, payload []byte) {
+	if consensus.recorder == nil {
+		return
+	}
+	if err := consensus.recorder.Record(kind, viewID, payload); err != nil {
+		consensus.getLogger().Warn().Err(err).
+			Str("kind", kind.String()).
+			Msg("[Recorder] Cannot record consensus event")
+	}
+}
+
+func (consensus *Consensus) recordProposal(block *types.Block) {
+	if consensus.recorder == nil {
+		return
+	}
+	encoded, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[Recorder] Cannot encode proposed block")
+		return
+	}
+	consensus.record(RecordedProposal, 0, encoded)
+}
+
+func (consensus *Consensus) recordVoteFallback(p quorum.Phase, blockNum, viewID uint64) {
+	if consensus.recorder == nil {
+		return
+	}
+	encoded, err := rlp.EncodeToBytes(&RecordedVoteFallback{Phase: p, BlockNum: blockNum})
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[Recorder] Cannot encode vote fallback")
+		return
+	}
+	consensus.record(RecordedTimeout, viewID, encoded)
+}
+
+// recordStart records the state the events recorded next apply to
+func (consensus *Consensus) recordStart() {
+	if consensus.recorder == nil || consensus.ChainReader == nil {
+		return
+	}
+	head := consensus.ChainReader.CurrentBlock()
+	shardState, err := consensus.ChainReader.ReadShardState(head.Epoch())
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).
+			Uint64("epoch", head.Epoch().Uint64()).
+			Msg("[Recorder] Cannot read shard state of recorded start")
+		return
+	}
+	encodedShardState, err := shard.EncodeWrapper(
+		*shardState, consensus.ChainReader.Config().IsStaking(head.Epoch()),
+	)
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[Recorder] Cannot encode shard state of recorded start")
+		return
+	}
+	start := RecordedStartState{
+		Head:       head,
+		ShardState: encodedShardState,
+		BlockNum:   consensus.blockNum,
+		ViewID:     consensus.viewID,
+		Mode:       consensus.current.Mode(),
+	}
+	if consensus.LeaderPubKey != nil {
+		start.LeaderPubKey = consensus.LeaderPubKey.Serialize()
+	}
+	encoded, err := rlp.EncodeToBytes(&start)
+	if err != nil {
+		consensus.getLogger().Warn().Err(err).Msg("[Recorder] Cannot encode recorded start")
+		return
+	}
+	consensus.record(RecordedStart, consensus.viewID, encoded)
+}
+
+// restoreStart moves consensus to the recorded start,
+// the chain reader must be at the recorded head already
+func (consensus *Consensus) restoreStart(start *RecordedStartState) error {
+	if head := consensus.ChainReader.CurrentHeader(); head.Hash() != start.Head.Hash() {
+		return errors.Errorf(
+			"chain at block %d %x, not at recorded head %d %x",
+			head.Number().Uint64(), head.Hash(), start.Head.NumberU64(), start.Head.Hash(),
+		)
+	}
+	// the committee of the head epoch, the leader and the mode are updated
+	// from the chain, then overridden by the recorded ones
+	consensus.UpdateConsensusInformation()
+	consensus.ResetState()
+	consensus.SetBlockNum(start.BlockNum)
+	consensus.SetViewID(start.ViewID)
+	consensus.SetMode(start.Mode)
+	if len(start.LeaderPubKey) > 0 {
+		leader := &bls.PublicKey{}
+		if err := leader.Deserialize(start.LeaderPubKey); err != nil {
+			return errors.Wrap(err, "cannot deserialize recorded leader")
+		}
+		consensus.LeaderPubKey = leader
+	}
+	return nil
+}
+
+// Replay feeds recorded events to consensus one after the other, as the
+// main loop would have handled them, without waiting on any timer
+func (consensus *Consensus) Replay(events []RecordedEvent) error {
+	for i := range events {
+		if err := consensus.ReplayEvent(events[i]); err != nil {
+			return errors.Wrapf(err, "cannot replay event %d", i)
+		}
+	}
+	return nil
+}
+
+// ReplayEvent feeds one recorded event to consensus. The chain must be at the
+// recorded head of a start event already, consensus restores the rest.
+func (consensus *Consensus) ReplayEvent(event RecordedEvent) error {
+	switch event.Kind {
+	case RecordedMessage:
+		consensus.handleMessageUpdate(event.Payload)
+	case RecordedTimeout:
+		if len(event.Payload) == 0 {
+			consensus.startViewChange(event.ViewID)
+			break
+		}
+		fallback := RecordedVoteFallback{}
+		if err := rlp.DecodeBytes(event.Payload, &fallback); err != nil {
+			return errors.Wrap(err, "cannot decode vote fallback")
+		}
+		consensus.releaseHeldVotes(fallback.Phase, fallback.BlockNum, event.ViewID)
+	case RecordedCommitFinish:
+		consensus.mutex.Lock()
+		defer consensus.mutex.Unlock()
+		if event.ViewID == consensus.viewID {
+			consensus.finalizeCommits()
+		}
+	case RecordedProposal:
+		block := &types.Block{}
+		if err := rlp.DecodeBytes(event.Payload, block); err != nil {
+			return errors.Wrap(err, "cannot decode proposal")
+		}
+		consensus.msgSender.Reset(block.NumberU64())
+		consensus.announce(block)
+	case RecordedStart:
+		start, err := DecodeRecordedStartState(event.Payload)
+		if err != nil {
+			return err
+		}
+		return consensus.restoreStart(start)
+	default:
+		return errors.Errorf("unknown event kind %d", event.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRecordedEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consensus.rlp")
+
+	recorder, err := NewEventRecorder(path)
+	if err != nil {
+		t.Fatalf("Cannot create recorder: %v", err)
+	}
+	if err := recorder.Record(RecordedMessage, 0, []byte("message")); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(RecordedTimeout, 7, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ReadRecordedEvents(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Cannot read recording: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Kind != RecordedMessage || !bytes.Equal(events[0].Payload, []byte("message")) {
+		t.Errorf("unexpected first event %+v", events[0])
+	}
+	if events[1].Kind != RecordedTimeout || events[1].ViewID != 7 {
+		t.Errorf("unexpected second event %+v", events[1])
+	}
+
+	if _, err := ReadRecordedEvents(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("expected truncated recording to fail")
+	}
+}
+
+func TestEventRecorderClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder, err := NewEventRecorder(filepath.Join(dir, "consensus.rlp"))
+	if err != nil {
+		t.Fatalf("Cannot create recorder: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(RecordedMessage, 0, nil); err != errRecorderClosed {
+		t.Errorf("expected closed recorder error, got %v", err)
+	}
+	if err := recorder.Close(); err != errRecorderClosed {
+		t.Errorf("expected closed recorder error, got %v", err)
+	}
+}
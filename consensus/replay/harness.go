@@ -0,0 +1,164 @@
+// Package replay feeds recorded consensus inputs to a node backed by an
+// in-memory chain, to reproduce consensus stalls and view change storms offline.
+//
+// Recordings come from a node started with -record_consensus, and are replayed
+// with harmony replay <recording>. Replaying with the BLS keys of the recording
+// node reproduces its own votes and proposals, any other key replays as a node
+// listening to the shard.
+//
+// A recording starts with the head block, committee and view of the recording
+// node. The harness makes the recorded head its own head without executing it,
+// and then trusts the recorded blocks, which the recording node verified and
+// executed, instead of executing them on the missing state again.
+package replay
+
+import (
+	"os"
+
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/node"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/pkg/errors"
+)
+
+// Harness is a consensus instance wired to a node with an in-memory chain
+type Harness struct {
+	Node      *node.Node
+	Consensus *consensus.Consensus
+	stopChan  chan struct{}
+}
+
+// New creates a harness for the given shard, starting from genesis until the
+// first recorded start is replayed
+func New(host p2p.Host, shardID uint32, key *multibls.PrivateKey) (*Harness, error) {
+	decider := quorum.NewDecider(quorum.SuperMajorityVote, shardID)
+	currentConsensus, err := consensus.New(
+		host, shardID, p2p.Peer{}, key, decider,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create consensus")
+	}
+	currentConsensus.Decider.SetMyPublicKeyProvider(func() (*multibls.PublicKey, error) {
+		return currentConsensus.GetPublicKeys(), nil
+	})
+
+	currentNode := node.New(
+		host, currentConsensus, &shardchain.MemDBFactory{}, nil, false,
+	)
+	currentConsensus.ChainReader = currentNode.Blockchain()
+	if err := currentNode.InitConsensusWithValidators(); err != nil {
+		utils.Logger().Warn().Err(err).
+			Uint32("shardID", shardID).
+			Msg("[Replay] InitConsensusWithValidators failed")
+	}
+	viewID := currentNode.Blockchain().CurrentBlock().Header().ViewID().Uint64()
+	currentConsensus.SetViewID(viewID + 1)
+	currentConsensus.BlockVerifier = currentNode.VerifyNewBlock
+	currentConsensus.OnConsensusDone = currentNode.PostConsensusProcessing
+	currentConsensus.SetMode(currentConsensus.UpdateConsensusInformation())
+
+	harness := &Harness{
+		Node:      currentNode,
+		Consensus: currentConsensus,
+		stopChan:  make(chan struct{}),
+	}
+	// nobody proposes blocks on a replay, new block requests are dropped
+	go func() {
+		for {
+			select {
+			case <-currentConsensus.ReadySignal:
+			case <-harness.stopChan:
+				return
+			}
+		}
+	}()
+	return harness, nil
+}
+
+// Replay feeds the events to consensus in order, moving the chain to the
+// recorded head of each start first
+func (h *Harness) Replay(events []consensus.RecordedEvent) error {
+	for i := range events {
+		if events[i].Kind == consensus.RecordedStart {
+			start, err := consensus.DecodeRecordedStartState(events[i].Payload)
+			if err != nil {
+				return errors.Wrapf(err, "cannot replay event %d", i)
+			}
+			if err := h.restoreChain(start); err != nil {
+				return errors.Wrapf(err, "cannot replay event %d", i)
+			}
+		}
+		if err := h.Consensus.ReplayEvent(events[i]); err != nil {
+			return errors.Wrapf(err, "cannot replay event %d", i)
+		}
+	}
+	return nil
+}
+
+// restoreChain makes the recorded head the head of the chain, with the
+// committee of its epoch, and from then on trusts the recorded blocks
+func (h *Harness) restoreChain(start *consensus.RecordedStartState) error {
+	chain := h.Node.Blockchain()
+	if err := rawdb.WriteShardStateBytes(
+		chain.ChainDb(), start.Head.Epoch(), start.ShardState,
+	); err != nil {
+		return errors.Wrap(err, "cannot write recorded shard state")
+	}
+	if err := chain.WriteHeadBlockUnexecuted(start.Head); err != nil {
+		return errors.Wrap(err, "cannot write recorded head block")
+	}
+	h.Consensus.BlockVerifier = h.verifyFollowsHead
+	h.Consensus.OnConsensusDone = h.commitUnexecuted
+	return nil
+}
+
+// verifyFollowsHead only checks that the block follows the head block,
+// the recording node verified it against the state
+func (h *Harness) verifyFollowsHead(block *types.Block) error {
+	head := h.Node.Blockchain().CurrentBlock()
+	if block.NumberU64() != head.NumberU64()+1 || block.ParentHash() != head.Hash() {
+		return errors.Errorf(
+			"block %d %x does not follow head block %d %x",
+			block.NumberU64(), block.Hash(), head.NumberU64(), head.Hash(),
+		)
+	}
+	return nil
+}
+
+// commitUnexecuted makes the block agreed on the head block without executing it
+func (h *Harness) commitUnexecuted(block *types.Block, commitSigAndBitmap []byte) {
+	if err := h.Node.Blockchain().WriteHeadBlockUnexecuted(block); err != nil {
+		utils.Logger().Error().Err(err).
+			Uint64("blockNum", block.NumberU64()).
+			Msg("[Replay] Cannot add block agreed on")
+		return
+	}
+	if len(block.Header().ShardState()) > 0 {
+		h.Consensus.SetMode(h.Consensus.UpdateConsensusInformation())
+	}
+}
+
+// ReplayFile feeds all the events of a recording to consensus in order
+func (h *Harness) ReplayFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.Wrapf(err, "cannot open consensus recording %s", path)
+	}
+	defer file.Close()
+	events, err := consensus.ReadRecordedEvents(file)
+	if err != nil {
+		return err
+	}
+	return h.Replay(events)
+}
+
+// Close stops the background routines of the harness
+func (h *Harness) Close() {
+	close(h.stopChan)
+}
@@ -0,0 +1,111 @@
+package replay
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/core/types"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/memhost"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestReplayFile(t *testing.T) {
+	host := memhost.NewNetwork(1).NewHost(p2p.Peer{IP: "127.0.0.1", Port: "9902"})
+	harness, err := New(
+		host, shard.BeaconChainShardID, multibls.GetPrivateKey(bls_cosi.RandPrivateKey()),
+	)
+	if err != nil {
+		t.Fatalf("Cannot create harness: %v", err)
+	}
+	defer harness.Close()
+
+	// a head far from genesis, as recorded by a node in the middle of the chain
+	chain := harness.Node.Blockchain()
+	shardState, err := chain.ReadShardState(big.NewInt(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encodedShardState, err := shard.EncodeWrapper(*shardState, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head := types.NewBlockWithHeader(blockfactory.NewTestHeader().With().
+		Number(big.NewInt(5)).
+		Epoch(big.NewInt(0)).
+		ParentHash(chain.Genesis().Hash()).
+		ViewID(big.NewInt(9)).
+		Header())
+	start, err := rlp.EncodeToBytes(&consensus.RecordedStartState{
+		Head:         head,
+		ShardState:   encodedShardState,
+		BlockNum:     6,
+		ViewID:       10,
+		Mode:         consensus.Normal,
+		LeaderPubKey: harness.Consensus.LeaderPubKey.Serialize(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "consensus.rlp")
+	recorder, err := consensus.NewEventRecorder(path)
+	if err != nil {
+		t.Fatalf("Cannot create recorder: %v", err)
+	}
+	if err := recorder.Record(consensus.RecordedStart, 10, start); err != nil {
+		t.Fatal(err)
+	}
+	// garbage is dropped by consensus like any malformed message
+	if err := recorder.Record(consensus.RecordedMessage, 0, []byte{0xde, 0xad}); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Record(consensus.RecordedTimeout, 11, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := harness.ReplayFile(path); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Errorf("expected the recorded head, got block %d", current.NumberU64())
+	}
+	if viewID := harness.Consensus.GetViewID(); viewID != 10 {
+		t.Errorf("expected the recorded view 10, got %d", viewID)
+	}
+	if mode := harness.Consensus.Mode(); mode != consensus.ViewChanging {
+		t.Errorf("expected view changing after replayed timeout, got %s", mode)
+	}
+
+	if err := harness.verifyFollowsHead(head); err == nil {
+		t.Error("verified a block not following the head")
+	}
+	next := types.NewBlockWithHeader(blockfactory.NewTestHeader().With().
+		Number(big.NewInt(6)).
+		Epoch(big.NewInt(0)).
+		ParentHash(head.Hash()).
+		Header())
+	if err := harness.verifyFollowsHead(next); err != nil {
+		t.Errorf("block following the head not verified: %v", err)
+	}
+	harness.commitUnexecuted(next, nil)
+	if current := chain.CurrentBlock(); current.Hash() != next.Hash() {
+		t.Errorf("expected the committed block as head, got block %d", current.NumberU64())
+	}
+}
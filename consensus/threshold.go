@@ -44,11 +44,12 @@ func (consensus *Consensus) didReachPrepareQuorum() error {
 
 	// so by this point, everyone has committed to the blockhash of this block
 	// in prepare and so this is the actual block.
-	for i, key := range consensus.PubKey.PublicKey {
+	keys := consensus.getSigningKeys()
+	for i, key := range keys.pubKey.PublicKey {
 		if _, err := consensus.Decider.SubmitVote(
 			quorum.Commit,
 			key,
-			consensus.priKey.PrivateKey[i].SignHash(commitPayload),
+			keys.priKey.PrivateKey[i].SignHash(commitPayload),
 			common.BytesToHash(consensus.blockHash[:]),
 			consensus.blockNum,
 			consensus.viewID,
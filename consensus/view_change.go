@@ -130,8 +130,9 @@ func (consensus *Consensus) startViewChange(viewID uint64) {
 		Str("NextLeader", consensus.LeaderPubKey.SerializeToHexStr()).
 		Msg("[startViewChange]")
 
-	for i, key := range consensus.PubKey.PublicKey {
-		msgToSend := consensus.constructViewChangeMessage(key, consensus.priKey.PrivateKey[i])
+	keys := consensus.getSigningKeys()
+	for i, key := range keys.pubKey.PublicKey {
+		msgToSend := consensus.constructViewChangeMessage(key, keys.priKey.PrivateKey[i])
 		consensus.host.SendMessageToGroups([]nodeconfig.GroupID{
 			nodeconfig.NewGroupIDByShardID(nodeconfig.ShardID(consensus.ShardID)),
 		},
@@ -172,6 +173,19 @@ func (consensus *Consensus) onViewChange(msg *msg_pb.Message) {
 		return
 	}
 
+	preparedHash := common.Hash{}
+	if len(recvMsg.Payload) >= common.HashLength {
+		preparedHash = common.BytesToHash(recvMsg.Payload[:common.HashLength])
+	}
+	if consensus.checkConflictingVote(
+		msg, recvMsg, preparedHash, msg.GetViewchange().ViewchangeSig,
+	) {
+		consensus.getLogger().Warn().
+			Str("validatorPubKey", recvMsg.SenderPubkey.SerializeToHexStr()).
+			Msg("[onViewChange] Conflicting view change from the validator")
+		return
+	}
+
 	senderKey := recvMsg.SenderPubkey
 
 	consensus.vcLock.Lock()
@@ -182,8 +196,8 @@ func (consensus *Consensus) onViewChange(msg *msg_pb.Message) {
 
 	// TODO: remove NIL type message
 	// add self m1 or m2 type message signature and bitmap
-	_, ok1 := consensus.nilSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()]
-	_, ok2 := consensus.bhpSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()]
+	_, ok1 := consensus.nilSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()]
+	_, ok2 := consensus.bhpSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()]
 	if !(ok1 || ok2) {
 		// add own signature for newview message
 		preparedMsgs := consensus.FBFTLog.GetMessagesByTypeSeq(
@@ -192,21 +206,21 @@ func (consensus *Consensus) onViewChange(msg *msg_pb.Message) {
 		preparedMsg := consensus.FBFTLog.FindMessageByMaxViewID(preparedMsgs)
 		if preparedMsg == nil {
 			consensus.getLogger().Debug().Msg("[onViewChange] add my M2(NIL) type messaage")
-			consensus.nilSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()] = newLeaderPriKey.SignHash(NIL)
+			consensus.nilSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()] = newLeaderPriKey.SignHash(NIL)
 			consensus.nilBitmap[recvMsg.ViewID].SetKey(newLeaderKey, true)
 		} else {
 			consensus.getLogger().Debug().Msg("[onViewChange] add my M1 type messaage")
 			msgToSign := append(preparedMsg.BlockHash[:], preparedMsg.Payload...)
-			consensus.bhpSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()] = newLeaderPriKey.SignHash(msgToSign)
+			consensus.bhpSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()] = newLeaderPriKey.SignHash(msgToSign)
 			consensus.bhpBitmap[recvMsg.ViewID].SetKey(newLeaderKey, true)
 		}
 	}
 	// add self m3 type message signature and bitmap
-	_, ok3 := consensus.viewIDSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()]
+	_, ok3 := consensus.viewIDSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()]
 	if !ok3 {
 		viewIDBytes := make([]byte, 8)
 		binary.LittleEndian.PutUint64(viewIDBytes, recvMsg.ViewID)
-		consensus.viewIDSigs[recvMsg.ViewID][consensus.PubKey.SerializeToHexStr()] = newLeaderPriKey.SignHash(viewIDBytes)
+		consensus.viewIDSigs[recvMsg.ViewID][consensus.GetPublicKeys().SerializeToHexStr()] = newLeaderPriKey.SignHash(viewIDBytes)
 		consensus.viewIDBitmap[recvMsg.ViewID].SetKey(newLeaderKey, true)
 	}
 
@@ -403,7 +417,7 @@ func (consensus *Consensus) onViewChange(msg *msg_pb.Message) {
 			Uint64("viewChangingID", consensus.current.ViewID()).
 			Msg("[onViewChange] New Leader Start Consensus Timer and Stop View Change Timer")
 		consensus.getLogger().Debug().
-			Str("myKey", consensus.PubKey.SerializeToHexStr()).
+			Str("myKey", consensus.GetPublicKeys().SerializeToHexStr()).
 			Uint64("viewID", consensus.viewID).
 			Uint64("block", consensus.blockNum).
 			Msg("[onViewChange] I am the New Leader")
@@ -525,11 +539,12 @@ func (consensus *Consensus) onNewView(msg *msg_pb.Message) {
 		binary.LittleEndian.PutUint64(blockNumHash, consensus.blockNum)
 		groupID := []nodeconfig.GroupID{
 			nodeconfig.NewGroupIDByShardID(nodeconfig.ShardID(consensus.ShardID))}
-		for i, key := range consensus.PubKey.PublicKey {
+		keys := consensus.getSigningKeys()
+		for i, key := range keys.pubKey.PublicKey {
 			network, err := consensus.construct(
 				msg_pb.MessageType_COMMIT,
 				append(blockNumHash, consensus.blockHash[:]...),
-				key, consensus.priKey.PrivateKey[i],
+				key, keys.priKey.PrivateKey[i],
 			)
 			if err != nil {
 				consensus.getLogger().Err(err).Msg("could not create commit message")
@@ -48,7 +48,9 @@ import (
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/shard/committee"
+	staking2 "github.com/harmony-one/harmony/staking"
 	"github.com/harmony-one/harmony/staking/apr"
+	"github.com/harmony-one/harmony/staking/availability"
 	"github.com/harmony-one/harmony/staking/effective"
 	"github.com/harmony-one/harmony/staking/slash"
 	staking "github.com/harmony-one/harmony/staking/types"
@@ -399,6 +401,13 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.hc.SetHead(head, delFn)
 	currentHeader := bc.hc.CurrentHeader()
 
+	// Drop the blocks after the new head from the ancient store, if any
+	if ancients, ok := bc.db.(rawdb.AncientWriter); ok {
+		if err := ancients.TruncateAncients(head + 1); err != nil {
+			return err
+		}
+	}
+
 	// Clear out any stale content from the caches
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
@@ -437,6 +446,34 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	return bc.loadLastState()
 }
 
+// FastSyncCommitHead sets the current head block to the one defined by the hash
+// irrelevant what the chain contents were prior, once its state has been
+// downloaded by a fast sync.
+func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
+	// Make sure that both the block as well at its state trie exists
+	block := bc.GetBlockByHash(hash)
+	if block == nil {
+		return fmt.Errorf("non existent block [%x…]", hash[:4])
+	}
+	if _, err := state.New(block.Root(), bc.stateCache); err != nil {
+		return err
+	}
+	// If all checks out, manually set the head block
+	bc.mu.Lock()
+	rawdb.WriteHeadBlockHash(bc.db, hash)
+	bc.currentBlock.Store(block)
+	if bc.CurrentHeader().Number().Cmp(block.Number()) < 0 {
+		bc.hc.SetCurrentHeader(block.Header())
+	}
+	bc.mu.Unlock()
+
+	utils.Logger().Info().
+		Str("number", block.Number().String()).
+		Str("hash", hash.Hex()).
+		Msg("Committed new head block")
+	return nil
+}
+
 // ShardID returns the shard Id of the blockchain.
 // TODO: use a better solution before resharding shuffle nodes to different shards
 func (bc *BlockChain) ShardID() uint32 {
@@ -618,6 +655,28 @@ func (bc *BlockChain) insert(block *types.Block) {
 	bc.insertWithWriter(bc.db, block)
 }
 
+// WriteHeadBlockUnexecuted writes the block with the shard state it carries,
+// if any, and makes it the head block without executing it. The state of the
+// block stays missing, so it is only meant for tools which never read the
+// state, like the offline replay of recorded consensus.
+func (bc *BlockChain) WriteHeadBlockUnexecuted(block *types.Block) error {
+	batch := bc.db.NewBatch()
+	rawdb.WriteBlock(batch, block)
+	if len(block.Header().ShardState()) > 0 {
+		if err := bc.writeShardStateOfHeader(batch, block.Header()); err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	bc.insert(block)
+	bc.shardStateCache.Purge()
+	return nil
+}
+
 // Genesis retrieves the chain's genesis block.
 func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
@@ -968,7 +1027,14 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		// Write all the data out into the database
 		rawdb.WriteBody(batch, block.Hash(), block.NumberU64(), block.Body())
 		rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+		rawdb.WriteCanonicalHash(batch, block.Hash(), block.NumberU64())
 		rawdb.WriteTxLookupEntries(batch, block)
+		rawdb.WriteCxLookupEntries(batch, block)
+		// Mark the incoming receipts as spent, as executing the block would
+		bc.WriteCXReceiptsProofSpent(batch, block.IncomingReceipts())
+		if err := bc.commitFastSyncedOffChainData(batch, block, receipts); err != nil {
+			return i, err
+		}
 
 		stats.processed++
 
@@ -987,12 +1053,12 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		}
 	}
 
-	// Update the head fast sync block if better
+	// Update the head fast sync block if higher, there is no total
+	// difficulty to compare the blocks by
 	bc.mu.Lock()
 	head := blockChain[len(blockChain)-1]
-	if td := bc.GetTd(head.Hash(), head.NumberU64()); td != nil { // Rewind may have occurred, skip in that case
-		currentFastBlock := bc.CurrentFastBlock()
-		if bc.GetTd(currentFastBlock.Hash(), currentFastBlock.NumberU64()).Cmp(td) < 0 {
+	if bc.HasHeader(head.Hash(), head.NumberU64()) { // Rewind may have occurred, skip in that case
+		if currentFastBlock := bc.CurrentFastBlock(); currentFastBlock.NumberU64() < head.NumberU64() {
 			rawdb.WriteHeadFastBlockHash(bc.db, head.Hash())
 			bc.currentFastBlock.Store(head)
 		}
@@ -1556,8 +1622,18 @@ func (bc *BlockChain) InsertHeaderChain(chain []*block.Header, checkFreq int) (i
 		bc.mu.Lock()
 		defer bc.mu.Unlock()
 
-		_, err := bc.hc.WriteHeader(header)
-		return err
+		if _, err := bc.hc.WriteHeader(header); err != nil {
+			return err
+		}
+		// the header chain only caches the header, which the bodies
+		// inserted by InsertReceiptChain need in the database
+		rawdb.WriteHeader(bc.db, header)
+		// The last header of an epoch carries the shard state of the next one,
+		// which is needed to verify the headers that follow
+		if len(header.ShardState()) > 0 {
+			return bc.writeShardStateOfHeader(bc.db, header)
+		}
+		return nil
 	}
 
 	return bc.hc.InsertHeaderChain(chain, whFunc, start)
@@ -1686,6 +1762,21 @@ func (bc *BlockChain) WriteShardStateBytes(db rawdb.DatabaseWriter,
 	return decodeShardState, nil
 }
 
+// writeShardStateOfHeader saves the shard state carried by the last header of
+// an epoch under the epoch it is for.
+func (bc *BlockChain) writeShardStateOfHeader(
+	db rawdb.DatabaseWriter, header *block.Header,
+) error {
+	newEpoch := new(big.Int).Add(header.Epoch(), common.Big1)
+	shardState, err := header.GetShardState()
+	if err == nil && shardState.Epoch != nil && bc.chainConfig.IsStaking(shardState.Epoch) {
+		// After staking, the epoch will be decided by the epoch in the shard state.
+		newEpoch = new(big.Int).Set(shardState.Epoch)
+	}
+	_, err = bc.WriteShardStateBytes(db, newEpoch, header.ShardState())
+	return err
+}
+
 // ReadLastCommits retrieves last commits.
 func (bc *BlockChain) ReadLastCommits() ([]byte, error) {
 	if cached, ok := bc.lastCommitsCache.Get("lastCommits"); ok {
@@ -2340,6 +2431,157 @@ func (bc *BlockChain) UpdateValidatorVotingPower(
 	return validatorStats, nil
 }
 
+// ReadValidatorAvailabilityHistory reads the availability of a validator in
+// each epoch since staking
+func (bc *BlockChain) ReadValidatorAvailabilityHistory(
+	addr common.Address,
+) (staking.AvailabilityHistory, error) {
+	history := staking.AvailabilityHistory{}
+	for epoch := new(big.Int).Set(bc.chainConfig.StakingEpoch); epoch.Cmp(
+		bc.CurrentHeader().Epoch(),
+	) <= 0; epoch.Add(epoch, common.Big1) {
+		availability, err := rawdb.ReadValidatorEpochAvailability(bc.db, addr, epoch)
+		if err != nil {
+			return nil, err
+		}
+		if availability != nil {
+			history = append(history, *availability)
+		}
+	}
+	return history, nil
+}
+
+// UpdateValidatorAvailabilityHistory records how each validator signed during
+// the epoch ended by block in its availability history, replacing the record
+// of the epoch written before, e.g. when the block is inserted again
+func (bc *BlockChain) UpdateValidatorAvailabilityHistory(
+	batch rawdb.DatabaseWriter,
+	block *types.Block,
+	newEpochSuperCommittee, currentEpochSuperCommittee *shard.State,
+	state *state.DB,
+) error {
+	if newEpochSuperCommittee == nil || currentEpochSuperCommittee == nil {
+		return shard.ErrSuperCommitteeNil
+	}
+	validators, err := bc.ReadValidatorList()
+	if err != nil {
+		return err
+	}
+	existing, replacing :=
+		currentEpochSuperCommittee.StakedValidators(),
+		newEpochSuperCommittee.StakedValidators()
+
+	for _, addr := range validators {
+		wrapper, err := state.ValidatorWrapper(addr)
+		if err != nil {
+			return err
+		}
+		_, elected := existing.LookupSet[addr]
+		epochAvailability := staking.EpochAvailability{
+			Epoch:           block.Epoch(),
+			NumBlocksSigned: big.NewInt(0),
+			NumBlocksToSign: big.NewInt(0),
+			Percentage:      numeric.ZeroDec(),
+			Elected:         elected,
+			BootedStatus:    effective.NotBooted,
+		}
+		if elected {
+			snapshot, err := bc.ReadValidatorSnapshotAtEpoch(block.Epoch(), addr)
+			if err != nil {
+				utils.Logger().Warn().Err(err).
+					Str("validator", addr.Hex()).
+					Uint64("epoch", block.Epoch().Uint64()).
+					Msg("no snapshot to compute the availability of an elected validator, epoch left out of its history")
+				continue
+			}
+			computed := availability.ComputeCurrentSigning(snapshot, wrapper)
+			epochAvailability.NumBlocksSigned = computed.Signed
+			epochAvailability.NumBlocksToSign = computed.ToSign
+			epochAvailability.Percentage = computed.Percentage
+			if _, keptSlot := replacing.LookupSet[addr]; !keptSlot {
+				epochAvailability.BootedStatus = effective.LostEPoSAuction
+			}
+		}
+		if wrapper.Status == effective.Inactive {
+			epochAvailability.BootedStatus = effective.TurnedInactiveOrInsufficientUptime
+		}
+		if slash.IsBanned(wrapper) {
+			epochAvailability.BootedStatus = effective.BannedForDoubleSigning
+		}
+
+		if err := rawdb.WriteValidatorEpochAvailability(
+			batch, addr, &epochAvailability,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadValidatorAPRHistory reads the apr of a validator at the end of each
+// epoch since staking
+func (bc *BlockChain) ReadValidatorAPRHistory(
+	addr common.Address,
+) (staking.APRHistory, error) {
+	history := staking.APRHistory{}
+	for epoch := new(big.Int).Set(bc.chainConfig.StakingEpoch); epoch.Cmp(
+		bc.CurrentHeader().Epoch(),
+	) <= 0; epoch.Add(epoch, common.Big1) {
+		epochAPR, err := rawdb.ReadValidatorEpochAPR(bc.db, addr, epoch)
+		if err != nil {
+			return nil, err
+		}
+		if epochAPR != nil {
+			history = append(history, *epochAPR)
+		}
+	}
+	return history, nil
+}
+
+// UpdateValidatorAPRHistory records the apr of each validator at the epoch
+// ended by block in its apr history, reusing the aprs already in stats and
+// replacing the record of the epoch written before
+func (bc *BlockChain) UpdateValidatorAPRHistory(
+	batch rawdb.DatabaseWriter,
+	block *types.Block,
+	state *state.DB,
+	stats map[common.Address]*staking.ValidatorStats,
+) error {
+	validators, err := bc.ReadValidatorList()
+	if err != nil {
+		return err
+	}
+	for _, addr := range validators {
+		epochAPR := staking.EpochAPR{
+			Epoch: block.Epoch(),
+			Value: numeric.ZeroDec(),
+		}
+		if computed, ok := stats[addr]; ok {
+			epochAPR.Value = computed.APR
+		} else {
+			wrapper, err := state.ValidatorWrapper(addr)
+			if err != nil {
+				return err
+			}
+			if wrapper.Delegations[0].Amount.Cmp(common.Big0) > 0 {
+				aprComputed, err := apr.ComputeForValidator(bc, block, wrapper)
+				if err != nil {
+					if errors.Cause(err) != apr.ErrInsufficientEpoch {
+						return err
+					}
+					continue
+				}
+				epochAPR.Value = *aprComputed
+			}
+		}
+
+		if err := rawdb.WriteValidatorEpochAPR(batch, addr, &epochAPR); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UpdateValidatorSnapshots updates the content snapshot of all validators
 // Note: this should only be called within the blockchain insert process.
 func (bc *BlockChain) UpdateValidatorSnapshots(
@@ -2452,9 +2694,9 @@ func (bc *BlockChain) writeDelegationsByDelegator(
 // Note: this should only be called within the blockchain insert process.
 func (bc *BlockChain) UpdateStakingMetaData(
 	batch rawdb.DatabaseWriter, txns staking.StakingTransactions,
-	state *state.DB, epoch, newEpoch *big.Int,
+	receipts []*types.Receipt, state *state.DB, epoch, newEpoch *big.Int,
 ) (newValidators []common.Address, err error) {
-	newValidators, newDelegations, err := bc.prepareStakingMetaData(txns, state)
+	newValidators, newDelegations, err := bc.prepareStakingMetaData(txns, receipts, state)
 	if err != nil {
 		utils.Logger().Warn().Msgf("oops, prepareStakingMetaData failed, err: %+v", err)
 		return newValidators, err
@@ -2506,14 +2748,15 @@ func (bc *BlockChain) UpdateStakingMetaData(
 }
 
 // prepareStakingMetaData prepare the updates of validator's
-// and the delegator's meta data according to staking transaction.
+// and the delegator's meta data according to staking transaction,
+// and to the delegations made through the staking precompile.
 // The following return values are cached end state to be written to DB.
 // The reason for the cached state is to solve the issue that batch DB changes
 // won't be reflected immediately so the intermediary state can't be read from DB.
 // newValidators - the addresses of the newly created validators
 // newDelegations - the map of delegator address and their updated delegation indexes
 func (bc *BlockChain) prepareStakingMetaData(
-	txns staking.StakingTransactions, state *state.DB,
+	txns staking.StakingTransactions, receipts []*types.Receipt, state *state.DB,
 ) (newValidators []common.Address,
 	newDelegations map[common.Address]staking.DelegationIndexes,
 	err error,
@@ -2572,10 +2815,77 @@ func (bc *BlockChain) prepareStakingMetaData(
 			newDelegations[delegate.DelegatorAddress] = delegations
 		case staking.DirectiveUndelegate:
 		case staking.DirectiveCollectRewards:
+		case staking.DirectiveRedelegate:
+			redelegate := decodePayload.(*staking.Redelegate)
+
+			delegations, ok := newDelegations[redelegate.DelegatorAddress]
+			if !ok {
+				// If the cache doesn't have it, load it from DB for the first time.
+				delegations, err = bc.ReadDelegationsByDelegator(redelegate.DelegatorAddress)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			if delegations, err = bc.addDelegationIndex(
+				delegations, redelegate.DelegatorAddress, redelegate.ToValidatorAddress, state,
+			); err != nil {
+				return nil, nil, err
+			}
+			newDelegations[redelegate.DelegatorAddress] = delegations
+		case staking.DirectiveSetAutoCompound:
+		case staking.DirectiveBatch:
+			batch := decodePayload.(*staking.Batch)
+
+			delegations, ok := newDelegations[batch.DelegatorAddress]
+			if !ok {
+				// If the cache doesn't have it, load it from DB for the first time.
+				delegations, err = bc.ReadDelegationsByDelegator(batch.DelegatorAddress)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			for _, op := range batch.Operations {
+				if op.Directive != staking.DirectiveDelegate {
+					continue
+				}
+				if delegations, err = bc.addDelegationIndex(
+					delegations, batch.DelegatorAddress, op.ValidatorAddress, state,
+				); err != nil {
+					return nil, nil, err
+				}
+			}
+			newDelegations[batch.DelegatorAddress] = delegations
+		case staking.DirectiveRotateKey:
 		default:
 		}
 	}
 
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if log.Address != vm.StakingPrecompileAddress || len(log.Topics) != 3 ||
+				log.Topics[0] != staking2.PrecompileDelegateTopic {
+				continue
+			}
+			delegatorAddress := common.BytesToAddress(log.Topics[1].Bytes())
+			validatorAddress := common.BytesToAddress(log.Topics[2].Bytes())
+
+			delegations, ok := newDelegations[delegatorAddress]
+			if !ok {
+				// If the cache doesn't have it, load it from DB for the first time.
+				delegations, err = bc.ReadDelegationsByDelegator(delegatorAddress)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			if delegations, err = bc.addDelegationIndex(
+				delegations, delegatorAddress, validatorAddress, state,
+			); err != nil {
+				return nil, nil, err
+			}
+			newDelegations[delegatorAddress] = delegations
+		}
+	}
+
 	return newValidators, newDelegations, nil
 }
 
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// chainCheckLogInterval is how many blocks are checked or repaired between two
+// progress logs.
+const chainCheckLogInterval = 100000
+
+// ChainDBReport is the outcome of the check of a chain database.
+type ChainDBReport struct {
+	First    uint64 // number of the first block, the checkpoint one if any
+	Head     uint64 // number of the head block
+	Checked  uint64 // number of blocks checked
+	Problems int    // number of inconsistencies found
+	// Details are the descriptions of the first inconsistencies found.
+	Details []string
+	// UnindexedEpochs is the number of epochs whose first block number is not
+	// in the database, which repair can fix.
+	UnindexedEpochs int
+}
+
+func (r *ChainDBReport) addProblem(maxDetails int, format string, args ...interface{}) {
+	r.Problems++
+	if len(r.Details) < maxDetails {
+		r.Details = append(r.Details, fmt.Sprintf(format, args...))
+	}
+}
+
+// CheckChainDB checks that the canonical chain in db is complete up to its head
+// block and consistent with its indices: the header numbers, the receipts, the
+// transaction lookups, the shard states, the epoch block numbers and, on the
+// beacon chain, the crosslinks. Up to maxDetails inconsistencies are described
+// in the report.
+func CheckChainDB(db ethdb.Database, shardID uint32, maxDetails int) (*ChainDBReport, error) {
+	headHash := rawdb.ReadHeadBlockHash(db)
+	if headHash == (common.Hash{}) {
+		return nil, errors.New("no head block in the database")
+	}
+	headNumber := rawdb.ReadHeaderNumber(db, headHash)
+	if headNumber == nil {
+		return nil, errors.Errorf("number of head block %x missing", headHash)
+	}
+	report := &ChainDBReport{Head: *headNumber}
+	if rawdb.ReadCanonicalHash(db, *headNumber) != headHash {
+		report.addProblem(maxDetails, "head block %x is not the canonical block %d", headHash, *headNumber)
+	}
+	first, err := firstChainBlock(db)
+	if err != nil {
+		return nil, err
+	}
+	report.First = first
+
+	var parent *block.Header
+	for number := first; number <= *headNumber; number++ {
+		report.Checked++
+		header := checkBlock(db, shardID, number, parent, report, maxDetails)
+		if header != nil && (parent == nil || header.Epoch().Cmp(parent.Epoch()) != 0) {
+			// the first block of an epoch
+			epochBlock, err := rawdb.ReadEpochBlockNumber(db, header.Epoch())
+			switch {
+			case err != nil:
+				report.UnindexedEpochs++
+			case !epochBlock.IsUint64() || epochBlock.Uint64() != number:
+				report.addProblem(maxDetails,
+					"first block of epoch %v is %d, indexed as %v", header.Epoch(), number, epochBlock)
+			}
+		}
+		parent = header
+		if number%chainCheckLogInterval == 0 {
+			utils.Logger().Info().
+				Uint64("blockNum", number).
+				Int("problems", report.Problems).
+				Msg("Checking the chain database")
+		}
+	}
+	return report, nil
+}
+
+// firstChainBlock returns the number of the first block of the canonical chain
+// in db, which is the checkpoint block the chain was synced from if any.
+func firstChainBlock(db ethdb.Database) (uint64, error) {
+	hash := rawdb.ReadCheckpointHash(db)
+	if hash == (common.Hash{}) {
+		return 0, nil
+	}
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return 0, errors.Errorf("number of checkpoint block %x missing", hash)
+	}
+	return *number, nil
+}
+
+// checkBlock checks the canonical block of the given number, whose parent is
+// given if it could be read, and returns its header if it could be read.
+func checkBlock(
+	db ethdb.Database, shardID uint32, number uint64, parent *block.Header,
+	report *ChainDBReport, maxDetails int,
+) *block.Header {
+	hash := rawdb.ReadCanonicalHash(db, number)
+	if hash == (common.Hash{}) {
+		report.addProblem(maxDetails, "canonical hash of block %d missing", number)
+		return nil
+	}
+	header := rawdb.ReadHeader(db, hash, number)
+	if header == nil {
+		report.addProblem(maxDetails, "header of block %d %x missing", number, hash)
+		return nil
+	}
+	if n := rawdb.ReadHeaderNumber(db, hash); n == nil || *n != number {
+		report.addProblem(maxDetails, "number of block %d %x not indexed", number, hash)
+	}
+	if parent != nil && header.ParentHash() != parent.Hash() {
+		report.addProblem(maxDetails, "block %d %x is not a child of the canonical block %d", number, hash, number-1)
+	}
+
+	blk := rawdb.ReadBlock(db, hash, number)
+	if blk == nil {
+		report.addProblem(maxDetails, "body of block %d %x missing", number, hash)
+		return header
+	}
+	txs := len(blk.Transactions()) + len(blk.StakingTransactions())
+	if txs > 0 {
+		if receipts := rawdb.ReadReceipts(db, hash, number); len(receipts) != txs {
+			report.addProblem(maxDetails,
+				"block %d %x has %d receipts for %d transactions", number, hash, len(receipts), txs)
+		}
+	}
+	checkTxLookup := func(txHash common.Hash, index int) {
+		blockHash, blockNumber, txIndex := rawdb.ReadTxLookupEntry(db, txHash)
+		if blockHash != hash || blockNumber != number || txIndex != uint64(index) {
+			report.addProblem(maxDetails, "lookup of transaction %x of block %d wrong or missing", txHash, number)
+		}
+	}
+	for i, tx := range blk.Transactions() {
+		checkTxLookup(tx.Hash(), i)
+	}
+	for i, tx := range blk.StakingTransactions() {
+		checkTxLookup(tx.Hash(), i)
+	}
+
+	if len(header.ShardState()) > 0 {
+		checkShardState(db, header, report, maxDetails)
+	}
+	if shardID == shard.BeaconChainShardID && len(header.CrossLinks()) > 0 {
+		crossLinks := types.CrossLinks{}
+		if err := rlp.DecodeBytes(header.CrossLinks(), &crossLinks); err != nil {
+			report.addProblem(maxDetails, "invalid crosslinks in block %d: %v", number, err)
+		}
+		for _, cl := range crossLinks {
+			if _, err := rawdb.ReadCrossLinkShardBlock(db, cl.ShardID(), cl.BlockNum()); err != nil {
+				report.addProblem(maxDetails,
+					"crosslink of block %d of shard %d in block %d missing", cl.BlockNum(), cl.ShardID(), number)
+			}
+		}
+	}
+	return header
+}
+
+// checkShardState checks that the shard state in the given last block of an
+// epoch is stored as the one of the next epoch.
+func checkShardState(db ethdb.Database, header *block.Header, report *ChainDBReport, maxDetails int) {
+	ss, err := shard.DecodeWrapper(header.ShardState())
+	if err != nil {
+		report.addProblem(maxDetails, "invalid shard state in block %v: %v", header.Number(), err)
+		return
+	}
+	// after staking, the shard state has its epoch
+	epochs := []*big.Int{new(big.Int).Add(header.Epoch(), common.Big1)}
+	if ss.Epoch != nil {
+		epochs = append(epochs, ss.Epoch)
+	}
+	if header.Number().Sign() == 0 {
+		// the genesis block has the shard state of its own epoch
+		epochs = []*big.Int{header.Epoch()}
+	}
+	for _, epoch := range epochs {
+		if _, err := rawdb.ReadShardState(db, epoch); err == nil {
+			return
+		}
+	}
+	report.addProblem(maxDetails, "shard state of epoch %v from block %v missing", epochs[len(epochs)-1], header.Number())
+}
+
+// RepairChainIndices rewrites the transaction lookups and the epoch block
+// numbers of the canonical chain in db from its blocks, returning how many
+// blocks were indexed. The canonical chain must be complete up to the head
+// block.
+func RepairChainIndices(db ethdb.Database) (uint64, error) {
+	headNumber := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadBlockHash(db))
+	if headNumber == nil {
+		return 0, errors.New("no head block in the database")
+	}
+	first, err := firstChainBlock(db)
+	if err != nil {
+		return 0, err
+	}
+	batch := db.NewBatch()
+	var prevEpoch *big.Int
+	for number := first; number <= *headNumber; number++ {
+		hash := rawdb.ReadCanonicalHash(db, number)
+		blk := rawdb.ReadBlock(db, hash, number)
+		if blk == nil {
+			return number, errors.Errorf(
+				"canonical block %d missing, revert the chain before it first", number,
+			)
+		}
+		rawdb.WriteTxLookupEntries(batch, blk)
+		if prevEpoch == nil || blk.Epoch().Cmp(prevEpoch) != 0 {
+			if err := rawdb.WriteEpochBlockNumber(
+				batch, blk.Epoch(), new(big.Int).SetUint64(number),
+			); err != nil {
+				return number, err
+			}
+		}
+		prevEpoch = blk.Epoch()
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return number, err
+			}
+			batch.Reset()
+		}
+		if number%chainCheckLogInterval == 0 {
+			utils.Logger().Info().Uint64("blockNum", number).Msg("Repairing the chain indices")
+		}
+	}
+	return *headNumber + 1 - first, batch.Write()
+}
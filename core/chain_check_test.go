@@ -0,0 +1,69 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// writeTestChain writes a canonical chain of the given number of blocks, the
+// last one in epoch 1, without the epoch block numbers.
+func writeTestChain(db ethdb.Database, length int) []*types.Block {
+	blocks := make([]*types.Block, length)
+	parentHash := common.Hash{}
+	for i := range blocks {
+		epoch := int64(0)
+		if i == length-1 {
+			epoch = 1
+		}
+		blocks[i] = types.NewBlockWithHeader(blockfactory.NewTestHeader().With().
+			Number(big.NewInt(int64(i))).
+			Epoch(big.NewInt(epoch)).
+			ParentHash(parentHash).
+			Header())
+		rawdb.WriteBlock(db, blocks[i])
+		rawdb.WriteCanonicalHash(db, blocks[i].Hash(), blocks[i].NumberU64())
+		parentHash = blocks[i].Hash()
+	}
+	rawdb.WriteHeadBlockHash(db, parentHash)
+	return blocks
+}
+
+func TestCheckAndRepairChainDB(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	writeTestChain(db, 4)
+
+	report, err := CheckChainDB(db, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Head != 3 || report.Checked != 4 || report.Problems != 0 {
+		t.Fatalf("unexpected report of a consistent chain: %+v", report)
+	}
+	if report.UnindexedEpochs != 2 {
+		t.Errorf("unindexed epochs mismatch: have %d, want 2", report.UnindexedEpochs)
+	}
+
+	if blocks, err := RepairChainIndices(db); err != nil || blocks != 4 {
+		t.Fatalf("failed to repair: %d blocks, %v", blocks, err)
+	}
+	if report, _ = CheckChainDB(db, 1, 10); report.UnindexedEpochs != 0 {
+		t.Errorf("unindexed epochs after repair: %d", report.UnindexedEpochs)
+	}
+	if number, err := rawdb.ReadEpochBlockNumber(db, big.NewInt(1)); err != nil || number.Uint64() != 3 {
+		t.Errorf("first block of epoch 1 mismatch: have %v (%v), want 3", number, err)
+	}
+
+	rawdb.DeleteCanonicalHash(db, 2)
+	if report, _ = CheckChainDB(db, 1, 10); report.Problems != 1 || len(report.Details) != 1 {
+		t.Errorf("missing canonical hash not reported: %+v", report)
+	}
+	if _, err := RepairChainIndices(db); err == nil {
+		t.Error("repaired a chain with a missing block")
+	}
+}
@@ -0,0 +1,296 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	staking "github.com/harmony-one/harmony/staking/types"
+	"github.com/pkg/errors"
+)
+
+// Checkpoint is a trusted block of the beacon chain, with the data kept off the
+// state of the chain which is needed to verify and execute the blocks after it.
+// A new node can sync forward from a checkpoint instead of from genesis,
+// trusting the committees of the epoch of the checkpoint.
+type Checkpoint struct {
+	Epoch       *big.Int
+	BlockNumber uint64
+	BlockHash   common.Hash
+	// ShardState is the encoded shard state of Epoch, as stored in the chain.
+	ShardState []byte
+	// Validators are the addresses of all the validators.
+	Validators []common.Address
+	// ValidatorSnapshots are the snapshots of the validators taken for Epoch.
+	ValidatorSnapshots []*staking.ValidatorWrapper
+	// LastCrossLinks are the last crosslinks of the shards.
+	LastCrossLinks []types.CrossLink
+	// EpochBlockNumber is the number of the first block of Epoch.
+	EpochBlockNumber *big.Int
+	// PreviousShardState is the encoded shard state of the epoch before Epoch,
+	// which the crosslinks of that epoch are verified against, empty for the
+	// first epoch.
+	PreviousShardState []byte
+	// VrfBlockNumbers are the blocks of Epoch with a VRF up to BlockNumber.
+	VrfBlockNumbers []uint64
+	// VdfBlockNumber is the encoded number of the block of Epoch with the VDF,
+	// as stored in the chain, empty if none yet.
+	VdfBlockNumber []byte
+	// BlockRewardAccumulator is the total block reward paid up to BlockNumber.
+	BlockRewardAccumulator *big.Int
+	// ValidatorStats are the stats of the validators which have some.
+	ValidatorStats []CheckpointValidatorStats
+}
+
+// CheckpointValidatorStats are the stats of a validator in a checkpoint.
+type CheckpointValidatorStats struct {
+	Address common.Address
+	Stats   *staking.ValidatorStats
+}
+
+// NewCheckpoint returns the checkpoint of the given block of the beacon chain.
+func NewCheckpoint(bc *BlockChain, number uint64) (*Checkpoint, error) {
+	if bc.ShardID() != shard.BeaconChainShardID {
+		return nil, errors.New("checkpoints are of the beacon chain")
+	}
+	block := bc.GetBlockByNumber(number)
+	if block == nil {
+		return nil, errors.Errorf("block %d not found", number)
+	}
+	epoch := block.Epoch()
+	shardState, err := bc.ReadShardState(epoch)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read shard state of epoch %v", epoch)
+	}
+	encodedShardState, err := shard.EncodeWrapper(*shardState, bc.Config().IsStaking(epoch))
+	if err != nil {
+		return nil, err
+	}
+	validators, err := bc.ReadValidatorList()
+	if err != nil {
+		return nil, err
+	}
+	epochBlockNumber, err := bc.epochBlockNumber(block.Header())
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot find the first block of epoch %v", epoch)
+	}
+	accumulator, err := bc.ReadBlockRewardAccumulator(number)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot read block reward accumulator of block %d", number)
+	}
+	cp := &Checkpoint{
+		Epoch:                  epoch,
+		BlockNumber:            number,
+		BlockHash:              block.Hash(),
+		ShardState:             encodedShardState,
+		Validators:             validators,
+		EpochBlockNumber:       epochBlockNumber,
+		BlockRewardAccumulator: accumulator,
+	}
+	if epoch.Sign() > 0 {
+		previousEpoch := new(big.Int).Sub(epoch, common.Big1)
+		previousShardState, err := bc.ReadShardState(previousEpoch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot read shard state of epoch %v", previousEpoch)
+		}
+		if cp.PreviousShardState, err = shard.EncodeWrapper(
+			*previousShardState, bc.Config().IsStaking(previousEpoch),
+		); err != nil {
+			return nil, err
+		}
+	}
+	// the randomness of the epoch may not be there yet
+	if vrfBlockNumbers, err := bc.ReadEpochVrfBlockNums(epoch); err == nil {
+		cp.VrfBlockNumbers = vrfBlockNumbers
+	}
+	if vdfBlockNumber, err := rawdb.ReadEpochVdfBlockNum(bc.db, epoch); err == nil {
+		cp.VdfBlockNumber = vdfBlockNumber
+	}
+	for _, addr := range validators {
+		// the validators created during the epoch have no snapshot
+		if snapshot, err := bc.ReadValidatorSnapshotAtEpoch(epoch, addr); err == nil && snapshot != nil {
+			cp.ValidatorSnapshots = append(cp.ValidatorSnapshots, snapshot)
+		}
+		// nor stats before their first election
+		if stats, err := bc.ReadValidatorStats(addr); err == nil {
+			cp.ValidatorStats = append(cp.ValidatorStats, CheckpointValidatorStats{addr, stats})
+		}
+	}
+	for _, committee := range shardState.Shards {
+		if cl, err := bc.ReadShardLastCrossLink(committee.ShardID); err == nil {
+			cp.LastCrossLinks = append(cp.LastCrossLinks, *cl)
+		}
+	}
+	return cp, nil
+}
+
+// epochBlockNumber returns the number of the first block of the epoch of the
+// given header, looking back through the headers if it is not indexed.
+func (bc *BlockChain) epochBlockNumber(header *block.Header) (*big.Int, error) {
+	if number, err := rawdb.ReadEpochBlockNumber(bc.db, header.Epoch()); err == nil {
+		return number, nil
+	}
+	for header.Number().Sign() > 0 {
+		parent := bc.GetHeader(header.ParentHash(), header.Number().Uint64()-1)
+		if parent == nil {
+			return nil, errors.Errorf("header %d missing", header.Number().Uint64()-1)
+		}
+		if parent.Epoch().Cmp(header.Epoch()) != 0 {
+			break
+		}
+		header = parent
+	}
+	return header.Number(), nil
+}
+
+// ReadCheckpointFile reads a checkpoint written by WriteCheckpointFile.
+func ReadCheckpointFile(fn string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	cp := &Checkpoint{}
+	if err := rlp.DecodeBytes(data, cp); err != nil {
+		return nil, errors.Wrapf(err, "invalid checkpoint file %s", fn)
+	}
+	if cp.Epoch == nil {
+		return nil, errors.Errorf("invalid checkpoint file %s: no epoch", fn)
+	}
+	if cp.EpochBlockNumber.Uint64() > cp.BlockNumber {
+		return nil, errors.Errorf(
+			"invalid checkpoint file %s: epoch starts at block %v after block %d",
+			fn, cp.EpochBlockNumber, cp.BlockNumber,
+		)
+	}
+	if _, err := shard.DecodeWrapper(cp.ShardState); err != nil {
+		return nil, errors.Wrapf(err, "invalid shard state in checkpoint file %s", fn)
+	}
+	if len(cp.PreviousShardState) > 0 {
+		if _, err := shard.DecodeWrapper(cp.PreviousShardState); err != nil {
+			return nil, errors.Wrapf(err, "invalid previous shard state in checkpoint file %s", fn)
+		}
+	}
+	return cp, nil
+}
+
+// WriteCheckpointFile writes the checkpoint in RLP to the given file.
+func WriteCheckpointFile(cp *Checkpoint, fn string) error {
+	data, err := rlp.EncodeToBytes(cp)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fn, data, 0644)
+}
+
+// ResetWithCheckpoint makes the block of the given checkpoint the head block of
+// the new chain, whose state must be in the database already, and writes the
+// data of the checkpoint, so that the blocks after it can be verified and
+// executed. The blocks before it are left out of the chain.
+func (bc *BlockChain) ResetWithCheckpoint(cp *Checkpoint, block *types.Block) error {
+	if bc.ShardID() != shard.BeaconChainShardID {
+		return errors.New("checkpoints are of the beacon chain")
+	}
+	if head := bc.CurrentBlock().NumberU64(); head != 0 {
+		return errors.Errorf("chain is not new, head block is %d", head)
+	}
+	if block.Hash() != cp.BlockHash || block.NumberU64() != cp.BlockNumber ||
+		block.Epoch().Cmp(cp.Epoch) != 0 {
+		return errors.Errorf(
+			"block %d %x of epoch %v is not the checkpoint block %d %x of epoch %v",
+			block.NumberU64(), block.Hash(), block.Epoch(), cp.BlockNumber, cp.BlockHash, cp.Epoch,
+		)
+	}
+	if _, err := state.New(block.Root(), bc.stateCache); err != nil {
+		return errors.Wrapf(err, "state of checkpoint block %d missing", block.NumberU64())
+	}
+	shardState, err := shard.DecodeWrapper(cp.ShardState)
+	if err != nil {
+		return err
+	}
+
+	batch := bc.db.NewBatch()
+	if err := rawdb.WriteShardStateBytes(batch, cp.Epoch, cp.ShardState); err != nil {
+		return err
+	}
+	if len(cp.PreviousShardState) > 0 {
+		if err := rawdb.WriteShardStateBytes(
+			batch, new(big.Int).Sub(cp.Epoch, common.Big1), cp.PreviousShardState,
+		); err != nil {
+			return err
+		}
+	}
+	// the committees of the next epoch, if the block is the last of its epoch
+	if len(block.Header().ShardState()) > 0 {
+		if err := bc.writeShardStateOfHeader(batch, block.Header()); err != nil {
+			return err
+		}
+	}
+	if err := rawdb.WriteEpochBlockNumber(batch, cp.Epoch, cp.EpochBlockNumber); err != nil {
+		return err
+	}
+	if len(cp.VrfBlockNumbers) > 0 {
+		encoded, err := rlp.EncodeToBytes(cp.VrfBlockNumbers)
+		if err != nil {
+			return err
+		}
+		if err := rawdb.WriteEpochVrfBlockNums(batch, cp.Epoch, encoded); err != nil {
+			return err
+		}
+	}
+	if len(cp.VdfBlockNumber) > 0 {
+		if err := rawdb.WriteEpochVdfBlockNum(batch, cp.Epoch, cp.VdfBlockNumber); err != nil {
+			return err
+		}
+	}
+	if err := rawdb.WriteBlockRewardAccumulator(
+		batch, cp.BlockRewardAccumulator, cp.BlockNumber,
+	); err != nil {
+		return err
+	}
+	if err := rawdb.WriteValidatorList(batch, cp.Validators, false); err != nil {
+		return err
+	}
+	if err := rawdb.WriteValidatorList(batch, shardState.StakedValidators().Addrs, true); err != nil {
+		return err
+	}
+	for _, snapshot := range cp.ValidatorSnapshots {
+		if err := rawdb.WriteValidatorSnapshot(batch, snapshot, cp.Epoch); err != nil {
+			return err
+		}
+	}
+	for _, cl := range cp.LastCrossLinks {
+		if err := rawdb.WriteShardLastCrossLink(batch, cl.ShardID(), cl.Serialize()); err != nil {
+			return err
+		}
+	}
+	for _, validator := range cp.ValidatorStats {
+		if err := rawdb.WriteValidatorStats(batch, validator.Address, validator.Stats); err != nil {
+			return err
+		}
+	}
+	rawdb.WriteBlock(batch, block)
+	rawdb.WriteCheckpointHash(batch, block.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	// also makes the block the head header and head fast block
+	bc.insert(block)
+	bc.shardStateCache.Purge()
+
+	utils.Logger().Info().
+		Uint64("blockNum", block.NumberU64()).
+		Str("blockHash", block.Hash().Hex()).
+		Str("epoch", cp.Epoch.String()).
+		Msg("Reset the chain to the checkpoint block")
+	return nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestCheckpointFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	shardState, err := shard.EncodeWrapper(shard.State{
+		Epoch:  big.NewInt(7),
+		Shards: []shard.Committee{{ShardID: 0}, {ShardID: 1}},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := &Checkpoint{
+		Epoch:                  big.NewInt(7),
+		BlockNumber:            1234,
+		BlockHash:              common.HexToHash("0x1234"),
+		ShardState:             shardState,
+		Validators:             []common.Address{common.HexToAddress("0x01"), common.HexToAddress("0x02")},
+		EpochBlockNumber:       big.NewInt(1200),
+		PreviousShardState:     shardState,
+		VrfBlockNumbers:        []uint64{1200, 1201},
+		BlockRewardAccumulator: big.NewInt(5),
+	}
+	fn := filepath.Join(dir, "checkpoint")
+	if err := WriteCheckpointFile(cp, fn); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadCheckpointFile(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Epoch.Cmp(cp.Epoch) != 0 || read.BlockNumber != cp.BlockNumber ||
+		read.BlockHash != cp.BlockHash || len(read.Validators) != 2 ||
+		read.Validators[1] != cp.Validators[1] {
+		t.Errorf("checkpoint mismatch: have %+v, want %+v", read, cp)
+	}
+	if read.EpochBlockNumber.Cmp(cp.EpochBlockNumber) != 0 || len(read.VrfBlockNumbers) != 2 ||
+		len(read.VdfBlockNumber) != 0 || read.BlockRewardAccumulator.Cmp(cp.BlockRewardAccumulator) != 0 {
+		t.Errorf("epoch data of checkpoint mismatch: have %+v, want %+v", read, cp)
+	}
+
+	if err := ioutil.WriteFile(fn, []byte("not a checkpoint"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCheckpointFile(fn); err == nil {
+		t.Error("read an invalid checkpoint file")
+	}
+}
+
+func TestCheckChainDBFromCheckpoint(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	blocks := writeTestChain(db, 4)
+	// the blocks before the checkpoint are not in a checkpoint synced chain
+	rawdb.DeleteCanonicalHash(db, 0)
+	rawdb.WriteCheckpointHash(db, blocks[1].Hash())
+
+	report, err := CheckChainDB(db, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.First != 1 || report.Checked != 3 || report.Problems != 0 {
+		t.Errorf("unexpected report of a chain from a checkpoint: %+v", report)
+	}
+	if blocks, err := RepairChainIndices(db); err != nil || blocks != 3 {
+		t.Errorf("failed to repair: %d blocks, %v", blocks, err)
+	}
+}
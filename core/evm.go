@@ -24,6 +24,7 @@ import (
 	consensus_engine "github.com/harmony-one/harmony/consensus/engine"
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/params"
 	staking "github.com/harmony-one/harmony/staking/types"
 )
 
@@ -41,6 +42,12 @@ type ChainContext interface {
 
 	// ReadValidatorSnapshot returns the snapshot of validator at the beginning of current epoch.
 	ReadValidatorSnapshot(common.Address) (*staking.ValidatorWrapper, error)
+
+	// ReadValidatorList returns the addresses of all validators.
+	ReadValidatorList() ([]common.Address, error)
+
+	// Config returns the chain configuration.
+	Config() *params.ChainConfig
 }
 
 // NewEVMContext creates a new context for use in the EVM.
@@ -53,17 +60,36 @@ func NewEVMContext(msg Message, header *block.Header, chain ChainContext, author
 		beneficiary = *author
 	}
 	return vm.Context{
-		CanTransfer: CanTransfer,
-		Transfer:    Transfer,
-		IsValidator: IsValidator,
-		GetHash:     GetHashFn(header, chain),
-		Origin:      msg.From(),
-		Coinbase:    beneficiary,
-		BlockNumber: header.Number(),
-		EpochNumber: header.Epoch(),
-		Time:        header.Time(),
-		GasLimit:    header.GasLimit(),
-		GasPrice:    new(big.Int).Set(msg.GasPrice()),
+		CanTransfer:  CanTransfer,
+		Transfer:     Transfer,
+		IsValidator:  IsValidator,
+		GetHash:      GetHashFn(header, chain),
+		ApplyStaking: StakingPrecompileFn(chain),
+		Origin:       msg.From(),
+		Coinbase:     beneficiary,
+		BlockNumber:  header.Number(),
+		EpochNumber:  header.Epoch(),
+		Time:         header.Time(),
+		GasLimit:     header.GasLimit(),
+		GasPrice:     new(big.Int).Set(msg.GasPrice()),
+	}
+}
+
+// StakingPrecompileFn returns an ApplyStakingFunc which applies the staking
+// messages of the staking precompile as the staking transactions would
+func StakingPrecompileFn(chain ChainContext) vm.ApplyStakingFunc {
+	return func(db vm.StateDB, epoch *big.Int, msg staking.StakeMsg) error {
+		switch m := msg.(type) {
+		case *staking.Delegate:
+			return verifyAndApplyDelegate(db, m)
+		case *staking.Undelegate:
+			return verifyAndApplyUndelegate(db, epoch, m)
+		case *staking.CollectRewards:
+			_, err := verifyAndApplyCollectRewards(db, chain, m)
+			return err
+		default:
+			return staking.ErrInvalidStakingKind
+		}
 	}
 }
 
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/core/vm"
+	"github.com/harmony-one/harmony/internal/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// precompileCaller returns the code of a contract calling the staking
+// precompile with its own call data, then reverting if revert is set
+func precompileCaller(revert bool) []byte {
+	code := []byte{
+		byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATACOPY),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.CALLDATASIZE), byte(vm.PUSH1), 0,
+		byte(vm.PUSH1), 0, byte(vm.PUSH20),
+	}
+	code = append(code, vm.StakingPrecompileAddress.Bytes()...)
+	code = append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+	if revert {
+		return append(code, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT))
+	}
+	return append(code, byte(vm.STOP))
+}
+
+// delegateThroughPrecompile calls a contract delegating amount to the
+// validator through the staking precompile, returning the receipt logs
+func delegateThroughPrecompile(
+	t *testing.T, statedb *state.DB, revert bool, amount *big.Int,
+) (common.Address, []*types.Log, error) {
+	contract := common.BigToAddress(big.NewInt(9))
+	statedb.SetCode(contract, precompileCaller(revert))
+	statedb.AddBalance(contract, twelveK)
+	env := vm.NewEVM(vm.Context{
+		CanTransfer:  CanTransfer,
+		Transfer:     Transfer,
+		IsValidator:  IsValidator,
+		ApplyStaking: StakingPrecompileFn(nil),
+		BlockNumber:  big.NewInt(1),
+		EpochNumber:  postStakingEpoch,
+	}, statedb, params.TestChainConfig, vm.Config{})
+	input := crypto.Keccak256([]byte("Delegate(address,address,uint256)"))[:4]
+	input = append(input, common.LeftPadBytes(contract.Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(validatorAddress.Bytes(), 32)...)
+	input = append(input, common.LeftPadBytes(amount.Bytes(), 32)...)
+	_, _, err := env.Call(
+		vm.AccountRef(common.BigToAddress(big.NewInt(10))), contract, input, 1000000, new(big.Int),
+	)
+	return contract, statedb.Logs(), err
+}
+
+// Test staking precompile: a contract delegates on the state, and the
+// delegation index is written from the precompile log
+func TestStakingPrecompileDelegate(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	addValidator(t, statedb, validatorAddress)
+	delegator, logs, err := delegateThroughPrecompile(t, statedb, false, oneThousandTimes(1))
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if balance := statedb.GetBalance(delegator); balance.Cmp(oneThousandTimes(11)) != 0 {
+		t.Error("expected balance", oneThousandTimes(11), "got", balance)
+	}
+	wrapper, _ := statedb.ValidatorWrapper(validatorAddress)
+	if len(wrapper.Delegations) != 2 ||
+		wrapper.Delegations[1].DelegatorAddress != delegator ||
+		wrapper.Delegations[1].Amount.Cmp(oneThousandTimes(1)) != 0 {
+		t.Fatal("expected a delegation of", oneThousandTimes(1), "got", wrapper.Delegations)
+	}
+
+	_, delegations, err := testDelegationIndexChain().prepareStakingMetaData(
+		nil, []*types.Receipt{{Logs: logs}}, statedb,
+	)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if indexes := delegations[delegator]; len(indexes) != 1 ||
+		indexes[0].ValidatorAddress != validatorAddress || indexes[0].Index != 1 {
+		t.Error("expected the delegation indexed, got", indexes)
+	}
+}
+
+// Test staking precompile: a reverted outer call leaves no delegation
+// and no delegation index
+func TestStakingPrecompileDelegateReverted(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	addValidator(t, statedb, validatorAddress)
+	delegator, logs, err := delegateThroughPrecompile(t, statedb, true, oneThousandTimes(1))
+	if err == nil {
+		t.Fatal("expected", "execution reverted", "got", nil)
+	}
+	if balance := statedb.GetBalance(delegator); balance.Cmp(twelveK) != 0 {
+		t.Error("expected balance", twelveK, "got", balance)
+	}
+	if wrapper, _ := statedb.ValidatorWrapper(validatorAddress); len(wrapper.Delegations) != 1 {
+		t.Error("expected only the self delegation, got", wrapper.Delegations)
+	}
+
+	_, delegations, err := testDelegationIndexChain().prepareStakingMetaData(
+		nil, []*types.Receipt{{Logs: logs}}, statedb,
+	)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if len(delegations) != 0 {
+		t.Error("expected no delegation index, got", delegations)
+	}
+}
+
+// testDelegationIndexChain is a blockchain knowing only the delegation
+// indexes, all empty
+func testDelegationIndexChain() *BlockChain {
+	cache, _ := lru.New(validatorListByDelegatorCacheLimit)
+	return &BlockChain{
+		db:                            ethdb.NewMemDatabase(),
+		validatorListByDelegatorCache: cache,
+	}
+}
@@ -38,23 +38,8 @@ func (bc *BlockChain) CommitOffChainData(
 	// Cross-shard txns
 	epoch := block.Header().Epoch()
 	if bc.chainConfig.HasCrossTxFields(block.Epoch()) {
-		shardingConfig := shard.Schedule.InstanceForEpoch(epoch)
-		shardNum := int(shardingConfig.NumShards())
-		for i := 0; i < shardNum; i++ {
-			if i == int(block.ShardID()) {
-				continue
-			}
-
-			shardReceipts := types.CXReceipts(cxReceipts).GetToShardReceipts(uint32(i))
-			if err := rawdb.WriteCXReceipts(
-				batch, uint32(i), block.NumberU64(), block.Hash(), shardReceipts,
-			); err != nil {
-				utils.Logger().Error().Err(err).
-					Interface("shardReceipts", shardReceipts).
-					Int("toShardID", i).
-					Msg("WriteCXReceipts cannot write into database")
-				return NonStatTy, err
-			}
+		if err := bc.writeCXReceipts(batch, block, cxReceipts); err != nil {
+			return NonStatTy, err
 		}
 		// Mark incomingReceipts in the block as spent
 		bc.WriteCXReceiptsProofSpent(batch, block.IncomingReceipts())
@@ -120,7 +105,7 @@ func (bc *BlockChain) CommitOffChainData(
 
 	// Do bookkeeping for new staking txns
 	newVals, err := bc.UpdateStakingMetaData(
-		batch, block.StakingTransactions(), state, epoch, newEpoch,
+		batch, block.StakingTransactions(), receipts, state, epoch, newEpoch,
 	)
 	if err != nil {
 		utils.Logger().Err(err).Msg("UpdateStakingMetaData failed")
@@ -214,6 +199,22 @@ func (bc *BlockChain) CommitOffChainData(
 			} else {
 				tempValidatorStats = stats
 			}
+			if isStaking {
+				if err := bc.UpdateValidatorAvailabilityHistory(
+					batch, block, shardState, currentSuperCommittee, state,
+				); err != nil {
+					utils.Logger().
+						Err(err).
+						Msg("[UpdateValidatorAvailabilityHistory] Failed to update availability history")
+				}
+				if err := bc.UpdateValidatorAPRHistory(
+					batch, block, state, tempValidatorStats,
+				); err != nil {
+					utils.Logger().
+						Err(err).
+						Msg("[UpdateValidatorAPRHistory] Failed to update apr history")
+				}
+			}
 		} else {
 			utils.Logger().
 				Err(err).
@@ -302,3 +303,97 @@ func (bc *BlockChain) CommitOffChainData(
 
 	return CanonStatTy, nil
 }
+
+// writeCXReceipts writes the outgoing cross shard receipts of the block,
+// grouped by the shard they are for.
+func (bc *BlockChain) writeCXReceipts(
+	batch rawdb.DatabaseWriter, block *types.Block, cxReceipts types.CXReceipts,
+) error {
+	shardingConfig := shard.Schedule.InstanceForEpoch(block.Epoch())
+	shardNum := int(shardingConfig.NumShards())
+	for i := 0; i < shardNum; i++ {
+		if i == int(block.ShardID()) {
+			continue
+		}
+
+		shardReceipts := cxReceipts.GetToShardReceipts(uint32(i))
+		if err := rawdb.WriteCXReceipts(
+			batch, uint32(i), block.NumberU64(), block.Hash(), shardReceipts,
+		); err != nil {
+			utils.Logger().Error().Err(err).
+				Interface("shardReceipts", shardReceipts).
+				Int("toShardID", i).
+				Msg("WriteCXReceipts cannot write into database")
+			return err
+		}
+	}
+	return nil
+}
+
+// commitFastSyncedOffChainData writes the off chain data of a block inserted
+// with its receipts but not executed, as by a fast sync: its outgoing cross
+// shard receipts, rebuilt from its transactions, and the first block number of
+// its epoch. The data only known from executing the block, such as the staking
+// bookkeeping of the beacon chain, is not written.
+func (bc *BlockChain) commitFastSyncedOffChainData(
+	batch rawdb.DatabaseWriter, block *types.Block, receipts types.Receipts,
+) error {
+	if bc.chainConfig.HasCrossTxFields(block.Epoch()) {
+		cxReceipts, err := bc.outgoingCXReceipts(block, receipts)
+		if err != nil {
+			return err
+		}
+		if err := bc.writeCXReceipts(batch, block, cxReceipts); err != nil {
+			return err
+		}
+	}
+	parent := bc.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil || parent.Epoch().Cmp(block.Epoch()) != 0 {
+		return rawdb.WriteEpochBlockNumber(batch, block.Epoch(), block.Number())
+	}
+	return nil
+}
+
+// outgoingCXReceipts rebuilds the outgoing cross shard receipts of a block
+// from its transactions and their receipts, as executing them would, and
+// checks them against the outgoing receipt root of the block.
+func (bc *BlockChain) outgoingCXReceipts(
+	block *types.Block, receipts types.Receipts,
+) (types.CXReceipts, error) {
+	signer := types.MakeSigner(bc.chainConfig, block.Epoch())
+	cxReceipts := types.CXReceipts{}
+	for i, tx := range block.Transactions() {
+		if tx.ShardID() == tx.ToShardID() {
+			continue
+		}
+		// no cross shard receipt for a failed transaction, receipts with
+		// a post state carry no status
+		if len(receipts[i].PostState) == 0 &&
+			receipts[i].Status == types.ReceiptStatusFailed {
+			continue
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return nil, errors.Wrapf(
+				err, "cannot recover sender of cross shard transaction %x", tx.Hash(),
+			)
+		}
+		cxReceipts = append(cxReceipts, &types.CXReceipt{
+			TxHash:    tx.Hash(),
+			From:      from,
+			To:        tx.To(),
+			ShardID:   tx.ShardID(),
+			ToShardID: tx.ToShardID(),
+			Amount:    tx.Value(),
+		})
+	}
+	root := block.OutgoingReceiptHash()
+	if cxReceipts.ComputeMerkleRoot() != root &&
+		types.DeriveMultipleShardsSha(cxReceipts) != root {
+		return nil, errors.Errorf(
+			"outgoing receipts of block %d do not match its outgoing receipt root %x",
+			block.NumberU64(), root,
+		)
+	}
+	return cxReceipts, nil
+}
@@ -41,6 +41,9 @@ const (
 // ReadCanonicalHash retrieves the hash assigned to a canonical block number.
 func ReadCanonicalHash(db DatabaseReader, number uint64) common.Hash {
 	data, _ := db.Get(headerHashKey(number))
+	if len(data) == 0 {
+		data = readAncient(db, freezerHashTable, number)
+	}
 	if len(data) == 0 {
 		return common.Hash{}
 	}
@@ -112,6 +115,24 @@ func ReadHeadFastBlockHash(db DatabaseReader) common.Hash {
 	return common.BytesToHash(data)
 }
 
+// ReadCheckpointHash retrieves the hash of the trusted block the chain was
+// synced from, which is empty if the chain was synced from genesis.
+func ReadCheckpointHash(db DatabaseReader) common.Hash {
+	data, _ := db.Get(checkpointKey)
+	if len(data) == 0 {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteCheckpointHash stores the hash of the trusted block the chain was synced
+// from.
+func WriteCheckpointHash(db DatabaseWriter, hash common.Hash) {
+	if err := db.Put(checkpointKey, hash.Bytes()); err != nil {
+		utils.Logger().Error().Msg("Failed to store checkpoint block's hash")
+	}
+}
+
 // WriteHeadFastBlockHash stores the hash of the current fast-sync head block.
 func WriteHeadFastBlockHash(db DatabaseWriter, hash common.Hash) {
 	if err := db.Put(headFastBlockKey, hash.Bytes()); err != nil {
@@ -122,13 +143,16 @@ func WriteHeadFastBlockHash(db DatabaseWriter, hash common.Hash) {
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
+	if len(data) == 0 && isAncientBlock(db, hash, number) {
+		data = readAncient(db, freezerHeaderTable, number)
+	}
 	return data
 }
 
 // HasHeader verifies the existence of a block header corresponding to the hash.
 func HasHeader(db DatabaseReader, hash common.Hash, number uint64) bool {
 	if has, err := db.Has(headerKey(number, hash)); !has || err != nil {
-		return false
+		return isAncientBlock(db, hash, number)
 	}
 	return true
 }
@@ -184,6 +208,9 @@ func DeleteHeader(db DatabaseDeleter, hash common.Hash, number uint64) {
 // ReadBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func ReadBodyRLP(db DatabaseReader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(number, hash))
+	if len(data) == 0 && isAncientBlock(db, hash, number) {
+		data = readAncient(db, freezerBodiesTable, number)
+	}
 	return data
 }
 
@@ -197,7 +224,7 @@ func WriteBodyRLP(db DatabaseWriter, hash common.Hash, number uint64, rlp rlp.Ra
 // HasBody verifies the existence of a block body corresponding to the hash.
 func HasBody(db DatabaseReader, hash common.Hash, number uint64) bool {
 	if has, err := db.Has(blockBodyKey(number, hash)); !has || err != nil {
-		return false
+		return isAncientBlock(db, hash, number)
 	}
 	return true
 }
@@ -268,6 +295,9 @@ func DeleteTd(db DatabaseDeleter, hash common.Hash, number uint64) {
 func ReadReceipts(db DatabaseReader, hash common.Hash, number uint64) types.Receipts {
 	// Retrieve the flattened receipt slice
 	data, _ := db.Get(blockReceiptsKey(number, hash))
+	if len(data) == 0 && isAncientBlock(db, hash, number) {
+		data = readAncient(db, freezerReceiptTable, number)
+	}
 	if len(data) == 0 {
 		return nil
 	}
@@ -340,6 +370,37 @@ func DeleteBlock(db DatabaseDeleter, hash common.Hash, number uint64) {
 	DeleteTd(db, hash, number)
 }
 
+// ReadBlockCommitSig retrieves the commit signature and bitmap over the
+// canonical block of the given number, from the header of its child.
+func ReadBlockCommitSig(db DatabaseReader, number uint64) []byte {
+	if data := readAncient(db, freezerCommitSigTable, number); len(data) > 0 {
+		return data
+	}
+	child := ReadHeader(db, ReadCanonicalHash(db, number+1), number+1)
+	if child == nil {
+		return nil
+	}
+	sig := child.LastCommitSignature()
+	return append(sig[:], child.LastCommitBitmap()...)
+}
+
+// readAncient retrieves the data of the given kind of a block from the ancient
+// store of the database, if it has one.
+func readAncient(db DatabaseReader, kind string, number uint64) []byte {
+	ancients, ok := db.(AncientReader)
+	if !ok {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// isAncientBlock tells whether the block is in the ancient store of the
+// database, which only has canonical blocks.
+func isAncientBlock(db DatabaseReader, hash common.Hash, number uint64) bool {
+	return bytes.Equal(readAncient(db, freezerHashTable, number), hash[:])
+}
+
 // FindCommonAncestor returns the last common ancestor of two block headers
 func FindCommonAncestor(db DatabaseReader, a, b *block.Header) *block.Header {
 	for bn := b.Number().Uint64(); a.Number().Uint64() > bn; {
@@ -246,6 +246,74 @@ func WriteValidatorStats(
 	return err
 }
 
+// ReadValidatorEpochAvailability retrieves validator's availability in the
+// epoch, nil if there is none
+func ReadValidatorEpochAvailability(
+	db DatabaseReader, addr common.Address, epoch *big.Int,
+) (*staking.EpochAvailability, error) {
+	data, err := db.Get(validatorAvailabilityKey(addr, epoch))
+	if err != nil || len(data) == 0 {
+		return nil, nil
+	}
+	availability := staking.EpochAvailability{}
+	if err := rlp.DecodeBytes(data, &availability); err != nil {
+		return nil, err
+	}
+	return &availability, nil
+}
+
+// WriteValidatorEpochAvailability stores validator's availability in the
+// epoch of the availability, replacing the one stored before if any
+func WriteValidatorEpochAvailability(
+	batch DatabaseWriter, addr common.Address, availability *staking.EpochAvailability,
+) error {
+	bytes, err := rlp.EncodeToBytes(availability)
+	if err != nil {
+		utils.Logger().Error().Msg("[WriteValidatorEpochAvailability] Failed to encode")
+		return err
+	}
+	if err := batch.Put(
+		validatorAvailabilityKey(addr, availability.Epoch), bytes,
+	); err != nil {
+		utils.Logger().Error().Msg("[WriteValidatorEpochAvailability] Failed to store to database")
+		return err
+	}
+	return nil
+}
+
+// ReadValidatorEpochAPR retrieves validator's apr at the end of the epoch,
+// nil if there is none
+func ReadValidatorEpochAPR(
+	db DatabaseReader, addr common.Address, epoch *big.Int,
+) (*staking.EpochAPR, error) {
+	data, err := db.Get(validatorAPRKey(addr, epoch))
+	if err != nil || len(data) == 0 {
+		return nil, nil
+	}
+	epochAPR := staking.EpochAPR{}
+	if err := rlp.DecodeBytes(data, &epochAPR); err != nil {
+		return nil, err
+	}
+	return &epochAPR, nil
+}
+
+// WriteValidatorEpochAPR stores validator's apr at the end of the epoch of
+// the apr, replacing the one stored before if any
+func WriteValidatorEpochAPR(
+	batch DatabaseWriter, addr common.Address, epochAPR *staking.EpochAPR,
+) error {
+	bytes, err := rlp.EncodeToBytes(epochAPR)
+	if err != nil {
+		utils.Logger().Error().Msg("[WriteValidatorEpochAPR] Failed to encode")
+		return err
+	}
+	if err := batch.Put(validatorAPRKey(addr, epochAPR.Epoch), bytes); err != nil {
+		utils.Logger().Error().Msg("[WriteValidatorEpochAPR] Failed to store to database")
+		return err
+	}
+	return nil
+}
+
 // ReadValidatorList retrieves staking validator by its address
 // Return only elected validators if electedOnly==true, otherwise, return all validators
 func ReadValidatorList(db DatabaseReader, electedOnly bool) ([]common.Address, error) {
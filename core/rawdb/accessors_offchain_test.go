@@ -0,0 +1,89 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/staking/effective"
+	staking "github.com/harmony-one/harmony/staking/types"
+)
+
+// Tests validator availability storage and retrieval operations.
+func TestValidatorEpochAvailabilityStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	addr := common.BytesToAddress([]byte{0x42})
+
+	if availability, err := ReadValidatorEpochAvailability(
+		db, addr, big.NewInt(7),
+	); err != nil || availability != nil {
+		t.Fatalf("Non existent availability returned: %v %v", availability, err)
+	}
+	history := staking.AvailabilityHistory{
+		{
+			Epoch:           big.NewInt(7),
+			NumBlocksSigned: big.NewInt(90),
+			NumBlocksToSign: big.NewInt(100),
+			Percentage:      numeric.NewDecWithPrec(9, 1),
+			Elected:         true,
+			BootedStatus:    effective.NotBooted,
+		},
+		{
+			Epoch:           big.NewInt(8),
+			NumBlocksSigned: big.NewInt(0),
+			NumBlocksToSign: big.NewInt(0),
+			Percentage:      numeric.ZeroDec(),
+			BootedStatus:    effective.LostEPoSAuction,
+		},
+	}
+	for i := range history {
+		if err := WriteValidatorEpochAvailability(db, addr, &history[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// writing an epoch again replaces its availability
+	if err := WriteValidatorEpochAvailability(db, addr, &history[1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range history {
+		entry, err := ReadValidatorEpochAvailability(db, addr, want.Epoch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry == nil || entry.Elected != want.Elected ||
+			!entry.Percentage.Equal(want.Percentage) ||
+			entry.BootedStatus != want.BootedStatus {
+			t.Fatalf("Retrieved availability mismatch: have %v, want %v", entry, want)
+		}
+	}
+	if entry, _ := ReadValidatorEpochAvailability(db, addr, big.NewInt(9)); entry != nil {
+		t.Fatalf("Availability of epoch not written: %v", entry)
+	}
+}
+
+// Tests validator apr storage and retrieval operations.
+func TestValidatorEpochAPRStorage(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	addr := common.BytesToAddress([]byte{0x42})
+
+	if epochAPR, err := ReadValidatorEpochAPR(db, addr, big.NewInt(7)); err != nil ||
+		epochAPR != nil {
+		t.Fatalf("Non existent apr returned: %v %v", epochAPR, err)
+	}
+	first := staking.EpochAPR{Epoch: big.NewInt(7), Value: numeric.NewDecWithPrec(12, 2)}
+	again := staking.EpochAPR{Epoch: big.NewInt(7), Value: numeric.NewDecWithPrec(13, 2)}
+	for _, epochAPR := range []staking.EpochAPR{first, again} {
+		if err := WriteValidatorEpochAPR(db, addr, &epochAPR); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entry, err := ReadValidatorEpochAPR(db, addr, big.NewInt(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || !entry.Value.Equal(again.Value) {
+		t.Fatalf("Retrieved apr mismatch: have %v, want %v", entry, again)
+	}
+}
@@ -0,0 +1,339 @@
+package rawdb
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// The kinds of data kept in the ancient store, one table each.
+const (
+	// freezerHashTable has the canonical hash of each block.
+	freezerHashTable = "hashes"
+	// freezerHeaderTable has the RLP encoded header of each block.
+	freezerHeaderTable = "headers"
+	// freezerBodiesTable has the RLP encoded body of each block.
+	freezerBodiesTable = "bodies"
+	// freezerReceiptTable has the RLP encoded receipts of each block, in their
+	// storage form.
+	freezerReceiptTable = "receipts"
+	// freezerCommitSigTable has the commit signature and bitmap over each
+	// block, which are also in the header of its child.
+	freezerCommitSigTable = "commits"
+)
+
+// freezerTables are the tables of the ancient store, with whether their items
+// are compressed.
+var freezerTables = map[string]bool{
+	freezerHashTable:      false,
+	freezerHeaderTable:    true,
+	freezerBodiesTable:    true,
+	freezerReceiptTable:   true,
+	freezerCommitSigTable: false,
+}
+
+const (
+	// AncientThreshold is how many blocks behind the head block a block must be
+	// to be moved to the ancient store, after which it cannot be rolled back.
+	AncientThreshold uint64 = 90000
+	// freezerBatchLimit is the maximum number of blocks moved at once.
+	freezerBatchLimit = 30000
+	// freezerRecheckInterval is how often the blocks to move are looked for.
+	freezerRecheckInterval = time.Minute
+)
+
+// errUnknownTable is returned if the kind of data asked for has no table.
+var errUnknownTable = errors.New("unknown ancient table")
+
+// freezer is the ancient store, keeping the immutable chain data of the
+// blocks 0..frozen-1 in flat files instead of in the key-value database.
+type freezer struct {
+	// frozen is the number of blocks in all the tables, accessed atomically
+	frozen uint64
+	tables map[string]*freezerTable
+	quit   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newFreezer opens the ancient store in dir, dropping from its tables the
+// blocks which are not in all of them.
+func newFreezer(dir string) (*freezer, error) {
+	f := &freezer{tables: make(map[string]*freezerTable), quit: make(chan struct{})}
+	for name, compress := range freezerTables {
+		table, err := newFreezerTable(dir, name, compress)
+		if err != nil {
+			f.closeTables()
+			return nil, errors.Wrapf(err, "cannot open ancient table %s", name)
+		}
+		f.tables[name] = table
+	}
+	frozen := uint64(0)
+	first := true
+	for _, table := range f.tables {
+		if items := table.Items(); first || items < frozen {
+			frozen, first = items, false
+		}
+	}
+	for name, table := range f.tables {
+		if err := table.truncate(frozen); err != nil {
+			f.closeTables()
+			return nil, errors.Wrapf(err, "cannot repair ancient table %s", name)
+		}
+	}
+	f.frozen = frozen
+	return f, nil
+}
+
+// HasAncient returns whether the ancient store has the data of the given kind
+// of the given block.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if _, ok := f.tables[kind]; !ok {
+		return false, errUnknownTable
+	}
+	return number < atomic.LoadUint64(&f.frozen), nil
+}
+
+// Ancient returns the data of the given kind of the given block.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	table, ok := f.tables[kind]
+	if !ok {
+		return nil, errUnknownTable
+	}
+	if number >= atomic.LoadUint64(&f.frozen) {
+		return nil, errOutOfBounds
+	}
+	return table.Retrieve(number)
+}
+
+// Ancients returns the number of blocks in the ancient store.
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+// AppendAncient adds the data of the given block, which must be the next one,
+// to the ancient store.
+func (f *freezer) AppendAncient(
+	number uint64, hash, header, body, receipts, commitSig []byte,
+) error {
+	if frozen := atomic.LoadUint64(&f.frozen); number != frozen {
+		return errors.Wrapf(errOutOrderInsertion, "have %d, want %d", number, frozen)
+	}
+	for name, blob := range map[string][]byte{
+		freezerHashTable:      hash,
+		freezerHeaderTable:    header,
+		freezerBodiesTable:    body,
+		freezerReceiptTable:   receipts,
+		freezerCommitSigTable: commitSig,
+	} {
+		if err := f.tables[name].Append(number, blob); err != nil {
+			// drop the data of the block appended to the other tables
+			f.truncateTables(number)
+			return errors.Wrapf(err, "cannot append block %d to ancient table %s", number, name)
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1)
+	return nil
+}
+
+// TruncateAncients drops the blocks from the given number on.
+func (f *freezer) TruncateAncients(items uint64) error {
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	if err := f.truncateTables(items); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+func (f *freezer) truncateTables(items uint64) error {
+	for name, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return errors.Wrapf(err, "cannot truncate ancient table %s", name)
+		}
+	}
+	return nil
+}
+
+// Sync writes the ancient store to disk.
+func (f *freezer) Sync() error {
+	for name, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			return errors.Wrapf(err, "cannot sync ancient table %s", name)
+		}
+	}
+	return nil
+}
+
+// closeTables closes all the tables of the ancient store.
+func (f *freezer) closeTables() error {
+	var err error
+	for _, table := range f.tables {
+		if e := table.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// freeze moves the blocks more than AncientThreshold blocks behind the head
+// block from db to the ancient store, until the store is closed. On a database
+// used before the ancient store, this migrates its old blocks.
+func (f *freezer) freeze(db ethdb.Database) {
+	defer f.wg.Done()
+	for {
+		if err := f.freezeBatch(db); err != nil {
+			utils.Logger().Error().Err(err).Msg("cannot move blocks to the ancient store")
+		}
+		select {
+		case <-f.quit:
+			return
+		case <-time.After(freezerRecheckInterval):
+		}
+	}
+}
+
+// freezeBatch moves up to freezerBatchLimit blocks from db to the ancient store.
+func (f *freezer) freezeBatch(db ethdb.Database) error {
+	head := ReadHeadBlockHash(db)
+	if head == (common.Hash{}) {
+		return nil
+	}
+	headNumber := ReadHeaderNumber(db, head)
+	if headNumber == nil || *headNumber <= AncientThreshold {
+		return nil
+	}
+	limit := *headNumber - AncientThreshold
+	frozen := atomic.LoadUint64(&f.frozen)
+	if limit-frozen > freezerBatchLimit {
+		limit = frozen + freezerBatchLimit
+	}
+	if frozen >= limit {
+		return nil
+	}
+
+	start := time.Now()
+	hashes := make([]common.Hash, 0, limit-frozen)
+	for number := frozen; number < limit; number++ {
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return errors.Errorf("canonical hash of block %d missing", number)
+		}
+		header := ReadHeaderRLP(db, hash, number)
+		if len(header) == 0 {
+			return errors.Errorf("header of block %d missing", number)
+		}
+		body := ReadBodyRLP(db, hash, number)
+		if len(body) == 0 {
+			return errors.Errorf("body of block %d missing", number)
+		}
+		receipts, _ := db.Get(blockReceiptsKey(number, hash))
+		if len(receipts) == 0 {
+			// blocks without transactions have no receipts written
+			receipts = emptyReceiptsRLP
+		}
+		child := ReadHeader(db, ReadCanonicalHash(db, number+1), number+1)
+		if child == nil {
+			return errors.Errorf("header of block %d missing", number+1)
+		}
+		sig := child.LastCommitSignature()
+		commitSig := append(sig[:], child.LastCommitBitmap()...)
+		if err := f.AppendAncient(number, hash[:], header, body, receipts, commitSig); err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	// Drop the moved data from db, keeping the hash to number mappings
+	batch := db.NewBatch()
+	for i, hash := range hashes {
+		number := frozen + uint64(i)
+		DeleteCanonicalHash(batch, number)
+		for _, key := range [][]byte{
+			headerKey(number, hash), blockBodyKey(number, hash), blockReceiptsKey(number, hash),
+		} {
+			if err := batch.Delete(key); err != nil {
+				return err
+			}
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	utils.Logger().Info().
+		Uint64("from", frozen).
+		Uint64("to", limit-1).
+		Str("elapsed", common.PrettyDuration(time.Since(start)).String()).
+		Msg("moved blocks to the ancient store")
+	return nil
+}
+
+// emptyReceiptsRLP is the RLP encoding of no receipts.
+var emptyReceiptsRLP = []byte{0xc0}
+
+// freezerdb is a database keeping the old immutable chain data in an ancient
+// store, and the rest in the key-value database.
+type freezerdb struct {
+	ethdb.Database
+	*freezer
+}
+
+// NewDatabaseWithFreezer returns a database keeping the chain data of the blocks
+// more than AncientThreshold blocks behind the head block in an ancient store in
+// freezerDir, moving them there from db in the background if freeze is set.
+func NewDatabaseWithFreezer(db ethdb.Database, freezerDir string, freeze bool) (ethdb.Database, error) {
+	f, err := newFreezer(freezerDir)
+	if err != nil {
+		return nil, err
+	}
+	// the blocks of the store must be older than the head block of db
+	if frozen, _ := f.Ancients(); frozen > 0 {
+		head := ReadHeaderNumber(db, ReadHeadBlockHash(db))
+		if head == nil || *head < frozen {
+			f.closeTables()
+			return nil, errors.Errorf(
+				"ancient store in %s has %d blocks, more than the database", freezerDir, frozen,
+			)
+		}
+	}
+	if freeze {
+		f.wg.Add(1)
+		go f.freeze(db)
+	}
+	return &freezerdb{Database: db, freezer: f}, nil
+}
+
+// Close stops moving blocks to the ancient store if it does, then closes it and
+// the key-value database.
+func (db *freezerdb) Close() {
+	close(db.freezer.quit)
+	db.freezer.wg.Wait()
+	if err := db.freezer.closeTables(); err != nil {
+		utils.Logger().Error().Err(err).Msg("cannot close the ancient store")
+	}
+	db.Database.Close()
+}
+
+// KeyValueStore returns the key-value database of db, without its ancient
+// store if it has one.
+func KeyValueStore(db ethdb.Database) ethdb.Database {
+	if fdb, ok := db.(*freezerdb); ok {
+		return fdb.Database
+	}
+	return db
+}
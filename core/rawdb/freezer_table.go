@@ -0,0 +1,232 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+)
+
+var (
+	// errOutOfBounds is returned if the item requested is not in the table.
+	errOutOfBounds = errors.New("out of bounds")
+	// errOutOrderInsertion is returned if an item is appended to a table at
+	// another position than the end.
+	errOutOrderInsertion = errors.New("the item number is not the next one of the table")
+	// errClosed is returned if the table is used after being closed.
+	errClosed = errors.New("closed")
+)
+
+// indexEntrySize is the size of an entry of the index file of a freezer table,
+// the offset at which the item ends in the data file.
+const indexEntrySize = 8
+
+// freezerTable is an append-only table of items numbered from 0, made of a data
+// file with the items one after the other, and an index file with where each
+// item ends in the data file.
+type freezerTable struct {
+	index    *os.File
+	data     *os.File
+	items    uint64 // number of items in the table
+	size     uint64 // size of the data file
+	compress bool   // whether the items are snappy compressed
+	lock     sync.RWMutex
+}
+
+// newFreezerTable opens the table of the given name in dir, creating it if
+// missing, and repairs it if the last append was not written completely.
+func newFreezerTable(dir, name string, compress bool) (*freezerTable, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	ext := "rdat"
+	if compress {
+		ext = "cdat"
+	}
+	index, err := os.OpenFile(filepath.Join(dir, name+".ridx"), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("%s.%s", name, ext)), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		index.Close()
+		return nil, err
+	}
+	table := &freezerTable{index: index, data: data, compress: compress}
+	if err := table.repair(); err != nil {
+		table.Close()
+		return nil, err
+	}
+	return table, nil
+}
+
+// repair drops the partial index entry, and the items of the index whose data
+// is missing, then the data not in the index.
+func (t *freezerTable) repair() error {
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	items := uint64(stat.Size()) / indexEntrySize
+	if stat, err = t.data.Stat(); err != nil {
+		return err
+	}
+	dataSize := uint64(stat.Size())
+	for ; items > 0; items-- {
+		end, err := t.offset(items)
+		if err != nil {
+			return err
+		}
+		if end <= dataSize {
+			dataSize = end
+			break
+		}
+	}
+	if items == 0 {
+		dataSize = 0
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(dataSize)); err != nil {
+		return err
+	}
+	t.items, t.size = items, dataSize
+	return nil
+}
+
+// offset returns where the given item ends in the data file, item 0 being the
+// start of the data file.
+func (t *freezerTable) offset(item uint64) (uint64, error) {
+	if item == 0 {
+		return 0, nil
+	}
+	var entry [indexEntrySize]byte
+	if _, err := t.index.ReadAt(entry[:], int64((item-1)*indexEntrySize)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(entry[:]), nil
+}
+
+// Items returns the number of items in the table.
+func (t *freezerTable) Items() uint64 {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	return t.items
+}
+
+// Append adds the item of the given number, which must be the number of items
+// in the table, at the end of the table.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.index == nil {
+		return errClosed
+	}
+	if item != t.items {
+		return errors.Wrapf(errOutOrderInsertion, "have %d, want %d", item, t.items)
+	}
+	if t.compress {
+		blob = snappy.Encode(nil, blob)
+	}
+	if _, err := t.data.WriteAt(blob, int64(t.size)); err != nil {
+		return err
+	}
+	var entry [indexEntrySize]byte
+	binary.BigEndian.PutUint64(entry[:], t.size+uint64(len(blob)))
+	if _, err := t.index.WriteAt(entry[:], int64(t.items*indexEntrySize)); err != nil {
+		return err
+	}
+	t.size += uint64(len(blob))
+	t.items++
+	return nil
+}
+
+// Retrieve returns the item of the given number.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.index == nil {
+		return nil, errClosed
+	}
+	if item >= t.items {
+		return nil, errOutOfBounds
+	}
+	start, err := t.offset(item)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.offset(item + 1)
+	if err != nil {
+		return nil, err
+	}
+	blob := make([]byte, end-start)
+	if _, err := t.data.ReadAt(blob, int64(start)); err != nil {
+		return nil, err
+	}
+	if t.compress {
+		return snappy.Decode(nil, blob)
+	}
+	return blob, nil
+}
+
+// truncate drops the items from the given number on.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.index == nil {
+		return errClosed
+	}
+	if items >= t.items {
+		return nil
+	}
+	size, err := t.offset(items)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(int64(items * indexEntrySize)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+// Sync writes the table to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.index == nil {
+		return errClosed
+	}
+	if err := t.data.Sync(); err != nil {
+		return err
+	}
+	return t.index.Sync()
+}
+
+// Close closes the files of the table.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var errs []error
+	for _, f := range []*os.File{t.index, t.data} {
+		if f == nil {
+			continue
+		}
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.index, t.data = nil, nil
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
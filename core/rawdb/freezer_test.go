@@ -0,0 +1,135 @@
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/core/types"
+)
+
+// Tests that items appended to a freezer table can be retrieved, also after the
+// table is reopened with a partially written append.
+func TestFreezerTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newFreezerTable(dir, "test", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := [][]byte{{1}, bytes.Repeat([]byte{2}, 100), {}, {3, 3, 3}}
+	for i, item := range items {
+		if err := table.Append(uint64(i), item); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.Append(10, []byte{4}); err == nil {
+		t.Fatal("out of order append succeeded")
+	}
+	if _, err := table.Retrieve(uint64(len(items))); err != errOutOfBounds {
+		t.Fatalf("retrieved missing item: %v", err)
+	}
+	table.Close()
+
+	// append a partial index entry, as left by a crash while appending
+	index, err := os.OpenFile(filepath.Join(dir, "test.ridx"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index.Write([]byte{0, 0, 1})
+	index.Close()
+
+	if table, err = newFreezerTable(dir, "test", true); err != nil {
+		t.Fatal(err)
+	}
+	defer table.Close()
+	if have := table.Items(); have != uint64(len(items)) {
+		t.Fatalf("items mismatch: have %d, want %d", have, len(items))
+	}
+	for i, item := range items {
+		if have, err := table.Retrieve(uint64(i)); err != nil || !bytes.Equal(have, item) {
+			t.Errorf("item %d mismatch: have %x (%v), want %x", i, have, err, item)
+		}
+	}
+	if err := table.truncate(1); err != nil {
+		t.Fatal(err)
+	}
+	if have := table.Items(); have != 1 {
+		t.Fatalf("items mismatch after truncate: have %d, want 1", have)
+	}
+}
+
+// Tests that the chain data of the blocks in the ancient store is read
+// transparently through the database.
+func TestFreezerDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := newFreezer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.closeTables()
+	db := &freezerdb{Database: ethdb.NewMemDatabase(), freezer: f}
+
+	blocks := make([]*types.Block, 3)
+	for i := range blocks {
+		blocks[i] = types.NewBlockWithHeader(blockfactory.NewTestHeader().With().
+			Number(big.NewInt(int64(i))).
+			Extra([]byte("test block")).
+			Header())
+		hash := blocks[i].Hash()
+		header, _ := rlp.EncodeToBytes(blocks[i].Header())
+		body, _ := rlp.EncodeToBytes(blocks[i].Body())
+		if err := f.AppendAncient(uint64(i), hash[:], header, body, emptyReceiptsRLP, nil); err != nil {
+			t.Fatalf("failed to append block %d: %v", i, err)
+		}
+	}
+	if frozen, _ := f.Ancients(); frozen != uint64(len(blocks)) {
+		t.Fatalf("ancients mismatch: have %d, want %d", frozen, len(blocks))
+	}
+
+	for _, blk := range blocks {
+		hash, number := blk.Hash(), blk.NumberU64()
+		if have := ReadCanonicalHash(db, number); have != hash {
+			t.Errorf("canonical hash of block %d mismatch: have %x, want %x", number, have, hash)
+		}
+		if have := ReadBlock(db, hash, number); have == nil || have.Hash() != hash {
+			t.Errorf("block %d mismatch: have %v, want %v", number, have, blk)
+		}
+		if !HasHeader(db, hash, number) || !HasBody(db, hash, number) {
+			t.Errorf("block %d not found", number)
+		}
+		if receipts := ReadReceipts(db, hash, number); receipts == nil || len(receipts) != 0 {
+			t.Errorf("receipts of block %d mismatch: have %v", number, receipts)
+		}
+		// only the canonical block is in the ancient store
+		if HasHeader(db, common.Hash{1}, number) {
+			t.Errorf("non canonical header of block %d found", number)
+		}
+	}
+
+	if err := f.TruncateAncients(1); err != nil {
+		t.Fatal(err)
+	}
+	if HasHeader(db, blocks[1].Hash(), 1) {
+		t.Error("truncated block found")
+	}
+	if ReadHeader(db, blocks[0].Hash(), 0) == nil {
+		t.Error("block before the truncation not found")
+	}
+}
@@ -0,0 +1,132 @@
+package rawdb
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// keyCategory is a kind of data of the schema, whose keys start with prefix
+// and, if length is not 0, are length bytes long.
+type keyCategory struct {
+	name   string
+	prefix []byte
+	length int
+}
+
+// keyCategories are the kinds of data of the schema keyed by a prefix, sorted
+// by decreasing prefix length so that the longest matching prefix is found
+// first.
+var keyCategories = func() []keyCategory {
+	categories := []keyCategory{
+		{"Headers", headerPrefix, len(headerPrefix) + 8 + common.HashLength},
+		{"Total difficulties", headerPrefix, len(headerPrefix) + 8 + common.HashLength + len(headerTDSuffix)},
+		{"Canonical hashes", headerPrefix, len(headerPrefix) + 8 + len(headerHashSuffix)},
+		{"Header numbers", headerNumberPrefix, len(headerNumberPrefix) + common.HashLength},
+		{"Bodies", blockBodyPrefix, len(blockBodyPrefix) + 8 + common.HashLength},
+		{"Receipts", blockReceiptsPrefix, len(blockReceiptsPrefix) + 8 + common.HashLength},
+		{"Transaction lookups", txLookupPrefix, len(txLookupPrefix) + common.HashLength},
+		{"Cross shard receipt lookups", cxLookupPrefix, len(cxLookupPrefix) + common.HashLength},
+		{"Bloom bits", bloomBitsPrefix, len(bloomBitsPrefix) + 2 + 8 + common.HashLength},
+		{"Bloom bits index", BloomBitsIndexPrefix, 0},
+		{"Shard states", shardStatePrefix, 0},
+		{"Crosslinks", crosslinkPrefix, len(crosslinkPrefix) + 4 + 8},
+		{"Last crosslinks", crosslinkPrefix, len(crosslinkPrefix) + 4},
+		{"Delegations", delegatorValidatorListPrefix, len(delegatorValidatorListPrefix) + common.AddressLength},
+		{"Cross shard receipts", cxReceiptPrefix, 0},
+		{"Spent cross shard receipts", cxReceiptSpentPrefix, 0},
+		{"Validator snapshots", validatorSnapshotPrefix, 0},
+		{"Validator stats", validatorStatsPrefix, 0},
+		{"Validator availabilities", validatorAvailabilityPrefix, 0},
+		{"Validator APRs", validatorAPRPrefix, 0},
+		{"Epoch block numbers", epochBlockNumberPrefix, 0},
+		{"Epoch VRF block numbers", epochVrfBlockNumbersPrefix, 0},
+		{"Epoch VDF block numbers", epochVdfBlockNumberPrefix, 0},
+		{"Block rewards", currentRewardGivenOutPrefix, 0},
+		{"Preimages", preimagePrefix, 0},
+		{"Chain configs", configPrefix, 0},
+	}
+	sort.SliceStable(categories, func(i, j int) bool {
+		return len(categories[i].prefix) > len(categories[j].prefix)
+	})
+	return categories
+}()
+
+// metadataKeys are the keys of the single values of the schema.
+var metadataKeys = [][]byte{
+	databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey, checkpointKey, lastCommitsKey,
+	pendingCrosslinkKey, pendingSlashingKey, validatorListKey, electedValidatorListKey,
+}
+
+// The categories of the keys not keyed by a prefix.
+const (
+	metadataCategory = "Metadata"
+	stateCategory    = "State trie nodes and codes"
+	unknownCategory  = "Unknown"
+)
+
+// KeyCategoryStat is the number of keys of a kind of data, and their size with
+// their values.
+type KeyCategoryStat struct {
+	Name  string
+	Count int
+	Size  common.StorageSize
+}
+
+// categorizeKey returns the kind of data of the given key.
+func categorizeKey(key []byte) string {
+	for _, metadataKey := range metadataKeys {
+		if bytes.Equal(key, metadataKey) {
+			return metadataCategory
+		}
+	}
+	// the state trie nodes and contract codes are keyed by their hash
+	if len(key) == common.HashLength {
+		return stateCategory
+	}
+	for _, category := range keyCategories {
+		if bytes.HasPrefix(key, category.prefix) &&
+			(category.length == 0 || len(key) == category.length) {
+			return category.name
+		}
+	}
+	return unknownCategory
+}
+
+// InspectDatabase returns the number and size of the keys of each kind of data
+// in db, by decreasing size. progress, if not nil, is called with the number of
+// keys counted so far every million keys.
+func InspectDatabase(db IterableDatabase, progress func(keys int)) ([]KeyCategoryStat, error) {
+	stats := make(map[string]*KeyCategoryStat)
+	it := db.NewIterator()
+	defer it.Release()
+	keys := 0
+	for it.Next() {
+		name := categorizeKey(it.Key())
+		stat, ok := stats[name]
+		if !ok {
+			stat = &KeyCategoryStat{Name: name}
+			stats[name] = stat
+		}
+		stat.Count++
+		stat.Size += common.StorageSize(len(it.Key()) + len(it.Value()))
+		if keys++; progress != nil && keys%1000000 == 0 {
+			progress(keys)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	result := make([]KeyCategoryStat, 0, len(stats))
+	for _, stat := range stats {
+		result = append(result, *stat)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
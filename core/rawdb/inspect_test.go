@@ -0,0 +1,40 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCategorizeKey(t *testing.T) {
+	hash := common.Hash{1}
+	tests := []struct {
+		key  []byte
+		want string
+	}{
+		{headerKey(1, hash), "Headers"},
+		{headerTDKey(1, hash), "Total difficulties"},
+		{headerHashKey(1), "Canonical hashes"},
+		{headerNumberKey(hash), "Header numbers"},
+		{blockBodyKey(1, hash), "Bodies"},
+		{txLookupKey(hash), "Transaction lookups"},
+		{cxLookupKey(hash), "Cross shard receipt lookups"},
+		{cxReceiptKey(1, 1, hash), "Cross shard receipts"},
+		{cxReceiptSpentKey(1, 1), "Spent cross shard receipts"},
+		{crosslinkKey(1, 1), "Crosslinks"},
+		{shardLastCrosslinkKey(1), "Last crosslinks"},
+		{shardStateKey(big.NewInt(3)), "Shard states"},
+		{epochBlockNumberKey(big.NewInt(3)), "Epoch block numbers"},
+		{blockRewardAccumKey(1), "Block rewards"},
+		{validatorListKey, metadataCategory},
+		{headBlockKey, metadataCategory},
+		{hash[:], stateCategory},
+		{[]byte("unknown key"), unknownCategory},
+	}
+	for _, test := range tests {
+		if have := categorizeKey(test.key); have != test.want {
+			t.Errorf("category of %q mismatch: have %q, want %q", test.key, have, test.want)
+		}
+	}
+}
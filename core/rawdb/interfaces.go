@@ -16,6 +16,11 @@
 
 package rawdb
 
+import (
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+)
+
 // DatabaseReader wraps the Has and Get method of a backing data store.
 type DatabaseReader interface {
 	Has(key []byte) (bool, error)
@@ -31,3 +36,25 @@ type DatabaseWriter interface {
 type DatabaseDeleter interface {
 	Delete(key []byte) error
 }
+
+// AncientReader wraps the reading methods of an ancient store, which keeps the
+// immutable chain data of the blocks 0..Ancients()-1.
+type AncientReader interface {
+	HasAncient(kind string, number uint64) (bool, error)
+	Ancient(kind string, number uint64) ([]byte, error)
+	Ancients() (uint64, error)
+}
+
+// AncientWriter wraps the writing methods of an ancient store.
+type AncientWriter interface {
+	AppendAncient(number uint64, hash, header, body, receipts, commitSig []byte) error
+	TruncateAncients(items uint64) error
+	Sync() error
+}
+
+// IterableDatabase is a key-value database whose keys can be iterated over in
+// order, as LDB.
+type IterableDatabase interface {
+	ethdb.Database
+	NewIterator() iterator.Iterator
+}
@@ -35,6 +35,9 @@ var (
 	headBlockKey = []byte("LastBlock")
 	// headFastBlockKey tracks the latest known incomplete block's hash duirng fast sync.
 	headFastBlockKey = []byte("LastFast")
+	// checkpointKey tracks the hash of the trusted block the chain was synced
+	// from, if not from genesis.
+	checkpointKey = []byte("Checkpoint")
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix                 = []byte("h")  // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix               = []byte("t")  // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -54,12 +57,14 @@ var (
 	crosslinkPrefix              = []byte("cl")               // prefix for crosslink
 	delegatorValidatorListPrefix = []byte("dvl")              // prefix for delegator's validator list
 	// TODO: shorten the key prefix so we don't waste db space
-	cxReceiptPrefix         = []byte("cxReceipt")              // prefix for cross shard transaction receipt
-	cxReceiptSpentPrefix    = []byte("cxReceiptSpent")         // prefix for indicator of unspent of cxReceiptsProof
-	validatorSnapshotPrefix = []byte("validator-snapshot")     // prefix for staking validator's snapshot information
-	validatorStatsPrefix    = []byte("validator-stats")        // prefix for staking validator's stats information
-	validatorListKey        = []byte("validator-list")         // key for all validators list
-	electedValidatorListKey = []byte("elected-validator-list") // key for elected validators list
+	cxReceiptPrefix             = []byte("cxReceipt")              // prefix for cross shard transaction receipt
+	cxReceiptSpentPrefix        = []byte("cxReceiptSpent")         // prefix for indicator of unspent of cxReceiptsProof
+	validatorSnapshotPrefix     = []byte("validator-snapshot")     // prefix for staking validator's snapshot information
+	validatorStatsPrefix        = []byte("validator-stats")        // prefix for staking validator's stats information
+	validatorAvailabilityPrefix = []byte("validator-availability") // prefix for staking validator's availability in an epoch
+	validatorAPRPrefix          = []byte("validator-apr")          // prefix for staking validator's apr at the end of an epoch
+	validatorListKey            = []byte("validator-list")         // key for all validators list
+	electedValidatorListKey     = []byte("elected-validator-list") // key for elected validators list
 	// epochBlockNumberPrefix + epoch (big.Int.Bytes())
 	// -> epoch block number (big.Int.Bytes())
 	epochBlockNumberPrefix = []byte("harmony-epoch-block-number")
@@ -215,6 +220,18 @@ func validatorStatsKey(addr common.Address) []byte {
 	return append(prefix, addr.Bytes()...)
 }
 
+func validatorAvailabilityKey(addr common.Address, epoch *big.Int) []byte {
+	prefix := validatorAvailabilityPrefix
+	tmp := append(prefix, addr.Bytes()...)
+	return append(tmp, epoch.Bytes()...)
+}
+
+func validatorAPRKey(addr common.Address, epoch *big.Int) []byte {
+	prefix := validatorAPRPrefix
+	tmp := append(prefix, addr.Bytes()...)
+	return append(tmp, epoch.Bytes()...)
+}
+
 func blockRewardAccumKey(number uint64) []byte {
 	return append(currentRewardGivenOutPrefix, encodeBlockNumber(number)...)
 }
@@ -9,7 +9,9 @@ import (
 	"github.com/harmony-one/harmony/common/denominations"
 	"github.com/harmony-one/harmony/core/vm"
 	common2 "github.com/harmony-one/harmony/internal/common"
+	"github.com/harmony-one/harmony/internal/params"
 	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/staking/effective"
 	staking "github.com/harmony-one/harmony/staking/types"
@@ -99,6 +101,19 @@ func VerifyAndEditValidatorFromMsg(
 	if err != nil {
 		return nil, err
 	}
+	for _, key := range []*shard.BLSPublicKey{msg.SlotKeyToRemove, msg.SlotKeyToAdd} {
+		if key != nil && wrapper.IsRotatingKey(*key) {
+			return nil, errors.Wrapf(errSlotKeyRotationPending, key.Hex())
+		}
+	}
+	if msg.SlotKeyToAdd != nil {
+		if err := checkSlotKeyNotInUse(
+			stateDB, chainContext, msg.ValidatorAddress, *msg.SlotKeyToAdd,
+		); err != nil {
+			return nil, err
+		}
+	}
+	currentRate := wrapper.Validator.Rate
 	if err := staking.UpdateValidatorFromEditMsg(&wrapper.Validator, msg, epoch); err != nil {
 		return nil, err
 	}
@@ -107,6 +122,17 @@ func VerifyAndEditValidatorFromMsg(
 		return nil, errCommissionRateChangeTooHigh
 	}
 
+	if chainContext.Config().IsCommissionSchedule(epoch) {
+		if err := scheduleCommissionChange(wrapper, currentRate, epoch); err != nil {
+			return nil, err
+		}
+		maxBLSKeyAllowed := shard.ExternalSlotsAvailableForEpoch(epoch) / 3
+		if err := wrapper.SanityCheck(maxBLSKeyAllowed); err != nil {
+			return nil, err
+		}
+		return wrapper, nil
+	}
+
 	snapshotValidator, err := chainContext.ReadValidatorSnapshot(wrapper.Address)
 	if err != nil {
 		return nil, errors.WithMessage(err, "Validator snapshot not found.")
@@ -130,6 +156,31 @@ func VerifyAndEditValidatorFromMsg(
 	return wrapper, nil
 }
 
+// scheduleCommissionChange queues the commission rate set by an edit to take
+// effect at a later epoch, restoring the rate charged until then
+func scheduleCommissionChange(
+	wrapper *staking.ValidatorWrapper, currentRate numeric.Dec, epoch *big.Int,
+) error {
+	newRate := wrapper.Validator.Rate
+	if newRate.Equal(currentRate) {
+		return nil
+	}
+	if wrapper.PendingCommission != nil {
+		return errCommissionChangePending
+	}
+	if newRate.Sub(currentRate).Abs().GT(wrapper.Validator.MaxChangeRate) {
+		return errCommissionRateChangeTooFast
+	}
+	wrapper.Validator.Rate = currentRate
+	wrapper.PendingCommission = &staking.PendingCommission{
+		Rate: newRate,
+		Epoch: new(big.Int).Add(
+			epoch, big.NewInt(staking.CommissionChangeDelay),
+		),
+	}
+	return nil
+}
+
 const oneThousand = 1000
 
 var (
@@ -280,6 +331,260 @@ func VerifyAndUndelegateFromMsg(
 	return nil, errNoDelegationToUndelegate
 }
 
+// VerifyAndRedelegateFromMsg verifies the redelegate message using the
+// stateDB and returns the edited validatorWrappers of the source and the
+// destination validator, with the tokens moved from one to the other.
+//
+// Note that this function never updates the stateDB, it only reads from stateDB.
+func VerifyAndRedelegateFromMsg(
+	stateDB vm.StateDB, epoch *big.Int, msg *staking.Redelegate,
+) (*staking.ValidatorWrapper, *staking.ValidatorWrapper, error) {
+	if stateDB == nil {
+		return nil, nil, errStateDBIsMissing
+	}
+	if epoch == nil {
+		return nil, nil, errEpochMissing
+	}
+	if msg.Amount.Sign() == -1 {
+		return nil, nil, errNegativeAmount
+	}
+	if msg.Amount.Cmp(minimumDelegation) < 0 {
+		return nil, nil, errDelegationTooSmall
+	}
+	if msg.FromValidatorAddress == msg.ToValidatorAddress {
+		return nil, nil, errRedelegateToSameValidator
+	}
+	if !stateDB.IsValidator(msg.FromValidatorAddress) ||
+		!stateDB.IsValidator(msg.ToValidatorAddress) {
+		return nil, nil, errValidatorNotExist
+	}
+
+	from, err := stateDB.ValidatorWrapper(msg.FromValidatorAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := stateDB.ValidatorWrapper(msg.ToValidatorAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	redelegated := false
+	for i := range from.Delegations {
+		delegation := &from.Delegations[i]
+		if bytes.Equal(delegation.DelegatorAddress.Bytes(), msg.DelegatorAddress.Bytes()) {
+			if err := delegation.Redelegate(
+				msg.ToValidatorAddress, epoch, msg.Amount,
+			); err != nil {
+				return nil, nil, err
+			}
+			redelegated = true
+			break
+		}
+	}
+	if !redelegated {
+		return nil, nil, errNoDelegationToRedelegate
+	}
+	if err := from.SanityCheck(staking.DoNotEnforceMaxBLS); err != nil {
+		// allow self delegation to go below min self delegation
+		// but set the status to inactive
+		if errors.Cause(err) == staking.ErrInvalidSelfDelegation {
+			from.Status = effective.Inactive
+		} else {
+			return nil, nil, err
+		}
+	}
+
+	delegated := false
+	for i := range to.Delegations {
+		delegation := &to.Delegations[i]
+		if bytes.Equal(delegation.DelegatorAddress.Bytes(), msg.DelegatorAddress.Bytes()) {
+			delegation.Amount.Add(delegation.Amount, msg.Amount)
+			delegated = true
+			break
+		}
+	}
+	if !delegated {
+		to.Delegations = append(
+			to.Delegations, staking.NewDelegation(
+				msg.DelegatorAddress, new(big.Int).Set(msg.Amount),
+			),
+		)
+	}
+	if err := to.SanityCheck(staking.DoNotEnforceMaxBLS); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// VerifyAndSetAutoCompoundFromMsg verifies the set auto-compound message
+// using the stateDB and returns the validatorWrapper with the flag of the
+// delegation updated.
+//
+// Note that this function never updates the stateDB, it only reads from stateDB.
+func VerifyAndSetAutoCompoundFromMsg(
+	stateDB vm.StateDB, msg *staking.SetAutoCompound,
+) (*staking.ValidatorWrapper, error) {
+	if stateDB == nil {
+		return nil, errStateDBIsMissing
+	}
+	if !stateDB.IsValidator(msg.ValidatorAddress) {
+		return nil, errValidatorNotExist
+	}
+	wrapper, err := stateDB.ValidatorWrapper(msg.ValidatorAddress)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wrapper.Delegations {
+		delegation := &wrapper.Delegations[i]
+		if bytes.Equal(delegation.DelegatorAddress.Bytes(), msg.DelegatorAddress.Bytes()) {
+			delegation.AutoCompound = msg.Enabled
+			return wrapper, nil
+		}
+	}
+	return nil, errNoDelegationToAutoCompound
+}
+
+// VerifyAndRotateKeyFromMsg verifies the rotate key message using the
+// stateDB and returns the validatorWrapper with the rotation scheduled.
+//
+// Note that this function never updates the stateDB, it only reads from stateDB.
+func VerifyAndRotateKeyFromMsg(
+	stateDB vm.StateDB, chainContext ChainContext,
+	epoch *big.Int, msg *staking.RotateKey,
+) (*staking.ValidatorWrapper, error) {
+	if stateDB == nil {
+		return nil, errStateDBIsMissing
+	}
+	if chainContext == nil {
+		return nil, errChainContextMissing
+	}
+	if epoch == nil || msg.Epoch == nil {
+		return nil, errEpochMissing
+	}
+	// the committee of the next epoch may already be elected
+	if new(big.Int).Sub(msg.Epoch, epoch).Cmp(common.Big2) < 0 {
+		return nil, errors.Wrapf(
+			errKeyRotationTooSoon, "epoch %v, effective epoch %v", epoch, msg.Epoch,
+		)
+	}
+	if !stateDB.IsValidator(msg.ValidatorAddress) {
+		return nil, errValidatorNotExist
+	}
+	wrapper, err := stateDB.ValidatorWrapper(msg.ValidatorAddress)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSlotKeyNotInUse(
+		stateDB, chainContext, msg.ValidatorAddress, msg.SlotKeyToAdd,
+	); err != nil {
+		return nil, err
+	}
+	if err := wrapper.ScheduleKeyRotation(msg, epoch); err != nil {
+		return nil, err
+	}
+	return wrapper, nil
+}
+
+// checkSlotKeyNotInUse errors when the key is a slot key of another
+// validator, or becomes one with a pending rotation
+func checkSlotKeyNotInUse(
+	stateDB vm.StateDB, chainContext ChainContext,
+	validator common.Address, key shard.BLSPublicKey,
+) error {
+	addrs, err := chainContext.ReadValidatorList()
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if addr == validator {
+			continue
+		}
+		wrapper, err := stateDB.ValidatorWrapper(addr)
+		if err != nil {
+			return err
+		}
+		for _, slotKey := range wrapper.SlotPubKeys {
+			if slotKey == key {
+				return errors.Wrapf(
+					errSlotKeyInUse, "%s by %s", key.Hex(), common2.MustAddressToBech32(addr),
+				)
+			}
+		}
+		for _, rotation := range wrapper.KeyRotations {
+			if rotation.SlotKeyToAdd == key {
+				return errors.Wrapf(
+					errSlotKeyInUse, "%s by %s", key.Hex(), common2.MustAddressToBech32(addr),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// BatchGas returns the gas charged for the operations of a batch, on top of
+// the intrinsic gas of the staking transaction
+func BatchGas(msg *staking.Batch) uint64 {
+	return uint64(len(msg.Operations)) * params.TxGasBatchOperation
+}
+
+// VerifyAndApplyBatchFromMsg verifies and applies the operations of the batch
+// message one after the other, each seeing the effects of the previous ones.
+//
+// Unlike the other verifiers, this function updates the stateDB, reverting
+// all of the operations if any of them fails.
+func VerifyAndApplyBatchFromMsg(
+	stateDB vm.StateDB, epoch *big.Int, msg *staking.Batch,
+) error {
+	if stateDB == nil {
+		return errStateDBIsMissing
+	}
+	if epoch == nil {
+		return errEpochMissing
+	}
+	if len(msg.Operations) == 0 {
+		return errEmptyBatch
+	}
+	if len(msg.Operations) > staking.MaxBatchOperations {
+		return errors.Wrapf(
+			errBatchTooLarge, "have %d, max %d",
+			len(msg.Operations), staking.MaxBatchOperations,
+		)
+	}
+	snapshot := stateDB.Snapshot()
+	for i, op := range msg.Operations {
+		if err := applyBatchOperation(stateDB, epoch, msg.DelegatorAddress, op); err != nil {
+			stateDB.RevertToSnapshot(snapshot)
+			return errors.Wrapf(err, "batch operation %d", i)
+		}
+	}
+	return nil
+}
+
+func applyBatchOperation(
+	stateDB vm.StateDB, epoch *big.Int,
+	delegator common.Address, op staking.BatchOperation,
+) error {
+	if op.Amount == nil {
+		return errNegativeAmount
+	}
+	switch op.Directive {
+	case staking.DirectiveDelegate:
+		return verifyAndApplyDelegate(stateDB, &staking.Delegate{
+			DelegatorAddress: delegator,
+			ValidatorAddress: op.ValidatorAddress,
+			Amount:           new(big.Int).Set(op.Amount),
+		})
+	case staking.DirectiveUndelegate:
+		return verifyAndApplyUndelegate(stateDB, epoch, &staking.Undelegate{
+			DelegatorAddress: delegator,
+			ValidatorAddress: op.ValidatorAddress,
+			Amount:           new(big.Int).Set(op.Amount),
+		})
+	default:
+		return errors.Wrapf(errInvalidBatchOperation, "got %s", op.Directive)
+	}
+}
+
 // VerifyAndCollectRewardsFromDelegation verifies and collects rewards
 // from the given delegation slice using the stateDB. It returns all of the
 // edited validatorWrappers and the sum total of the rewards.
@@ -9,11 +9,14 @@ import (
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/core/state"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
 	"github.com/harmony-one/harmony/crypto/hash"
 	common2 "github.com/harmony-one/harmony/internal/common"
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/staking/effective"
 	staking "github.com/harmony-one/harmony/staking/types"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -520,3 +523,378 @@ func TestCV39(t *testing.T) {
 		t.Error("expected", "max_total_delegation can not be less than min_self_delegation", "got", nil)
 	}
 }
+
+func stateWithValidator(t *testing.T) *state.DB {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	msg := createValidator()
+	statedb.AddBalance(msg.ValidatorAddress, tenK)
+	wrapper, err := VerifyAndCreateValidatorFromMsg(
+		statedb, postStakingEpoch, big.NewInt(0), msg,
+	)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if err := statedb.UpdateValidatorWrapper(wrapper.Address, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	statedb.SetValidatorFlag(wrapper.Address)
+	return statedb
+}
+
+func oneThousandTimes(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n*1000), big.NewInt(1e18))
+}
+
+// Test batch: delegate then undelegate from the same validator
+func TestBatch(t *testing.T) {
+	statedb := stateWithValidator(t)
+	delegator := common.BigToAddress(big.NewInt(7))
+	statedb.AddBalance(delegator, twelveK)
+	msg := &staking.Batch{
+		DelegatorAddress: delegator,
+		Operations: []staking.BatchOperation{
+			{
+				Directive:        staking.DirectiveDelegate,
+				ValidatorAddress: validatorAddress,
+				Amount:           oneThousandTimes(2),
+			},
+			{
+				Directive:        staking.DirectiveUndelegate,
+				ValidatorAddress: validatorAddress,
+				Amount:           oneThousandTimes(1),
+			},
+		},
+	}
+	if err := VerifyAndApplyBatchFromMsg(statedb, postStakingEpoch, msg); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if balance := statedb.GetBalance(delegator); balance.Cmp(tenK) != 0 {
+		t.Error("expected balance", tenK, "got", balance)
+	}
+	wrapper, _ := statedb.ValidatorWrapper(validatorAddress)
+	if len(wrapper.Delegations) != 2 ||
+		wrapper.Delegations[1].Amount.Cmp(oneThousandTimes(1)) != 0 {
+		t.Error("expected a delegation of", oneThousandTimes(1), "got", wrapper.Delegations)
+	}
+}
+
+// Test batch: a failing operation reverts the ones before it
+func TestBatchAllOrNothing(t *testing.T) {
+	statedb := stateWithValidator(t)
+	delegator := common.BigToAddress(big.NewInt(7))
+	statedb.AddBalance(delegator, twelveK)
+	msg := &staking.Batch{
+		DelegatorAddress: delegator,
+		Operations: []staking.BatchOperation{
+			{
+				Directive:        staking.DirectiveDelegate,
+				ValidatorAddress: validatorAddress,
+				Amount:           oneThousandTimes(1),
+			},
+			// goes over the max total delegation
+			{
+				Directive:        staking.DirectiveDelegate,
+				ValidatorAddress: validatorAddress,
+				Amount:           oneThousandTimes(2),
+			},
+		},
+	}
+	if err := VerifyAndApplyBatchFromMsg(statedb, postStakingEpoch, msg); err == nil {
+		t.Fatal("expected", "total delegation can not be bigger than max_total_delegation", "got", nil)
+	}
+	if balance := statedb.GetBalance(delegator); balance.Cmp(twelveK) != 0 {
+		t.Error("expected balance", twelveK, "got", balance)
+	}
+	wrapper, _ := statedb.ValidatorWrapper(validatorAddress)
+	if len(wrapper.Delegations) != 1 {
+		t.Error("expected only the self delegation, got", wrapper.Delegations)
+	}
+}
+
+// Test batch: only delegate and undelegate operations are allowed
+func TestBatchInvalidOperation(t *testing.T) {
+	statedb := stateWithValidator(t)
+	msg := &staking.Batch{
+		DelegatorAddress: validatorAddress,
+		Operations: []staking.BatchOperation{
+			{
+				Directive:        staking.DirectiveCollectRewards,
+				ValidatorAddress: validatorAddress,
+				Amount:           big.NewInt(0),
+			},
+		},
+	}
+	if err := VerifyAndApplyBatchFromMsg(
+		statedb, postStakingEpoch, msg,
+	); !strings.Contains(err.Error(), errInvalidBatchOperation.Error()) {
+		t.Error("expected", errInvalidBatchOperation, "got", err)
+	}
+	if err := VerifyAndApplyBatchFromMsg(
+		statedb, postStakingEpoch, &staking.Batch{DelegatorAddress: validatorAddress},
+	); err != errEmptyBatch {
+		t.Error("expected", errEmptyBatch, "got", err)
+	}
+}
+
+// addValidator puts a validator self delegating ten thousand at address
+// in the state, as created by the default create validator message
+func addValidator(t *testing.T, statedb *state.DB, address common.Address) {
+	msg := createValidator()
+	wrapper := &staking.ValidatorWrapper{
+		Validator: staking.Validator{
+			Address:              address,
+			SlotPubKeys:          msg.SlotPubKeys,
+			LastEpochInCommittee: new(big.Int),
+			MinSelfDelegation:    msg.MinSelfDelegation,
+			MaxTotalDelegation:   msg.MaxTotalDelegation,
+			Status:               effective.Active,
+			Commission: staking.Commission{
+				CommissionRates: msg.CommissionRates,
+				UpdateHeight:    big.NewInt(0),
+			},
+			Description:    msg.Description,
+			CreationHeight: big.NewInt(0),
+		},
+		Delegations: staking.Delegations{
+			staking.NewDelegation(address, new(big.Int).Set(tenK)),
+		},
+		BlockReward: big.NewInt(0),
+	}
+	wrapper.Counters.NumBlocksSigned = big.NewInt(0)
+	wrapper.Counters.NumBlocksToSign = big.NewInt(0)
+	if err := statedb.UpdateValidatorWrapper(address, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	statedb.SetValidatorFlag(address)
+}
+
+// Test redelegate: move part of a delegation to another validator
+func TestRedelegate(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	fromValidator, toValidator := validatorAddress, common.BigToAddress(big.NewInt(8))
+	delegator := common.BigToAddress(big.NewInt(7))
+	addValidator(t, statedb, fromValidator)
+	addValidator(t, statedb, toValidator)
+	wrapper, _ := statedb.ValidatorWrapper(fromValidator)
+	wrapper.Delegations = append(
+		wrapper.Delegations, staking.NewDelegation(delegator, oneThousandTimes(2)),
+	)
+	if err := statedb.UpdateValidatorWrapper(fromValidator, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	msg := &staking.Redelegate{
+		DelegatorAddress:     delegator,
+		FromValidatorAddress: fromValidator,
+		ToValidatorAddress:   toValidator,
+		Amount:               oneThousandTimes(1),
+	}
+	from, to, err := VerifyAndRedelegateFromMsg(statedb, postStakingEpoch, msg)
+	if err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	moved := from.Delegations[1]
+	if moved.Amount.Cmp(oneThousandTimes(1)) != 0 {
+		t.Error("expected", oneThousandTimes(1), "left, got", moved.Amount)
+	}
+	if len(moved.Redelegations) != 1 ||
+		moved.Redelegations[0].ValidatorAddress != toValidator ||
+		moved.Redelegations[0].Epoch.Cmp(postStakingEpoch) != 0 {
+		t.Error("expected the redelegation kept for slashing, got", moved.Redelegations)
+	}
+	if len(to.Delegations) != 2 ||
+		to.Delegations[1].DelegatorAddress != delegator ||
+		to.Delegations[1].Amount.Cmp(oneThousandTimes(1)) != 0 {
+		t.Error("expected a delegation of", oneThousandTimes(1), "got", to.Delegations)
+	}
+	// the state is only read
+	if wrapper, _ := statedb.ValidatorWrapper(fromValidator); wrapper.Delegations[1].Amount.Cmp(
+		oneThousandTimes(2),
+	) != 0 {
+		t.Error("expected the state unchanged, got", wrapper.Delegations[1].Amount)
+	}
+}
+
+// Test redelegate: invalid redelegations
+func TestRedelegateErrors(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	fromValidator, toValidator := validatorAddress, common.BigToAddress(big.NewInt(8))
+	delegator := common.BigToAddress(big.NewInt(7))
+	addValidator(t, statedb, fromValidator)
+	addValidator(t, statedb, toValidator)
+	wrapper, _ := statedb.ValidatorWrapper(fromValidator)
+	wrapper.Delegations = append(
+		wrapper.Delegations, staking.NewDelegation(delegator, oneThousandTimes(2)),
+	)
+	if err := statedb.UpdateValidatorWrapper(fromValidator, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+
+	wrapper, _ = statedb.ValidatorWrapper(toValidator)
+	wrapper.Delegations = append(
+		wrapper.Delegations, staking.NewDelegation(
+			common.BigToAddress(big.NewInt(9)), oneThousandTimes(2),
+		),
+	)
+	if err := statedb.UpdateValidatorWrapper(toValidator, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	redelegate := func(delegator, from, to common.Address, amount *big.Int) staking.Redelegate {
+		return staking.Redelegate{
+			DelegatorAddress:     delegator,
+			FromValidatorAddress: from,
+			ToValidatorAddress:   to,
+			Amount:               amount,
+		}
+	}
+
+	tests := []struct {
+		msg  staking.Redelegate
+		want string
+	}{
+		{
+			msg:  redelegate(delegator, fromValidator, fromValidator, oneThousandTimes(1)),
+			want: errRedelegateToSameValidator.Error(),
+		},
+		{
+			msg:  redelegate(delegator, fromValidator, toValidator, big.NewInt(1)),
+			want: errDelegationTooSmall.Error(),
+		},
+		{
+			msg:  redelegate(toValidator, fromValidator, toValidator, oneThousandTimes(1)),
+			want: errNoDelegationToRedelegate.Error(),
+		},
+		{
+			msg:  redelegate(delegator, fromValidator, common.Address{}, oneThousandTimes(1)),
+			want: errValidatorNotExist.Error(),
+		},
+		// goes over the max total delegation of the destination
+		{
+			msg:  redelegate(delegator, fromValidator, toValidator, oneThousandTimes(1)),
+			want: "total delegation can not be bigger than max_total_delegation",
+		},
+	}
+	for i, test := range tests {
+		if _, _, err := VerifyAndRedelegateFromMsg(
+			statedb, postStakingEpoch, &test.msg,
+		); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("case %d: expected %s, got %v", i, test.want, err)
+		}
+	}
+}
+
+func TestScheduleCommissionChange(t *testing.T) {
+	rate, _ := numeric.NewDecFromStr("0.1")
+	maxChangeRate, _ := numeric.NewDecFromStr("0.05")
+	wrapper := &staking.ValidatorWrapper{}
+	wrapper.MaxChangeRate = maxChangeRate
+
+	wrapper.Rate, _ = numeric.NewDecFromStr("0.2")
+	if err := scheduleCommissionChange(
+		wrapper, rate, postStakingEpoch,
+	); err != errCommissionRateChangeTooFast {
+		t.Errorf("expected %v, got %v", errCommissionRateChangeTooFast, err)
+	}
+
+	newRate, _ := numeric.NewDecFromStr("0.14")
+	wrapper.Rate = newRate
+	if err := scheduleCommissionChange(wrapper, rate, postStakingEpoch); err != nil {
+		t.Fatal(err)
+	}
+	if !wrapper.Rate.Equal(rate) {
+		t.Errorf("commission rate changed before its epoch: %s", wrapper.Rate)
+	}
+	pending := wrapper.PendingCommission
+	if pending == nil || !pending.Rate.Equal(newRate) ||
+		pending.Epoch.Cmp(big.NewInt(202)) != 0 {
+		t.Fatalf("expected %s pending for epoch 202, got %+v", newRate, pending)
+	}
+
+	wrapper.Rate, _ = numeric.NewDecFromStr("0.12")
+	if err := scheduleCommissionChange(
+		wrapper, rate, postStakingEpoch,
+	); err != errCommissionChangePending {
+		t.Errorf("expected %v, got %v", errCommissionChangePending, err)
+	}
+}
+
+// validatorListChain is a chain context knowing only the validator list
+type validatorListChain struct {
+	ChainContext
+	validators []common.Address
+}
+
+func (c validatorListChain) ReadValidatorList() ([]common.Address, error) {
+	return c.validators, nil
+}
+
+// slotKey returns a generated slot key
+func slotKey() shard.BLSPublicKey {
+	key := shard.BLSPublicKey{}
+	key.FromLibBLSPublicKey(bls_cosi.RandPrivateKey().GetPublicKey())
+	return key
+}
+
+// Test rotate key: the key to add is a slot key of another validator
+func TestRotateKeyInUse(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	other := common.BigToAddress(big.NewInt(8))
+	addValidator(t, statedb, validatorAddress)
+	addValidator(t, statedb, other)
+	otherKey, rotatingKey := slotKey(), slotKey()
+	wrapper, _ := statedb.ValidatorWrapper(other)
+	wrapper.SlotPubKeys = []shard.BLSPublicKey{otherKey}
+	wrapper.KeyRotations = staking.KeyRotations{{
+		SlotKeyToRemove: otherKey,
+		SlotKeyToAdd:    rotatingKey,
+		Epoch:           big.NewInt(205),
+	}}
+	if err := statedb.UpdateValidatorWrapper(other, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	chain := validatorListChain{validators: []common.Address{validatorAddress, other}}
+
+	ownKey, _ := generateBLSKeySigPair()
+	for _, key := range []shard.BLSPublicKey{otherKey, rotatingKey} {
+		msg := &staking.RotateKey{
+			ValidatorAddress: validatorAddress,
+			SlotKeyToRemove:  ownKey,
+			SlotKeyToAdd:     key,
+			Epoch:            big.NewInt(205),
+		}
+		if _, err := VerifyAndRotateKeyFromMsg(
+			statedb, chain, postStakingEpoch, msg,
+		); errors.Cause(err) != errSlotKeyInUse {
+			t.Error("expected", errSlotKeyInUse, "got", err)
+		}
+	}
+}
+
+// Test edit validator: slot keys with a pending rotation can not be edited
+func TestEditValidatorRotatingKey(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	addValidator(t, statedb, validatorAddress)
+	ownKey, _ := generateBLSKeySigPair()
+	rotatingKey := slotKey()
+	wrapper, _ := statedb.ValidatorWrapper(validatorAddress)
+	wrapper.KeyRotations = staking.KeyRotations{{
+		SlotKeyToRemove: ownKey,
+		SlotKeyToAdd:    rotatingKey,
+		Epoch:           big.NewInt(205),
+	}}
+	if err := statedb.UpdateValidatorWrapper(validatorAddress, wrapper); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	chain := validatorListChain{validators: []common.Address{validatorAddress}}
+
+	for _, msg := range []*staking.EditValidator{
+		{ValidatorAddress: validatorAddress, SlotKeyToRemove: &ownKey},
+		{ValidatorAddress: validatorAddress, SlotKeyToAdd: &rotatingKey},
+	} {
+		if _, err := VerifyAndEditValidatorFromMsg(
+			statedb, chain, postStakingEpoch, big.NewInt(0), msg,
+		); errors.Cause(err) != errSlotKeyRotationPending {
+			t.Error("expected", errSlotKeyRotationPending, "got", err)
+		}
+	}
+}
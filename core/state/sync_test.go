@@ -0,0 +1,78 @@
+package state
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that an empty state is not scheduled for syncing.
+func TestEmptyStateSync(t *testing.T) {
+	empty := common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	if req := NewStateSync(empty, ethdb.NewMemDatabase()).Missing(1); len(req) != 0 {
+		t.Errorf("content requested for empty state: %v", req)
+	}
+}
+
+// Tests that a state with balances, codes and storage can be rebuilt node by
+// node from the hashes the scheduler asks for.
+func TestIterativeStateSync(t *testing.T) {
+	srcDb := NewDatabase(ethdb.NewMemDatabase())
+	src, _ := New(common.Hash{}, srcDb)
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		src.AddBalance(addr, big.NewInt(int64(11*i)))
+		src.SetNonce(addr, uint64(42*i))
+		if i%3 == 0 {
+			src.SetCode(addr, []byte{i, i, i, i, i})
+			src.SetState(addr, common.BytesToHash([]byte{i}), common.BytesToHash([]byte{i, i}))
+		}
+	}
+	srcRoot, err := src.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dstDb := ethdb.NewMemDatabase()
+	sched := NewStateSync(srcRoot, dstDb)
+	queue := sched.Missing(100)
+	for len(queue) > 0 {
+		results := make([]trie.SyncResult, len(queue))
+		for i, hash := range queue {
+			data, err := srcDb.TrieDB().Node(hash)
+			if err != nil {
+				t.Fatalf("failed to retrieve node data for %x", hash)
+			}
+			results[i] = trie.SyncResult{Hash: hash, Data: data}
+		}
+		if _, index, err := sched.Process(results); err != nil {
+			t.Fatalf("failed to process result #%d: %v", index, err)
+		}
+		if index, err := sched.Commit(dstDb); err != nil {
+			t.Fatalf("failed to commit data #%d: %v", index, err)
+		}
+		queue = sched.Missing(100)
+	}
+
+	dst, err := New(srcRoot, NewDatabase(dstDb))
+	if err != nil {
+		t.Fatalf("failed to create state trie at %x: %v", srcRoot, err)
+	}
+	for i := byte(0); i < 64; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		if balance := dst.GetBalance(addr); balance.Cmp(src.GetBalance(addr)) != 0 {
+			t.Errorf("account %d: balance mismatch: have %v, want %v", i, balance, src.GetBalance(addr))
+		}
+		if code := dst.GetCode(addr); !bytes.Equal(code, src.GetCode(addr)) {
+			t.Errorf("account %d: code mismatch: have %x, want %x", i, code, src.GetCode(addr))
+		}
+		key := common.BytesToHash([]byte{i})
+		if value := dst.GetState(addr, key); value != src.GetState(addr, key) {
+			t.Errorf("account %d: storage mismatch: have %x, want %x", i, value, src.GetState(addr, key))
+		}
+	}
+}
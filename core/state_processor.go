@@ -134,6 +134,15 @@ func (p *StateProcessor) Process(
 				"[Process] Cannot finalize block",
 			)
 		}
+		if !p.config.IsSignedEvidence(header.Epoch()) {
+			for i := range slashes {
+				if len(slashes[i].Evidence.SignedMessages) > 0 {
+					return nil, nil, nil, 0, nil, errors.New(
+						"[Process] Slash evidence with signed messages before fork",
+					)
+				}
+			}
+		}
 	}
 
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
@@ -0,0 +1,207 @@
+package core
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// stateBloomHashes is the number of bits set in the state bloom for each key.
+const stateBloomHashes = 4
+
+var emptyCodeHash = crypto.Keccak256Hash(nil)
+
+// stateBloom is a bloom filter of the keys of the state trie nodes and contract
+// codes to keep. As the keys are hashes, their bytes are used as the hashes of
+// the filter. A false positive only keeps an unreachable node.
+type stateBloom struct {
+	bits []uint64
+}
+
+// newStateBloom returns a state bloom of the given size in bytes.
+func newStateBloom(size uint64) *stateBloom {
+	if size < 8 {
+		size = 8
+	}
+	return &stateBloom{bits: make([]uint64, size/8)}
+}
+
+func (b *stateBloom) positions(key []byte) [stateBloomHashes]uint64 {
+	var positions [stateBloomHashes]uint64
+	for i := range positions {
+		positions[i] = binary.BigEndian.Uint64(key[i*8:]) % uint64(len(b.bits)*64)
+	}
+	return positions
+}
+
+func (b *stateBloom) add(key []byte) {
+	for _, pos := range b.positions(key) {
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+func (b *stateBloom) contains(key []byte) bool {
+	for _, pos := range b.positions(key) {
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PruneState deletes from the database of the chain the state trie nodes and
+// contract codes which are not reachable from the state of the latest retain
+// blocks, nor from the state of the last block of the previous epoch, which
+// the validator snapshots of the current epoch are taken from. The states of
+// those blocks which are not on disk are skipped, but the state of the head
+// block must be. The chain must not be in use while pruning. bloomSize is the
+// size in bytes of the bloom filter of the keys to keep, whose false positives
+// leave some unreachable data.
+func PruneState(bc *BlockChain, retain uint64, bloomSize uint64) error {
+	db, ok := rawdb.KeyValueStore(bc.ChainDb()).(rawdb.IterableDatabase)
+	if !ok {
+		return errors.New("the chain database cannot be iterated over")
+	}
+	head := bc.CurrentBlock()
+	if _, err := state.New(head.Root(), state.NewDatabase(db)); err != nil {
+		return errors.Wrapf(err, "state of head block %d missing", head.NumberU64())
+	}
+
+	roots := []common.Hash{}
+	for number := head.NumberU64(); number+retain > head.NumberU64(); number-- {
+		if header := bc.GetHeaderByNumber(number); header != nil {
+			roots = append(roots, header.Root())
+		}
+		if number == 0 {
+			break
+		}
+	}
+	// the last block of an epoch carries the shard state of the next epoch
+	for number := head.NumberU64(); number > 0; number-- {
+		header := bc.GetHeaderByNumber(number - 1)
+		if header == nil {
+			break
+		}
+		if len(header.ShardState()) > 0 {
+			roots = append(roots, header.Root())
+			break
+		}
+	}
+	return pruneState(db, roots, newStateBloom(bloomSize))
+}
+
+// pruneState deletes the 32 bytes keys of db, which are the keys of the state
+// trie nodes and contract codes, not reachable from the given state roots.
+func pruneState(db rawdb.IterableDatabase, roots []common.Hash, bloom *stateBloom) error {
+	start := time.Now()
+	stateDB := state.NewDatabase(db)
+	for _, root := range roots {
+		if err := markState(stateDB, root, bloom); err != nil {
+			return err
+		}
+	}
+	utils.Logger().Info().
+		Int("roots", len(roots)).
+		Str("elapsed", common.PrettyDuration(time.Since(start)).String()).
+		Msg("Marked the state to keep")
+
+	deleted, size := 0, common.StorageSize(0)
+	batch := db.NewBatch()
+	it := db.NewIterator()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength || bloom.contains(key) {
+			continue
+		}
+		if err := batch.Delete(common.CopyBytes(key)); err != nil {
+			it.Release()
+			return err
+		}
+		deleted++
+		size += common.StorageSize(len(key) + len(it.Value()))
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				it.Release()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	utils.Logger().Info().
+		Int("nodes", deleted).
+		Str("size", size.String()).
+		Str("elapsed", common.PrettyDuration(time.Since(start)).String()).
+		Msg("Deleted the unreachable state")
+
+	// reclaim the space of the deleted data
+	if ldb, ok := db.(*ethdb.LDBDatabase); ok {
+		if err := ldb.LDB().CompactRange(util.Range{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markState adds to the bloom the keys of the state trie nodes, storage trie
+// nodes and contract codes of the state of the given root. A state which is not
+// on disk is skipped.
+func markState(stateDB state.Database, root common.Hash, bloom *stateBloom) error {
+	accountTrie, err := stateDB.OpenTrie(root)
+	if err != nil {
+		utils.Logger().Debug().Str("root", root.Hex()).Msg("Skipping state not on disk")
+		return nil
+	}
+	it := accountTrie.NodeIterator(nil)
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			bloom.add(hash[:])
+		}
+		if !it.Leaf() {
+			continue
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return errors.Wrapf(err, "invalid account in state %x", root)
+		}
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCodeHash {
+			bloom.add(codeHash[:])
+		}
+		if account.Root == types.EmptyRootHash {
+			continue
+		}
+		storageTrie, err := stateDB.OpenStorageTrie(common.BytesToHash(it.LeafKey()), account.Root)
+		if err != nil {
+			return errors.Wrapf(err, "storage trie %x missing in state %x", account.Root, root)
+		}
+		storageIt := storageTrie.NodeIterator(nil)
+		for storageIt.Next(true) {
+			if hash := storageIt.Hash(); hash != (common.Hash{}) {
+				bloom.add(hash[:])
+			}
+		}
+		if err := storageIt.Error(); err != nil {
+			return errors.Wrapf(err, "cannot walk storage trie %x in state %x", account.Root, root)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return errors.Wrapf(err, "cannot walk state %x", root)
+	}
+	return nil
+}
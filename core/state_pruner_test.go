@@ -0,0 +1,98 @@
+package core
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+)
+
+func TestStateBloom(t *testing.T) {
+	bloom := newStateBloom(1024)
+	added := crypto.Keccak256([]byte("added"))
+	bloom.add(added)
+	if !bloom.contains(added) {
+		t.Error("added key not found")
+	}
+	if bloom.contains(crypto.Keccak256([]byte("missing"))) {
+		t.Error("missing key found")
+	}
+}
+
+// Tests that pruning keeps the state of the given roots and deletes the rest.
+func TestPruneState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pruner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := ethdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	stateDB := state.NewDatabase(db)
+	commit := func(root common.Hash, balance int64) common.Hash {
+		statedb, err := state.New(root, stateDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := common.BytesToAddress([]byte{byte(balance)})
+		statedb.AddBalance(addr, big.NewInt(balance))
+		statedb.SetCode(addr, []byte{byte(balance), 1})
+		statedb.SetState(addr, common.Hash{1}, common.Hash{byte(balance)})
+		newRoot, err := statedb.Commit(false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stateDB.TrieDB().Commit(newRoot, false); err != nil {
+			t.Fatal(err)
+		}
+		return newRoot
+	}
+	root1 := commit(common.Hash{}, 1)
+	root2 := commit(root1, 2)
+	garbage := crypto.Keccak256([]byte("garbage"))
+	if err := db.Put(garbage, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	other := []byte("not a state key")
+	if err := db.Put(other, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pruneState(db, []common.Hash{root2}, newStateBloom(1<<16)); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	statedb, err := state.New(root2, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("kept state missing: %v", err)
+	}
+	for _, balance := range []int64{1, 2} {
+		addr := common.BytesToAddress([]byte{byte(balance)})
+		if have := statedb.GetBalance(addr); have.Int64() != balance {
+			t.Errorf("balance of %x mismatch: have %v, want %d", addr, have, balance)
+		}
+		if have := statedb.GetCode(addr); len(have) != 2 || have[0] != byte(balance) {
+			t.Errorf("code of %x mismatch: have %x", addr, have)
+		}
+		if have := statedb.GetState(addr, common.Hash{1}); have != (common.Hash{byte(balance)}) {
+			t.Errorf("storage of %x mismatch: have %x", addr, have)
+		}
+	}
+	if ok, _ := db.Has(root1[:]); ok {
+		t.Error("pruned state root still present")
+	}
+	if ok, _ := db.Has(garbage); ok {
+		t.Error("garbage still present")
+	}
+	if ok, _ := db.Has(other); !ok {
+		t.Error("non state key deleted")
+	}
+}
@@ -42,8 +42,22 @@ var (
 	errNoDelegationToUndelegate    = errors.New("no delegation to undelegate")
 	errCommissionRateChangeTooFast = errors.New("change on commission rate can not be more than max change rate within the same epoch")
 	errCommissionRateChangeTooHigh = errors.New("commission rate can not be higher than maximum commission rate")
+	errCommissionChangePending     = errors.New("commission rate can not be edited while a previous edit is pending")
 	errNoRewardsToCollect          = errors.New("no rewards to collect")
 	errNegativeAmount              = errors.New("amount can not be negative")
+	errNoDelegationToRedelegate    = errors.New("no delegation to redelegate")
+	errRedelegateToSameValidator   = errors.New("cannot redelegate to the same validator")
+	errRedelegationNotActive       = errors.New("redelegation is not active in this epoch")
+	errNoDelegationToAutoCompound  = errors.New("no delegation to set auto-compounding on")
+	errAutoCompoundNotActive       = errors.New("auto-compounding is not active in this epoch")
+	errEmptyBatch                  = errors.New("batch has no operations")
+	errBatchTooLarge               = errors.New("batch has too many operations")
+	errInvalidBatchOperation       = errors.New("batch operation must be a delegate or an undelegate")
+	errBatchStakingNotActive       = errors.New("batch staking is not active in this epoch")
+	errKeyRotationTooSoon          = errors.New("key rotation must take effect at least two epochs later")
+	errKeyRotationNotActive        = errors.New("key rotation is not active in this epoch")
+	errSlotKeyRotationPending      = errors.New("slot key has a pending rotation")
+	errSlotKeyInUse                = errors.New("slot key is used by another validator")
 )
 
 /*
@@ -353,6 +367,49 @@ func (st *StateTransition) StakingTransitionDb() (usedGas uint64, err error) {
 			return 0, errInvalidSigner
 		}
 		err = st.verifyAndApplyUndelegateTx(stkMsg)
+	case types.Redelegate:
+		stkMsg := &staking.Redelegate{}
+		if err = rlp.DecodeBytes(msg.Data(), stkMsg); err != nil {
+			return 0, err
+		}
+		utils.Logger().Info().Msgf("[DEBUG STAKING] staking type: %s, gas: %d, txn: %+v", msg.Type(), gas, stkMsg)
+		if msg.From() != stkMsg.DelegatorAddress {
+			return 0, errInvalidSigner
+		}
+		err = st.verifyAndApplyRedelegateTx(stkMsg)
+	case types.SetAutoCompound:
+		stkMsg := &staking.SetAutoCompound{}
+		if err = rlp.DecodeBytes(msg.Data(), stkMsg); err != nil {
+			return 0, err
+		}
+		utils.Logger().Info().Msgf("[DEBUG STAKING] staking type: %s, gas: %d, txn: %+v", msg.Type(), gas, stkMsg)
+		if msg.From() != stkMsg.DelegatorAddress {
+			return 0, errInvalidSigner
+		}
+		err = st.verifyAndApplySetAutoCompoundTx(stkMsg)
+	case types.Batch:
+		stkMsg := &staking.Batch{}
+		if err = rlp.DecodeBytes(msg.Data(), stkMsg); err != nil {
+			return 0, err
+		}
+		utils.Logger().Info().Msgf("[DEBUG STAKING] staking type: %s, gas: %d, txn: %+v", msg.Type(), gas, stkMsg)
+		if msg.From() != stkMsg.DelegatorAddress {
+			return 0, errInvalidSigner
+		}
+		if err = st.useGas(BatchGas(stkMsg)); err != nil {
+			return 0, err
+		}
+		err = st.verifyAndApplyBatchTx(stkMsg)
+	case types.RotateKey:
+		stkMsg := &staking.RotateKey{}
+		if err = rlp.DecodeBytes(msg.Data(), stkMsg); err != nil {
+			return 0, err
+		}
+		utils.Logger().Info().Msgf("[DEBUG STAKING] staking type: %s, gas: %d, txn: %+v", msg.Type(), gas, stkMsg)
+		if msg.From() != stkMsg.ValidatorAddress {
+			return 0, errInvalidSigner
+		}
+		err = st.verifyAndApplyRotateKeyTx(stkMsg)
 	case types.CollectRewards:
 		stkMsg := &staking.CollectRewards{}
 		if err = rlp.DecodeBytes(msg.Data(), stkMsg); err != nil {
@@ -414,20 +471,83 @@ func (st *StateTransition) verifyAndApplyEditValidatorTx(
 }
 
 func (st *StateTransition) verifyAndApplyDelegateTx(delegate *staking.Delegate) error {
-	wrapper, balanceToBeDeducted, err := VerifyAndDelegateFromMsg(st.state, delegate)
+	return verifyAndApplyDelegate(st.state, delegate)
+}
+
+// verifyAndApplyDelegate is shared by the delegate
+// staking transactions and the staking precompile
+func verifyAndApplyDelegate(stateDB vm.StateDB, delegate *staking.Delegate) error {
+	wrapper, balanceToBeDeducted, err := VerifyAndDelegateFromMsg(stateDB, delegate)
 	if err != nil {
 		return err
 	}
 
-	st.state.SubBalance(delegate.DelegatorAddress, balanceToBeDeducted)
+	stateDB.SubBalance(delegate.DelegatorAddress, balanceToBeDeducted)
 
-	return st.state.UpdateValidatorWrapper(wrapper.Address, wrapper)
+	return stateDB.UpdateValidatorWrapper(wrapper.Address, wrapper)
 }
 
 func (st *StateTransition) verifyAndApplyUndelegateTx(
 	undelegate *staking.Undelegate,
 ) error {
-	wrapper, err := VerifyAndUndelegateFromMsg(st.state, st.evm.EpochNumber, undelegate)
+	return verifyAndApplyUndelegate(st.state, st.evm.EpochNumber, undelegate)
+}
+
+// verifyAndApplyUndelegate is shared by the undelegate
+// staking transactions and the staking precompile
+func verifyAndApplyUndelegate(
+	stateDB vm.StateDB, epoch *big.Int, undelegate *staking.Undelegate,
+) error {
+	wrapper, err := VerifyAndUndelegateFromMsg(stateDB, epoch, undelegate)
+	if err != nil {
+		return err
+	}
+	return stateDB.UpdateValidatorWrapper(wrapper.Address, wrapper)
+}
+
+func (st *StateTransition) verifyAndApplyRedelegateTx(
+	redelegate *staking.Redelegate,
+) error {
+	if !st.evm.ChainConfig().IsRedelegation(st.evm.EpochNumber) {
+		return errRedelegationNotActive
+	}
+	from, to, err := VerifyAndRedelegateFromMsg(st.state, st.evm.EpochNumber, redelegate)
+	if err != nil {
+		return err
+	}
+	if err := st.state.UpdateValidatorWrapper(from.Address, from); err != nil {
+		return err
+	}
+	return st.state.UpdateValidatorWrapper(to.Address, to)
+}
+
+func (st *StateTransition) verifyAndApplySetAutoCompoundTx(
+	setAutoCompound *staking.SetAutoCompound,
+) error {
+	if !st.evm.ChainConfig().IsAutoCompound(st.evm.EpochNumber) {
+		return errAutoCompoundNotActive
+	}
+	wrapper, err := VerifyAndSetAutoCompoundFromMsg(st.state, setAutoCompound)
+	if err != nil {
+		return err
+	}
+	return st.state.UpdateValidatorWrapper(wrapper.Address, wrapper)
+}
+
+func (st *StateTransition) verifyAndApplyBatchTx(batch *staking.Batch) error {
+	if !st.evm.ChainConfig().IsBatchStaking(st.evm.EpochNumber) {
+		return errBatchStakingNotActive
+	}
+	return VerifyAndApplyBatchFromMsg(st.state, st.evm.EpochNumber, batch)
+}
+
+func (st *StateTransition) verifyAndApplyRotateKeyTx(rotateKey *staking.RotateKey) error {
+	if !st.evm.ChainConfig().IsKeyRotation(st.evm.EpochNumber) {
+		return errKeyRotationNotActive
+	}
+	wrapper, err := VerifyAndRotateKeyFromMsg(
+		st.state, st.bc, st.evm.EpochNumber, rotateKey,
+	)
 	if err != nil {
 		return err
 	}
@@ -435,25 +555,33 @@ func (st *StateTransition) verifyAndApplyUndelegateTx(
 }
 
 func (st *StateTransition) verifyAndApplyCollectRewards(collectRewards *staking.CollectRewards) (*big.Int, error) {
-	if st.bc == nil {
+	return verifyAndApplyCollectRewards(st.state, st.bc, collectRewards)
+}
+
+// verifyAndApplyCollectRewards is shared by the collect rewards
+// staking transactions and the staking precompile
+func verifyAndApplyCollectRewards(
+	stateDB vm.StateDB, bc ChainContext, collectRewards *staking.CollectRewards,
+) (*big.Int, error) {
+	if bc == nil {
 		return network.NoReward, errors.New("[CollectRewards] No chain context provided")
 	}
 	// TODO(audit): make sure the delegation index is always consistent with onchain data
-	delegations, err := st.bc.ReadDelegationsByDelegator(collectRewards.DelegatorAddress)
+	delegations, err := bc.ReadDelegationsByDelegator(collectRewards.DelegatorAddress)
 	if err != nil {
 		return network.NoReward, err
 	}
 	updatedValidatorWrappers, totalRewards, err := VerifyAndCollectRewardsFromDelegation(
-		st.state, delegations,
+		stateDB, delegations,
 	)
 	if err != nil {
 		return network.NoReward, err
 	}
 	for _, wrapper := range updatedValidatorWrappers {
-		if err := st.state.UpdateValidatorWrapper(wrapper.Address, wrapper); err != nil {
+		if err := stateDB.UpdateValidatorWrapper(wrapper.Address, wrapper); err != nil {
 			return network.NoReward, err
 		}
 	}
-	st.state.AddBalance(collectRewards.DelegatorAddress, totalRewards)
+	stateDB.AddBalance(collectRewards.DelegatorAddress, totalRewards)
 	return totalRewards, nil
 }
@@ -820,6 +820,106 @@ func (pool *TxPool) validateStakingTx(tx *staking.StakingTransaction) error {
 		}
 		_, err = VerifyAndUndelegateFromMsg(pool.currentState, pendingEpoch, stkMsg)
 		return err
+	case staking.DirectiveRedelegate:
+		msg, err := staking.RLPDecodeStakeMsg(tx.Data(), staking.DirectiveRedelegate)
+		if err != nil {
+			return err
+		}
+		stkMsg, ok := msg.(*staking.Redelegate)
+		if !ok {
+			return ErrInvalidMsgForStakingDirective
+		}
+		if from != stkMsg.DelegatorAddress {
+			return errors.WithMessagef(ErrInvalidSender, "staking transaction sender is %s", b32)
+		}
+		pendingEpoch := pool.chain.CurrentBlock().Epoch()
+		if shard.Schedule.IsLastBlock(pool.chain.CurrentBlock().Number().Uint64()) {
+			pendingEpoch = new(big.Int).Add(pendingEpoch, big.NewInt(1))
+		}
+		if !pool.chainconfig.IsRedelegation(pendingEpoch) {
+			return errRedelegationNotActive
+		}
+		_, _, err = VerifyAndRedelegateFromMsg(pool.currentState, pendingEpoch, stkMsg)
+		return err
+	case staking.DirectiveSetAutoCompound:
+		msg, err := staking.RLPDecodeStakeMsg(tx.Data(), staking.DirectiveSetAutoCompound)
+		if err != nil {
+			return err
+		}
+		stkMsg, ok := msg.(*staking.SetAutoCompound)
+		if !ok {
+			return ErrInvalidMsgForStakingDirective
+		}
+		if from != stkMsg.DelegatorAddress {
+			return errors.WithMessagef(ErrInvalidSender, "staking transaction sender is %s", b32)
+		}
+		pendingEpoch := pool.chain.CurrentBlock().Epoch()
+		if shard.Schedule.IsLastBlock(pool.chain.CurrentBlock().Number().Uint64()) {
+			pendingEpoch = new(big.Int).Add(pendingEpoch, big.NewInt(1))
+		}
+		if !pool.chainconfig.IsAutoCompound(pendingEpoch) {
+			return errAutoCompoundNotActive
+		}
+		_, err = VerifyAndSetAutoCompoundFromMsg(pool.currentState, stkMsg)
+		return err
+	case staking.DirectiveBatch:
+		msg, err := staking.RLPDecodeStakeMsg(tx.Data(), staking.DirectiveBatch)
+		if err != nil {
+			return err
+		}
+		stkMsg, ok := msg.(*staking.Batch)
+		if !ok {
+			return ErrInvalidMsgForStakingDirective
+		}
+		if from != stkMsg.DelegatorAddress {
+			return errors.WithMessagef(ErrInvalidSender, "staking transaction sender is %s", b32)
+		}
+		intrGas, err := IntrinsicGas(tx.Data(), false, pool.homestead, false)
+		if err != nil {
+			return err
+		}
+		if tx.Gas() < intrGas+BatchGas(stkMsg) {
+			return errors.WithMessagef(ErrIntrinsicGas, "transaction gas is %d", tx.Gas())
+		}
+		pendingEpoch := pool.chain.CurrentBlock().Epoch()
+		if shard.Schedule.IsLastBlock(pool.chain.CurrentBlock().Number().Uint64()) {
+			pendingEpoch = new(big.Int).Add(pendingEpoch, big.NewInt(1))
+		}
+		if !pool.chainconfig.IsBatchStaking(pendingEpoch) {
+			return errBatchStakingNotActive
+		}
+		// the operations are applied one after the other, so apply them
+		// to the pool state and always revert it afterwards
+		snapshot := pool.currentState.Snapshot()
+		defer pool.currentState.RevertToSnapshot(snapshot)
+		return VerifyAndApplyBatchFromMsg(pool.currentState, pendingEpoch, stkMsg)
+	case staking.DirectiveRotateKey:
+		msg, err := staking.RLPDecodeStakeMsg(tx.Data(), staking.DirectiveRotateKey)
+		if err != nil {
+			return err
+		}
+		stkMsg, ok := msg.(*staking.RotateKey)
+		if !ok {
+			return ErrInvalidMsgForStakingDirective
+		}
+		if from != stkMsg.ValidatorAddress {
+			return errors.WithMessagef(ErrInvalidSender, "staking transaction sender is %s", b32)
+		}
+		pendingEpoch := pool.chain.CurrentBlock().Epoch()
+		if shard.Schedule.IsLastBlock(pool.chain.CurrentBlock().Number().Uint64()) {
+			pendingEpoch = new(big.Int).Add(pendingEpoch, big.NewInt(1))
+		}
+		if !pool.chainconfig.IsKeyRotation(pendingEpoch) {
+			return errKeyRotationNotActive
+		}
+		chainContext, ok := pool.chain.(ChainContext)
+		if !ok {
+			chainContext = nil // might use testing blockchain, set to nil for verifier to handle.
+		}
+		_, err = VerifyAndRotateKeyFromMsg(
+			pool.currentState, chainContext, pendingEpoch, stkMsg,
+		)
+		return err
 	case staking.DirectiveCollectRewards:
 		msg, err := staking.RLPDecodeStakeMsg(tx.Data(), staking.DirectiveCollectRewards)
 		if err != nil {
@@ -55,12 +55,18 @@ const (
 	Delegate
 	Undelegate
 	CollectRewards
+	Redelegate
+	SetAutoCompound
+	Batch
+	RotateKey
 )
 
 // StakingTypeMap is the map from staking type to transactionType
 var StakingTypeMap = map[staking.Directive]TransactionType{staking.DirectiveCreateValidator: StakeCreateVal,
 	staking.DirectiveEditValidator: StakeEditVal, staking.DirectiveDelegate: Delegate,
-	staking.DirectiveUndelegate: Undelegate, staking.DirectiveCollectRewards: CollectRewards}
+	staking.DirectiveUndelegate: Undelegate, staking.DirectiveCollectRewards: CollectRewards,
+	staking.DirectiveRedelegate: Redelegate, staking.DirectiveSetAutoCompound: SetAutoCompound,
+	staking.DirectiveBatch: Batch, staking.DirectiveRotateKey: RotateKey}
 
 // Transaction struct.
 type Transaction struct {
@@ -105,6 +111,14 @@ func (txType TransactionType) String() string {
 		return "Undelegate"
 	} else if txType == CollectRewards {
 		return "CollectRewards"
+	} else if txType == Redelegate {
+		return "Redelegate"
+	} else if txType == SetAutoCompound {
+		return "SetAutoCompound"
+	} else if txType == Batch {
+		return "Batch"
+	} else if txType == RotateKey {
+		return "RotateKey"
 	}
 	return "Unknown"
 }
@@ -20,12 +20,17 @@ import (
 	"crypto/sha256"
 	"errors"
 	"math/big"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/crypto/bn256"
+	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/internal/params"
+	staking2 "github.com/harmony-one/harmony/staking"
+	staking "github.com/harmony-one/harmony/staking/types"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -68,6 +73,51 @@ func RunPrecompiledContract(p PrecompiledContract, input []byte, contract *Contr
 	return nil, ErrOutOfGas
 }
 
+// WriteCapablePrecompiledContract is the interface for native Go contracts
+// which change the state, so run with the context of the call.
+type WriteCapablePrecompiledContract interface {
+	// RequiredGas calculates the contract gas use
+	RequiredGas(evm *EVM, contract *Contract, input []byte) (uint64, error)
+	// RunWriteCapable runs the precompiled contract
+	RunWriteCapable(evm *EVM, contract *Contract, input []byte) ([]byte, error)
+}
+
+// StakingPrecompileAddress is the address of the precompile through which
+// smart contracts delegate, undelegate and collect rewards as msg.sender
+var StakingPrecompileAddress = common.BytesToAddress([]byte{252})
+
+// WriteCapablePrecompiledContractsStaking contains the precompiled contracts
+// added with the staking precompile fork
+var WriteCapablePrecompiledContractsStaking = map[common.Address]WriteCapablePrecompiledContract{
+	StakingPrecompileAddress: &stakingPrecompile{},
+}
+
+// RunWriteCapablePrecompiledContract runs and evaluates the output of a write capable precompiled contract.
+func RunWriteCapablePrecompiledContract(
+	p WriteCapablePrecompiledContract, evm *EVM, contract *Contract, input []byte, readOnly bool,
+) ([]byte, error) {
+	// a static call must not change the state
+	if readOnly {
+		return nil, errWriteProtection
+	}
+	gas, err := p.RequiredGas(evm, contract, input)
+	if err != nil {
+		return nil, err
+	}
+	if !contract.UseGas(gas) {
+		return nil, ErrOutOfGas
+	}
+	return p.RunWriteCapable(evm, contract, input)
+}
+
+func (evm *EVM) isWriteCapablePrecompile(addr common.Address) bool {
+	if !evm.ChainConfig().IsStakingPrecompile(evm.EpochNumber) {
+		return false
+	}
+	_, ok := WriteCapablePrecompiledContractsStaking[addr]
+	return ok
+}
+
 // ECRECOVER implemented as a native contract.
 type ecrecover struct{}
 
@@ -358,3 +408,125 @@ func (c *bn256Pairing) Run(input []byte) ([]byte, error) {
 	}
 	return false32Byte, nil
 }
+
+const stakingPrecompileABIJSON = `[
+	{"type":"function","name":"Delegate","inputs":[
+		{"name":"delegatorAddress","type":"address"},
+		{"name":"validatorAddress","type":"address"},
+		{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"Undelegate","inputs":[
+		{"name":"delegatorAddress","type":"address"},
+		{"name":"validatorAddress","type":"address"},
+		{"name":"amount","type":"uint256"}]},
+	{"type":"function","name":"CollectRewards","inputs":[
+		{"name":"delegatorAddress","type":"address"}]}
+]`
+
+var (
+	stakingPrecompileABI = mustParseABI(stakingPrecompileABIJSON)
+
+	errStakingPrecompileUnavailable = errors.New("staking precompile is not available")
+	errStakingPrecompileInput       = errors.New("invalid staking precompile input")
+	errStakingPrecompileSender      = errors.New("delegator address must be msg.sender")
+	errStakingPrecompileCallOnly    = errors.New("staking precompile can only be called directly, without value")
+)
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// stakingPrecompile lets smart contracts delegate, undelegate and collect
+// rewards with the calling contract as the delegator.
+type stakingPrecompile struct{}
+
+func (c *stakingPrecompile) RequiredGas(evm *EVM, contract *Contract, input []byte) (uint64, error) {
+	return params.StakingPrecompileGas, nil
+}
+
+func (c *stakingPrecompile) RunWriteCapable(evm *EVM, contract *Contract, input []byte) ([]byte, error) {
+	if evm.Context.ApplyStaking == nil {
+		return nil, errStakingPrecompileUnavailable
+	}
+	// with callcode or delegatecall, msg.sender would not be the delegator
+	if *contract.CodeAddr != contract.Address() || contract.Value().Sign() != 0 {
+		return nil, errStakingPrecompileCallOnly
+	}
+	msg, err := parseStakingPrecompileInput(input)
+	if err != nil {
+		return nil, err
+	}
+	var delegator common.Address
+	switch m := msg.(type) {
+	case *staking.Delegate:
+		delegator = m.DelegatorAddress
+	case *staking.Undelegate:
+		delegator = m.DelegatorAddress
+	case *staking.CollectRewards:
+		delegator = m.DelegatorAddress
+	}
+	if delegator != contract.Caller() {
+		return nil, errStakingPrecompileSender
+	}
+	if err := evm.Context.ApplyStaking(evm.StateDB, evm.EpochNumber, msg); err != nil {
+		return nil, err
+	}
+	if delegate, ok := msg.(*staking.Delegate); ok {
+		// the delegation index of the chain is updated from this log
+		evm.StateDB.AddLog(&types.Log{
+			Address: StakingPrecompileAddress,
+			Topics: []common.Hash{
+				staking2.PrecompileDelegateTopic,
+				delegate.DelegatorAddress.Hash(),
+				delegate.ValidatorAddress.Hash(),
+			},
+			Data:        common.LeftPadBytes(delegate.Amount.Bytes(), 32),
+			BlockNumber: evm.BlockNumber.Uint64(),
+		})
+	}
+	return nil, nil
+}
+
+func parseStakingPrecompileInput(input []byte) (staking.StakeMsg, error) {
+	if len(input) < 4 {
+		return nil, errStakingPrecompileInput
+	}
+	method, err := stakingPrecompileABI.MethodById(input[:4])
+	if err != nil {
+		return nil, errStakingPrecompileInput
+	}
+	args, err := method.Inputs.UnpackValues(input[4:])
+	if err != nil || len(args) != len(method.Inputs) {
+		return nil, errStakingPrecompileInput
+	}
+	delegator, ok := args[0].(common.Address)
+	if !ok {
+		return nil, errStakingPrecompileInput
+	}
+	if method.Name == "CollectRewards" {
+		return &staking.CollectRewards{DelegatorAddress: delegator}, nil
+	}
+	validator, ok := args[1].(common.Address)
+	if !ok {
+		return nil, errStakingPrecompileInput
+	}
+	amount, ok := args[2].(*big.Int)
+	if !ok {
+		return nil, errStakingPrecompileInput
+	}
+	if method.Name == "Delegate" {
+		return &staking.Delegate{
+			DelegatorAddress: delegator,
+			ValidatorAddress: validator,
+			Amount:           amount,
+		}, nil
+	}
+	return &staking.Undelegate{
+		DelegatorAddress: delegator,
+		ValidatorAddress: validator,
+		Amount:           amount,
+	}, nil
+}
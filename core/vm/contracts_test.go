@@ -22,6 +22,11 @@ import (
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/internal/params"
+	staking2 "github.com/harmony-one/harmony/staking"
+	staking "github.com/harmony-one/harmony/staking/types"
 )
 
 // precompiledTest defines the input/output pairs for precompiled contract tests.
@@ -481,3 +486,62 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+func TestStakingPrecompile(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	var applied staking.StakeMsg
+	env := NewEVM(Context{
+		BlockNumber: big.NewInt(1),
+		EpochNumber: big.NewInt(0),
+		ApplyStaking: func(db StateDB, epoch *big.Int, msg staking.StakeMsg) error {
+			applied = msg
+			return nil
+		},
+	}, statedb, params.TestChainConfig, Config{})
+
+	delegator := common.BytesToAddress([]byte{1})
+	validator := common.BytesToAddress([]byte{2})
+	newContract := func(caller common.Address, gas uint64) *Contract {
+		contract := NewContract(
+			AccountRef(caller), AccountRef(StakingPrecompileAddress), new(big.Int), gas,
+		)
+		contract.SetCallCode(&StakingPrecompileAddress, common.Hash{}, nil)
+		return contract
+	}
+	input, err := stakingPrecompileABI.Pack("Delegate", delegator, validator, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := run(env, newContract(delegator, params.StakingPrecompileGas), input, true); err != errWriteProtection {
+		t.Error("expected", errWriteProtection, "got", err)
+	}
+	if _, err := run(env, newContract(delegator, params.StakingPrecompileGas-1), input, false); err != ErrOutOfGas {
+		t.Error("expected", ErrOutOfGas, "got", err)
+	}
+	if _, err := run(env, newContract(validator, params.StakingPrecompileGas), input, false); err != errStakingPrecompileSender {
+		t.Error("expected", errStakingPrecompileSender, "got", err)
+	}
+	if applied != nil {
+		t.Fatal("expected nothing applied, got", applied)
+	}
+
+	if _, err := run(env, newContract(delegator, params.StakingPrecompileGas), input, false); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	delegate, ok := applied.(*staking.Delegate)
+	if !ok || delegate.ValidatorAddress != validator || delegate.Amount.Cmp(big.NewInt(100)) != 0 {
+		t.Error("expected a delegation of 100 to", validator.Hex(), "got", applied)
+	}
+	if logs := statedb.Logs(); len(logs) != 1 || logs[0].Topics[0] != staking2.PrecompileDelegateTopic {
+		t.Error("expected a delegation log, got", logs)
+	}
+
+	input, _ = stakingPrecompileABI.Pack("CollectRewards", delegator)
+	if _, err := run(env, newContract(delegator, params.StakingPrecompileGas), input, false); err != nil {
+		t.Fatal("expected", nil, "got", err)
+	}
+	if _, ok := applied.(*staking.CollectRewards); !ok {
+		t.Error("expected rewards collected, got", applied)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/harmony-one/harmony/internal/params"
 
 	"github.com/harmony-one/harmony/core/types"
+	staking "github.com/harmony-one/harmony/staking/types"
 )
 
 // emptyCodeHash is used by create to ensure deployment is disallowed to already
@@ -42,6 +43,9 @@ type (
 	// GetHashFunc returns the nth block hash in the blockchain
 	// and is used by the BLOCKHASH EVM op code.
 	GetHashFunc func(uint64) common.Hash
+	// ApplyStakingFunc verifies and applies a staking message sent through
+	// the staking precompile
+	ApplyStakingFunc func(StateDB, *big.Int, staking.StakeMsg) error
 )
 
 // run runs the given contract and takes care of running precompiles with a fallback to the byte code interpreter.
@@ -54,6 +58,11 @@ func run(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, err
 		if p := precompiles[*contract.CodeAddr]; p != nil {
 			return RunPrecompiledContract(p, input, contract)
 		}
+		if evm.ChainConfig().IsStakingPrecompile(evm.EpochNumber) {
+			if p := WriteCapablePrecompiledContractsStaking[*contract.CodeAddr]; p != nil {
+				return RunWriteCapablePrecompiledContract(p, evm, contract, input, readOnly)
+			}
+		}
 	}
 	for _, interpreter := range evm.interpreters {
 		if interpreter.CanRun(contract.Code) {
@@ -86,6 +95,9 @@ type Context struct {
 	// true: is a validator address; false: is smart contract address
 	IsValidator IsValidatorFunc
 
+	// ApplyStaking applies the staking messages of the staking precompile
+	ApplyStaking ApplyStakingFunc
+
 	// Message information
 	Origin   common.Address // Provides information for ORIGIN
 	GasPrice *big.Int       // Provides information for GASPRICE
@@ -219,7 +231,8 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 		if evm.ChainConfig().IsS3(evm.EpochNumber) {
 			precompiles = PrecompiledContractsByzantium
 		}
-		if precompiles[addr] == nil && evm.ChainConfig().IsS3(evm.EpochNumber) && value.Sign() == 0 {
+		if precompiles[addr] == nil && !evm.isWriteCapablePrecompile(addr) &&
+			evm.ChainConfig().IsS3(evm.EpochNumber) && value.Sign() == 0 {
 			// Calling a non existing account, don't do anything, but ping the tracer
 			if evm.vmConfig.Debug && evm.depth == 0 {
 				evm.vmConfig.Tracer.CaptureStart(caller.Address(), addr, false, input, gas, value)
@@ -26,9 +26,11 @@ import (
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/shard/committee"
+	"github.com/harmony-one/harmony/staking/apr"
 	"github.com/harmony-one/harmony/staking/availability"
 	"github.com/harmony-one/harmony/staking/effective"
 	"github.com/harmony-one/harmony/staking/network"
+	"github.com/harmony-one/harmony/staking/slash"
 	staking "github.com/harmony-one/harmony/staking/types"
 	"github.com/pkg/errors"
 )
@@ -41,6 +43,13 @@ type APIBackend struct {
 		BlockHeight  int64
 		TotalStaking *big.Int
 	}
+	// EPoSRoundCache keeps the auction the apr projections are based on,
+	// computed once per epoch
+	EPoSRoundCache struct {
+		sync.Mutex
+		Epoch *big.Int
+		Round *committee.CompletedEPoSRound
+	}
 }
 
 // ChainDb ...
@@ -412,6 +421,120 @@ func (b *APIBackend) GetValidatorInformation(
 	return defaultReply, nil
 }
 
+// GetValidatorAvailabilityHistory returns how the validator signed in each epoch
+func (b *APIBackend) GetValidatorAvailabilityHistory(
+	addr common.Address,
+) (staking.AvailabilityHistory, error) {
+	return b.hmy.BlockChain().ReadValidatorAvailabilityHistory(addr)
+}
+
+// GetValidatorAPRHistory returns the apr of the validator at the end of each epoch
+func (b *APIBackend) GetValidatorAPRHistory(
+	addr common.Address,
+) (staking.APRHistory, error) {
+	return b.hmy.BlockChain().ReadValidatorAPRHistory(addr)
+}
+
+// GetNetworkAverageAPR returns the apr of the elected validators,
+// weighted by their total delegation
+func (b *APIBackend) GetNetworkAverageAPR() (numeric.Dec, error) {
+	bc := b.hmy.BlockChain()
+	elected, err := bc.ReadElectedValidatorList()
+	if err != nil {
+		return numeric.ZeroDec(), err
+	}
+	state, err := bc.State()
+	if err != nil {
+		return numeric.ZeroDec(), err
+	}
+	weighted, total := numeric.ZeroDec(), numeric.ZeroDec()
+	for _, addr := range elected {
+		stats, err := bc.ReadValidatorStats(addr)
+		if err != nil || stats.APR.IsNil() {
+			continue
+		}
+		wrapper, err := state.ValidatorWrapper(addr)
+		if err != nil {
+			return numeric.ZeroDec(), err
+		}
+		stake := numeric.NewDecFromBigInt(wrapper.TotalDelegation())
+		weighted = weighted.Add(stats.APR.Mul(stake))
+		total = total.Add(stake)
+	}
+	if total.IsZero() {
+		return numeric.ZeroDec(), nil
+	}
+	return weighted.Quo(total), nil
+}
+
+// GetDelegationAPRProjection estimates the return of delegating amount to
+// the validator from its current apr, the median stake of the current
+// auction and its commission, the pending one if an edit is scheduled
+func (b *APIBackend) GetDelegationAPRProjection(
+	validator common.Address, amount *big.Int,
+) (*apr.Projection, error) {
+	bc := b.hmy.BlockChain()
+	wrapper, err := bc.ReadValidatorInformation(validator)
+	if err != nil {
+		s, _ := internal_common.AddressToBech32(validator)
+		return nil, errors.Wrapf(err, "not found address in current state %s", s)
+	}
+	aprNow := numeric.ZeroDec()
+	if stats, err := bc.ReadValidatorStats(validator); err == nil && !stats.APR.IsNil() {
+		aprNow = stats.APR
+	}
+	round, err := b.epoSRoundOfEpoch()
+	if err != nil {
+		return nil, err
+	}
+	commission := wrapper.Rate
+	if wrapper.PendingCommission != nil {
+		commission = wrapper.PendingCommission.Rate
+	}
+	return apr.Project(
+		aprNow, wrapper.TotalDelegation(), amount,
+		len(wrapper.SlotPubKeys), round.MedianStake, commission,
+	)
+}
+
+// epoSRoundOfEpoch returns the auction run against the state of the first
+// call in the current epoch
+func (b *APIBackend) epoSRoundOfEpoch() (*committee.CompletedEPoSRound, error) {
+	b.EPoSRoundCache.Lock()
+	defer b.EPoSRoundCache.Unlock()
+	bc := b.hmy.BlockChain()
+	epoch := bc.CurrentHeader().Epoch()
+	if b.EPoSRoundCache.Round != nil && b.EPoSRoundCache.Epoch.Cmp(epoch) == 0 {
+		return b.EPoSRoundCache.Round, nil
+	}
+	round, err := committee.NewEPoSRound(bc)
+	if err != nil {
+		return nil, err
+	}
+	b.EPoSRoundCache.Epoch, b.EPoSRoundCache.Round = epoch, round
+	return round, nil
+}
+
+// SimulateSlash runs the slashing of the validator at rate against the
+// current state without applying it, only for delegator when given
+func (b *APIBackend) SimulateSlash(
+	validator common.Address, delegator *common.Address, rate numeric.Dec,
+) (*slash.Simulation, error) {
+	bc := b.hmy.BlockChain()
+	snapshot, err := bc.ReadValidatorSnapshot(validator)
+	if err != nil {
+		s, _ := internal_common.AddressToBech32(validator)
+		return nil, errors.Wrapf(err, "no snapshot of validator %s", s)
+	}
+	state, err := bc.State()
+	if err != nil {
+		return nil, err
+	}
+	return slash.Simulate(
+		snapshot, state, rate, bc.CurrentHeader().Epoch(), delegator,
+	)
+}
+
 // GetMedianRawStakeSnapshot ..
 func (b *APIBackend) GetMedianRawStakeSnapshot() (
 	*committee.CompletedEPoSRound, error,
@@ -419,6 +542,14 @@ func (b *APIBackend) GetMedianRawStakeSnapshot() (
 	return committee.NewEPoSRound(b.hmy.BlockChain())
 }
 
+// GetElectionDryRun runs the election of the next committee against the
+// current state, with extraStake added to the stake of validator when given
+func (b *APIBackend) GetElectionDryRun(
+	validator *common.Address, extraStake *big.Int,
+) (*committee.DryRunEPoSRound, error) {
+	return committee.NewEPoSRoundDryRun(b.hmy.BlockChain(), validator, extraStake)
+}
+
 // GetLatestChainHeaders ..
 func (b *APIBackend) GetLatestChainHeaders() *block.HeaderPair {
 	return &block.HeaderPair{
@@ -15,6 +15,7 @@ import (
 	"github.com/harmony-one/harmony/consensus/reward"
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/internal/params"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/multibls"
 	"github.com/harmony-one/harmony/shard"
@@ -259,6 +260,10 @@ func (e *engineImpl) Finalize(
 			return nil, nil, err
 		}
 
+		if err := updateValidatorsForNewEpoch(chain, header, state); err != nil {
+			return nil, nil, err
+		}
+
 		// Needs to be after payoutUndelegations because payoutUndelegations
 		// depends on the old LastEpochInCommittee
 		if err := setLastEpochInCommittee(header, state); err != nil {
@@ -305,6 +310,69 @@ func (e *engineImpl) Finalize(
 	return types.NewBlock(header, txs, receipts, outcxs, incxs, stks), payout, nil
 }
 
+// Replace the slot keys rotated by and set the commission rates scheduled for
+// the epoch of the new shard state, so that the validators and their snapshots
+// of that epoch match the elected committee, and compound the rewards of
+// the delegations opted in to it, writing each validator once
+func updateValidatorsForNewEpoch(
+	chain engine.ChainReader, header *block.Header, state *state.DB,
+) error {
+	config := chain.Config()
+	rotateKeys := config.IsKeyRotation(header.Epoch())
+	scheduleCommissions := config.IsCommissionSchedule(header.Epoch())
+	autoCompound := config.IsAutoCompound(header.Epoch())
+	if !rotateKeys && !scheduleCommissions && !autoCompound {
+		return nil
+	}
+	newEpoch := new(big.Int).Add(header.Epoch(), common.Big1)
+	validators, err := chain.ReadValidatorList()
+	if err != nil {
+		return errors.Wrap(err, "[Finalize] failed to read all validators")
+	}
+	for _, validator := range validators {
+		wrapper, err := state.ValidatorWrapper(validator)
+		if err != nil {
+			return errors.Wrap(
+				err, "[Finalize] failed to get validator from state to update",
+			)
+		}
+		changed := false
+		if rotateKeys && wrapper.ApplyKeyRotations(newEpoch) {
+			changed = true
+			utils.Logger().Info().
+				Uint64("epoch", newEpoch.Uint64()).
+				Str("validator", validator.Hex()).
+				Msg("[Finalize] rotated slot keys")
+		}
+		if scheduleCommissions &&
+			wrapper.ApplyPendingCommission(newEpoch, header.Number()) {
+			changed = true
+			utils.Logger().Info().
+				Uint64("epoch", newEpoch.Uint64()).
+				Str("validator", validator.Hex()).
+				Str("rate", wrapper.Rate.String()).
+				Msg("[Finalize] set scheduled commission rate")
+		}
+		if autoCompound {
+			if compounded := compoundDelegationRewards(wrapper); compounded.Sign() > 0 {
+				changed = true
+				utils.Logger().Info().
+					Uint64("epoch", header.Epoch().Uint64()).
+					Str("validator", validator.Hex()).
+					Str("compounded", compounded.String()).
+					Msg("[Finalize] compounded delegation rewards")
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := state.UpdateValidatorWrapper(validator, wrapper); err != nil {
+			return errors.Wrap(err, "[Finalize] failed update validator info")
+		}
+	}
+	return nil
+}
+
 // Withdraw unlocked tokens to the delegators' accounts
 func payoutUndelegations(
 	chain engine.ChainReader, header *block.Header, state *state.DB,
@@ -334,6 +402,9 @@ func payoutUndelegations(
 				header.Epoch(), wrapper.LastEpochInCommittee,
 			)
 			state.AddBalance(delegation.DelegatorAddress, totalWithdraw)
+			delegation.RemoveExpiredRedelegations(
+				header.Epoch(), wrapper.LastEpochInCommittee,
+			)
 		}
 		countTrack[validator] = len(wrapper.Delegations)
 		if err := state.UpdateValidatorWrapper(
@@ -476,23 +547,44 @@ func applySlashes(
 func QuorumForBlock(
 	chain engine.ChainReader, h *block.Header, reCalculate bool,
 ) (quorum int, err error) {
-	ss := new(shard.State)
+	ss, err := shardStateForBlock(chain, h, reCalculate)
+	if err != nil {
+		return 0, err
+	}
+	subComm, err := ss.FindCommitteeByID(h.ShardID())
+	if err != nil {
+		return 0, errors.Errorf("cannot find shard %d in shard state", h.ShardID())
+	}
+	return quorumOf(subComm), nil
+}
+
+// quorumOf returns the number of signatures needed from the committee before
+// staking.
+func quorumOf(subComm *shard.Committee) int {
+	return len(subComm.Slots)*2/3 + 1
+}
+
+// shardStateForBlock returns the shard state of the epoch of the given block
+// header, computed again from the chain if reCalculate is set.
+func shardStateForBlock(
+	chain engine.ChainReader, h *block.Header, reCalculate bool,
+) (*shard.State, error) {
 	if reCalculate {
-		ss, _ = committee.WithStakingEnabled.Compute(h.Epoch(), chain)
-	} else {
-		ss, err = chain.ReadShardState(h.Epoch())
-		if err != nil {
-			return 0, errors.Wrapf(
-				err, "failed to read shard state of epoch %d", h.Epoch().Uint64(),
+		ss, err := committee.WithStakingEnabled.Compute(h.Epoch(), chain)
+		if ss == nil {
+			return nil, errors.Errorf(
+				"cannot compute shard state of epoch %d: %v", h.Epoch().Uint64(), err,
 			)
 		}
+		return ss, nil
 	}
-
-	subComm, err := ss.FindCommitteeByID(h.ShardID())
+	ss, err := chain.ReadShardState(h.Epoch())
 	if err != nil {
-		return 0, errors.Errorf("cannot find shard %d in shard state", h.ShardID())
+		return nil, errors.Wrapf(
+			err, "failed to read shard state of epoch %d", h.Epoch().Uint64(),
+		)
 	}
-	return (len(subComm.Slots))*2/3 + 1, nil
+	return ss, nil
 }
 
 // Similiar to VerifyHeader, which is only for verifying the block headers of one's own chain, this verification
@@ -504,7 +596,31 @@ func (e *engineImpl) VerifyHeaderWithSignature(chain engine.ChainReader, header
 		// Never recalculate after staking is enabled
 		reCalculate = false
 	}
-	publicKeys, err := GetPublicKeys(chain, header, reCalculate)
+	shardState, err := shardStateForBlock(chain, header, reCalculate)
+	if err != nil {
+		return errors.Wrap(err, "[VerifyHeaderWithSignature] Cannot get publickeys for block header")
+	}
+	return VerifyCommitSig(chain.Config(), shardState, header, commitSig, commitBitmap)
+}
+
+// VerifyCommitSig verifies the commit signature and bitmap over the block
+// header against the committee of its shard in the given shard state, which is
+// the one of the epoch of the header. Unlike VerifyHeaderWithSignature, it needs
+// no chain, so that headers can be verified with the shard states alone.
+func VerifyCommitSig(
+	config *params.ChainConfig, shardState *shard.State, header *block.Header,
+	commitSig []byte, commitBitmap []byte,
+) error {
+	subComm, err := shardState.FindCommitteeByID(header.ShardID())
+	if err != nil {
+		return errors.Wrapf(
+			err,
+			"cannot find shard in the shard state at block %d shard %d",
+			header.Number(),
+			header.ShardID(),
+		)
+	}
+	publicKeys, err := subComm.BLSPublicKeys()
 	if err != nil {
 		return errors.New("[VerifyHeaderWithSignature] Cannot get publickeys for block header")
 	}
@@ -519,16 +635,7 @@ func (e *engineImpl) VerifyHeaderWithSignature(chain engine.ChainReader, header
 	}
 	hash := header.Hash()
 
-	if e := header.Epoch(); chain.Config().IsStaking(e) {
-		slotList, err := chain.ReadShardState(e)
-		if err != nil {
-			return errors.Wrapf(err, "cannot read shard state")
-		}
-
-		subComm, err := slotList.FindCommitteeByID(header.ShardID())
-		if err != nil {
-			return err
-		}
+	if e := header.Epoch(); config.IsStaking(e) {
 		// TODO(audit): reuse a singleton decider and not recreate it for every single block
 		d := quorum.NewDecider(quorum.SuperMajorityStake, subComm.ShardID)
 		d.SetMyPublicKeyProvider(func() (*multibls.PublicKey, error) {
@@ -544,12 +651,7 @@ func (e *engineImpl) VerifyHeaderWithSignature(chain engine.ChainReader, header
 			)
 		}
 	} else {
-		quorumCount, err := QuorumForBlock(chain, header, reCalculate)
-		if err != nil {
-			return errors.Wrapf(err,
-				"cannot calculate quorum for block %s", header.Number())
-		}
-		if count := utils.CountOneBits(mask.Bitmap); count < int64(quorumCount) {
+		if count := utils.CountOneBits(mask.Bitmap); count < int64(quorumOf(subComm)) {
 			return errors.New(
 				"[VerifyHeaderWithSignature] Not enough signature in commitSignature from Block Header",
 			)
@@ -570,17 +672,9 @@ func (e *engineImpl) VerifyHeaderWithSignature(chain engine.ChainReader, header
 func GetPublicKeys(
 	chain engine.ChainReader, header *block.Header, reCalculate bool,
 ) ([]*bls.PublicKey, error) {
-	shardState := new(shard.State)
-	var err error
-	if reCalculate {
-		shardState, _ = committee.WithStakingEnabled.Compute(header.Epoch(), chain)
-	} else {
-		shardState, err = chain.ReadShardState(header.Epoch())
-		if err != nil {
-			return nil, errors.Wrapf(
-				err, "failed to read shard state of epoch %d", header.Epoch().Uint64(),
-			)
-		}
+	shardState, err := shardStateForBlock(chain, header, reCalculate)
+	if err != nil {
+		return nil, err
 	}
 
 	subCommittee, err := shardState.FindCommitteeByID(header.ShardID())
@@ -0,0 +1,142 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/block"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/consensus/engine"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/staking/effective"
+	staking "github.com/harmony-one/harmony/staking/types"
+)
+
+// finalizeChain is a chain reader knowing only what Finalize reads
+// at an epoch boundary of the beacon chain
+type finalizeChain struct {
+	engine.ChainReader
+	current    *block.Header
+	validators []common.Address
+}
+
+func (c finalizeChain) Config() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (c finalizeChain) CurrentHeader() *block.Header {
+	return c.current
+}
+
+func (c finalizeChain) CurrentBlock() *types.Block {
+	return types.NewBlockWithHeader(c.current)
+}
+
+func (c finalizeChain) ReadValidatorList() ([]common.Address, error) {
+	return c.validators, nil
+}
+
+func (c finalizeChain) ReadShardState(epoch *big.Int) (*shard.State, error) {
+	return &shard.State{Epoch: epoch}, nil
+}
+
+func ones(n int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18))
+}
+
+// Test that compounding at an epoch boundary fills the room left under
+// MaxTotalDelegation in delegation order, along with the commission
+// scheduled for the new epoch
+func TestFinalizeCompoundsUpToMaxTotalDelegation(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	validator := common.BigToAddress(big.NewInt(1))
+	first := common.BigToAddress(big.NewInt(2))
+	second := common.BigToAddress(big.NewInt(3))
+
+	slotKey := shard.BLSPublicKey{}
+	slotKey.FromLibBLSPublicKey(bls.RandPrivateKey().GetPublicKey())
+	rate, _ := numeric.NewDecFromStr("0.1")
+	newRate, _ := numeric.NewDecFromStr("0.15")
+	wrapper := &staking.ValidatorWrapper{
+		Validator: staking.Validator{
+			Address:              validator,
+			SlotPubKeys:          []shard.BLSPublicKey{slotKey},
+			LastEpochInCommittee: big.NewInt(0),
+			MinSelfDelegation:    ones(10000),
+			MaxTotalDelegation:   ones(100000),
+			Status:               effective.Active,
+			Commission: staking.Commission{
+				CommissionRates: staking.CommissionRates{
+					Rate:          rate,
+					MaxRate:       numeric.NewDecWithPrec(5, 1),
+					MaxChangeRate: numeric.NewDecWithPrec(1, 1),
+				},
+				UpdateHeight: big.NewInt(0),
+			},
+			CreationHeight: big.NewInt(0),
+		},
+		PendingCommission: &staking.PendingCommission{
+			Rate: newRate, Epoch: big.NewInt(2),
+		},
+		BlockReward: big.NewInt(0),
+	}
+	self := staking.NewDelegation(validator, ones(40000))
+	self.Reward = ones(1000)
+	firstDelegation := staking.NewDelegation(first, ones(30000))
+	firstDelegation.Reward = ones(15000)
+	firstDelegation.AutoCompound = true
+	secondDelegation := staking.NewDelegation(second, ones(10000))
+	secondDelegation.Reward = ones(20000)
+	secondDelegation.AutoCompound = true
+	wrapper.Delegations = staking.Delegations{self, firstDelegation, secondDelegation}
+	if err := statedb.UpdateValidatorWrapper(validator, wrapper); err != nil {
+		t.Fatal(err)
+	}
+
+	header := blockfactory.ForTest.NewHeader(big.NewInt(1))
+	header.SetNumber(big.NewInt(0))
+	shardState, err := shard.EncodeWrapper(shard.State{Epoch: big.NewInt(2)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.SetShardState(shardState)
+	chain := finalizeChain{current: header, validators: []common.Address{validator}}
+	e := &engineImpl{beacon: chain}
+	if _, _, err := e.Finalize(
+		chain, header, statedb, nil, nil, nil, nil, nil, nil,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	wrapper, err = statedb.ValidatorWrapper(validator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := wrapper.TotalDelegation(); total.Cmp(wrapper.MaxTotalDelegation) != 0 {
+		t.Errorf("expected the total delegation to reach %s, got %s",
+			wrapper.MaxTotalDelegation, total)
+	}
+	for i, expected := range []struct{ amount, reward *big.Int }{
+		{ones(40000), ones(1000)},
+		{ones(45000), ones(0)},
+		{ones(15000), ones(15000)},
+	} {
+		delegation := wrapper.Delegations[i]
+		if delegation.Amount.Cmp(expected.amount) != 0 ||
+			delegation.Reward.Cmp(expected.reward) != 0 {
+			t.Errorf("delegation %d: expected amount %s reward %s, got %s %s",
+				i, expected.amount, expected.reward, delegation.Amount, delegation.Reward)
+		}
+	}
+	if !wrapper.Rate.Equal(newRate) || wrapper.PendingCommission != nil {
+		t.Errorf("expected the scheduled rate %s, got %s pending %v",
+			newRate, wrapper.Rate, wrapper.PendingCommission)
+	}
+}
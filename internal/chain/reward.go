@@ -17,6 +17,7 @@ import (
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/staking/availability"
 	"github.com/harmony-one/harmony/staking/network"
+	staking "github.com/harmony-one/harmony/staking/types"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/singleflight"
 )
@@ -376,3 +377,19 @@ func AccumulateRewardsAndCountSigs(
 
 	return network.NewPreStakingEraRewarded(totalAmount), nil
 }
+
+// compoundDelegationRewards adds the rewards of the delegations opted in to
+// auto-compounding to their delegated amount, as far as the
+// MaxTotalDelegation of the validator allows, returning the amount compounded
+func compoundDelegationRewards(wrapper *staking.ValidatorWrapper) *big.Int {
+	room := new(big.Int).Sub(
+		wrapper.MaxTotalDelegation, wrapper.TotalDelegation(),
+	)
+	compounded := big.NewInt(0)
+	for i := range wrapper.Delegations {
+		moved := wrapper.Delegations[i].CompoundReward(room)
+		room.Sub(room, moved)
+		compounded.Add(compounded, moved)
+	}
+	return compounded
+}
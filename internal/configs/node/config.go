@@ -85,6 +85,8 @@ type ConfigType struct {
 	shardingSchedule shardingconfig.Schedule
 	DNSZone          string
 	isArchival       bool
+	FastSync         bool // sync a new shard chain from a recent state instead of from genesis
+	StreamSync       bool // sync from the peers found through libp2p over streams of the p2p host
 	WebHooks         struct {
 		Hooks *webhooks.Hooks
 	}
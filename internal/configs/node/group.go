@@ -25,6 +25,7 @@ const (
 	GroupIDBeaconClient      GroupID = "%s/0.0.1/client/beacon"
 	GroupIDShardPrefix       GroupID = "%s/0.0.1/node/shard/%s"
 	GroupIDShardClientPrefix GroupID = "%s/0.0.1/client/shard/%s"
+	GroupIDAggregatorPrefix  GroupID = "%s/0.0.1/node/shard/%s/aggregator/%s"
 	GroupIDGlobal            GroupID = "%s/0.0.1/node/global"
 	GroupIDGlobalClient      GroupID = "%s/0.0.1/node/global"
 	GroupIDUnknown           GroupID = "%s/B1acKh0lE"
@@ -71,6 +72,14 @@ func NewClientGroupIDByShardID(shardID ShardID) GroupID {
 	return GroupID(fmt.Sprintf(GroupIDShardClientPrefix.String(), getNetworkPrefix(shardID), strconv.Itoa(int(shardID))))
 }
 
+// NewAggregatorGroupID returns a new groupID for the votes aggregated by the
+// given hex encoded bls key of a shard
+func NewAggregatorGroupID(shardID ShardID, aggregator string) GroupID {
+	return GroupID(fmt.Sprintf(
+		GroupIDAggregatorPrefix.String(), getNetworkPrefix(shardID), strconv.Itoa(int(shardID)), aggregator,
+	))
+}
+
 // ActionType lists action on group
 type ActionType uint
 
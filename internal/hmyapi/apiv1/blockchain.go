@@ -25,7 +25,9 @@ import (
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/shard/committee"
+	"github.com/harmony-one/harmony/staking/apr"
 	"github.com/harmony-one/harmony/staking/network"
+	"github.com/harmony-one/harmony/staking/slash"
 	staking "github.com/harmony-one/harmony/staking/types"
 	"github.com/pkg/errors"
 )
@@ -523,6 +525,7 @@ func (s *PublicBlockChainAPI) LatestHeader(ctx context.Context) *HeaderInformati
 
 var (
 	errNotBeaconChainShard = errors.New("cannot call this rpc on non beaconchain node")
+	errSlashRateOutOfRange = errors.New("slash rate must be between 0 and 1")
 )
 
 // GetTotalStaking returns total staking by validators, only meant to be called on beaconchain
@@ -596,6 +599,85 @@ func (s *PublicBlockChainAPI) GetValidatorInformationByBlockNumber(
 	)
 }
 
+// GetValidatorAvailabilityHistory returns how the validator signed in each
+// epoch, whether elected or not, oldest epoch first.
+func (s *PublicBlockChainAPI) GetValidatorAvailabilityHistory(
+	ctx context.Context, address string,
+) (staking.AvailabilityHistory, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return nil, errNotBeaconChainShard
+	}
+	return s.b.GetValidatorAvailabilityHistory(internal_common.ParseAddr(address))
+}
+
+// GetValidatorAPRHistory returns the apr of the validator at the end of
+// each epoch, oldest epoch first.
+func (s *PublicBlockChainAPI) GetValidatorAPRHistory(
+	ctx context.Context, address string,
+) (staking.APRHistory, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return nil, errNotBeaconChainShard
+	}
+	return s.b.GetValidatorAPRHistory(internal_common.ParseAddr(address))
+}
+
+// GetNetworkAverageAPR returns the apr of the elected validators,
+// weighted by their total delegation.
+func (s *PublicBlockChainAPI) GetNetworkAverageAPR(
+	ctx context.Context,
+) (numeric.Dec, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return numeric.ZeroDec(), errNotBeaconChainShard
+	}
+	return s.b.GetNetworkAverageAPR()
+}
+
+// GetDelegationAPRProjection estimates the yearly return of delegating
+// amount to the validator.
+func (s *PublicBlockChainAPI) GetDelegationAPRProjection(
+	ctx context.Context, validator string, amount *hexutil.Big,
+) (*apr.Projection, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return nil, errNotBeaconChainShard
+	}
+	if amount == nil {
+		return nil, errors.New("amount to delegate is required")
+	}
+	return s.b.GetDelegationAPRProjection(
+		internal_common.ParseAddr(validator), amount.ToInt(),
+	)
+}
+
+// SimulateSlash returns what slashing the validator for a double sign in the
+// current epoch would take from each of its delegators, or only from the
+// delegator when given. The rate defaults to the rate of one double signer.
+func (s *PublicBlockChainAPI) SimulateSlash(
+	ctx context.Context, validator string, delegator *string, rate *string,
+) (*slash.Simulation, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return nil, errNotBeaconChainShard
+	}
+	var delegatorAddress *common.Address
+	if delegator != nil {
+		addr := internal_common.ParseAddr(*delegator)
+		delegatorAddress = &addr
+	}
+	slashRate := numeric.ZeroDec()
+	if rate != nil {
+		r, err := numeric.NewDecFromStr(*rate)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid slash rate %s", *rate)
+		}
+		if r.IsNegative() || r.GT(numeric.OneDec()) {
+			return nil, errors.Wrapf(errSlashRateOutOfRange, "given %s", *rate)
+		}
+		slashRate = r
+	}
+	return s.b.SimulateSlash(
+		internal_common.ParseAddr(validator), delegatorAddress, slashRate,
+	)
+}
+
 func (s *PublicBlockChainAPI) getAllValidatorInformation(
 	ctx context.Context, page int, blockNr rpc.BlockNumber,
 ) ([]*staking.ValidatorRPCEnchanced, error) {
@@ -861,6 +943,24 @@ func (s *PublicBlockChainAPI) GetCirculatingSupply() (numeric.Dec, error) {
 	return numeric.NewDec(initSupply).Mul(reward.PercentageForTimeStamp(timestamp.Unix())), nil
 }
 
+// GetElectionDryRun runs the election of the next committee against the
+// current state without electing anyone, with extraStake added to the
+// stake of the validator when given, and returns the slots, effective stake
+// and stake needed to win of each candidate.
+func (s *PublicBlockChainAPI) GetElectionDryRun(
+	ctx context.Context, validator *string, extraStake *hexutil.Big,
+) (*committee.DryRunEPoSRound, error) {
+	if s.b.GetShardID() != shard.BeaconChainShardID {
+		return nil, errNotBeaconChainShard
+	}
+	var validatorAddress *common.Address
+	if validator != nil {
+		addr := internal_common.ParseAddr(*validator)
+		validatorAddress = &addr
+	}
+	return s.b.GetElectionDryRun(validatorAddress, extraStake.ToInt())
+}
+
 // GetStakingNetworkInfo ..
 func (s *PublicBlockChainAPI) GetStakingNetworkInfo(
 	ctx context.Context,
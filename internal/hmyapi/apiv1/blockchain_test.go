@@ -0,0 +1,53 @@
+package apiv1
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/staking/slash"
+	"github.com/pkg/errors"
+)
+
+// beaconBackend is a beacon chain backend simulating slashes only
+type beaconBackend struct {
+	Backend
+	rate numeric.Dec
+}
+
+func (b *beaconBackend) GetShardID() uint32 {
+	return shard.BeaconChainShardID
+}
+
+func (b *beaconBackend) SimulateSlash(
+	validator common.Address, delegator *common.Address, rate numeric.Dec,
+) (*slash.Simulation, error) {
+	b.rate = rate
+	return &slash.Simulation{}, nil
+}
+
+func TestSimulateSlashRate(t *testing.T) {
+	backend := &beaconBackend{}
+	api := NewPublicBlockChainAPI(backend)
+	validator := "one1pdv9lrdwl0rg5vglh4xtyrv3wjk3wsqket7zxy"
+
+	for _, rate := range []string{"-0.1", "1.000000000000000001", "2"} {
+		if _, err := api.SimulateSlash(
+			context.Background(), validator, nil, &rate,
+		); errors.Cause(err) != errSlashRateOutOfRange {
+			t.Errorf("rate %s: expected %v, got %v", rate, errSlashRateOutOfRange, err)
+		}
+	}
+	for _, rate := range []string{"0", "0.5", "1"} {
+		if _, err := api.SimulateSlash(
+			context.Background(), validator, nil, &rate,
+		); err != nil {
+			t.Errorf("rate %s: expected no error, got %v", rate, err)
+		}
+		if !backend.rate.Equal(numeric.MustNewDecFromStr(rate)) {
+			t.Errorf("expected rate %s simulated, got %s", rate, backend.rate)
+		}
+	}
+}
@@ -299,6 +299,87 @@ func newRPCStakingTransaction(tx *types2.StakingTransaction, blockHash common.Ha
 			"validatorAddress": validatorAddress,
 			"amount":           msg.Amount,
 		}
+	case types2.DirectiveRedelegate:
+		msg, ok := message.(types2.Redelegate)
+		if !ok {
+			return nil
+		}
+		delegatorAddress, err := internal_common.AddressToBech32(msg.DelegatorAddress)
+		if err != nil {
+			return nil
+		}
+		fromValidatorAddress, err := internal_common.AddressToBech32(msg.FromValidatorAddress)
+		if err != nil {
+			return nil
+		}
+		toValidatorAddress, err := internal_common.AddressToBech32(msg.ToValidatorAddress)
+		if err != nil {
+			return nil
+		}
+		fields = map[string]interface{}{
+			"delegatorAddress":     delegatorAddress,
+			"fromValidatorAddress": fromValidatorAddress,
+			"toValidatorAddress":   toValidatorAddress,
+			"amount":               (*hexutil.Big)(msg.Amount),
+		}
+	case types2.DirectiveSetAutoCompound:
+		msg, ok := message.(types2.SetAutoCompound)
+		if !ok {
+			return nil
+		}
+		delegatorAddress, err := internal_common.AddressToBech32(msg.DelegatorAddress)
+		if err != nil {
+			return nil
+		}
+		validatorAddress, err := internal_common.AddressToBech32(msg.ValidatorAddress)
+		if err != nil {
+			return nil
+		}
+		fields = map[string]interface{}{
+			"delegatorAddress": delegatorAddress,
+			"validatorAddress": validatorAddress,
+			"enabled":          msg.Enabled,
+		}
+	case types2.DirectiveBatch:
+		msg, ok := message.(types2.Batch)
+		if !ok {
+			return nil
+		}
+		delegatorAddress, err := internal_common.AddressToBech32(msg.DelegatorAddress)
+		if err != nil {
+			return nil
+		}
+		operations := make([]map[string]interface{}, len(msg.Operations))
+		for i, op := range msg.Operations {
+			validatorAddress, err := internal_common.AddressToBech32(op.ValidatorAddress)
+			if err != nil {
+				return nil
+			}
+			operations[i] = map[string]interface{}{
+				"type":             op.Directive.String(),
+				"validatorAddress": validatorAddress,
+				"amount":           (*hexutil.Big)(op.Amount),
+			}
+		}
+		fields = map[string]interface{}{
+			"delegatorAddress": delegatorAddress,
+			"operations":       operations,
+		}
+	case types2.DirectiveRotateKey:
+		msg, ok := message.(types2.RotateKey)
+		if !ok {
+			return nil
+		}
+		validatorAddress, err := internal_common.AddressToBech32(msg.ValidatorAddress)
+		if err != nil {
+			return nil
+		}
+		fields = map[string]interface{}{
+			"validatorAddress": validatorAddress,
+			"slotKeyToRemove":  msg.SlotKeyToRemove,
+			"slotKeyToAdd":     msg.SlotKeyToAdd,
+			"epoch":            (*hexutil.Big)(msg.Epoch),
+		}
 	case types2.DirectiveUndelegate:
 		msg, ok := message.(types2.Undelegate)
 		if !ok {
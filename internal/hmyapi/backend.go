@@ -18,9 +18,12 @@ import (
 	"github.com/harmony-one/harmony/internal/hmyapi/apiv2"
 	commonRPC "github.com/harmony-one/harmony/internal/hmyapi/common"
 	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/shard/committee"
+	"github.com/harmony-one/harmony/staking/apr"
 	"github.com/harmony-one/harmony/staking/network"
+	"github.com/harmony-one/harmony/staking/slash"
 	staking "github.com/harmony-one/harmony/staking/types"
 )
 
@@ -59,6 +62,11 @@ type Backend interface {
 	GetElectedValidatorAddresses() []common.Address
 	GetAllValidatorAddresses() []common.Address
 	GetValidatorInformation(addr common.Address, block *types.Block) (*staking.ValidatorRPCEnchanced, error)
+	GetValidatorAvailabilityHistory(addr common.Address) (staking.AvailabilityHistory, error)
+	GetValidatorAPRHistory(addr common.Address) (staking.APRHistory, error)
+	GetNetworkAverageAPR() (numeric.Dec, error)
+	GetDelegationAPRProjection(validator common.Address, amount *big.Int) (*apr.Projection, error)
+	SimulateSlash(validator common.Address, delegator *common.Address, rate numeric.Dec) (*slash.Simulation, error)
 	GetDelegationsByValidator(validator common.Address) []*staking.Delegation
 	GetDelegationsByDelegator(delegator common.Address) ([]common.Address, []*staking.Delegation)
 	GetValidatorSelfDelegation(addr common.Address) *big.Int
@@ -66,6 +74,7 @@ type Backend interface {
 	GetCurrentStakingErrorSink() []staking.RPCTransactionError
 	GetCurrentTransactionErrorSink() []types.RPCTransactionError
 	GetMedianRawStakeSnapshot() (*committee.CompletedEPoSRound, error)
+	GetElectionDryRun(validator *common.Address, extraStake *big.Int) (*committee.DryRunEPoSRound, error)
 	GetPendingCXReceipts() []*types.CXReceiptsProof
 	GetCurrentUtilityMetrics() (*network.UtilityMetric, error)
 	GetSuperCommittees() (*quorum.Transition, error)
@@ -25,77 +25,125 @@ var EpochTBD = big.NewInt(10000000)
 var (
 	// MainnetChainConfig is the chain parameters to run a node on the main network.
 	MainnetChainConfig = &ChainConfig{
-		ChainID:         MainnetChainID,
-		CrossTxEpoch:    big.NewInt(28),
-		CrossLinkEpoch:  EpochTBD,
-		StakingEpoch:    EpochTBD,
-		PreStakingEpoch: EpochTBD,
-		EIP155Epoch:     big.NewInt(28),
-		S3Epoch:         big.NewInt(28),
-		ReceiptLogEpoch: big.NewInt(101),
+		ChainID:                 MainnetChainID,
+		CrossTxEpoch:            big.NewInt(28),
+		CrossLinkEpoch:          EpochTBD,
+		StakingEpoch:            EpochTBD,
+		PreStakingEpoch:         EpochTBD,
+		EIP155Epoch:             big.NewInt(28),
+		S3Epoch:                 big.NewInt(28),
+		ReceiptLogEpoch:         big.NewInt(101),
+		LeaderRotationEpoch:     EpochTBD,
+		RedelegationEpoch:       EpochTBD,
+		AutoCompoundEpoch:       EpochTBD,
+		BatchStakingEpoch:       EpochTBD,
+		StakingPrecompileEpoch:  EpochTBD,
+		KeyRotationEpoch:        EpochTBD,
+		CommissionScheduleEpoch: EpochTBD,
+		SignedEvidenceEpoch:     EpochTBD,
 	}
 
 	// TestnetChainConfig contains the chain parameters to run a node on the harmony test network.
 	TestnetChainConfig = &ChainConfig{
-		ChainID:         TestnetChainID,
-		CrossTxEpoch:    big.NewInt(0),
-		CrossLinkEpoch:  big.NewInt(4),
-		StakingEpoch:    big.NewInt(4),
-		PreStakingEpoch: big.NewInt(2),
-		EIP155Epoch:     big.NewInt(0),
-		S3Epoch:         big.NewInt(0),
-		ReceiptLogEpoch: big.NewInt(0),
+		ChainID:                 TestnetChainID,
+		CrossTxEpoch:            big.NewInt(0),
+		CrossLinkEpoch:          big.NewInt(4),
+		StakingEpoch:            big.NewInt(4),
+		PreStakingEpoch:         big.NewInt(2),
+		EIP155Epoch:             big.NewInt(0),
+		S3Epoch:                 big.NewInt(0),
+		ReceiptLogEpoch:         big.NewInt(0),
+		LeaderRotationEpoch:     EpochTBD,
+		RedelegationEpoch:       EpochTBD,
+		AutoCompoundEpoch:       EpochTBD,
+		BatchStakingEpoch:       EpochTBD,
+		StakingPrecompileEpoch:  EpochTBD,
+		KeyRotationEpoch:        EpochTBD,
+		CommissionScheduleEpoch: EpochTBD,
+		SignedEvidenceEpoch:     EpochTBD,
 	}
 
 	// PangaeaChainConfig contains the chain parameters for the Pangaea network.
 	// All features except for CrossLink are enabled at launch.
 	PangaeaChainConfig = &ChainConfig{
-		ChainID:         PangaeaChainID,
-		CrossTxEpoch:    big.NewInt(0),
-		CrossLinkEpoch:  big.NewInt(2),
-		StakingEpoch:    big.NewInt(2),
-		PreStakingEpoch: big.NewInt(1),
-		EIP155Epoch:     big.NewInt(0),
-		S3Epoch:         big.NewInt(0),
-		ReceiptLogEpoch: big.NewInt(0),
+		ChainID:                 PangaeaChainID,
+		CrossTxEpoch:            big.NewInt(0),
+		CrossLinkEpoch:          big.NewInt(2),
+		StakingEpoch:            big.NewInt(2),
+		PreStakingEpoch:         big.NewInt(1),
+		EIP155Epoch:             big.NewInt(0),
+		S3Epoch:                 big.NewInt(0),
+		ReceiptLogEpoch:         big.NewInt(0),
+		LeaderRotationEpoch:     EpochTBD,
+		RedelegationEpoch:       EpochTBD,
+		AutoCompoundEpoch:       EpochTBD,
+		BatchStakingEpoch:       EpochTBD,
+		StakingPrecompileEpoch:  EpochTBD,
+		KeyRotationEpoch:        EpochTBD,
+		CommissionScheduleEpoch: EpochTBD,
+		SignedEvidenceEpoch:     EpochTBD,
 	}
 
 	// PartnerChainConfig contains the chain parameters for the Partner network.
 	// All features except for CrossLink are enabled at launch.
 	PartnerChainConfig = &ChainConfig{
-		ChainID:         PartnerChainID,
-		CrossTxEpoch:    big.NewInt(0),
-		CrossLinkEpoch:  big.NewInt(2),
-		StakingEpoch:    big.NewInt(2),
-		PreStakingEpoch: big.NewInt(1),
-		EIP155Epoch:     big.NewInt(0),
-		S3Epoch:         big.NewInt(0),
-		ReceiptLogEpoch: big.NewInt(0),
+		ChainID:                 PartnerChainID,
+		CrossTxEpoch:            big.NewInt(0),
+		CrossLinkEpoch:          big.NewInt(2),
+		StakingEpoch:            big.NewInt(2),
+		PreStakingEpoch:         big.NewInt(1),
+		EIP155Epoch:             big.NewInt(0),
+		S3Epoch:                 big.NewInt(0),
+		ReceiptLogEpoch:         big.NewInt(0),
+		LeaderRotationEpoch:     EpochTBD,
+		RedelegationEpoch:       EpochTBD,
+		AutoCompoundEpoch:       EpochTBD,
+		BatchStakingEpoch:       EpochTBD,
+		StakingPrecompileEpoch:  EpochTBD,
+		KeyRotationEpoch:        EpochTBD,
+		CommissionScheduleEpoch: EpochTBD,
+		SignedEvidenceEpoch:     EpochTBD,
 	}
 
 	// StressnetChainConfig contains the chain parameters for the Stress test network.
 	// All features except for CrossLink are enabled at launch.
 	StressnetChainConfig = &ChainConfig{
-		ChainID:         StressnetChainID,
-		CrossTxEpoch:    big.NewInt(0),
-		CrossLinkEpoch:  big.NewInt(2),
-		StakingEpoch:    big.NewInt(2),
-		PreStakingEpoch: big.NewInt(1),
-		EIP155Epoch:     big.NewInt(0),
-		S3Epoch:         big.NewInt(0),
-		ReceiptLogEpoch: big.NewInt(0),
+		ChainID:                 StressnetChainID,
+		CrossTxEpoch:            big.NewInt(0),
+		CrossLinkEpoch:          big.NewInt(2),
+		StakingEpoch:            big.NewInt(2),
+		PreStakingEpoch:         big.NewInt(1),
+		EIP155Epoch:             big.NewInt(0),
+		S3Epoch:                 big.NewInt(0),
+		ReceiptLogEpoch:         big.NewInt(0),
+		LeaderRotationEpoch:     EpochTBD,
+		RedelegationEpoch:       EpochTBD,
+		AutoCompoundEpoch:       EpochTBD,
+		BatchStakingEpoch:       EpochTBD,
+		StakingPrecompileEpoch:  EpochTBD,
+		KeyRotationEpoch:        EpochTBD,
+		CommissionScheduleEpoch: EpochTBD,
+		SignedEvidenceEpoch:     EpochTBD,
 	}
 
 	// LocalnetChainConfig contains the chain parameters to run for local development.
 	LocalnetChainConfig = &ChainConfig{
-		ChainID:         TestnetChainID,
-		CrossTxEpoch:    big.NewInt(0),
-		CrossLinkEpoch:  big.NewInt(2),
-		StakingEpoch:    big.NewInt(2),
-		PreStakingEpoch: big.NewInt(0),
-		EIP155Epoch:     big.NewInt(0),
-		S3Epoch:         big.NewInt(0),
-		ReceiptLogEpoch: big.NewInt(0),
+		ChainID:                 TestnetChainID,
+		CrossTxEpoch:            big.NewInt(0),
+		CrossLinkEpoch:          big.NewInt(2),
+		StakingEpoch:            big.NewInt(2),
+		PreStakingEpoch:         big.NewInt(0),
+		EIP155Epoch:             big.NewInt(0),
+		S3Epoch:                 big.NewInt(0),
+		ReceiptLogEpoch:         big.NewInt(0),
+		LeaderRotationEpoch:     big.NewInt(0),
+		RedelegationEpoch:       big.NewInt(0),
+		AutoCompoundEpoch:       big.NewInt(0),
+		BatchStakingEpoch:       big.NewInt(0),
+		StakingPrecompileEpoch:  big.NewInt(0),
+		KeyRotationEpoch:        big.NewInt(0),
+		CommissionScheduleEpoch: big.NewInt(0),
+		SignedEvidenceEpoch:     big.NewInt(0),
 	}
 
 	// AllProtocolChanges ...
@@ -110,6 +158,14 @@ var (
 		big.NewInt(0),             // EIP155Epoch
 		big.NewInt(0),             // S3Epoch
 		big.NewInt(0),             // ReceiptLogEpoch
+		big.NewInt(0),             // LeaderRotationEpoch
+		big.NewInt(0),             // RedelegationEpoch
+		big.NewInt(0),             // AutoCompoundEpoch
+		big.NewInt(0),             // BatchStakingEpoch
+		big.NewInt(0),             // StakingPrecompileEpoch
+		big.NewInt(0),             // KeyRotationEpoch
+		big.NewInt(0),             // CommissionScheduleEpoch
+		big.NewInt(0),             // SignedEvidenceEpoch
 	}
 
 	// TestChainConfig ...
@@ -124,6 +180,14 @@ var (
 		big.NewInt(0), // EIP155Epoch
 		big.NewInt(0), // S3Epoch
 		big.NewInt(0), // ReceiptLogEpoch
+		big.NewInt(0), // LeaderRotationEpoch
+		big.NewInt(0), // RedelegationEpoch
+		big.NewInt(0), // AutoCompoundEpoch
+		big.NewInt(0), // BatchStakingEpoch
+		big.NewInt(0), // StakingPrecompileEpoch
+		big.NewInt(0), // KeyRotationEpoch
+		big.NewInt(0), // CommissionScheduleEpoch
+		big.NewInt(0), // SignedEvidenceEpoch
 	}
 
 	// TestRules ...
@@ -173,6 +237,35 @@ type ChainConfig struct {
 
 	// ReceiptLogEpoch is the first epoch support receiptlog
 	ReceiptLogEpoch *big.Int `json:"receipt-log-epoch,omitempty"`
+
+	// LeaderRotationEpoch is the first epoch where the leader rotates
+	// within the epoch on a schedule weighted by voting power
+	LeaderRotationEpoch *big.Int `json:"leader-rotation-epoch,omitempty"`
+
+	// RedelegationEpoch is the first epoch accepting the redelegate staking directive
+	RedelegationEpoch *big.Int `json:"redelegation-epoch,omitempty"`
+
+	// AutoCompoundEpoch is the first epoch where delegators can opt in to have
+	// their rewards added to their delegation at each epoch boundary
+	AutoCompoundEpoch *big.Int `json:"auto-compound-epoch,omitempty"`
+
+	// BatchStakingEpoch is the first epoch accepting the batch staking directive
+	BatchStakingEpoch *big.Int `json:"batch-staking-epoch,omitempty"`
+
+	// StakingPrecompileEpoch is the first epoch where smart contracts
+	// can stake through the staking precompile
+	StakingPrecompileEpoch *big.Int `json:"staking-precompile-epoch,omitempty"`
+
+	// KeyRotationEpoch is the first epoch accepting the rotate key staking directive
+	KeyRotationEpoch *big.Int `json:"key-rotation-epoch,omitempty"`
+
+	// CommissionScheduleEpoch is the first epoch where commission rate edits
+	// are queued to take effect at a later epoch instead of immediately
+	CommissionScheduleEpoch *big.Int `json:"commission-schedule-epoch,omitempty"`
+
+	// SignedEvidenceEpoch is the first epoch accepting slash evidence which carries
+	// the signed messages of a prepare or view change double sign
+	SignedEvidenceEpoch *big.Int `json:"signed-evidence-epoch,omitempty"`
 }
 
 // String implements the fmt.Stringer interface.
@@ -237,6 +330,46 @@ func (c *ChainConfig) IsReceiptLog(epoch *big.Int) bool {
 	return isForked(c.ReceiptLogEpoch, epoch)
 }
 
+// IsLeaderRotation returns whether epoch is either equal to the LeaderRotation fork epoch or greater.
+func (c *ChainConfig) IsLeaderRotation(epoch *big.Int) bool {
+	return isForked(c.LeaderRotationEpoch, epoch)
+}
+
+// IsRedelegation returns whether epoch is either equal to the Redelegation fork epoch or greater.
+func (c *ChainConfig) IsRedelegation(epoch *big.Int) bool {
+	return isForked(c.RedelegationEpoch, epoch)
+}
+
+// IsAutoCompound returns whether epoch is either equal to the AutoCompound fork epoch or greater.
+func (c *ChainConfig) IsAutoCompound(epoch *big.Int) bool {
+	return isForked(c.AutoCompoundEpoch, epoch)
+}
+
+// IsBatchStaking returns whether epoch is either equal to the BatchStaking fork epoch or greater.
+func (c *ChainConfig) IsBatchStaking(epoch *big.Int) bool {
+	return isForked(c.BatchStakingEpoch, epoch)
+}
+
+// IsStakingPrecompile returns whether epoch is either equal to the StakingPrecompile fork epoch or greater.
+func (c *ChainConfig) IsStakingPrecompile(epoch *big.Int) bool {
+	return isForked(c.StakingPrecompileEpoch, epoch)
+}
+
+// IsKeyRotation returns whether epoch is either equal to the KeyRotation fork epoch or greater.
+func (c *ChainConfig) IsKeyRotation(epoch *big.Int) bool {
+	return isForked(c.KeyRotationEpoch, epoch)
+}
+
+// IsCommissionSchedule returns whether epoch is either equal to the CommissionSchedule fork epoch or greater.
+func (c *ChainConfig) IsCommissionSchedule(epoch *big.Int) bool {
+	return isForked(c.CommissionScheduleEpoch, epoch)
+}
+
+// IsSignedEvidence returns whether epoch is either equal to the SignedEvidence fork epoch or greater.
+func (c *ChainConfig) IsSignedEvidence(epoch *big.Int) bool {
+	return isForked(c.SignedEvidenceEpoch, epoch)
+}
+
 // GasTable returns the gas table corresponding to the current phase (homestead or homestead reprice).
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
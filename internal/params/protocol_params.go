@@ -25,6 +25,10 @@ const (
 	TxGasContractCreation uint64 = 53000 // Per transaction that creates a contract. NOTE: Not payable on data of calls between transactions.
 	// TxGasValidatorCreation ...
 	TxGasValidatorCreation uint64 = 5300000 // Per transaction that creates a new validator. NOTE: Not payable on data of calls between transactions.
+	// TxGasBatchOperation ...
+	TxGasBatchOperation uint64 = 10000 // Per delegate or undelegate operation of a batch staking transaction, on top of TxGas.
+	// StakingPrecompileGas ...
+	StakingPrecompileGas uint64 = 21000 // Per delegate, undelegate or collect rewards through the staking precompile, as much as a staking transaction.
 	// TxDataZeroGas ...
 	TxDataZeroGas uint64 = 4 // Per byte of data attached to a transaction that equals zero. NOTE: Not payable on data of calls between transactions.
 	// QuadCoeffDiv ...
@@ -0,0 +1,324 @@
+package shardchain
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// badgerGCInterval is how often the space of the overwritten and deleted
+	// values is reclaimed from the value log.
+	badgerGCInterval = 10 * time.Minute
+	// badgerGCDiscardRatio is the ratio of stale data above which a value log
+	// file is rewritten.
+	badgerGCDiscardRatio = 0.5
+)
+
+// errNotFound is returned by Get if the key is not in the database, as for LDB.
+var errNotFound = errors.New("not found")
+
+// BadgerDatabase is a Badger-backed ethdb.Database.
+type BadgerDatabase struct {
+	dir  string
+	db   *badger.DB
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewBadgerDatabase opens the Badger database in dir, creating it if missing.
+func NewBadgerDatabase(dir string) (*BadgerDatabase, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	opts := badger.DefaultOptions(dir).
+		WithLogger(badgerLogger{}).
+		// drop the partial writes of a crash instead of failing to open
+		WithTruncate(true)
+	db, err := badger.Open(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "cannot open badger database in %s", dir)
+	}
+	bdb := &BadgerDatabase{dir: dir, db: db, quit: make(chan struct{})}
+	bdb.wg.Add(1)
+	go bdb.collectGarbage()
+	return bdb, nil
+}
+
+// Path returns the directory of the database.
+func (db *BadgerDatabase) Path() string {
+	return db.dir
+}
+
+// Put sets the value of the given key.
+func (db *BadgerDatabase) Put(key []byte, value []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(common.CopyBytes(key), common.CopyBytes(value))
+	})
+}
+
+// Has returns whether the database has the given key.
+func (db *BadgerDatabase) Has(key []byte) (bool, error) {
+	err := db.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(key)
+		return err
+	})
+	switch err {
+	case nil:
+		return true, nil
+	case badger.ErrKeyNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// Get returns the value of the given key.
+func (db *BadgerDatabase) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if err == badger.ErrKeyNotFound {
+		return nil, errNotFound
+	}
+	return value, err
+}
+
+// Delete removes the given key.
+func (db *BadgerDatabase) Delete(key []byte) error {
+	return db.db.Update(func(txn *badger.Txn) error {
+		return txn.Delete(common.CopyBytes(key))
+	})
+}
+
+// NewIterator returns an iterator over all the keys of the database, in order.
+// The iterator can only go forward.
+func (db *BadgerDatabase) NewIterator() iterator.Iterator {
+	txn := db.db.NewTransaction(false)
+	return &badgerIterator{txn: txn, it: txn.NewIterator(badger.DefaultIteratorOptions)}
+}
+
+// Close stops the garbage collection and closes the database.
+func (db *BadgerDatabase) Close() {
+	close(db.quit)
+	db.wg.Wait()
+	if err := db.db.Close(); err != nil {
+		utils.Logger().Error().Err(err).Str("dir", db.dir).Msg("Failed to close badger database")
+	}
+}
+
+// NewBatch returns a batch of writes to the database.
+func (db *BadgerDatabase) NewBatch() ethdb.Batch {
+	return &badgerBatch{db: db.db}
+}
+
+// collectGarbage reclaims the space of the stale values until the database is
+// closed.
+func (db *BadgerDatabase) collectGarbage() {
+	defer db.wg.Done()
+	for {
+		select {
+		case <-db.quit:
+			return
+		case <-time.After(badgerGCInterval):
+		}
+		// rewrite value log files until none has enough stale data
+		for db.db.RunValueLogGC(badgerGCDiscardRatio) == nil {
+		}
+	}
+}
+
+type badgerWrite struct {
+	key, value []byte
+	delete     bool
+}
+
+// badgerBatch buffers the writes until Write, which commits them in a single
+// transaction. Its size counts the keys as well, as they count towards the
+// size limit of a transaction.
+type badgerBatch struct {
+	db     *badger.DB
+	writes []badgerWrite
+	size   int
+}
+
+func (b *badgerBatch) Put(key, value []byte) error {
+	b.writes = append(b.writes, badgerWrite{key: common.CopyBytes(key), value: common.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+func (b *badgerBatch) Delete(key []byte) error {
+	b.writes = append(b.writes, badgerWrite{key: common.CopyBytes(key), delete: true})
+	b.size += len(key)
+	return nil
+}
+
+func (b *badgerBatch) ValueSize() int {
+	return b.size
+}
+
+// Write commits all the writes of the batch or none of them, a batch too big
+// for a transaction is refused.
+func (b *badgerBatch) Write() error {
+	txn := b.db.NewTransaction(true)
+	defer txn.Discard()
+	for _, w := range b.writes {
+		var err error
+		if w.delete {
+			err = txn.Delete(w.key)
+		} else {
+			err = txn.Set(w.key, w.value)
+		}
+		if err == badger.ErrTxnTooBig {
+			return errors.Wrapf(err, "batch of %d writes (%d bytes)", len(b.writes), b.size)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+func (b *badgerBatch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// badgerIterator is a forward only goleveldb iterator over a Badger read
+// transaction, which is positioned before the first key when created.
+type badgerIterator struct {
+	txn      *badger.Txn
+	it       *badger.Iterator
+	started  bool
+	value    []byte
+	err      error
+	released bool
+	releaser util.Releaser
+}
+
+var errBackwardIteration = errors.New("badger iterator cannot go backward")
+
+func (i *badgerIterator) First() bool {
+	if i.released {
+		return false
+	}
+	i.started = true
+	i.it.Rewind()
+	return i.load()
+}
+
+func (i *badgerIterator) Last() bool {
+	i.err = errBackwardIteration
+	return false
+}
+
+func (i *badgerIterator) Seek(key []byte) bool {
+	if i.released {
+		return false
+	}
+	i.started = true
+	i.it.Seek(key)
+	return i.load()
+}
+
+func (i *badgerIterator) Next() bool {
+	if i.released {
+		return false
+	}
+	if !i.started {
+		return i.First()
+	}
+	if i.it.Valid() {
+		i.it.Next()
+	}
+	return i.load()
+}
+
+func (i *badgerIterator) Prev() bool {
+	i.err = errBackwardIteration
+	return false
+}
+
+// load reads the value at the position of the iterator.
+func (i *badgerIterator) load() bool {
+	i.value = nil
+	if i.err != nil || !i.it.Valid() {
+		return false
+	}
+	if i.value, i.err = i.it.Item().ValueCopy(nil); i.err != nil {
+		return false
+	}
+	return true
+}
+
+func (i *badgerIterator) Valid() bool {
+	return !i.released && i.started && i.err == nil && i.it.Valid()
+}
+
+func (i *badgerIterator) Key() []byte {
+	if !i.Valid() {
+		return nil
+	}
+	return i.it.Item().Key()
+}
+
+func (i *badgerIterator) Value() []byte {
+	if !i.Valid() {
+		return nil
+	}
+	return i.value
+}
+
+func (i *badgerIterator) Error() error {
+	return i.err
+}
+
+func (i *badgerIterator) Release() {
+	if i.released {
+		return
+	}
+	i.released = true
+	i.it.Close()
+	i.txn.Discard()
+	if i.releaser != nil {
+		i.releaser.Release()
+	}
+}
+
+func (i *badgerIterator) SetReleaser(releaser util.Releaser) {
+	i.releaser = releaser
+}
+
+// badgerLogger sends the logs of Badger to the harmony logger.
+type badgerLogger struct{}
+
+func (badgerLogger) Errorf(format string, args ...interface{}) {
+	utils.Logger().Error().Msgf("badger: "+format, args...)
+}
+
+func (badgerLogger) Warningf(format string, args ...interface{}) {
+	utils.Logger().Warn().Msgf("badger: "+format, args...)
+}
+
+func (badgerLogger) Infof(format string, args ...interface{}) {
+	utils.Logger().Info().Msgf("badger: "+format, args...)
+}
+
+func (badgerLogger) Debugf(format string, args ...interface{}) {
+	utils.Logger().Debug().Msgf("badger: "+format, args...)
+}
@@ -0,0 +1,113 @@
+package shardchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/dgraph-io/badger"
+	"github.com/pkg/errors"
+)
+
+func TestBadgerDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewBadgerDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := db.Put([]byte("b"), []byte{2}); err != nil {
+		t.Fatal(err)
+	}
+	batch := db.NewBatch()
+	batch.Put([]byte("a"), []byte{1})
+	batch.Put([]byte("c"), []byte{})
+	batch.Delete([]byte("b"))
+	if ok, _ := db.Has([]byte("a")); ok {
+		t.Error("batch written before Write")
+	}
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := db.Get([]byte("a")); err != nil || !bytes.Equal(value, []byte{1}) {
+		t.Errorf("value of a mismatch: have %x (%v), want 01", value, err)
+	}
+	if ok, err := db.Has([]byte("c")); !ok || err != nil {
+		t.Errorf("empty value not found: %v", err)
+	}
+	if _, err := db.Get([]byte("b")); err == nil {
+		t.Error("deleted key found")
+	}
+
+	it := db.NewIterator()
+	defer it.Release()
+	keys := []string{}
+	for it.Next() {
+		keys = append(keys, string(it.Key()))
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("iterated keys mismatch: have %v, want [a c]", keys)
+	}
+	if !it.Seek([]byte("b")) || string(it.Key()) != "c" {
+		t.Errorf("seek mismatch: have %s", it.Key())
+	}
+}
+
+func TestCheckBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewBadgerDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if err := checkBackend(dir, BadgerBackend); err != nil {
+		t.Errorf("badger database refused: %v", err)
+	}
+	if err := checkBackend(dir, LDBBackend); err == nil {
+		t.Error("badger database opened as leveldb")
+	}
+}
+
+func TestBadgerBatchTooBig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := NewBadgerDatabase(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// more keys than a transaction takes
+	batch := db.NewBatch()
+	key := make([]byte, 1024)
+	for i := 0; i < 16*1024; i++ {
+		binary.BigEndian.PutUint32(key, uint32(i))
+		batch.Put(key, []byte{1})
+	}
+	if err := batch.Write(); errors.Cause(err) != badger.ErrTxnTooBig {
+		t.Fatalf("batch too big not refused: %v", err)
+	}
+	it := db.NewIterator()
+	defer it.Release()
+	if it.Next() {
+		t.Errorf("key %x of a refused batch written", it.Key()[:4])
+	}
+}
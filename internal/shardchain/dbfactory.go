@@ -2,11 +2,31 @@ package shardchain
 
 import (
 	"fmt"
+	"os"
 	"path"
+	"strings"
 
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/pkg/errors"
 )
 
+// The storage engines the shard databases can be kept in.
+const (
+	LDBBackend    = "leveldb"
+	BadgerBackend = "badger"
+)
+
+// DBBackends are the storage engines the shard databases can be kept in.
+var DBBackends = []string{LDBBackend, BadgerBackend}
+
+// backendMarkers are files which only a database of the given engine has in its
+// directory, used to refuse opening a database with another engine.
+var backendMarkers = map[string]string{
+	LDBBackend:    "CURRENT",
+	BadgerBackend: "MANIFEST",
+}
+
 // DBFactory is a blockchain database factory.
 type DBFactory interface {
 	// NewChainDB returns a new database for the blockchain for
@@ -14,15 +34,95 @@ type DBFactory interface {
 	NewChainDB(shardID uint32) (ethdb.Database, error)
 }
 
+// NewDBFactory returns the factory of the shard databases in rootDir kept in
+// the given storage engine, with their ancient stores in ancientDir if set.
+func NewDBFactory(backend, rootDir, ancientDir string) (DBFactory, error) {
+	switch backend {
+	case LDBBackend:
+		return &LDBFactory{RootDir: rootDir, AncientDir: ancientDir}, nil
+	case BadgerBackend:
+		return &BadgerFactory{RootDir: rootDir, AncientDir: ancientDir}, nil
+	default:
+		return nil, errors.Errorf(
+			"unknown database backend %#v, must be one of %s", backend, strings.Join(DBBackends, ", "),
+		)
+	}
+}
+
+// ChainDBName returns the name of the database of the given shard, in the root
+// directory of a factory.
+func ChainDBName(shardID uint32) string {
+	return fmt.Sprintf("harmony_db_%d", shardID)
+}
+
+// checkBackend returns an error if dir has a database of another engine than
+// the given one.
+func checkBackend(dir, backend string) error {
+	for other, marker := range backendMarkers {
+		if other == backend {
+			continue
+		}
+		if _, err := os.Stat(path.Join(dir, marker)); err == nil {
+			return errors.Errorf("%s has a %s database, not a %s one", dir, other, backend)
+		}
+	}
+	return nil
+}
+
+// withAncientStore returns db with the old blocks in the ancient store of the
+// given name in ancientDir if set, closing db on error.
+func withAncientStore(db ethdb.Database, ancientDir, name string) (ethdb.Database, error) {
+	if ancientDir == "" {
+		return db, nil
+	}
+	ancientDB, err := rawdb.NewDatabaseWithFreezer(db, path.Join(ancientDir, name), true)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ancientDB, nil
+}
+
 // LDBFactory is a LDB-backed blockchain database factory.
 type LDBFactory struct {
-	RootDir string // directory in which to put shard databases in.
+	RootDir    string // directory in which to put shard databases in.
+	AncientDir string // if not empty, directory in which to put the ancient stores of shard databases in.
 }
 
-// NewChainDB returns a new LDB for the blockchain for given shard.
+// NewChainDB returns a new LDB for the blockchain for given shard, with the old
+// blocks in an ancient store if AncientDir is set.
 func (f *LDBFactory) NewChainDB(shardID uint32) (ethdb.Database, error) {
-	dir := path.Join(f.RootDir, fmt.Sprintf("harmony_db_%d", shardID))
-	return ethdb.NewLDBDatabase(dir, 0, 0)
+	name := ChainDBName(shardID)
+	dir := path.Join(f.RootDir, name)
+	if err := checkBackend(dir, LDBBackend); err != nil {
+		return nil, err
+	}
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return withAncientStore(db, f.AncientDir, name)
+}
+
+// BadgerFactory is a Badger-backed blockchain database factory.
+type BadgerFactory struct {
+	RootDir    string // directory in which to put shard databases in.
+	AncientDir string // if not empty, directory in which to put the ancient stores of shard databases in.
+}
+
+// NewChainDB returns a new Badger database for the blockchain for given shard,
+// with the old blocks in an ancient store if AncientDir is set.
+func (f *BadgerFactory) NewChainDB(shardID uint32) (ethdb.Database, error) {
+	name := ChainDBName(shardID)
+	dir := path.Join(f.RootDir, name)
+	if err := checkBackend(dir, BadgerBackend); err != nil {
+		return nil, err
+	}
+	db, err := NewBadgerDatabase(dir)
+	if err != nil {
+		return nil, err
+	}
+	return withAncientStore(db, f.AncientDir, name)
 }
 
 // MemDBFactory is a memory-backed blockchain database factory.
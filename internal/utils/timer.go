@@ -19,29 +19,36 @@ type Timeout struct {
 	state TimeoutState
 	d     time.Duration
 	start time.Time
+	now   func() time.Time
 }
 
 // NewTimeout creates a new timeout class
 func NewTimeout(d time.Duration) *Timeout {
-	timeout := Timeout{state: Inactive, d: d, start: time.Now()}
+	timeout := Timeout{state: Inactive, d: d, start: time.Now(), now: time.Now}
 	return &timeout
 }
 
+// SetClock makes the timeout run on the given clock instead of the wall clock
+func (timeout *Timeout) SetClock(now func() time.Time) {
+	timeout.now = now
+	timeout.start = now()
+}
+
 // Start starts the timeout clock
 func (timeout *Timeout) Start() {
 	timeout.state = Active
-	timeout.start = time.Now()
+	timeout.start = timeout.now()
 }
 
 // Stop stops the timeout clock
 func (timeout *Timeout) Stop() {
 	timeout.state = Inactive
-	timeout.start = time.Now()
+	timeout.start = timeout.now()
 }
 
 // CheckExpire checks whether the timeout is reached/expired
 func (timeout *Timeout) CheckExpire() bool {
-	if timeout.state == Active && time.Since(timeout.start) > timeout.d {
+	if timeout.state == Active && timeout.now().Sub(timeout.start) > timeout.d {
 		timeout.state = Expired
 	}
 	if timeout.state == Expired {
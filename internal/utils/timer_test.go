@@ -29,3 +29,18 @@ func TestCheckExpire(t *testing.T) {
 	}
 
 }
+
+func TestTimeoutClock(t *testing.T) {
+	now := time.Unix(0, 0)
+	timer := NewTimeout(time.Second)
+	timer.SetClock(func() time.Time { return now })
+	timer.Start()
+	now = now.Add(time.Second)
+	if timer.CheckExpire() {
+		t.Fatalf("CheckExpire should be false before the duration passed on the clock")
+	}
+	now = now.Add(time.Millisecond)
+	if !timer.CheckExpire() {
+		t.Fatalf("CheckExpire should be true once the duration passed on the clock")
+	}
+}
@@ -0,0 +1,133 @@
+package light
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/harmony-one/harmony/block"
+	internal_common "github.com/harmony-one/harmony/internal/common"
+	"github.com/pkg/errors"
+)
+
+// PublicLightAPI serves the queries of the beacon chain state to a light node,
+// answered with the state proven against the verified headers.
+type PublicLightAPI struct {
+	chain     *LightChain
+	retriever StateRetriever
+}
+
+// NewPublicLightAPI creates a new light node API.
+func NewPublicLightAPI(chain *LightChain, retriever StateRetriever) *PublicLightAPI {
+	return &PublicLightAPI{chain: chain, retriever: retriever}
+}
+
+// APIs returns the RPC services of a light node.
+func APIs(chain *LightChain, retriever StateRetriever) []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "hmy",
+			Version:   "1.0",
+			Service:   NewPublicLightAPI(chain, retriever),
+			Public:    true,
+		},
+	}
+}
+
+// HeaderInformation is the verified header of a block.
+type HeaderInformation struct {
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	ParentHash  common.Hash    `json:"parentHash"`
+	Epoch       hexutil.Uint64 `json:"epoch"`
+	ViewID      hexutil.Uint64 `json:"viewID"`
+	StateRoot   common.Hash    `json:"stateRoot"`
+	Timestamp   hexutil.Uint64 `json:"timestamp"`
+}
+
+// header returns the verified header of the given block number, the latest
+// one for the rpc.LatestBlockNumber and rpc.PendingBlockNumber meta numbers.
+func (s *PublicLightAPI) header(blockNr rpc.BlockNumber) (*block.Header, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return s.chain.CurrentHeader(), nil
+	}
+	header := s.chain.GetHeaderByNumber(uint64(blockNr))
+	if header == nil {
+		return nil, errors.Errorf("header %d not verified", blockNr)
+	}
+	return header, nil
+}
+
+// accountState retrieves the proven state of the account of the given address,
+// and of the given storage keys of it, in the state of the given block number.
+func (s *PublicLightAPI) accountState(
+	address string, keys []common.Hash, blockNr rpc.BlockNumber,
+) (*AccountState, error) {
+	header, err := s.header(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return s.retriever.RetrieveState(header, internal_common.ParseAddr(address), keys)
+}
+
+// BlockNumber returns the number of the latest verified header.
+func (s *PublicLightAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(s.chain.CurrentHeader().Number().Uint64())
+}
+
+// GetHeaderByNumber returns the verified header of the given block number.
+func (s *PublicLightAPI) GetHeaderByNumber(blockNr rpc.BlockNumber) (*HeaderInformation, error) {
+	header, err := s.header(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return &HeaderInformation{
+		BlockHash:   header.Hash(),
+		BlockNumber: hexutil.Uint64(header.Number().Uint64()),
+		ParentHash:  header.ParentHash(),
+		Epoch:       hexutil.Uint64(header.Epoch().Uint64()),
+		ViewID:      hexutil.Uint64(header.ViewID().Uint64()),
+		StateRoot:   header.Root(),
+		Timestamp:   hexutil.Uint64(header.Time().Uint64()),
+	}, nil
+}
+
+// GetBalance returns the amount of Nano for the given address in the state of
+// the given block number.
+func (s *PublicLightAPI) GetBalance(
+	ctx context.Context, address string, blockNr rpc.BlockNumber,
+) (*hexutil.Big, error) {
+	account, err := s.accountState(address, nil, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(account.Balance), nil
+}
+
+// GetTransactionCount returns the nonce of the given address in the state of
+// the given block number.
+func (s *PublicLightAPI) GetTransactionCount(
+	ctx context.Context, address string, blockNr rpc.BlockNumber,
+) (*hexutil.Uint64, error) {
+	account, err := s.accountState(address, nil, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	nonce := hexutil.Uint64(account.Nonce)
+	return &nonce, nil
+}
+
+// GetStorageAt returns the storage of the given address at the given key in
+// the state of the given block number.
+func (s *PublicLightAPI) GetStorageAt(
+	ctx context.Context, address string, key string, blockNr rpc.BlockNumber,
+) (hexutil.Bytes, error) {
+	slot := common.HexToHash(key)
+	account, err := s.accountState(address, []common.Hash{slot}, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	value := account.Storage[slot]
+	return value[:], nil
+}
@@ -0,0 +1,238 @@
+package light
+
+import (
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	"github.com/harmony-one/harmony/internal/chain"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/shard"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+)
+
+const shardStateCacheLimit = 10
+
+// LightChain is the chain of the headers of the beacon chain, without the
+// block bodies nor the state. Every header is verified with the commit
+// signature of the committee of its epoch, and the shard states carried by the
+// last headers of the epochs give the committees of the next epochs, so that
+// the chain is trusted from its genesis or checkpoint on.
+type LightChain struct {
+	db              ethdb.Database
+	config          *params.ChainConfig
+	hc              *core.HeaderChain
+	mu              sync.Mutex // serializes the writes of headers
+	shardStateCache *lru.Cache
+}
+
+// NewLightChain returns the light chain in db, which must have the genesis
+// block of the beacon chain.
+func NewLightChain(db ethdb.Database, config *params.ChainConfig) (*LightChain, error) {
+	hc, err := core.NewHeaderChain(db, config, chain.Engine, func() bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	// the light chain has no head block but a head header
+	if hash := rawdb.ReadHeadHeaderHash(db); hash != (common.Hash{}) {
+		if head := hc.GetHeaderByHash(hash); head != nil {
+			hc.SetCurrentHeader(head)
+		}
+	}
+	shardStateCache, _ := lru.New(shardStateCacheLimit)
+	return &LightChain{
+		db:              db,
+		config:          config,
+		hc:              hc,
+		shardStateCache: shardStateCache,
+	}, nil
+}
+
+// Config retrieves the chain configuration.
+func (lc *LightChain) Config() *params.ChainConfig { return lc.config }
+
+// ShardID returns the shard of the chain, which is the beacon chain.
+func (lc *LightChain) ShardID() uint32 { return shard.BeaconChainShardID }
+
+// CurrentHeader retrieves the last verified header.
+func (lc *LightChain) CurrentHeader() *block.Header {
+	return lc.hc.CurrentHeader()
+}
+
+// GetHeaderByNumber retrieves the verified header of the given number.
+func (lc *LightChain) GetHeaderByNumber(number uint64) *block.Header {
+	return lc.hc.GetHeaderByNumber(number)
+}
+
+// GetHeaderByHash retrieves the verified header of the given hash.
+func (lc *LightChain) GetHeaderByHash(hash common.Hash) *block.Header {
+	return lc.hc.GetHeaderByHash(hash)
+}
+
+// ReadShardState retrieves the shard state of the given epoch.
+func (lc *LightChain) ReadShardState(epoch *big.Int) (*shard.State, error) {
+	cacheKey := string(epoch.Bytes())
+	if cached, ok := lc.shardStateCache.Get(cacheKey); ok {
+		return cached.(*shard.State), nil
+	}
+	shardState, err := rawdb.ReadShardState(lc.db, epoch)
+	if err != nil {
+		return nil, err
+	}
+	lc.shardStateCache.Add(cacheKey, shardState)
+	return shardState, nil
+}
+
+// InsertHeaders verifies and writes the given consecutive headers following
+// the current header. As the commit signature over a header is carried by its
+// child, all the headers but the last one are written, the last one being
+// given again with its child later. It returns the number of headers written.
+func (lc *LightChain) InsertHeaders(headers []*block.Header) (int, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	parent := lc.hc.CurrentHeader()
+	for _, header := range headers {
+		if header.ParentHash() != parent.Hash() ||
+			header.Number().Uint64() != parent.Number().Uint64()+1 {
+			return 0, errors.Errorf(
+				"header %d %x is not a child of header %d %x",
+				header.Number(), header.Hash(), parent.Number(), parent.Hash(),
+			)
+		}
+		if header.ShardID() != shard.BeaconChainShardID {
+			return 0, errors.Errorf("header %d is of shard %d", header.Number(), header.ShardID())
+		}
+		// the epoch only moves on after the last header of an epoch, the one
+		// carrying the committees of the next epoch
+		nextEpoch := new(big.Int).Add(parent.Epoch(), common.Big1)
+		if header.Epoch().Cmp(parent.Epoch()) != 0 &&
+			(len(parent.ShardState()) == 0 || header.Epoch().Cmp(nextEpoch) != 0) {
+			return 0, errors.Errorf(
+				"header %d of epoch %v does not follow the epoch %v of header %d",
+				header.Number(), header.Epoch(), parent.Epoch(), parent.Number(),
+			)
+		}
+		parent = header
+	}
+	if len(headers) < 2 {
+		return 0, nil
+	}
+
+	for i := 0; i < len(headers)-1; i++ {
+		header, child := headers[i], headers[i+1]
+		shardState, err := lc.ReadShardState(header.Epoch())
+		if err != nil {
+			return i, errors.Wrapf(err, "no committee of epoch %v", header.Epoch())
+		}
+		sig := child.LastCommitSignature()
+		if err := chain.VerifyCommitSig(
+			lc.config, shardState, header, sig[:], child.LastCommitBitmap(),
+		); err != nil {
+			return i, errors.Wrapf(err, "header %d", header.Number())
+		}
+		if err := lc.writeHeader(header); err != nil {
+			return i, err
+		}
+	}
+	head := lc.hc.CurrentHeader()
+	utils.Logger().Info().
+		Uint64("blockNum", head.Number().Uint64()).
+		Str("blockHash", head.Hash().Hex()).
+		Str("epoch", head.Epoch().String()).
+		Msg("[LIGHT] Inserted verified headers")
+	return len(headers) - 1, nil
+}
+
+// writeHeader writes the given verified header as the current header, and the
+// shard state it carries if it is the last header of its epoch.
+func (lc *LightChain) writeHeader(header *block.Header) error {
+	batch := lc.db.NewBatch()
+	if err := lc.writeShardStateOf(batch, header); err != nil {
+		return err
+	}
+	rawdb.WriteHeader(batch, header)
+	rawdb.WriteCanonicalHash(batch, header.Hash(), header.Number().Uint64())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	lc.hc.SetCurrentHeader(header)
+	return nil
+}
+
+// writeShardStateOf writes the shard state carried by the given header, if
+// any, under the next epoch it is for. The committees already stored for that
+// epoch are kept, a header carrying other ones is rejected.
+func (lc *LightChain) writeShardStateOf(batch rawdb.DatabaseWriter, header *block.Header) error {
+	if len(header.ShardState()) == 0 {
+		return nil
+	}
+	shardState, err := shard.DecodeWrapper(header.ShardState())
+	if err != nil {
+		return errors.Wrapf(err, "invalid shard state in header %d", header.Number())
+	}
+	epoch := new(big.Int).Add(header.Epoch(), common.Big1)
+	if shardState.Epoch != nil && shardState.Epoch.Cmp(epoch) != 0 {
+		return errors.Errorf(
+			"shard state of epoch %v in header %d of epoch %v",
+			shardState.Epoch, header.Number(), header.Epoch(),
+		)
+	}
+	if stored, err := lc.ReadShardState(epoch); err == nil {
+		if stored.Hash() != shardState.Hash() {
+			return errors.Errorf(
+				"header %d carries other committees than the ones stored for epoch %v",
+				header.Number(), epoch,
+			)
+		}
+		return nil
+	}
+	return rawdb.WriteShardStateBytes(batch, epoch, header.ShardState())
+}
+
+// ResetWithCheckpoint makes the header of the given trusted checkpoint the
+// current header of the new chain, with the committees of its epoch, so that
+// the chain is synced from it instead of from genesis.
+func (lc *LightChain) ResetWithCheckpoint(cp *core.Checkpoint, header *block.Header) error {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	if head := lc.hc.CurrentHeader().Number().Uint64(); head != 0 {
+		return errors.Errorf("chain is not new, current header is %d", head)
+	}
+	if header.Hash() != cp.BlockHash || header.Number().Uint64() != cp.BlockNumber ||
+		header.Epoch().Cmp(cp.Epoch) != 0 {
+		return errors.Errorf(
+			"header %d %x of epoch %v is not the checkpoint block %d %x of epoch %v",
+			header.Number(), header.Hash(), header.Epoch(), cp.BlockNumber, cp.BlockHash, cp.Epoch,
+		)
+	}
+	if _, err := shard.DecodeWrapper(cp.ShardState); err != nil {
+		return errors.Wrap(err, "invalid shard state in checkpoint")
+	}
+	batch := lc.db.NewBatch()
+	if err := rawdb.WriteShardStateBytes(batch, cp.Epoch, cp.ShardState); err != nil {
+		return err
+	}
+	rawdb.WriteCheckpointHash(batch, header.Hash())
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	if err := lc.writeHeader(header); err != nil {
+		return err
+	}
+	lc.shardStateCache.Purge()
+
+	utils.Logger().Info().
+		Uint64("blockNum", header.Number().Uint64()).
+		Str("blockHash", header.Hash().Hex()).
+		Str("epoch", cp.Epoch.String()).
+		Msg("[LIGHT] Reset the light chain to the checkpoint header")
+	return nil
+}
@@ -0,0 +1,276 @@
+package light
+
+import (
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	"github.com/harmony-one/harmony/block"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func newTestHeader(number int64, epoch int64, parent *block.Header) *block.Header {
+	parentHash := common.Hash{}
+	if parent != nil {
+		parentHash = parent.Hash()
+	}
+	return blockfactory.NewTestHeader().With().
+		Number(big.NewInt(number)).
+		Epoch(big.NewInt(epoch)).
+		ParentHash(parentHash).
+		Header()
+}
+
+// newTestLightChain returns a light chain with only a genesis header.
+func newTestLightChain(t *testing.T, config *params.ChainConfig) (*LightChain, *block.Header) {
+	db := ethdb.NewMemDatabase()
+	genesis := newTestHeader(0, 0, nil)
+	rawdb.WriteHeader(db, genesis)
+	rawdb.WriteCanonicalHash(db, genesis.Hash(), 0)
+	lc, err := NewLightChain(db, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lc, genesis
+}
+
+func TestLightChainInsertHeaders(t *testing.T) {
+	lc, genesis := newTestLightChain(t, params.TestChainConfig)
+	if head := lc.CurrentHeader(); head.Hash() != genesis.Hash() {
+		t.Fatalf("current header is not genesis: %d", head.Number())
+	}
+
+	header1 := newTestHeader(1, 0, genesis)
+	header2 := newTestHeader(2, 0, header1)
+	if _, err := lc.InsertHeaders([]*block.Header{header2}); err == nil {
+		t.Error("inserted a header not following the current header")
+	}
+	if _, err := lc.InsertHeaders([]*block.Header{header1, newTestHeader(3, 0, header1)}); err == nil {
+		t.Error("inserted headers not linked together")
+	}
+	// the commit signature over the last header is not known
+	if n, err := lc.InsertHeaders([]*block.Header{header1}); err != nil || n != 0 {
+		t.Errorf("inserted %d of a single header: %v", n, err)
+	}
+	// the test headers have no valid commit signature
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err == nil || n != 0 {
+		t.Errorf("inserted %d unsigned headers", n)
+	}
+	if head := lc.CurrentHeader(); head.Hash() != genesis.Hash() {
+		t.Errorf("current header moved to unverified header %d", head.Number())
+	}
+}
+
+func TestLightChainResetWithCheckpoint(t *testing.T) {
+	lc, genesis := newTestLightChain(t, params.TestChainConfig)
+	header := newTestHeader(100, 3, genesis)
+	shardState, err := shard.EncodeWrapper(shard.State{
+		Epoch:  big.NewInt(3),
+		Shards: []shard.Committee{{ShardID: 0}},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := &core.Checkpoint{
+		Epoch:       big.NewInt(3),
+		BlockNumber: 100,
+		BlockHash:   header.Hash(),
+		ShardState:  shardState,
+	}
+	if err := lc.ResetWithCheckpoint(cp, newTestHeader(100, 2, genesis)); err == nil {
+		t.Error("reset with a header which is not the checkpoint block")
+	}
+	if err := lc.ResetWithCheckpoint(cp, header); err != nil {
+		t.Fatal(err)
+	}
+	if head := lc.CurrentHeader(); head.Hash() != header.Hash() {
+		t.Errorf("current header is not the checkpoint header: %d", head.Number())
+	}
+	if _, err := lc.ReadShardState(big.NewInt(3)); err != nil {
+		t.Errorf("committee of the checkpoint epoch missing: %v", err)
+	}
+	if hash := rawdb.ReadCheckpointHash(lc.db); hash != header.Hash() {
+		t.Errorf("checkpoint hash mismatch: have %x, want %x", hash, header.Hash())
+	}
+	if err := lc.ResetWithCheckpoint(cp, header); err == nil {
+		t.Error("reset a chain which is not new")
+	}
+
+	// the light chain reopens at the checkpoint header
+	reopened, err := NewLightChain(lc.db, params.TestChainConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head := reopened.CurrentHeader(); head.Hash() != header.Hash() {
+		t.Errorf("reopened light chain at header %d", head.Number())
+	}
+}
+
+// preStakingTestConfig returns a chain config whose committees are counted by
+// signatures, not by stake.
+func preStakingTestConfig() *params.ChainConfig {
+	config := *params.TestChainConfig
+	config.PreStakingEpoch = big.NewInt(100)
+	config.StakingEpoch = big.NewInt(100)
+	return &config
+}
+
+// testCommittee is a beacon chain committee with the keys of its slots.
+type testCommittee struct {
+	keys       []*bls.SecretKey
+	shardState []byte
+}
+
+func newTestCommittee(t *testing.T, epoch int64, size int) *testCommittee {
+	c := &testCommittee{}
+	committee := shard.Committee{ShardID: shard.BeaconChainShardID}
+	for i := 0; i < size; i++ {
+		key := bls_cosi.RandPrivateKey()
+		c.keys = append(c.keys, key)
+		committee.Slots = append(committee.Slots, shard.Slot{
+			BLSPublicKey: *shard.FromLibBLSPublicKeyUnsafe(key.GetPublicKey()),
+		})
+	}
+	shardState, err := shard.EncodeWrapper(shard.State{
+		Epoch:  big.NewInt(epoch),
+		Shards: []shard.Committee{committee},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.shardState = shardState
+	return c
+}
+
+// signParent sets the commit signature over the parent of the header, by the
+// first signers of the committee.
+func (c *testCommittee) signParent(t *testing.T, header, parent *block.Header, signers int) {
+	publicKeys := []*bls.PublicKey{}
+	for _, key := range c.keys {
+		publicKeys = append(publicKeys, key.GetPublicKey())
+	}
+	mask, err := bls_cosi.NewMask(publicKeys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitPayload := make([]byte, 8)
+	binary.LittleEndian.PutUint64(commitPayload, parent.Number().Uint64())
+	parentHash := parent.Hash()
+	commitPayload = append(commitPayload, parentHash[:]...)
+	sigs := []*bls.Sign{}
+	for _, key := range c.keys[:signers] {
+		sigs = append(sigs, key.SignHash(commitPayload))
+		if err := mask.SetKey(key.GetPublicKey(), true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var sig [96]byte
+	copy(sig[:], bls_cosi.AggregateSig(sigs).Serialize())
+	header.With().LastCommitSignature(sig).LastCommitBitmap(mask.Bitmap)
+}
+
+func TestLightChainInsertSignedHeaders(t *testing.T) {
+	lc, genesis := newTestLightChain(t, preStakingTestConfig())
+	committee0, committee1 := newTestCommittee(t, 0, 3), newTestCommittee(t, 1, 4)
+	if err := rawdb.WriteShardStateBytes(lc.db, big.NewInt(0), committee0.shardState); err != nil {
+		t.Fatal(err)
+	}
+
+	// header1 is the last header of epoch 0, with the committee of epoch 1
+	header1 := newTestHeader(1, 0, genesis)
+	header1.With().ShardState(committee1.shardState)
+	header2 := newTestHeader(2, 1, header1)
+	committee0.signParent(t, header2, header1, 3)
+	header3 := newTestHeader(3, 1, header2)
+	committee1.signParent(t, header3, header2, 3)
+	header4 := newTestHeader(4, 1, header3)
+	committee1.signParent(t, header4, header3, 2)
+
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err != nil || n != 1 {
+		t.Fatalf("inserted %d of a signed header: %v", n, err)
+	}
+	if _, err := lc.ReadShardState(big.NewInt(1)); err != nil {
+		t.Fatalf("committee of the next epoch not written: %v", err)
+	}
+	// header2 is verified by the committee of epoch 1
+	if n, err := lc.InsertHeaders([]*block.Header{header2, header3}); err != nil || n != 1 {
+		t.Fatalf("inserted %d of a signed header across epochs: %v", n, err)
+	}
+	// 2 of the 4 signers of epoch 1 are below the quorum of 3
+	if n, err := lc.InsertHeaders([]*block.Header{header3, header4}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers signed below quorum", n)
+	}
+	if head := lc.CurrentHeader(); head.Hash() != header2.Hash() {
+		t.Errorf("current header mismatch: have %d, want 2", head.Number())
+	}
+
+	// signed by the committee of another epoch
+	forged := newTestHeader(4, 1, header3)
+	committee0.signParent(t, forged, header3, 3)
+	if n, err := lc.InsertHeaders([]*block.Header{header3, forged}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers signed by the wrong committee", n)
+	}
+}
+
+func TestLightChainInsertHeadersEpochs(t *testing.T) {
+	lc, genesis := newTestLightChain(t, preStakingTestConfig())
+	committee0, committee1 := newTestCommittee(t, 0, 3), newTestCommittee(t, 1, 3)
+	if err := rawdb.WriteShardStateBytes(lc.db, big.NewInt(0), committee0.shardState); err != nil {
+		t.Fatal(err)
+	}
+
+	// the epoch goes backwards
+	header1 := newTestHeader(1, 1, genesis)
+	header2 := newTestHeader(2, 0, header1)
+	committee0.signParent(t, header2, header1, 3)
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers whose epoch goes backwards", n)
+	}
+	// the epoch moves on after a header without the committees of the next epoch
+	header1 = newTestHeader(1, 0, genesis)
+	header2 = newTestHeader(2, 1, header1)
+	committee0.signParent(t, header2, header1, 3)
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers moving to an epoch without its committees", n)
+	}
+	// the committees carried are not the ones of the next epoch
+	otherEpoch, err := shard.EncodeWrapper(shard.State{
+		Epoch:  big.NewInt(2),
+		Shards: []shard.Committee{{ShardID: shard.BeaconChainShardID}},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header1.With().ShardState(otherEpoch)
+	header2 = newTestHeader(2, 1, header1)
+	committee0.signParent(t, header2, header1, 3)
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers carrying the committees of another epoch", n)
+	}
+	// the committees carried are not the ones already stored
+	if err := rawdb.WriteShardStateBytes(lc.db, big.NewInt(1), committee1.shardState); err != nil {
+		t.Fatal(err)
+	}
+	header1.With().ShardState(newTestCommittee(t, 1, 3).shardState)
+	header2 = newTestHeader(2, 1, header1)
+	committee0.signParent(t, header2, header1, 3)
+	if n, err := lc.InsertHeaders([]*block.Header{header1, header2}); err == nil || n != 0 {
+		t.Errorf("inserted %d headers overwriting the stored committees", n)
+	}
+	if stored, err := lc.ReadShardState(big.NewInt(1)); err != nil ||
+		stored.Shards[0].Slots[0].BLSPublicKey !=
+			*shard.FromLibBLSPublicKeyUnsafe(committee1.keys[0].GetPublicKey()) {
+		t.Errorf("stored committees of epoch 1 changed: %v", err)
+	}
+	if head := lc.CurrentHeader(); head.Hash() != genesis.Hash() {
+		t.Errorf("current header moved to header %d", head.Number())
+	}
+}
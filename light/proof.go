@@ -0,0 +1,93 @@
+package light
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/harmony-one/harmony/block"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/core/types"
+	"github.com/pkg/errors"
+)
+
+// AccountState is the state of an account, and of some storage keys of it,
+// proven against the state root of a verified header.
+type AccountState struct {
+	Address common.Address
+	state.Account
+	// Storage are the values of the proven storage keys.
+	Storage map[common.Hash]common.Hash
+}
+
+// StateRetriever retrieves the proven state of accounts from full nodes.
+type StateRetriever interface {
+	// RetrieveState retrieves the state of the account of the given address,
+	// and of the given storage keys of it, in the state of the given header.
+	RetrieveState(header *block.Header, address common.Address, keys []common.Hash) (*AccountState, error)
+}
+
+// VerifyStateProof verifies the Merkle proof of the account of the given
+// address against the state root, and the proofs of the given storage keys of
+// it against its storage root, returning the proven state. An account missing
+// from the state is proven empty.
+func VerifyStateProof(
+	root common.Hash, address common.Address, keys []common.Hash,
+	accountProof [][]byte, storageProofs [][][]byte,
+) (*AccountState, error) {
+	if len(storageProofs) != len(keys) {
+		return nil, errors.Errorf("%d storage proofs for %d keys", len(storageProofs), len(keys))
+	}
+	value, err := verifyProof(root, crypto.Keccak256(address.Bytes()), accountProof)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid proof of account %s", address.Hex())
+	}
+	result := &AccountState{
+		Address: address,
+		Account: state.Account{Balance: new(big.Int), Root: types.EmptyRootHash, CodeHash: crypto.Keccak256(nil)},
+		Storage: make(map[common.Hash]common.Hash, len(keys)),
+	}
+	if value != nil {
+		if err := rlp.DecodeBytes(value, &result.Account); err != nil {
+			return nil, errors.Wrapf(err, "invalid account %s", address.Hex())
+		}
+	}
+	for i, key := range keys {
+		if result.Root == types.EmptyRootHash {
+			// no storage to prove
+			result.Storage[key] = common.Hash{}
+			continue
+		}
+		value, err := verifyProof(result.Root, crypto.Keccak256(key.Bytes()), storageProofs[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid proof of storage %s of account %s", key.Hex(), address.Hex())
+		}
+		var slot common.Hash
+		if value != nil {
+			// the values are stored RLP encoded without their leading zeros
+			_, content, _, err := rlp.Split(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid storage %s of account %s", key.Hex(), address.Hex())
+			}
+			slot.SetBytes(content)
+		}
+		result.Storage[key] = slot
+	}
+	return result, nil
+}
+
+// verifyProof returns the value of the given key in the trie of the given root
+// proven by the given trie nodes, or nil if the key is proven missing.
+func verifyProof(root common.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	proofDB := ethdb.NewMemDatabase()
+	for _, node := range proof {
+		if err := proofDB.Put(crypto.Keccak256(node), node); err != nil {
+			return nil, err
+		}
+	}
+	value, _, err := trie.VerifyProof(root, key, proofDB)
+	return value, err
+}
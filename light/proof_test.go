@@ -0,0 +1,74 @@
+package light
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/harmony-one/harmony/core/state"
+)
+
+func TestVerifyStateProof(t *testing.T) {
+	stateDB, err := state.New(common.Hash{}, state.NewDatabase(ethdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := common.HexToAddress("0x1234")
+	key, value := common.HexToHash("0x01"), common.HexToHash("0x0102")
+	stateDB.SetBalance(addr, big.NewInt(42))
+	stateDB.SetNonce(addr, 7)
+	stateDB.SetState(addr, key, value)
+	root, err := stateDB.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missingKey := common.HexToHash("0x02")
+	keys := []common.Hash{key, missingKey}
+	accountProof, err := stateDB.GetProof(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	storageProofs := make([][][]byte, len(keys))
+	for i := range keys {
+		if storageProofs[i], err = stateDB.GetStorageProof(addr, keys[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	account, err := VerifyStateProof(root, addr, keys, accountProof, storageProofs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.Balance.Cmp(big.NewInt(42)) != 0 || account.Nonce != 7 {
+		t.Errorf("account mismatch: balance %v nonce %d", account.Balance, account.Nonce)
+	}
+	if account.Storage[key] != value || account.Storage[missingKey] != (common.Hash{}) {
+		t.Errorf("storage mismatch: %v", account.Storage)
+	}
+
+	// an account missing from the state is proven empty
+	other := common.HexToAddress("0x5678")
+	otherProof, err := stateDB.GetProof(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account, err := VerifyStateProof(root, other, nil, otherProof, nil); err != nil ||
+		account.Balance.Sign() != 0 || account.Nonce != 0 {
+		t.Errorf("missing account not proven empty: %+v, %v", account, err)
+	}
+
+	// a proof of another state does not verify
+	stateDB.SetBalance(addr, big.NewInt(43))
+	otherRoot, err := stateDB.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyStateProof(otherRoot, addr, nil, accountProof, nil); err == nil {
+		t.Error("verified the proof of an account against another state root")
+	}
+	if _, err := VerifyStateProof(root, addr, keys, accountProof, storageProofs[:1]); err == nil {
+		t.Error("verified a state proof missing a storage proof")
+	}
+}
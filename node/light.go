@@ -0,0 +1,160 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/harmony-one/harmony/api/service/networkinfo"
+	"github.com/harmony-one/harmony/api/service/syncing"
+	"github.com/harmony-one/harmony/core"
+	"github.com/harmony-one/harmony/core/rawdb"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/light"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// LightSyncFrequency is the interval in seconds between two syncs of the
+// light chain, about the block time.
+const LightSyncFrequency = 8
+
+// LightNode is a node which syncs only the headers of the beacon chain and the
+// shard states they carry, verifying the commit signature over every header
+// against the elected committee, and serves the state of accounts proven by
+// the full nodes against the state roots of the verified headers.
+type LightNode struct {
+	SelfPeer            p2p.Peer
+	NodeConfig          *nodeconfig.ConfigType
+	SyncingPeerProvider SyncingPeerProvider
+	// Checkpoint, if set, is the trusted checkpoint a new light chain is synced
+	// from instead of from genesis.
+	Checkpoint *core.Checkpoint
+
+	host         p2p.Host
+	db           ethdb.Database
+	chain        *light.LightChain
+	stateSync    *syncing.StateSync
+	syncID       [SyncIDLength]byte
+	httpListener net.Listener
+}
+
+// NewLightNode creates a new light node of the beacon chain, whose headers are
+// kept in the database of the beacon chain made by chainDBFactory.
+func NewLightNode(host p2p.Host, chainDBFactory shardchain.DBFactory) (*LightNode, error) {
+	ln := &LightNode{
+		NodeConfig: nodeconfig.GetShardConfig(shard.BeaconChainShardID),
+		host:       host,
+	}
+	copy(ln.syncID[:], GenerateRandomString(SyncIDLength))
+	if host != nil {
+		ln.SelfPeer = host.GetSelfPeer()
+	}
+	db, err := chainDBFactory.NewChainDB(shard.BeaconChainShardID)
+	if err != nil {
+		return nil, err
+	}
+	if rawdb.ReadCanonicalHash(db, 0) == (common.Hash{}) {
+		// the genesis block of the beacon chain, set up as by a full node
+		gi := &genesisInitializer{&Node{NodeConfig: ln.NodeConfig}}
+		if err := gi.InitChainDB(db, shard.BeaconChainShardID); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	chainConfig := ln.NodeConfig.GetNetworkType().ChainConfig()
+	ln.chain, err = light.NewLightChain(db, &chainConfig)
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "cannot open the light chain")
+	}
+	ln.db = db
+	return ln, nil
+}
+
+// Chain returns the light chain of the node.
+func (ln *LightNode) Chain() *light.LightChain {
+	return ln.chain
+}
+
+// Start starts syncing the light chain, and serving the RPC of the light node
+// on the HTTP port derived from nodePort.
+func (ln *LightNode) Start(nodePort string) error {
+	ln.stateSync = syncing.CreateStateSync(ln.SelfPeer.IP, ln.SelfPeer.Port, ln.syncID)
+	if ln.NodeConfig.StreamSync && ln.host != nil {
+		ln.stateSync.EnableStreams(ln.host.GetP2PHost(), shard.BeaconChainShardID)
+		// the peers serving the syncing streams are found through the DHT
+		go networkinfo.MustNew(
+			ln.host, nodeconfig.NewGroupIDByShardID(shard.BeaconChainShardID), nil, nil, "",
+		).StartService()
+	}
+	go ln.DoSyncing()
+	return ln.StartRPC(nodePort)
+}
+
+// DoSyncing keeps the light chain in sync with the peers.
+func (ln *LightNode) DoSyncing() {
+	for {
+		ln.doSync()
+		time.Sleep(LightSyncFrequency * time.Second)
+	}
+}
+
+func (ln *LightNode) doSync() {
+	if ln.stateSync.GetActivePeerNumber() < MinConnectedPeers {
+		peers, err := ln.SyncingPeerProvider.SyncingPeers(shard.BeaconChainShardID)
+		if err != nil {
+			utils.Logger().Warn().Err(err).Msg("[LIGHT] cannot retrieve beacon syncing peers")
+			return
+		}
+		if err := ln.stateSync.CreateSyncConfig(peers, true); err != nil {
+			utils.Logger().Warn().Err(err).Msg("[LIGHT] cannot create beacon sync config")
+			return
+		}
+	}
+	if err := ln.stateSync.LightSync(ln.chain, ln.Checkpoint); err != nil {
+		utils.Logger().Warn().Err(err).Msg("[LIGHT] light sync failed, will retry")
+	}
+}
+
+// StartRPC starts the HTTP RPC of the light node.
+func (ln *LightNode) StartRPC(nodePort string) error {
+	port, _ := strconv.Atoi(nodePort)
+	ip := ""
+	if !nodeconfig.GetPublicRPC() {
+		ip = "127.0.0.1"
+	}
+	endpoint := fmt.Sprintf("%v:%v", ip, port+rpcHTTPPortOffset)
+	listener, _, err := rpc.StartHTTPEndpoint(
+		endpoint, light.APIs(ln.chain, ln.stateSync), []string{"hmy"},
+		httpOrigins, httpVirtualHosts, httpTimeouts,
+	)
+	if err != nil {
+		return err
+	}
+	ln.httpListener = listener
+	utils.Logger().Info().
+		Str("url", fmt.Sprintf("http://%s", endpoint)).
+		Msg("Light node HTTP endpoint opened")
+	return nil
+}
+
+// ShutDown gracefully shuts down the light node.
+func (ln *LightNode) ShutDown() {
+	if ln.httpListener != nil {
+		ln.httpListener.Close()
+	}
+	ln.db.Close()
+	msg := "Successfully shut down!\n"
+	utils.Logger().Print(msg)
+	fmt.Print(msg)
+	os.Exit(0)
+}
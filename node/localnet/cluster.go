@@ -0,0 +1,263 @@
+// Package localnet runs the nodes of one or more shards in one process,
+// connected by an in-memory network, for integration tests of consensus, view
+// change, resharding and crosslinks without spawning any harmony process.
+//
+// On a network with a manual clock, the consensus timeouts run on that clock,
+// and the cluster moves it forward while waiting for blocks, so messages are
+// delivered in a reproducible order.
+package localnet
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	ethCommon "github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/consensus"
+	"github.com/harmony-one/harmony/consensus/quorum"
+	"github.com/harmony-one/harmony/internal/common"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/internal/shardchain"
+	"github.com/harmony-one/harmony/internal/utils"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/node"
+	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/p2p/memhost"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+// p2pMsgPrefixSize is the size of the p2p type and message size prefix
+const p2pMsgPrefixSize = 5
+
+// pollInterval is how often the nodes are checked while waiting, and how far
+// a manual network clock is moved forward each time
+const pollInterval = 10 * time.Millisecond
+
+// stopper stops a background routine and waits for it to end
+type stopper struct {
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// Cluster is a set of nodes of one or more shards running consensus over an in-memory network
+type Cluster struct {
+	Network *memhost.Network
+	Nodes   []*node.Node
+	hosts   []*memhost.Host
+	// block proposers are stopped before consensus they feed blocks to
+	proposers  []stopper
+	consensus  []stopper
+	cancelRecv context.CancelFunc
+}
+
+// NewCluster creates one node for each of the keys of each shard, starting
+// from genesis. The keys must belong to the genesis committee of their shard
+// for the nodes to take part in consensus.
+func NewCluster(
+	network *memhost.Network, keys map[uint32][]*multibls.PrivateKey, blockPeriod time.Duration,
+) (*Cluster, error) {
+	cluster := &Cluster{Network: network}
+	shardIDs := make([]uint32, 0, len(keys))
+	for shardID := range keys {
+		shardIDs = append(shardIDs, shardID)
+	}
+	sort.Slice(shardIDs, func(i, j int) bool { return shardIDs[i] < shardIDs[j] })
+	for _, shardID := range shardIDs {
+		for _, key := range keys[shardID] {
+			if err := cluster.addNode(shardID, key, blockPeriod); err != nil {
+				return nil, errors.Wrapf(err, "cannot create node %d", len(cluster.Nodes))
+			}
+		}
+	}
+	return cluster, nil
+}
+
+func (c *Cluster) addNode(shardID uint32, key *multibls.PrivateKey, blockPeriod time.Duration) error {
+	host := c.Network.NewHost(p2p.Peer{IP: "127.0.0.1", Port: "0"})
+	decider := quorum.NewDecider(quorum.SuperMajorityVote, shardID)
+	currentConsensus, err := consensus.New(host, shardID, p2p.Peer{}, key, decider)
+	if err != nil {
+		return errors.Wrap(err, "cannot create consensus")
+	}
+	currentConsensus.Decider.SetMyPublicKeyProvider(func() (*multibls.PublicKey, error) {
+		return currentConsensus.GetPublicKeys(), nil
+	})
+	// as set up by the harmony binary, block rewards go to the genesis accounts of the keys
+	currentConsensus.SelfAddresses = map[string]ethCommon.Address{}
+	genesisInstance := shard.Schedule.InstanceForEpoch(big.NewInt(0))
+	for _, pubKey := range currentConsensus.GetPublicKeys().PublicKey {
+		if _, account := genesisInstance.FindAccount(pubKey.SerializeToHexStr()); account != nil {
+			currentConsensus.SelfAddresses[pubKey.SerializeToHexStr()] = common.ParseAddr(account.Address)
+		}
+	}
+
+	currentNode := node.New(host, currentConsensus, &shardchain.MemDBFactory{}, nil, false)
+	currentConsensus.ChainReader = currentNode.Blockchain()
+	currentNode.NodeConfig.SetRole(nodeconfig.Validator)
+	currentNode.NodeConfig.SetShardGroupID(nodeconfig.NewGroupIDByShardID(nodeconfig.ShardID(shardID)))
+	currentNode.NodeConfig.SetClientGroupID(nodeconfig.NewClientGroupIDByShardID(nodeconfig.ShardID(shardID)))
+	currentNode.NodeConfig.SetBeaconGroupID(nodeconfig.NewGroupIDByShardID(shard.BeaconChainShardID))
+	if err := currentNode.InitConsensusWithValidators(); err != nil {
+		return errors.Wrap(err, "cannot init validators")
+	}
+	viewID := currentNode.Blockchain().CurrentBlock().Header().ViewID().Uint64()
+	currentConsensus.SetViewID(viewID + 1)
+	currentConsensus.BlockVerifier = currentNode.VerifyNewBlock
+	currentConsensus.OnConsensusDone = currentNode.PostConsensusProcessing
+	currentConsensus.SetMode(currentConsensus.UpdateConsensusInformation())
+	currentConsensus.BlockPeriod = blockPeriod
+	currentConsensus.NextBlockDue = time.Now()
+	currentConsensus.SetClock(c.Network.Now)
+
+	c.Nodes = append(c.Nodes, currentNode)
+	c.hosts = append(c.hosts, host)
+	return nil
+}
+
+// Shard returns the nodes of the given shard
+func (c *Cluster) Shard(shardID uint32) []*node.Node {
+	nodes := []*node.Node{}
+	for _, currentNode := range c.Nodes {
+		if currentNode.Consensus.ShardID == shardID {
+			nodes = append(nodes, currentNode)
+		}
+	}
+	return nodes
+}
+
+// Host returns the in-memory host of the i-th node
+func (c *Cluster) Host(i int) *memhost.Host {
+	return c.hosts[i]
+}
+
+// Start starts receiving messages, proposing blocks and running consensus on all the nodes
+func (c *Cluster) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancelRecv = cancel
+	for i, currentNode := range c.Nodes {
+		groups := map[nodeconfig.GroupID]struct{}{
+			currentNode.NodeConfig.GetShardGroupID():                       {},
+			currentNode.NodeConfig.GetClientGroupID():                      {},
+			nodeconfig.NewClientGroupIDByShardID(shard.BeaconChainShardID): {},
+		}
+		for _, group := range currentNode.Consensus.AggregatorGroups() {
+			groups[group] = struct{}{}
+		}
+		for group := range groups {
+			receiver, err := c.hosts[i].GroupReceiver(group)
+			if err != nil {
+				return errors.Wrapf(err, "cannot receive group %s on node %d", group, i)
+			}
+			go c.receive(ctx, currentNode, receiver)
+		}
+
+		consensusLoop := stopper{make(chan struct{}), make(chan struct{})}
+		proposer := stopper{make(chan struct{}), make(chan struct{})}
+		startChan := make(chan struct{}, 1)
+		currentNode.Consensus.Start(
+			currentNode.BlockChannel, consensusLoop.stop, consensusLoop.stopped, startChan,
+		)
+		currentNode.WaitForConsensusReadyV2(
+			currentNode.Consensus.ReadySignal, proposer.stop, proposer.stopped,
+		)
+		startChan <- struct{}{}
+		c.consensus = append(c.consensus, consensusLoop)
+		c.proposers = append(c.proposers, proposer)
+	}
+	// all the nodes are ready, a manual clock skips the wait of the proposers for them
+	c.Network.Advance(node.ConsensusReadyDelay)
+	return nil
+}
+
+func (c *Cluster) receive(ctx context.Context, currentNode *node.Node, receiver p2p.GroupReceiver) {
+	for {
+		msg, sender, err := receiver.Receive(ctx)
+		if err != nil {
+			return
+		}
+		if sender == currentNode.SelfPeer.PeerID || len(msg) < p2pMsgPrefixSize {
+			continue
+		}
+		currentNode.HandleMessage(msg[p2pMsgPrefixSize:], sender)
+	}
+}
+
+// WaitForBlock waits until every node of the shard has block num in its chain
+func (c *Cluster) WaitForBlock(shardID uint32, num uint64, timeout time.Duration) error {
+	return c.WaitForNodes(c.Shard(shardID), num, timeout)
+}
+
+// WaitForNodes waits until each of the given nodes has block num in its chain
+func (c *Cluster) WaitForNodes(nodes []*node.Node, num uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for i, currentNode := range nodes {
+		currentNode := currentNode
+		if !c.WaitFor(func() bool {
+			return currentNode.Blockchain().CurrentBlock().NumberU64() >= num
+		}, time.Until(deadline)) {
+			return errors.Errorf(
+				"node %d of shard %d at block %d, expected %d",
+				i, currentNode.Consensus.ShardID, currentNode.Blockchain().CurrentBlock().NumberU64(), num,
+			)
+		}
+	}
+	return nil
+}
+
+// WaitFor waits until cond holds, false if it does not within timeout
+func (c *Cluster) WaitFor(cond func() bool, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		// a no-op unless the network runs on a manual clock
+		c.Network.Advance(pollInterval)
+		time.Sleep(pollInterval)
+		c.syncNodes()
+	}
+	return true
+}
+
+// syncNodes tells consensus of the nodes in syncing mode that their chain is
+// in sync, as the block syncing of a node does once it caught up. Nodes start
+// from genesis in syncing mode, where their timeouts are stopped.
+func (c *Cluster) syncNodes() {
+	for i := range c.consensus {
+		currentNode := c.Nodes[i]
+		if currentNode.Consensus.Mode() == consensus.Syncing &&
+			currentNode.Blockchain().CurrentBlock().NumberU64() > 0 {
+			currentNode.Consensus.BlocksSynchronized()
+		}
+	}
+}
+
+// Stop stops all the nodes
+func (c *Cluster) Stop() {
+	for i := range c.proposers {
+		close(c.proposers[i].stop)
+		<-c.proposers[i].stopped
+	}
+	// a leader may be waiting to ask its stopped proposer for a new block
+	for i := range c.consensus {
+		close(c.consensus[i].stop)
+		for stopped := false; !stopped; {
+			select {
+			case <-c.consensus[i].stopped:
+				stopped = true
+			case <-c.Nodes[i].Consensus.ReadySignal:
+			}
+		}
+	}
+	c.proposers, c.consensus = nil, nil
+	if c.cancelRecv != nil {
+		c.cancelRecv()
+	}
+	for i := range c.hosts {
+		if err := c.hosts[i].Close(); err != nil {
+			utils.Logger().Warn().Err(err).Int("node", i).Msg("[localnet] cannot close host")
+		}
+	}
+}
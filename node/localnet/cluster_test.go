@@ -0,0 +1,243 @@
+package localnet
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	shardingconfig "github.com/harmony-one/harmony/internal/configs/sharding"
+	"github.com/harmony-one/harmony/internal/genesis"
+	"github.com/harmony-one/harmony/internal/params"
+	"github.com/harmony-one/harmony/multibls"
+	"github.com/harmony-one/harmony/node"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/p2p/memhost"
+	"github.com/harmony-one/harmony/shard"
+	libp2p_peer "github.com/libp2p/go-libp2p-peer"
+)
+
+// testSchedule is the localnet schedule with a genesis committee of generated keys
+type testSchedule struct {
+	shardingconfig.Schedule
+	instance shardingconfig.Instance
+}
+
+func (s testSchedule) InstanceForEpoch(epoch *big.Int) shardingconfig.Instance {
+	return s.instance
+}
+
+// useTestNetwork makes generated keys the genesis committee, the k-th key goes
+// to shard k%numShards. The nodes run the mainnet genesis, since the genesis of
+// the test networks funds a contract deployer key which ecdsa.GenerateKey does
+// not derive the same way every time from its fixed stream.
+func useTestNetwork(
+	t *testing.T, numShards uint32, nodesPerShard int,
+) map[uint32][]*multibls.PrivateKey {
+	keys := map[uint32][]*multibls.PrivateKey{}
+	accounts := []genesis.DeployAccount{}
+	for k := 0; k < int(numShards)*nodesPerShard; k++ {
+		key := bls_cosi.RandPrivateKey()
+		shardID := uint32(k) % numShards
+		keys[shardID] = append(keys[shardID], multibls.GetPrivateKey(key))
+		accounts = append(accounts, genesis.DeployAccount{
+			Index:        strconv.Itoa(k),
+			Address:      common.BytesToAddress(key.GetPublicKey().Serialize()).Hex(),
+			BLSPublicKey: key.GetPublicKey().SerializeToHexStr(),
+		})
+	}
+	schedule := shardingconfig.LocalnetSchedule
+	instance, err := shardingconfig.NewInstance(
+		numShards, nodesPerShard, nodesPerShard, numeric.OneDec(), accounts, nil,
+		[]*big.Int{big.NewInt(0)}, schedule.BlocksPerEpoch(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousSchedule := shard.Schedule
+	previousNetworkType := nodeconfig.GetDefaultConfig().GetNetworkType()
+	shard.Schedule = testSchedule{Schedule: schedule, instance: instance}
+	nodeconfig.SetNetworkType(nodeconfig.Mainnet)
+	t.Cleanup(func() {
+		shard.Schedule = previousSchedule
+		nodeconfig.SetNetworkType(previousNetworkType)
+	})
+	return keys
+}
+
+func TestClusterConsensus(t *testing.T) {
+	const numBlocks = 3
+	keys := useTestNetwork(t, 2, 4)
+	network := memhost.NewNetwork(1)
+	network.UseManualClock()
+	network.SetDelay(5 * time.Millisecond)
+	cluster, err := NewCluster(network, keys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("cannot create cluster: %v", err)
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("cannot start cluster: %v", err)
+	}
+	defer cluster.Stop()
+
+	for shardID := range keys {
+		if len(cluster.Shard(shardID)) != len(keys[shardID]) {
+			t.Fatalf("expected %d nodes in shard %d", len(keys[shardID]), shardID)
+		}
+		if err := cluster.WaitForBlock(shardID, numBlocks, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		checkSameBlocks(t, cluster.Shard(shardID), numBlocks)
+	}
+}
+
+// phaseTimeout is past the timeout of a consensus phase, after which the
+// validators start a view change
+const phaseTimeout = time.Minute + time.Second
+
+// leaderOf returns the index of the leader among the nodes
+func leaderOf(t *testing.T, nodes []*node.Node) int {
+	for i, currentNode := range nodes {
+		if currentNode.Consensus.IsLeader() {
+			return i
+		}
+	}
+	t.Fatal("no leader")
+	return -1
+}
+
+// checkSameBlocks checks the nodes agree on the blocks 1..num
+func checkSameBlocks(t *testing.T, nodes []*node.Node, num uint64) {
+	for n := uint64(1); n <= num; n++ {
+		hash := nodes[0].Blockchain().GetBlockByNumber(n).Hash()
+		for i := range nodes[1:] {
+			if other := nodes[i+1].Blockchain().GetBlockByNumber(n); other.Hash() != hash {
+				t.Errorf("node %d has another block %d", i+1, n)
+			}
+		}
+	}
+}
+
+func TestClusterViewChange(t *testing.T) {
+	keys := useTestNetwork(t, 1, 4)
+	network := memhost.NewNetwork(1)
+	network.UseManualClock()
+	network.SetDelay(5 * time.Millisecond)
+	cluster, err := NewCluster(network, keys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("cannot create cluster: %v", err)
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("cannot start cluster: %v", err)
+	}
+	defer cluster.Stop()
+	if err := cluster.WaitForBlock(0, 1, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	leader := leaderOf(t, cluster.Nodes)
+	leaderKey := cluster.Nodes[leader].Consensus.LeaderPubKey
+	others := append(append([]*node.Node{}, cluster.Nodes[:leader]...), cluster.Nodes[leader+1:]...)
+	network.Partition([]libp2p_peer.ID{cluster.Host(leader).GetID()})
+	head := uint64(0)
+	for _, currentNode := range others {
+		if num := currentNode.Blockchain().CurrentBlock().NumberU64(); num > head {
+			head = num
+		}
+	}
+	// the others time out waiting for the leader and elect the next one
+	network.Advance(phaseTimeout)
+	if err := cluster.WaitForNodes(others, head+1, time.Minute); err != nil {
+		t.Fatalf("no block after the leader was partitioned away: %v", err)
+	}
+	for i, currentNode := range others {
+		if currentNode.Consensus.LeaderPubKey.IsEqual(leaderKey) {
+			t.Errorf("node %d still follows the partitioned leader", i)
+		}
+	}
+
+	network.Heal()
+	if err := cluster.WaitForNodes(others, head+3, time.Minute); err != nil {
+		t.Fatalf("no block after the partition healed: %v", err)
+	}
+	checkSameBlocks(t, others, head+3)
+}
+
+func TestClusterMessageLoss(t *testing.T) {
+	const numBlocks = 3
+	keys := useTestNetwork(t, 1, 7)
+	network := memhost.NewNetwork(1)
+	network.UseManualClock()
+	network.SetDelay(50 * time.Millisecond)
+	cluster, err := NewCluster(network, keys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("cannot create cluster: %v", err)
+	}
+	// a validator is silent and another one hears nothing from the leader,
+	// which leaves the leader and four validators, just a quorum of seven
+	leader := leaderOf(t, cluster.Nodes)
+	silent, deaf := (leader+1)%len(cluster.Nodes), (leader+2)%len(cluster.Nodes)
+	leaderID, silentID, deafID := cluster.Host(leader).GetID(), cluster.Host(silent).GetID(), cluster.Host(deaf).GetID()
+	network.SetFilter(func(from, to libp2p_peer.ID, msg []byte) bool {
+		return from != silentID && (from != leaderID || to != deafID)
+	})
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("cannot start cluster: %v", err)
+	}
+	defer cluster.Stop()
+
+	voters := []*node.Node{}
+	for i, currentNode := range cluster.Nodes {
+		if i != silent && i != deaf {
+			voters = append(voters, currentNode)
+		}
+	}
+	if err := cluster.WaitForNodes(voters, numBlocks, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	checkSameBlocks(t, voters, numBlocks)
+}
+
+func TestClusterCrossLink(t *testing.T) {
+	keys := useTestNetwork(t, 2, 4)
+	// the mainnet chain config of the nodes has no crosslinks yet
+	previousCrossLinkEpoch := params.MainnetChainConfig.CrossLinkEpoch
+	params.MainnetChainConfig.CrossLinkEpoch = big.NewInt(0)
+	defer func() { params.MainnetChainConfig.CrossLinkEpoch = previousCrossLinkEpoch }()
+	network := memhost.NewNetwork(1)
+	network.UseManualClock()
+	network.SetDelay(5 * time.Millisecond)
+	cluster, err := NewCluster(network, keys, 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("cannot create cluster: %v", err)
+	}
+	if err := cluster.Start(); err != nil {
+		t.Fatalf("cannot start cluster: %v", err)
+	}
+	defer cluster.Stop()
+
+	// the leader of shard 1 sends the crosslinks of its blocks to the beacon
+	// chain, whose leader proposes them in a block
+	for i, currentNode := range cluster.Shard(shard.BeaconChainShardID) {
+		beaconchain := currentNode.Blockchain()
+		if !cluster.WaitFor(func() bool {
+			_, err := beaconchain.ReadShardLastCrossLink(1)
+			return err == nil
+		}, time.Minute) {
+			t.Fatalf("no crosslink of shard 1 on beacon node %d at block %d",
+				i, beaconchain.CurrentBlock().NumberU64())
+		}
+		link, err := beaconchain.ReadShardLastCrossLink(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		linked := cluster.Shard(1)[0].Blockchain().GetHeaderByNumber(link.BlockNum())
+		if linked == nil || linked.Hash() != link.Hash() {
+			t.Errorf("beacon node %d links block %d %s, not in shard 1",
+				i, link.BlockNum(), link.Hash().Hex())
+		}
+	}
+}
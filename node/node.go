@@ -141,6 +141,9 @@ type Node struct {
 
 	peerRegistrationRecord map[string]*syncConfig // record registration time (unixtime) of peers begin in syncing
 	SyncingPeerProvider    SyncingPeerProvider
+	// BeaconCheckpoint, if set, is the trusted block a new beacon chain is
+	// synced from instead of from genesis.
+	BeaconCheckpoint *core.Checkpoint
 
 	// The p2p host used to send/receive p2p messages
 	host p2p.Host
@@ -162,6 +165,9 @@ type Node struct {
 	// Client Message Receiver to handle light client messages
 	// Beacon leader needs to use this receiver to talk to new node
 	clientReceiver p2p.GroupReceiver
+	// Receivers of the votes aggregated by my keys, by group
+	aggregatorReceivers      map[nodeconfig.GroupID]p2p.GroupReceiver
+	aggregatorReceiversMutex sync.Mutex
 	// Duplicated Ping Message Received
 	duplicatedPing sync.Map
 	// Channel to notify consensus service to really start consensus
@@ -393,9 +399,35 @@ func (node *Node) StartServer() {
 	// start the goroutine to receive supercommittee level messages
 	// FIXME (leo): we use beacon client topic as the global topic for now
 	node.startRxPipeline(node.globalGroupReceiver, node.globalRxQueue, GlobalRxWorkers)
+	node.ReceiveAggregatorGroups()
 	select {}
 }
 
+// ReceiveAggregatorGroups starts receiving the votes sent to any of the
+// consensus keys as their aggregator, for the keys it does not receive them yet.
+// The votes are handled along with the messages of the shard group.
+func (node *Node) ReceiveAggregatorGroups() {
+	node.aggregatorReceiversMutex.Lock()
+	defer node.aggregatorReceiversMutex.Unlock()
+	if node.aggregatorReceivers == nil {
+		node.aggregatorReceivers = map[nodeconfig.GroupID]p2p.GroupReceiver{}
+	}
+	for _, group := range node.Consensus.AggregatorGroups() {
+		if _, ok := node.aggregatorReceivers[group]; ok {
+			continue
+		}
+		receiver, err := node.host.GroupReceiver(group)
+		if err != nil {
+			utils.Logger().Error().Err(err).
+				Str("group", group.String()).
+				Msg("Failed to create aggregator receiver")
+			continue
+		}
+		node.aggregatorReceivers[group] = receiver
+		go node.receiveGroupMessage(receiver, node.shardRxQueue)
+	}
+}
+
 // GetSyncID returns the syncID of this node
 func (node *Node) GetSyncID() [SyncIDLength]byte {
 	return node.syncID
@@ -609,7 +641,7 @@ func (node *Node) InitConsensusWithValidators() (err error) {
 	}
 
 	for _, key := range pubKeys {
-		if node.Consensus.PubKey.Contains(key) {
+		if node.Consensus.GetPublicKeys().Contains(key) {
 			utils.Logger().Info().
 				Uint64("blockNum", blockNum).
 				Int("numPubKeys", len(pubKeys)).
@@ -694,6 +726,7 @@ func (node *Node) ServiceManager() *service.Manager {
 
 // ShutDown gracefully shut down the node server and dump the in-memory blockchain state into DB.
 func (node *Node) ShutDown() {
+	node.Consensus.CloseEventRecorder()
 	node.Blockchain().Stop()
 	node.Beaconchain().Stop()
 	msg := "Successfully shut down!\n"
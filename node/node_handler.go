@@ -420,7 +420,7 @@ func (node *Node) numSignaturesIncludedInBlock(block *types.Block) uint32 {
 	if err != nil {
 		return count
 	}
-	for _, key := range node.Consensus.PubKey.PublicKey {
+	for _, key := range node.Consensus.GetPublicKeys().PublicKey {
 		if ok, err := mask.KeyEnabled(key); err == nil && ok {
 			count++
 		}
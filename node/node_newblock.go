@@ -18,20 +18,32 @@ import (
 const (
 	SleepPeriod           = 20 * time.Millisecond
 	IncomingReceiptsLimit = 6000 // 2000 * (numShards - 1)
+	// ConsensusReadyDelay is how long, on the clock of consensus, the proposer
+	// waits for the other nodes to be ready before its first block
+	ConsensusReadyDelay = 30 * time.Second
 )
 
 // WaitForConsensusReadyV2 listen for the readiness signal from consensus and generate new block for consensus.
 // only leader will receive the ready signal
 // TODO: clean pending transactions for validators; or validators not prepare pending transactions
 func (node *Node) WaitForConsensusReadyV2(readySignal chan struct{}, stopChan chan struct{}, stoppedChan chan struct{}) {
+	// Wait for other nodes to be ready (test-only)
+	ready := node.Consensus.Now().Add(ConsensusReadyDelay)
 	go func() {
 		// Setup stoppedChan
 		defer close(stoppedChan)
 
 		utils.Logger().Debug().
 			Msg("Waiting for Consensus ready")
-		// TODO: make local net start faster
-		time.Sleep(30 * time.Second) // Wait for other nodes to be ready (test-only)
+		for node.Consensus.Now().Before(ready) {
+			select {
+			case <-stopChan:
+				utils.Logger().Debug().
+					Msg("Consensus new block proposal: STOPPED!")
+				return
+			case <-time.After(SleepPeriod):
+			}
+		}
 
 		for {
 			// keep waiting for Consensus ready
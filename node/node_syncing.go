@@ -19,6 +19,8 @@ import (
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/node/worker"
 	"github.com/harmony-one/harmony/p2p"
+	"github.com/harmony-one/harmony/shard"
+	libp2p_host "github.com/libp2p/go-libp2p-core/host"
 )
 
 // Constants related to doing syncing.
@@ -48,10 +50,20 @@ func (node *Node) DoSyncWithoutConsensus() {
 	go node.DoSyncing(node.Blockchain(), node.Worker, false) //Don't join consensus
 }
 
+// createStateSync returns a new state syncing of the chain of the given shard,
+// which queries the peers over libp2p streams if the node syncs over streams.
+func (node *Node) createStateSync(shardID uint32) *syncing.StateSync {
+	stateSync := syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+	if node.NodeConfig.StreamSync && node.host != nil {
+		stateSync.EnableStreams(node.host.GetP2PHost(), shardID)
+	}
+	return stateSync
+}
+
 // IsSameHeight tells whether node is at same bc height as a peer
 func (node *Node) IsSameHeight() (uint64, bool) {
 	if node.stateSync == nil {
-		node.stateSync = syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+		node.stateSync = node.createStateSync(node.Blockchain().ShardID())
 	}
 	return node.stateSync.IsSameBlockchainHeight(node.Blockchain())
 }
@@ -121,6 +133,35 @@ func (p *DNSSyncingPeerProvider) SyncingPeers(shardID uint32) (peers []p2p.Peer,
 	return peers, nil
 }
 
+// StreamSyncingPeerProvider uses the peers connected to the p2p host, which are
+// found through the libp2p DHT, serving the syncing stream protocol of a shard.
+type StreamSyncingPeerProvider struct {
+	host libp2p_host.Host
+}
+
+// NewStreamSyncingPeerProvider returns a provider of the peers connected to the
+// given p2p host which serve the syncing stream protocol of the shard queried.
+func NewStreamSyncingPeerProvider(host p2p.Host) *StreamSyncingPeerProvider {
+	return &StreamSyncingPeerProvider{host: host.GetP2PHost()}
+}
+
+// SyncingPeers returns the connected peers serving the chain of the given shard.
+func (p *StreamSyncingPeerProvider) SyncingPeers(shardID uint32) (peers []p2p.Peer, err error) {
+	protocolID := string(downloader.StreamProtocolID(shardID))
+	for _, peerID := range p.host.Network().Peers() {
+		protocols, err := p.host.Peerstore().SupportsProtocols(peerID, protocolID)
+		if err != nil || len(protocols) == 0 {
+			continue
+		}
+		peers = append(peers, p2p.Peer{PeerID: peerID, Addrs: p.host.Peerstore().Addrs(peerID)})
+	}
+	if len(peers) == 0 {
+		return nil, errors.Errorf(
+			"[SYNC] no connected peer serves protocol %s", protocolID)
+	}
+	return peers, nil
+}
+
 // LocalSyncingPeerProvider uses localnet deployment convention to synthesize
 // syncing peers.
 type LocalSyncingPeerProvider struct {
@@ -178,7 +219,7 @@ func (node *Node) DoBeaconSyncing() {
 	for {
 		if node.beaconSync == nil {
 			utils.Logger().Info().Msg("initializing beacon sync")
-			node.beaconSync = syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+			node.beaconSync = node.createStateSync(shard.BeaconChainShardID)
 		}
 		if node.beaconSync.GetActivePeerNumber() == 0 {
 			utils.Logger().Info().Msg("no peers; bootstrapping beacon sync config")
@@ -195,6 +236,11 @@ func (node *Node) DoBeaconSyncing() {
 				continue
 			}
 		}
+		if err := node.checkpointSync(node.beaconSync, node.Beaconchain()); err != nil {
+			utils.Logger().Warn().Err(err).Msg("[SYNC] beacon checkpoint sync failed, will retry")
+			time.Sleep(time.Duration(SyncFrequency) * time.Second)
+			continue
+		}
 		node.beaconSync.SyncLoop(node.Beaconchain(), node.BeaconWorker, true, nil)
 		time.Sleep(time.Duration(SyncFrequency) * time.Second)
 	}
@@ -217,7 +263,7 @@ func (node *Node) DoSyncing(bc *core.BlockChain, worker *worker.Worker, willJoin
 // doSync keep the node in sync with other peers, willJoinConsensus means the node will try to join consensus after catch up
 func (node *Node) doSync(bc *core.BlockChain, worker *worker.Worker, willJoinConsensus bool) {
 	if node.stateSync == nil {
-		node.stateSync = syncing.CreateStateSync(node.SelfPeer.IP, node.SelfPeer.Port, node.GetSyncID())
+		node.stateSync = node.createStateSync(node.Blockchain().ShardID())
 		utils.Logger().Debug().Msg("[SYNC] initialized state sync")
 	}
 	if node.stateSync.GetActivePeerNumber() < MinConnectedPeers {
@@ -247,6 +293,16 @@ func (node *Node) doSync(bc *core.BlockChain, worker *worker.Worker, willJoinCon
 		if willJoinConsensus {
 			node.Consensus.BlocksNotSynchronized()
 		}
+		if node.NodeConfig.FastSync && bc.ShardID() != shard.BeaconChainShardID {
+			if err := node.stateSync.FastSync(bc); err != nil {
+				utils.Logger().Warn().Err(err).Msg("[SYNC] fast sync failed, will retry")
+				return
+			}
+		}
+		if err := node.checkpointSync(node.stateSync, bc); err != nil {
+			utils.Logger().Warn().Err(err).Msg("[SYNC] checkpoint sync failed, will retry")
+			return
+		}
 		node.stateSync.SyncLoop(bc, worker, false, node.Consensus)
 		if willJoinConsensus {
 			node.stateMutex.Lock()
@@ -260,6 +316,15 @@ func (node *Node) doSync(bc *core.BlockChain, worker *worker.Worker, willJoinCon
 	node.stateMutex.Unlock()
 }
 
+// checkpointSync syncs the given chain from BeaconCheckpoint if it is a new
+// beacon chain and a checkpoint is set.
+func (node *Node) checkpointSync(stateSync *syncing.StateSync, bc *core.BlockChain) error {
+	if node.BeaconCheckpoint == nil || bc.ShardID() != shard.BeaconChainShardID {
+		return nil
+	}
+	return stateSync.CheckpointSync(bc, node.BeaconCheckpoint)
+}
+
 // SupportBeaconSyncing sync with beacon chain for archival node in beacon chan or non-beacon node
 func (node *Node) SupportBeaconSyncing() {
 	go node.DoBeaconSyncing()
@@ -299,6 +364,9 @@ func (node *Node) StartSyncingServer() {
 	if node.downloaderServer.GrpcServer == nil {
 		node.downloaderServer.Start(node.SelfPeer.IP, syncing.GetSyncingPort(node.SelfPeer.Port))
 	}
+	if node.host != nil {
+		node.downloaderServer.StartStream(node.host.GetP2PHost(), node.Blockchain().ShardID())
+	}
 }
 
 // SendNewBlockToUnsync send latest verified block to unsync, registered nodes
@@ -406,6 +474,76 @@ func (node *Node) CalculateResponse(request *downloader_pb.DownloaderRequest, in
 			}
 		}
 
+	case downloader_pb.DownloaderRequest_RECEIPT:
+		// one payload per block, in order, so the receipts can be matched
+		// with the blocks they are for
+		var hash common.Hash
+		for _, bytes := range request.Hashes {
+			hash.SetBytes(bytes)
+			receipts := node.Blockchain().GetReceiptsByHash(hash)
+			if receipts == nil {
+				receipts = types.Receipts{}
+			}
+			encodedReceipts, err := rlp.EncodeToBytes(receipts)
+			if err != nil {
+				return response, err
+			}
+			response.Payload = append(response.Payload, encodedReceipts)
+		}
+
+	case downloader_pb.DownloaderRequest_STATETRIE:
+		if len(request.Hashes) > syncing.StateSyncBatchSize {
+			return response, fmt.Errorf("[SYNC] GetTrieNodes Request contains too many hashes %v", len(request.Hashes))
+		}
+		var hash common.Hash
+		for _, bytes := range request.Hashes {
+			hash.SetBytes(bytes)
+			data, err := node.Blockchain().TrieNode(hash)
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			response.Payload = append(response.Payload, data)
+		}
+
+	case downloader_pb.DownloaderRequest_STATEPROOF:
+		// the first hash is the address, followed by the storage keys
+		if len(request.Hashes) == 0 || len(request.Hashes) > syncing.StateProofKeysLimit+1 {
+			return response, fmt.Errorf("[SYNC] GetStateProof Request contains %v hashes", len(request.Hashes))
+		}
+		header := node.Blockchain().GetHeaderByHash(common.BytesToHash(request.BlockHash))
+		if header == nil {
+			return response, fmt.Errorf("[SYNC] GetStateProof Request cannot find block %x", request.BlockHash)
+		}
+		stateDB, err := node.Blockchain().StateAt(header.Root())
+		if err != nil {
+			return response, errors.Wrapf(err, "[SYNC] GetStateProof Request no state of block %x", request.BlockHash)
+		}
+		address := common.BytesToAddress(request.Hashes[0])
+		proof, err := stateDB.GetProof(address)
+		if err != nil {
+			return response, err
+		}
+		encoded, err := rlp.EncodeToBytes(proof)
+		if err != nil {
+			return response, err
+		}
+		response.Payload = append(response.Payload, encoded)
+		// an account missing from the state has no storage to prove
+		exists := stateDB.Exist(address)
+		for _, key := range request.Hashes[1:] {
+			proof := [][]byte{}
+			if exists {
+				if proof, err = stateDB.GetStorageProof(address, common.BytesToHash(key)); err != nil {
+					return response, errors.Wrapf(err, "[SYNC] GetStateProof Request cannot prove storage %x", key)
+				}
+			}
+			encoded, err := rlp.EncodeToBytes(proof)
+			if err != nil {
+				return response, err
+			}
+			response.Payload = append(response.Payload, encoded)
+		}
+
 	case downloader_pb.DownloaderRequest_BLOCKHEIGHT:
 		response.BlockHeight = node.Blockchain().CurrentBlock().NumberU64()
 
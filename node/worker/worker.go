@@ -379,6 +379,7 @@ func (w *Worker) verifySlashes(
 			w.chain, workingState, &d[i],
 		); err != nil {
 			failures = append(failures, d[i])
+			continue
 		}
 		successes = append(successes, d[i])
 	}
@@ -0,0 +1,356 @@
+// Package memhost implements p2p.Host over an in-memory network, so that many
+// nodes run in one process without any socket. The network decides for every
+// message whether and when it is delivered, which lets tests delay, drop or
+// partition consensus traffic. On a manual clock, messages are only delivered
+// when the test advances the clock, in the order they were sent.
+package memhost
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/p2p"
+	libp2p_host "github.com/libp2p/go-libp2p-host"
+	libp2p_peer "github.com/libp2p/go-libp2p-peer"
+	"github.com/pkg/errors"
+)
+
+var (
+	errReceiverClosed = errors.New("group receiver closed")
+)
+
+// Filter tells whether a message from one host to another gets delivered
+type Filter func(from, to libp2p_peer.ID, msg []byte) bool
+
+// Network connects in-memory hosts
+type Network struct {
+	mutex    sync.Mutex
+	hosts    []*Host
+	delay    time.Duration
+	dropRate float64
+	rand     *rand.Rand
+	// partition index of each host, hosts only reach hosts of the same partition
+	partitions map[libp2p_peer.ID]int
+	filter     Filter
+	// manual tells whether delivery waits for Advance, at the network time now
+	// since start
+	manual  bool
+	start   time.Time
+	now     time.Duration
+	pending []pendingMessage
+}
+
+// pendingMessage is a message waiting for the manual clock to reach due
+type pendingMessage struct {
+	due      time.Duration
+	receiver *groupReceiver
+	msg      []byte
+	sender   libp2p_peer.ID
+}
+
+// NewNetwork creates an empty network, seed drives the random message drops
+func NewNetwork(seed int64) *Network {
+	return &Network{
+		rand:       rand.New(rand.NewSource(seed)),
+		partitions: map[libp2p_peer.ID]int{},
+		start:      time.Now(),
+	}
+}
+
+// NewHost adds a host to the network
+func (n *Network) NewHost(self p2p.Peer) *Host {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	self.PeerID = libp2p_peer.ID(fmt.Sprintf("memhost-%d", len(n.hosts)))
+	host := &Host{
+		network:   n,
+		self:      self,
+		receivers: map[nodeconfig.GroupID][]*groupReceiver{},
+	}
+	n.hosts = append(n.hosts, host)
+	return host
+}
+
+// SetDelay delays the delivery of all messages
+func (n *Network) SetDelay(delay time.Duration) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.delay = delay
+}
+
+// UseManualClock makes the network deliver messages only when Advance moves
+// its clock past their delay, instead of on the wall clock
+func (n *Network) UseManualClock() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.manual = true
+}
+
+// Advance moves the manual clock forward by d and delivers the messages due
+// by then, earliest first and in the order they were sent at the same time.
+// Returns the number of messages delivered.
+func (n *Network) Advance(d time.Duration) int {
+	n.mutex.Lock()
+	n.now += d
+	// the sort is stable, so messages due at the same time stay in send order
+	sort.SliceStable(n.pending, func(i, j int) bool {
+		return n.pending[i].due < n.pending[j].due
+	})
+	count := sort.Search(len(n.pending), func(i int) bool {
+		return n.pending[i].due > n.now
+	})
+	due := n.pending[:count]
+	n.pending = append([]pendingMessage{}, n.pending[count:]...)
+	n.mutex.Unlock()
+	for _, message := range due {
+		message.receiver.push(message.msg, message.sender)
+	}
+	return len(due)
+}
+
+// Now returns the time on the manual clock, or the wall clock if the network has none
+func (n *Network) Now() time.Time {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if !n.manual {
+		return time.Now()
+	}
+	return n.start.Add(n.now)
+}
+
+// Pending returns the number of messages waiting for the manual clock
+func (n *Network) Pending() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return len(n.pending)
+}
+
+// SetDropRate drops each message with the given probability
+func (n *Network) SetDropRate(rate float64) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.dropRate = rate
+}
+
+// SetFilter only delivers the messages accepted by filter, nil accepts all
+func (n *Network) SetFilter(filter Filter) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.filter = filter
+}
+
+// Partition splits the network, hosts of different groups cannot reach each other.
+// Hosts in none of the groups form one more partition.
+func (n *Network) Partition(groups ...[]libp2p_peer.ID) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.partitions = map[libp2p_peer.ID]int{}
+	for i, group := range groups {
+		for _, id := range group {
+			n.partitions[id] = i + 1
+		}
+	}
+}
+
+// Heal removes all the partitions
+func (n *Network) Heal() {
+	n.Partition()
+}
+
+// deliverable tells whether the message reaches the destination, caller holds the lock
+func (n *Network) deliverable(from, to libp2p_peer.ID, msg []byte) bool {
+	if n.partitions[from] != n.partitions[to] {
+		return false
+	}
+	if n.filter != nil && !n.filter(from, to, msg) {
+		return false
+	}
+	if from != to && n.dropRate > 0 && n.rand.Float64() < n.dropRate {
+		return false
+	}
+	return true
+}
+
+func (n *Network) send(from *Host, groups []nodeconfig.GroupID, msg []byte) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	sender := from.self.PeerID
+	for _, group := range groups {
+		for _, host := range n.hosts {
+			if !n.deliverable(sender, host.self.PeerID, msg) {
+				continue
+			}
+			for _, receiver := range host.groupReceivers(group) {
+				receiver := receiver
+				if n.manual {
+					n.pending = append(n.pending, pendingMessage{
+						due: n.now + n.delay, receiver: receiver, msg: msg, sender: sender,
+					})
+					continue
+				}
+				if n.delay == 0 {
+					receiver.push(msg, sender)
+					continue
+				}
+				time.AfterFunc(n.delay, func() { receiver.push(msg, sender) })
+			}
+		}
+	}
+}
+
+// Host is a p2p.Host on an in-memory network
+type Host struct {
+	network   *Network
+	self      p2p.Peer
+	mutex     sync.Mutex
+	receivers map[nodeconfig.GroupID][]*groupReceiver
+}
+
+// GetSelfPeer gets self peer
+func (host *Host) GetSelfPeer() p2p.Peer {
+	return host.self
+}
+
+// Close closes all the group receivers of the host
+func (host *Host) Close() error {
+	host.mutex.Lock()
+	receivers := host.receivers
+	host.receivers = map[nodeconfig.GroupID][]*groupReceiver{}
+	host.mutex.Unlock()
+	for _, group := range receivers {
+		for _, receiver := range group {
+			receiver.Close()
+		}
+	}
+	return nil
+}
+
+// AddPeer is a no-op, every host of the network is a peer
+func (host *Host) AddPeer(*p2p.Peer) error {
+	return nil
+}
+
+// GetID returns the peer ID of the host
+func (host *Host) GetID() libp2p_peer.ID {
+	return host.self.PeerID
+}
+
+// GetP2PHost returns nil, there is no libp2p host behind the in-memory one
+func (host *Host) GetP2PHost() libp2p_host.Host {
+	return nil
+}
+
+// GetPeerCount returns the number of other hosts on the network
+func (host *Host) GetPeerCount() int {
+	host.network.mutex.Lock()
+	defer host.network.mutex.Unlock()
+	return len(host.network.hosts) - 1
+}
+
+// ConnectHostPeer is a no-op, every host of the network is connected
+func (host *Host) ConnectHostPeer(p2p.Peer) {}
+
+// SendMessageToGroups sends a message to one or more multicast groups
+func (host *Host) SendMessageToGroups(groups []nodeconfig.GroupID, msg []byte) error {
+	host.network.send(host, groups, msg)
+	return nil
+}
+
+// GroupReceiver returns a receiver of messages sent to a multicast group
+func (host *Host) GroupReceiver(group nodeconfig.GroupID) (p2p.GroupReceiver, error) {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+	receiver := &groupReceiver{host: host, group: group, notify: make(chan struct{}, 1)}
+	host.receivers[group] = append(host.receivers[group], receiver)
+	return receiver, nil
+}
+
+func (host *Host) groupReceivers(group nodeconfig.GroupID) []*groupReceiver {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+	return append([]*groupReceiver{}, host.receivers[group]...)
+}
+
+func (host *Host) removeReceiver(receiver *groupReceiver) {
+	host.mutex.Lock()
+	defer host.mutex.Unlock()
+	receivers := host.receivers[receiver.group]
+	for i := range receivers {
+		if receivers[i] == receiver {
+			host.receivers[receiver.group] = append(receivers[:i:i], receivers[i+1:]...)
+			return
+		}
+	}
+}
+
+type envelope struct {
+	msg    []byte
+	sender libp2p_peer.ID
+}
+
+// groupReceiver queues the messages of one group without bound, so that
+// a slow consumer never blocks the senders
+type groupReceiver struct {
+	host   *Host
+	group  nodeconfig.GroupID
+	mutex  sync.Mutex
+	queue  []envelope
+	closed bool
+	notify chan struct{}
+}
+
+func (r *groupReceiver) push(msg []byte, sender libp2p_peer.ID) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed {
+		return
+	}
+	r.queue = append(r.queue, envelope{msg: msg, sender: sender})
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Receive a message
+func (r *groupReceiver) Receive(ctx context.Context) ([]byte, libp2p_peer.ID, error) {
+	for {
+		r.mutex.Lock()
+		if r.closed {
+			r.mutex.Unlock()
+			return nil, "", errReceiverClosed
+		}
+		if len(r.queue) > 0 {
+			next := r.queue[0]
+			r.queue = r.queue[1:]
+			r.mutex.Unlock()
+			return next.msg, next.sender, nil
+		}
+		r.mutex.Unlock()
+		select {
+		case <-r.notify:
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+}
+
+// Close closes the receiver, pending messages are discarded
+func (r *groupReceiver) Close() error {
+	r.mutex.Lock()
+	if r.closed {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.queue = nil
+	close(r.notify)
+	r.mutex.Unlock()
+	r.host.removeReceiver(r)
+	return nil
+}
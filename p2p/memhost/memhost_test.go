@@ -0,0 +1,130 @@
+package memhost
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	nodeconfig "github.com/harmony-one/harmony/internal/configs/node"
+	"github.com/harmony-one/harmony/p2p"
+	libp2p_peer "github.com/libp2p/go-libp2p-peer"
+)
+
+func receiveWithin(t *testing.T, receiver p2p.GroupReceiver, timeout time.Duration) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	msg, _, err := receiver.Receive(ctx)
+	if err == context.DeadlineExceeded {
+		return nil, false
+	}
+	if err != nil {
+		t.Fatalf("receive failed: %v", err)
+	}
+	return msg, true
+}
+
+func TestNetwork(t *testing.T) {
+	network := NewNetwork(1)
+	group := nodeconfig.NewGroupIDByShardID(0)
+	hosts := []*Host{}
+	receivers := []p2p.GroupReceiver{}
+	for i := 0; i < 3; i++ {
+		host := network.NewHost(p2p.Peer{})
+		receiver, err := host.GroupReceiver(group)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts = append(hosts, host)
+		receivers = append(receivers, receiver)
+	}
+	if hosts[0].GetPeerCount() != 2 {
+		t.Errorf("expected 2 peers, got %d", hosts[0].GetPeerCount())
+	}
+
+	hosts[0].SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("all"))
+	for i := range receivers {
+		if msg, ok := receiveWithin(t, receivers[i], time.Second); !ok || string(msg) != "all" {
+			t.Errorf("host %d did not receive the message", i)
+		}
+	}
+
+	network.Partition([]libp2p_peer.ID{hosts[0].GetID(), hosts[1].GetID()})
+	hosts[0].SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("partitioned"))
+	for i := 0; i < 2; i++ {
+		if _, ok := receiveWithin(t, receivers[i], time.Second); !ok {
+			t.Errorf("host %d of the same partition did not receive the message", i)
+		}
+	}
+	if _, ok := receiveWithin(t, receivers[2], 50*time.Millisecond); ok {
+		t.Error("message crossed the partition")
+	}
+
+	network.Heal()
+	network.SetDelay(20 * time.Millisecond)
+	sent := time.Now()
+	hosts[2].SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("delayed"))
+	if _, ok := receiveWithin(t, receivers[0], time.Second); !ok {
+		t.Fatal("healed network did not deliver the message")
+	}
+	if time.Since(sent) < 20*time.Millisecond {
+		t.Error("message delivered before the delay")
+	}
+
+	network.SetDelay(0)
+	network.SetDropRate(1)
+	hosts[1].SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("dropped"))
+	if _, ok := receiveWithin(t, receivers[0], 50*time.Millisecond); ok {
+		t.Error("message not dropped")
+	}
+
+	receivers[0].Close()
+	if _, _, err := receivers[0].Receive(context.Background()); err != errReceiverClosed {
+		t.Errorf("expected %v, got %v", errReceiverClosed, err)
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	network := NewNetwork(1)
+	network.UseManualClock()
+	start := network.Now()
+	defer func() {
+		if elapsed := network.Now().Sub(start); elapsed != 20*time.Millisecond {
+			t.Errorf("expected the clock moved by 20ms, got %v", elapsed)
+		}
+	}()
+	group := nodeconfig.NewGroupIDByShardID(0)
+	sender := network.NewHost(p2p.Peer{})
+	receiver, err := network.NewHost(p2p.Peer{}).GroupReceiver(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	network.SetDelay(20 * time.Millisecond)
+	sender.SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("late"))
+	network.SetDelay(10 * time.Millisecond)
+	sender.SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("first"))
+	sender.SendMessageToGroups([]nodeconfig.GroupID{group}, []byte("second"))
+	if _, ok := receiveWithin(t, receiver, 50*time.Millisecond); ok {
+		t.Fatal("message delivered before the clock moved")
+	}
+	if n := network.Advance(5 * time.Millisecond); n != 0 {
+		t.Errorf("delivered %d messages before their delay", n)
+	}
+	if n := network.Advance(5 * time.Millisecond); n != 2 {
+		t.Errorf("expected 2 messages delivered, got %d", n)
+	}
+	for _, want := range []string{"first", "second"} {
+		if msg, ok := receiveWithin(t, receiver, time.Second); !ok || string(msg) != want {
+			t.Errorf("expected %q, got %q", want, msg)
+		}
+	}
+	if network.Pending() != 1 {
+		t.Errorf("expected 1 pending message, got %d", network.Pending())
+	}
+	if n := network.Advance(10 * time.Millisecond); n != 1 {
+		t.Errorf("expected the late message delivered, got %d", n)
+	}
+	if msg, ok := receiveWithin(t, receiver, time.Second); !ok || string(msg) != "late" {
+		t.Errorf("expected %q, got %q", "late", msg)
+	}
+}
@@ -5,6 +5,7 @@ export GO111MODULE=on
 declare -A SRC
 SRC[harmony]=cmd/harmony/main.go
 SRC[bootnode]=cmd/bootnode/main.go
+SRC[election]=cmd/election/main.go
 
 BINDIR=bin
 BUCKET=unique-bucket-bin
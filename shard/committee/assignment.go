@@ -88,6 +88,13 @@ func NewEPoSRound(stakedReader StakingCandidatesReader) (
 	maxExternalSlots := shard.ExternalSlotsAvailableForEpoch(
 		stakedReader.CurrentBlock().Epoch(),
 	)
+	return completeEPoSRound(eligibleCandidate, maxExternalSlots), nil
+}
+
+func completeEPoSRound(
+	eligibleCandidate map[common.Address]*effective.SlotOrder,
+	maxExternalSlots int,
+) *CompletedEPoSRound {
 	median, winners := effective.Apply(
 		eligibleCandidate, maxExternalSlots,
 	)
@@ -119,13 +126,16 @@ func NewEPoSRound(stakedReader StakingCandidatesReader) (
 		MaximumExternalSlot: maxExternalSlots,
 		AuctionWinners:      winners,
 		AuctionCandidates:   auctionCandidates,
-	}, nil
+	}
 }
 
 func prepareOrders(
 	stakedReader StakingCandidatesReader,
 ) (map[common.Address]*effective.SlotOrder, error) {
 	candidates := stakedReader.ValidatorCandidates()
+	// the committee is elected for the next epoch,
+	// so with the slot keys rotated by then
+	nextEpoch := new(big.Int).Add(stakedReader.CurrentBlock().Epoch(), common.Big1)
 	blsKeys := map[shard.BLSPublicKey]struct{}{}
 	essentials := map[common.Address]*effective.SlotOrder{}
 	totalStaked, tempZero := big.NewInt(0), numeric.ZeroDec()
@@ -156,8 +166,9 @@ func prepareOrders(
 
 		totalStaked.Add(totalStaked, validatorStake)
 
+		slotPubKeys := validator.SlotPubKeysAt(nextEpoch)
 		found := false
-		for _, key := range validator.SlotPubKeys {
+		for _, key := range slotPubKeys {
 			if _, ok := blsKeys[key]; ok {
 				found = true
 			} else {
@@ -171,7 +182,7 @@ func prepareOrders(
 
 		essentials[validator.Address] = &effective.SlotOrder{
 			validatorStake,
-			validator.SlotPubKeys,
+			slotPubKeys,
 			tempZero,
 		}
 	}
@@ -0,0 +1,140 @@
+package committee
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	common2 "github.com/harmony-one/harmony/internal/common"
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/staking/effective"
+	"github.com/pkg/errors"
+)
+
+var (
+	errNotEligibleForEPoS = errors.New("validator is not eligible for the epos auction")
+)
+
+// DryRunValidator is how a candidate fares in a dry run of the election
+type DryRunValidator struct {
+	Validator      common.Address
+	Stake          *big.Int
+	NumSlots       int
+	SlotsWon       int
+	EffectiveStake numeric.Dec
+	// StakeNeededToWin is the stake to add for all of its slots to win,
+	// nil when it has more slots than can be won
+	StakeNeededToWin *numeric.Dec
+}
+
+// MarshalJSON ..
+func (v DryRunValidator) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Validator        string       `json:"validator"`
+		Stake            *big.Int     `json:"stake"`
+		NumSlots         int          `json:"slots"`
+		SlotsWon         int          `json:"slots-won"`
+		EffectiveStake   numeric.Dec  `json:"effective-stake"`
+		StakeNeededToWin *numeric.Dec `json:"stake-needed-to-win"`
+	}{
+		common2.MustAddressToBech32(v.Validator),
+		v.Stake,
+		v.NumSlots,
+		v.SlotsWon,
+		v.EffectiveStake,
+		v.StakeNeededToWin,
+	})
+}
+
+// DryRunEPoSRound is the election of the next committee run against the
+// current state, without electing anyone
+type DryRunEPoSRound struct {
+	*CompletedEPoSRound
+	// LowestWinningStake is the actual stake of the last slot won
+	LowestWinningStake numeric.Dec       `json:"lowest-winning-stake"`
+	Validators         []DryRunValidator `json:"validators"`
+}
+
+// NewEPoSRoundDryRun runs the election of the next committee like
+// NewEPoSRound, with extraStake added to the stake of validator when given,
+// and reports for each candidate the slots it wins, its effective stake and
+// the stake it needs to win all of its slots
+func NewEPoSRoundDryRun(
+	stakedReader StakingCandidatesReader,
+	validator *common.Address, extraStake *big.Int,
+) (*DryRunEPoSRound, error) {
+	eligibleCandidate, err := prepareOrders(stakedReader)
+	if err != nil {
+		return nil, err
+	}
+	if validator != nil && extraStake != nil && extraStake.Sign() != 0 {
+		order, ok := eligibleCandidate[*validator]
+		if !ok {
+			return nil, errors.Wrapf(
+				errNotEligibleForEPoS, "validator %s",
+				common2.MustAddressToBech32(*validator),
+			)
+		}
+		order.Stake = new(big.Int).Add(order.Stake, extraStake)
+		if order.Stake.Sign() < 0 {
+			return nil, errors.New("extra stake cannot exceed the stake of the validator")
+		}
+		totalStaked := big.NewInt(0)
+		for _, value := range eligibleCandidate {
+			totalStaked.Add(totalStaked, value.Stake)
+		}
+		totalStakedDec := numeric.NewDecFromBigInt(totalStaked)
+		for _, value := range eligibleCandidate {
+			value.Percentage = numeric.NewDecFromBigInt(value.Stake).Quo(totalStakedDec)
+		}
+	}
+
+	maxExternalSlots := shard.ExternalSlotsAvailableForEpoch(
+		stakedReader.CurrentBlock().Epoch(),
+	)
+	// the picks with their actual stake, before the effective stake clamping
+	_, picks := effective.Compute(eligibleCandidate, maxExternalSlots)
+	round := completeEPoSRound(eligibleCandidate, maxExternalSlots)
+
+	result := &DryRunEPoSRound{
+		CompletedEPoSRound: round,
+		LowestWinningStake: numeric.ZeroDec(),
+		Validators:         []DryRunValidator{},
+	}
+	if l := len(picks); l > 0 {
+		result.LowestWinningStake = picks[l-1].Stake
+	}
+
+	for addr, order := range eligibleCandidate {
+		candidate := DryRunValidator{
+			Validator:      addr,
+			Stake:          order.Stake,
+			NumSlots:       len(order.SpreadAmong),
+			EffectiveStake: numeric.ZeroDec(),
+		}
+		for _, winner := range round.AuctionWinners {
+			if winner.Addr == addr {
+				candidate.SlotsWon++
+				candidate.EffectiveStake = candidate.EffectiveStake.Add(winner.Stake)
+			}
+		}
+		if needed, possible := effective.StakeNeededToWin(
+			picks, addr, order.Stake, candidate.NumSlots, maxExternalSlots,
+		); possible {
+			candidate.StakeNeededToWin = &needed
+		}
+		result.Validators = append(result.Validators, candidate)
+	}
+	sort.SliceStable(
+		result.Validators,
+		func(i, j int) bool {
+			return bytes.Compare(
+				result.Validators[i].Validator[:], result.Validators[j].Validator[:],
+			) == -1
+		},
+	)
+	return result, nil
+}
@@ -0,0 +1,79 @@
+package apr
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/harmony-one/harmony/numeric"
+	"github.com/harmony-one/harmony/staking/effective"
+	"github.com/pkg/errors"
+)
+
+var (
+	errProjectionNoAmount = errors.New("amount to project a delegation of must be positive")
+	errProjectionNoSlots  = errors.New("validator has no slot to project a delegation to")
+)
+
+// Projection is the expected return of a delegation over a year
+type Projection struct {
+	Amount *big.Int `json:"amount"`
+	// EffectiveStake is the effective stake of the validator with the delegation
+	EffectiveStake numeric.Dec `json:"effective-stake"`
+	// RewardPerYear is the reward of the delegation over a year, after commission
+	RewardPerYear numeric.Dec `json:"reward-per-year"`
+	APR           numeric.Dec `json:"apr"`
+}
+
+func (p *Projection) String() string {
+	s, _ := json.Marshal(p)
+	return string(s)
+}
+
+// Project estimates the return of delegating amount to a validator which
+// earned apr on its totalDelegation, spread evenly over numSlots slots.
+// The reward of the validator follows its effective stake, each slot
+// clamped around the median stake, and commission is taken from it before
+// the rest is shared pro-rata among the delegations.
+func Project(
+	apr numeric.Dec, totalDelegation, amount *big.Int,
+	numSlots int, median, commission numeric.Dec,
+) (*Projection, error) {
+	if amount == nil || amount.Sign() <= 0 {
+		return nil, errProjectionNoAmount
+	}
+	if numSlots <= 0 {
+		return nil, errProjectionNoSlots
+	}
+	total := numeric.NewDecFromBigInt(totalDelegation)
+	after := total.Add(numeric.NewDecFromBigInt(amount))
+	effectiveBefore := slotsEffectiveStake(total, numSlots, median)
+	effectiveAfter := slotsEffectiveStake(after, numSlots, median)
+
+	projection := &Projection{
+		Amount:         amount,
+		EffectiveStake: effectiveAfter,
+		RewardPerYear:  numeric.ZeroDec(),
+		APR:            numeric.ZeroDec(),
+	}
+	if apr.IsNil() || effectiveBefore.IsZero() {
+		return projection, nil
+	}
+
+	validatorReward := apr.Mul(total).Mul(effectiveAfter).Quo(effectiveBefore)
+	share := numeric.NewDecFromBigInt(amount).Quo(after)
+	projection.RewardPerYear = validatorReward.
+		Mul(numeric.OneDec().Sub(commission)).
+		Mul(share)
+	projection.APR = projection.RewardPerYear.Quo(
+		numeric.NewDecFromBigInt(amount),
+	)
+	return projection, nil
+}
+
+func slotsEffectiveStake(stake numeric.Dec, numSlots int, median numeric.Dec) numeric.Dec {
+	if median.IsNil() || median.IsZero() {
+		return stake
+	}
+	perSlot := stake.QuoInt64(int64(numSlots))
+	return effective.EffectiveStake(median, perSlot).MulInt64(int64(numSlots))
+}
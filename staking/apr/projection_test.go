@@ -0,0 +1,38 @@
+package apr
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/harmony-one/harmony/numeric"
+)
+
+func TestProject(t *testing.T) {
+	apr := numeric.MustNewDecFromStr("0.1")
+	commission := numeric.MustNewDecFromStr("0.1")
+	total, amount := big.NewInt(1000), big.NewInt(1000)
+
+	tests := []struct {
+		median numeric.Dec
+		want   numeric.Dec
+	}{
+		// without a median the reward doubles with the stake,
+		// half of it after commission goes to the delegation
+		{numeric.ZeroDec(), numeric.MustNewDecFromStr("0.09")},
+		// the 2000 stake only counts as 1150 once clamped around the median
+		{numeric.NewDec(1000), numeric.MustNewDecFromStr("0.05175")},
+	}
+	for i, test := range tests {
+		projection, err := Project(apr, total, amount, 1, test.median, commission)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !projection.APR.Equal(test.want) {
+			t.Errorf("test %d: expected apr %s, got %s", i, test.want, projection.APR)
+		}
+	}
+
+	if _, err := Project(apr, total, big.NewInt(0), 1, numeric.ZeroDec(), commission); err != errProjectionNoAmount {
+		t.Errorf("expected %v, got %v", errProjectionNoAmount, err)
+	}
+}
@@ -20,7 +20,9 @@ var (
 	oneMinusC = numeric.OneDec().Sub(c)
 )
 
-func effectiveStake(median, actual numeric.Dec) numeric.Dec {
+// EffectiveStake is the stake a slot counts with in the committee,
+// the actual stake clamped around the median stake of the auction
+func EffectiveStake(median, actual numeric.Dec) numeric.Dec {
 	left := numeric.MinDec(onePlusC.Mul(median), actual)
 	right := oneMinusC.Mul(median)
 	return numeric.MaxDec(left, right)
@@ -144,8 +146,40 @@ func Apply(shortHand map[common.Address]*SlotOrder, pull int) (
 ) {
 	median, picks := Compute(shortHand, pull)
 	for i := range picks {
-		picks[i].Stake = effectiveStake(median, picks[i].Stake)
+		picks[i].Stake = EffectiveStake(median, picks[i].Stake)
 	}
 
 	return median, picks
 }
+
+// StakeNeededToWin is the stake to add to the stake of the validator at
+// addr, spread evenly over its numSlots slots, for all of them to be among
+// the pull slots picked, given picks as returned by Compute. It returns
+// false when the validator has more slots than can ever be picked.
+func StakeNeededToWin(
+	picks []SlotPurchase, addr common.Address,
+	stake *big.Int, numSlots, pull int,
+) (numeric.Dec, bool) {
+	if numSlots <= 0 || numSlots > pull {
+		return numeric.ZeroDec(), false
+	}
+	others := []SlotPurchase{}
+	for _, pick := range picks {
+		if pick.Addr != addr {
+			others = append(others, pick)
+		}
+	}
+	if len(others)+numSlots <= pull {
+		return numeric.ZeroDec(), true
+	}
+	// the picks are sorted by stake, so the slots of the validator
+	// displace the lowest ones from this one on
+	displaced := others[pull-numSlots].Stake
+	needed := displaced.MulInt64(int64(numSlots)).Sub(
+		numeric.NewDecFromBigInt(stake),
+	)
+	if needed.IsNegative() {
+		return numeric.ZeroDec(), true
+	}
+	return needed, true
+}
@@ -92,7 +92,7 @@ func TestEffectiveStake(t *testing.T) {
 		expectedStake := numeric.MaxDec(
 			numeric.MinDec(numeric.OneDec().Add(c).Mul(expectedMedian), val.Stake),
 			numeric.OneDec().Sub(c).Mul(expectedMedian))
-		calculatedStake := effectiveStake(expectedMedian, val.Stake)
+		calculatedStake := EffectiveStake(expectedMedian, val.Stake)
 		if !expectedStake.Equal(calculatedStake) {
 			t.Errorf(
 				"Expected: %s, Got: %s", expectedStake.String(), calculatedStake.String(),
@@ -104,3 +104,37 @@ func TestEffectiveStake(t *testing.T) {
 func TestApply(t *testing.T) {
 	//
 }
+
+func TestStakeNeededToWin(t *testing.T) {
+	a, b, c, d := common.Address{1}, common.Address{2}, common.Address{3}, common.Address{4}
+	picks := []SlotPurchase{
+		{Addr: a, Stake: numeric.NewDec(100)},
+		{Addr: b, Stake: numeric.NewDec(90)},
+		{Addr: c, Stake: numeric.NewDec(80)},
+	}
+	tests := []struct {
+		addr     common.Address
+		stake    int64
+		numSlots int
+		want     numeric.Dec
+		possible bool
+	}{
+		// already picked
+		{c, 80, 1, numeric.ZeroDec(), true},
+		// displaces c
+		{d, 50, 1, numeric.NewDec(30), true},
+		// displaces b and c, so needs 90 per slot
+		{d, 100, 2, numeric.NewDec(80), true},
+		// more slots than picked
+		{d, 100, 4, numeric.ZeroDec(), false},
+	}
+	for i, test := range tests {
+		needed, possible := StakeNeededToWin(
+			picks, test.addr, big.NewInt(test.stake), test.numSlots, 3,
+		)
+		if possible != test.possible || !needed.Equal(test.want) {
+			t.Errorf("test %d: expected %s %v, got %s %v",
+				i, test.want, test.possible, needed, possible)
+		}
+	}
+}
@@ -5,14 +5,16 @@ import (
 )
 
 const (
-	isValidatorKeyStr = "Harmony/IsValidator/Key/v1"
-	isValidatorStr    = "Harmony/IsValidator/Value/v1"
-	collectRewardsStr = "Harmony/CollectRewards"
+	isValidatorKeyStr     = "Harmony/IsValidator/Key/v1"
+	isValidatorStr        = "Harmony/IsValidator/Value/v1"
+	collectRewardsStr     = "Harmony/CollectRewards"
+	precompileDelegateStr = "Harmony/PrecompileDelegate"
 )
 
 // keys used to retrieve staking related informatio
 var (
-	IsValidatorKey      = crypto.Keccak256Hash([]byte(isValidatorKeyStr))
-	IsValidator         = crypto.Keccak256Hash([]byte(isValidatorStr))
-	CollectRewardsTopic = crypto.Keccak256Hash([]byte(collectRewardsStr))
+	IsValidatorKey          = crypto.Keccak256Hash([]byte(isValidatorKeyStr))
+	IsValidator             = crypto.Keccak256Hash([]byte(isValidatorStr))
+	CollectRewardsTopic     = crypto.Keccak256Hash([]byte(collectRewardsStr))
+	PrecompileDelegateTopic = crypto.Keccak256Hash([]byte(precompileDelegateStr))
 )
@@ -7,6 +7,7 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/consensus/votepower"
@@ -14,6 +15,7 @@ import (
 	"github.com/harmony-one/harmony/core/types"
 	"github.com/harmony-one/harmony/crypto/hash"
 	common2 "github.com/harmony-one/harmony/internal/common"
+	"github.com/harmony-one/harmony/internal/params"
 	"github.com/harmony-one/harmony/internal/utils"
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
@@ -60,6 +62,10 @@ type Moment struct {
 type Evidence struct {
 	Moment
 	ConflictingBallots
+	// SignedMessages are the two signed consensus messages carrying the ballots
+	// of a prepare or view change double sign. Commit evidence leaves it empty,
+	// which keeps its encoding unchanged.
+	SignedMessages [][]byte `rlp:"tail"`
 }
 
 // ConflictingBallots ..
@@ -92,7 +98,8 @@ func (e Evidence) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Moment
 		ConflictingBallots
-	}{e.Moment, e.ConflictingBallots})
+		SignedMessages []hexutil.Bytes `json:"signed-messages,omitempty"`
+	}{e.Moment, e.ConflictingBallots, signedMessagesJSON(e.SignedMessages)})
 }
 
 // Records ..
@@ -110,6 +117,8 @@ var (
 	errSignerKeyNotRightSize   = errors.New("bls keys from slash candidate not right side")
 	errSlashFromFutureEpoch    = errors.New("cannot have slash from future epoch")
 	errSlashBlockNoConflict    = errors.New("cannot slash for signing on non-conflicting blocks")
+	errSlashNilBallot          = errors.New("cannot slash for a ballot on no block")
+	errSignedEvidenceNotForked = errors.New("slash evidence with signed messages not accepted yet")
 )
 
 // MarshalJSON ..
@@ -138,6 +147,7 @@ func (r Record) String() string {
 type CommitteeReader interface {
 	ReadShardState(epoch *big.Int) (*shard.State, error)
 	CurrentBlock() *types.Block
+	Config() *params.ChainConfig
 }
 
 // Verify checks that the slash is valid
@@ -159,6 +169,14 @@ func Verify(
 		return errReporterAndOffenderSame
 	}
 
+	// evidence without signed messages encodes as before the fork
+	if len(candidate.Evidence.SignedMessages) > 0 &&
+		!chain.Config().IsSignedEvidence(chain.CurrentBlock().Epoch()) {
+		return errors.Wrapf(
+			errSignedEvidenceNotForked, "current-epoch %v", chain.CurrentBlock().Epoch(),
+		)
+	}
+
 	first, second :=
 		candidate.Evidence.AlreadyCastBallot,
 		candidate.Evidence.DoubleSignedBallot
@@ -176,6 +194,11 @@ func Verify(
 		return errors.Wrapf(errSlashBlockNoConflict, "first %v+ second %v+", first, second)
 	}
 
+	// a view change without prepared block votes for no block, it conflicts with none
+	if first.BlockHeaderHash == (common.Hash{}) || second.BlockHeaderHash == (common.Hash{}) {
+		return errors.Wrapf(errSlashNilBallot, "first %v+ second %v+", first, second)
+	}
+
 	if shard.CompareBLSPublicKey(first.SignerPubKey, second.SignerPubKey) != 0 {
 		k1, k2 := first.SignerPubKey.Hex(), second.SignerPubKey.Hex()
 		return errors.Wrapf(
@@ -226,6 +249,10 @@ func Verify(
 		)
 	}
 
+	if len(candidate.Evidence.SignedMessages) > 0 {
+		return verifySignedMessages(&candidate.Evidence)
+	}
+
 	for _, ballot := range [...]votepower.Ballot{
 		candidate.Evidence.AlreadyCastBallot,
 		candidate.Evidence.DoubleSignedBallot,
@@ -321,6 +348,63 @@ func payDownAsMuchAsCan(
 	return nil
 }
 
+func payDownRedelegations(
+	snapshot, current *staking.ValidatorWrapper,
+	state *state.DB,
+	delegationNow staking.Delegation,
+	slashDebt, doubleSignEpoch *big.Int,
+	slashDiff *Application,
+) error {
+	for i := range delegationNow.Redelegations {
+		redelegate := &delegationNow.Redelegations[i]
+		// the epoch matters, only those redelegation
+		// such that epoch>= doubleSignEpoch should be slashable
+		if redelegate.Epoch.Cmp(doubleSignEpoch) < 0 {
+			continue
+		}
+		if slashDebt.Cmp(common.Big0) <= 0 {
+			return nil
+		}
+		wrapper, err := state.ValidatorWrapper(redelegate.ValidatorAddress)
+		if err != nil {
+			return err
+		}
+		for j := range wrapper.Delegations {
+			delegation := &wrapper.Delegations[j]
+			if delegation.DelegatorAddress != delegationNow.DelegatorAddress {
+				continue
+			}
+			// only what was moved is slashable, not the whole delegation
+			nowAmt := redelegate.Amount
+			if delegation.Amount.Cmp(nowAmt) < 0 {
+				nowAmt = new(big.Int).Set(delegation.Amount)
+			} else {
+				nowAmt = new(big.Int).Set(nowAmt)
+			}
+			before := new(big.Int).Set(nowAmt)
+			if err := payDownAsMuchAsCan(
+				snapshot, current, slashDebt, nowAmt, slashDiff,
+			); err != nil {
+				return err
+			}
+			paid := before.Sub(before, nowAmt)
+			delegation.Amount.Sub(delegation.Amount, paid)
+			redelegate.Amount.Sub(redelegate.Amount, paid)
+			utils.Logger().Info().
+				RawJSON("redelegation", []byte(delegationNow.Redelegations.String())).
+				Uint64("paid", paid.Uint64()).
+				Msg("paid slash debt from a redelegation")
+			break
+		}
+		if err := state.UpdateValidatorWrapper(
+			redelegate.ValidatorAddress, wrapper,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func delegatorSlashApply(
 	snapshot, current *staking.ValidatorWrapper,
 	rate numeric.Dec,
@@ -379,9 +463,18 @@ func delegatorSlashApply(
 					}
 				}
 
+				// then the tokens redelegated to other validators since the
+				// double sign epoch, taken from those validators' delegations
+				if err := payDownRedelegations(
+					snapshot, current, state, delegationNow,
+					slashDebt, doubleSignEpoch, slashDiff,
+				); err != nil {
+					return err
+				}
+
 				// if we still have a slashdebt
 				// even after taking away from delegation amount
-				// and even after taking away from undelegate,
+				// and even after taking away from undelegate and redelegate,
 				// then we need to take from their pending rewards
 				if slashDebt.Cmp(common.Big0) == 1 {
 					nowAmt := delegationNow.Reward
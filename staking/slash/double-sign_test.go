@@ -11,15 +11,18 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/block"
+	blockfactory "github.com/harmony-one/harmony/block/factory"
 	"github.com/harmony-one/harmony/common/denominations"
 	"github.com/harmony-one/harmony/consensus/votepower"
 	"github.com/harmony-one/harmony/core/state"
 	"github.com/harmony-one/harmony/core/types"
 	common2 "github.com/harmony-one/harmony/internal/common"
+	"github.com/harmony-one/harmony/internal/params"
 	"github.com/harmony-one/harmony/numeric"
 	"github.com/harmony-one/harmony/shard"
 	"github.com/harmony-one/harmony/staking/effective"
 	staking "github.com/harmony-one/harmony/staking/types"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -387,12 +390,21 @@ func (m mockOutSnapshotReader) ReadValidatorSnapshotAtEpoch(
 	return &m.snapshot, nil
 }
 
-type mockOutChainReader struct{}
+type mockOutChainReader struct {
+	config *params.ChainConfig
+}
+
+func (m mockOutChainReader) Config() *params.ChainConfig {
+	if m.config == nil {
+		return params.TestChainConfig
+	}
+	return m.config
+}
 
 func (mockOutChainReader) CurrentBlock() *types.Block {
-	b := types.Block{}
-	b.Header().SetEpoch(doubleSignEpochBig)
-	return &b
+	return types.NewBlockWithHeader(
+		blockfactory.NewTestHeader().With().Epoch(doubleSignEpochBig).Header(),
+	)
 }
 
 func (mockOutChainReader) ReadShardState(epoch *big.Int) (*shard.State, error) {
@@ -424,6 +436,104 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestVerifySignedEvidenceFork(t *testing.T) {
+	stateHandle := defaultStateWithAccountsApplied()
+	if err := stateHandle.UpdateValidatorWrapper(
+		offenderAddr, scenarioTwoPercent.snapshot,
+	); err != nil {
+		t.Fatal(err)
+	}
+	record := exampleSlashRecords()[0]
+	record.Evidence.SignedMessages = [][]byte{{0x01}, {0x02}}
+
+	config := *params.TestChainConfig
+	config.SignedEvidenceEpoch = new(big.Int).Add(doubleSignEpochBig, common.Big1)
+	err := Verify(mockOutChainReader{&config}, stateHandle, &record)
+	if errors.Cause(err) != errSignedEvidenceNotForked {
+		t.Errorf("expected %v before the fork, got %v", errSignedEvidenceNotForked, err)
+	}
+	// past the fork the signed messages are checked
+	err = Verify(mockOutChainReader{}, stateHandle, &record)
+	if err == nil || errors.Cause(err) == errSignedEvidenceNotForked {
+		t.Errorf("expected invalid signed messages past the fork, got %v", err)
+	}
+}
+
+func TestVerifyNilBallot(t *testing.T) {
+	stateHandle := defaultStateWithAccountsApplied()
+	if err := stateHandle.UpdateValidatorWrapper(
+		offenderAddr, scenarioTwoPercent.snapshot,
+	); err != nil {
+		t.Fatal(err)
+	}
+	record := exampleSlashRecords()[0]
+	record.Evidence.AlreadyCastBallot.BlockHeaderHash = common.BytesToHash([]byte("prepared"))
+	record.Evidence.DoubleSignedBallot.BlockHeaderHash = common.Hash{}
+	err := Verify(mockOutChainReader{}, stateHandle, &record)
+	if errors.Cause(err) != errSlashNilBallot {
+		t.Errorf("expected %v for a ballot on no block, got %v", errSlashNilBallot, err)
+	}
+}
+
+func TestPayDownRedelegations(t *testing.T) {
+	stateHandle := defaultStateWithAccountsApplied()
+	toValidator := common.BigToAddress(big.NewInt(0xde))
+	one := big.NewInt(denominations.One)
+	ones := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), one) }
+
+	to := &staking.ValidatorWrapper{
+		Validator: scenarioTwoPercent.current.Validator,
+		Delegations: staking.Delegations{
+			staking.NewDelegation(toValidator, new(big.Int).Set(tenKOnes)),
+			staking.NewDelegation(randoDel, ones(3)),
+		},
+	}
+	to.Address = toValidator
+	if err := stateHandle.UpdateValidatorWrapper(toValidator, to); err != nil {
+		t.Fatal(err)
+	}
+	delegationNow := staking.NewDelegation(randoDel, big.NewInt(0))
+	delegationNow.Redelegations = staking.Redelegations{
+		// before the double sign, the validator does not even exist
+		{
+			ValidatorAddress: common.BigToAddress(big.NewInt(0xad)),
+			Amount:           ones(5),
+			Epoch:            big.NewInt(doubleSignEpoch - 1),
+		},
+		{
+			ValidatorAddress: toValidator,
+			Amount:           ones(2),
+			Epoch:            big.NewInt(doubleSignEpoch),
+		},
+	}
+
+	// more debt than redelegated, only what was moved is taken
+	slashDebt, slashDiff := ones(4), &Application{big.NewInt(0), big.NewInt(0)}
+	if err := payDownRedelegations(
+		scenarioTwoPercent.snapshot, scenarioTwoPercent.current, stateHandle,
+		delegationNow, slashDebt, doubleSignEpochBig, slashDiff,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if slashDebt.Cmp(ones(2)) != 0 || slashDiff.TotalSlashed.Cmp(ones(2)) != 0 {
+		t.Errorf("expected 2 slashed and 2 left to pay, got %v slashed and %v left",
+			slashDiff.TotalSlashed, slashDebt)
+	}
+	if amount := delegationNow.Redelegations[1].Amount; amount.Sign() != 0 {
+		t.Errorf("expected the redelegation paid off, got %v", amount)
+	}
+	if amount := delegationNow.Redelegations[0].Amount; amount.Cmp(ones(5)) != 0 {
+		t.Errorf("redelegation before the double sign was slashed, left %v", amount)
+	}
+	wrapper, err := stateHandle.ValidatorWrapper(toValidator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount := wrapper.Delegations[1].Amount; amount.Cmp(ones(1)) != 0 {
+		t.Errorf("expected 1 left delegated to the other validator, got %v", amount)
+	}
+}
+
 func testScenario(
 	t *testing.T, stateHandle *state.DB, slashes Records, s *scenario,
 ) {
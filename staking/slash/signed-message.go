@@ -0,0 +1,146 @@
+package slash
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/consensus/votepower"
+	"github.com/harmony-one/harmony/crypto/hash"
+	"github.com/pkg/errors"
+)
+
+// Prepare signatures only cover the block hash and view change signatures
+// only cover the prepared block, so on their own they cannot tell at which
+// height and view they were cast. The evidence of such double signs carries
+// the whole consensus messages instead, whose signature binds the shard,
+// height, view and sender to the ballot.
+
+var (
+	errSignedMessagesCount     = errors.New("slash evidence must carry two signed messages")
+	errSignedMessageType       = errors.New("signed messages must both be prepare or both be view change")
+	errSignedMessageMismatch   = errors.New("signed message does not match its ballot")
+	errFailVerifySignedMessage = errors.New("could not verify bls key signature on signed message")
+)
+
+// signedVote is the ballot carried by a signed prepare or view change message
+type signedVote struct {
+	msgType   msg_pb.MessageType
+	shardID   uint32
+	height    uint64
+	viewID    uint64
+	senderKey []byte
+	// blockHash is the prepared block, or the zero hash for a view change
+	// without prepared block
+	blockHash common.Hash
+	signature []byte
+}
+
+func parseSignedVote(raw []byte) (*signedVote, error) {
+	msg := &msg_pb.Message{}
+	if err := protobuf.Unmarshal(raw, msg); err != nil {
+		return nil, err
+	}
+	vote := &signedVote{msgType: msg.GetType()}
+	switch vote.msgType {
+	case msg_pb.MessageType_PREPARE:
+		consensusMsg := msg.GetConsensus()
+		if consensusMsg == nil {
+			return nil, errSignedMessageType
+		}
+		vote.shardID, vote.height, vote.viewID =
+			consensusMsg.ShardId, consensusMsg.BlockNum, consensusMsg.ViewId
+		vote.senderKey = consensusMsg.SenderPubkey
+		vote.blockHash = common.BytesToHash(consensusMsg.BlockHash)
+		vote.signature = consensusMsg.Payload
+	case msg_pb.MessageType_VIEWCHANGE:
+		vcMsg := msg.GetViewchange()
+		if vcMsg == nil {
+			return nil, errSignedMessageType
+		}
+		vote.shardID, vote.height, vote.viewID =
+			vcMsg.ShardId, vcMsg.BlockNum, vcMsg.ViewId
+		vote.senderKey = vcMsg.SenderPubkey
+		if len(vcMsg.Payload) >= common.HashLength {
+			vote.blockHash = common.BytesToHash(vcMsg.Payload[:common.HashLength])
+		}
+		vote.signature = vcMsg.ViewchangeSig
+	default:
+		return nil, errSignedMessageType
+	}
+
+	publicKey := &bls.PublicKey{}
+	if err := publicKey.Deserialize(vote.senderKey); err != nil {
+		return nil, err
+	}
+	signature := &bls.Sign{}
+	if err := signature.Deserialize(msg.Signature); err != nil {
+		return nil, err
+	}
+	msg.Signature = nil
+	unsigned, err := protobuf.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	if !signature.VerifyHash(publicKey, hash.Keccak256(unsigned)) {
+		return nil, errFailVerifySignedMessage
+	}
+	return vote, nil
+}
+
+// matches tells whether the vote is the given ballot, cast in the given shard
+func (vote *signedVote) matches(ballot *votepower.Ballot, shardID uint32) bool {
+	return vote.shardID == shardID &&
+		vote.height == ballot.Height &&
+		vote.viewID == ballot.ViewID &&
+		vote.blockHash == ballot.BlockHeaderHash &&
+		bytes.Equal(vote.senderKey, ballot.SignerPubKey[:]) &&
+		bytes.Equal(vote.signature, ballot.Signature)
+}
+
+// verifySignedMessages checks the ballots of the evidence are the ones of
+// its two signed messages, both of the same phase
+func verifySignedMessages(evidence *Evidence) error {
+	if len(evidence.SignedMessages) != 2 {
+		return errors.Wrapf(
+			errSignedMessagesCount, "got %d", len(evidence.SignedMessages),
+		)
+	}
+	ballots := [...]*votepower.Ballot{
+		&evidence.AlreadyCastBallot, &evidence.DoubleSignedBallot,
+	}
+	votes := [2]*signedVote{}
+	for i := range votes {
+		vote, err := parseSignedVote(evidence.SignedMessages[i])
+		if err != nil {
+			return errors.Wrapf(err, "signed message %d", i)
+		}
+		if !vote.matches(ballots[i], evidence.ShardID) {
+			return errors.Wrapf(errSignedMessageMismatch, "signed message %d", i)
+		}
+		if vote.blockHash == (common.Hash{}) {
+			return errors.Wrapf(errSlashNilBallot, "signed message %d", i)
+		}
+		votes[i] = vote
+	}
+	if votes[0].msgType != votes[1].msgType {
+		return errors.Wrapf(
+			errSignedMessageType, "%s %s", votes[0].msgType, votes[1].msgType,
+		)
+	}
+	return nil
+}
+
+func signedMessagesJSON(messages [][]byte) []hexutil.Bytes {
+	if len(messages) == 0 {
+		return nil
+	}
+	encoded := make([]hexutil.Bytes, len(messages))
+	for i := range messages {
+		encoded[i] = messages[i]
+	}
+	return encoded
+}
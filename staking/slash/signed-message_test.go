@@ -0,0 +1,168 @@
+package slash
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/harmony-one/bls/ffi/go/bls"
+	msg_pb "github.com/harmony-one/harmony/api/proto/message"
+	"github.com/harmony-one/harmony/consensus/votepower"
+	bls_cosi "github.com/harmony-one/harmony/crypto/bls"
+	"github.com/harmony-one/harmony/crypto/hash"
+	"github.com/harmony-one/harmony/shard"
+	"github.com/pkg/errors"
+)
+
+func signedPrepare(
+	t *testing.T, key *bls.SecretKey, blockHash common.Hash,
+) ([]byte, votepower.Ballot) {
+	prepareSig := key.SignHash(blockHash[:]).Serialize()
+	msg := &msg_pb.Message{
+		ServiceType: msg_pb.ServiceType_CONSENSUS,
+		Type:        msg_pb.MessageType_PREPARE,
+		Request: &msg_pb.Message_Consensus{
+			Consensus: &msg_pb.ConsensusRequest{
+				ViewId:       7,
+				BlockNum:     5,
+				ShardId:      1,
+				BlockHash:    blockHash[:],
+				SenderPubkey: key.GetPublicKey().Serialize(),
+				Payload:      prepareSig,
+			},
+		},
+	}
+	return signMessage(t, key, msg), votepower.Ballot{
+		SignerPubKey:    *shard.FromLibBLSPublicKeyUnsafe(key.GetPublicKey()),
+		BlockHeaderHash: blockHash,
+		Signature:       prepareSig,
+		Height:          5,
+		ViewID:          7,
+	}
+}
+
+// signedNilViewChange is a view change without prepared block, a vote for no block
+func signedNilViewChange(t *testing.T, key *bls.SecretKey) ([]byte, votepower.Ballot) {
+	nilSig := key.SignHash([]byte{0x01}).Serialize()
+	msg := &msg_pb.Message{
+		ServiceType: msg_pb.ServiceType_CONSENSUS,
+		Type:        msg_pb.MessageType_VIEWCHANGE,
+		Request: &msg_pb.Message_Viewchange{
+			Viewchange: &msg_pb.ViewChangeRequest{
+				ViewId:        7,
+				BlockNum:      5,
+				ShardId:       1,
+				SenderPubkey:  key.GetPublicKey().Serialize(),
+				ViewchangeSig: nilSig,
+			},
+		},
+	}
+	return signMessage(t, key, msg), votepower.Ballot{
+		SignerPubKey: *shard.FromLibBLSPublicKeyUnsafe(key.GetPublicKey()),
+		Signature:    nilSig,
+		Height:       5,
+		ViewID:       7,
+	}
+}
+
+func signMessage(t *testing.T, key *bls.SecretKey, msg *msg_pb.Message) []byte {
+	unsigned, err := protobuf.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg.Signature = key.SignHash(hash.Keccak256(unsigned)).Serialize()
+	signed, err := protobuf.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifySignedMessages(t *testing.T) {
+	key := bls_cosi.RandPrivateKey()
+	first, firstBallot := signedPrepare(t, key, common.BytesToHash([]byte("first")))
+	second, secondBallot := signedPrepare(t, key, common.BytesToHash([]byte("second")))
+	evidence := Evidence{
+		Moment: Moment{ShardID: 1},
+		ConflictingBallots: ConflictingBallots{
+			AlreadyCastBallot:  firstBallot,
+			DoubleSignedBallot: secondBallot,
+		},
+		SignedMessages: [][]byte{first, second},
+	}
+	if err := verifySignedMessages(&evidence); err != nil {
+		t.Fatalf("conflicting prepares not verified: %v", err)
+	}
+
+	evidence.ShardID = 0
+	if err := verifySignedMessages(&evidence); err == nil {
+		t.Error("expected evidence of another shard to fail")
+	}
+	evidence.ShardID = 1
+
+	evidence.DoubleSignedBallot.ViewID = 8
+	if err := verifySignedMessages(&evidence); err == nil {
+		t.Error("expected ballot not matching its message to fail")
+	}
+	evidence.DoubleSignedBallot.ViewID = 7
+
+	other := bls_cosi.RandPrivateKey()
+	forged, _ := signedPrepare(t, other, secondBallot.BlockHeaderHash)
+	evidence.SignedMessages[1] = forged
+	if err := verifySignedMessages(&evidence); err == nil {
+		t.Error("expected message of another signer to fail")
+	}
+
+	evidence.SignedMessages = evidence.SignedMessages[:1]
+	if err := verifySignedMessages(&evidence); err == nil {
+		t.Error("expected single message to fail")
+	}
+}
+
+func TestVerifySignedMessagesNilBallot(t *testing.T) {
+	key := bls_cosi.RandPrivateKey()
+	prepare, prepareBallot := signedPrepare(t, key, common.BytesToHash([]byte("prepared")))
+	nilVote, nilBallot := signedNilViewChange(t, key)
+	evidence := Evidence{
+		Moment: Moment{ShardID: 1},
+		ConflictingBallots: ConflictingBallots{
+			AlreadyCastBallot:  prepareBallot,
+			DoubleSignedBallot: nilBallot,
+		},
+		SignedMessages: [][]byte{prepare, nilVote},
+	}
+	if err := verifySignedMessages(&evidence); errors.Cause(err) != errSlashNilBallot {
+		t.Errorf("expected %v for a prepare and a nil vote, got %v", errSlashNilBallot, err)
+	}
+}
+
+func TestEvidenceEncodingWithoutSignedMessages(t *testing.T) {
+	commitOnly := struct {
+		Moment
+		ConflictingBallots
+	}{
+		Moment{Epoch: common.Big1, TimeUnixNano: common.Big2, ShardID: 1},
+		ConflictingBallots{},
+	}
+	legacy, err := rlp.EncodeToBytes(commitOnly)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := rlp.EncodeToBytes(Evidence{
+		Moment: commitOnly.Moment, ConflictingBallots: commitOnly.ConflictingBallots,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(legacy) != string(current) {
+		t.Error("commit evidence encoding changed")
+	}
+	decoded := Evidence{}
+	if err := rlp.DecodeBytes(legacy, &decoded); err != nil {
+		t.Fatalf("cannot decode legacy evidence: %v", err)
+	}
+	if len(decoded.SignedMessages) != 0 {
+		t.Error("legacy evidence decoded with signed messages")
+	}
+}
@@ -0,0 +1,121 @@
+package slash
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/harmony-one/harmony/core/state"
+	"github.com/harmony-one/harmony/numeric"
+	staking "github.com/harmony-one/harmony/staking/types"
+)
+
+// DelegatorDebt is what slashing a validator takes from one of its delegators
+type DelegatorDebt struct {
+	DelegatorAddress common.Address `json:"delegator-address"`
+	// Debt is the slash rate applied to the delegation snapshot
+	Debt             *big.Int `json:"debt"`
+	FromDelegation   *big.Int `json:"from-delegation"`
+	FromUndelegation *big.Int `json:"from-undelegation"`
+	FromRedelegation *big.Int `json:"from-redelegation"`
+	FromReward       *big.Int `json:"from-reward"`
+	// Unpaid is the debt left once all of the above is taken
+	Unpaid *big.Int `json:"unpaid"`
+}
+
+// Simulation is the outcome of slashing a validator, without applying it
+type Simulation struct {
+	ValidatorAddress common.Address  `json:"validator-address"`
+	Rate             numeric.Dec     `json:"rate"`
+	DoubleSignEpoch  *big.Int        `json:"double-sign-epoch"`
+	Application      *Application    `json:"application"`
+	Delegators       []DelegatorDebt `json:"delegators"`
+}
+
+func (s *Simulation) String() string {
+	str, _ := json.Marshal(s)
+	return string(str)
+}
+
+// Simulate applies the slashing of the validator of the snapshot at rate to
+// a copy of state, as if it double signed at doubleSignEpoch, and reports
+// what each of its delegators would lose. Rates below the rate of a single
+// double signer are raised to it. When delegator is given only that
+// delegator is slashed. The given state is never modified.
+func Simulate(
+	snapshot *staking.ValidatorWrapper, state *state.DB,
+	rate numeric.Dec, doubleSignEpoch *big.Int, delegator *common.Address,
+) (*Simulation, error) {
+	if rate.IsNil() || rate.LT(oneDoubleSignerRate) {
+		rate = oneDoubleSignerRate
+	}
+	before, err := state.ValidatorWrapper(snapshot.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	slashed := *snapshot
+	if delegator != nil {
+		slashed.Delegations = staking.Delegations{}
+		for _, delegation := range snapshot.Delegations {
+			if delegation.DelegatorAddress == *delegator {
+				slashed.Delegations = append(slashed.Delegations, delegation)
+			}
+		}
+	}
+
+	simulated := state.Copy()
+	current, err := simulated.ValidatorWrapper(snapshot.Address)
+	if err != nil {
+		return nil, err
+	}
+	slashDiff := &Application{big.NewInt(0), big.NewInt(0)}
+	if err := delegatorSlashApply(
+		&slashed, current, rate, simulated,
+		common.Address{}, doubleSignEpoch, slashDiff,
+	); err != nil {
+		return nil, err
+	}
+
+	result := &Simulation{
+		ValidatorAddress: snapshot.Address,
+		Rate:             rate,
+		DoubleSignEpoch:  doubleSignEpoch,
+		Application:      slashDiff,
+		Delegators:       []DelegatorDebt{},
+	}
+	for _, delegationSnapshot := range slashed.Delegations {
+		debt := DelegatorDebt{
+			DelegatorAddress: delegationSnapshot.DelegatorAddress,
+			Debt:             applySlashRate(delegationSnapshot.Amount, rate),
+			FromDelegation:   big.NewInt(0),
+			FromUndelegation: big.NewInt(0),
+			FromRedelegation: big.NewInt(0),
+			FromReward:       big.NewInt(0),
+		}
+		for i := range before.Delegations {
+			was, now := before.Delegations[i], current.Delegations[i]
+			if was.DelegatorAddress != debt.DelegatorAddress {
+				continue
+			}
+			debt.FromDelegation.Sub(was.Amount, now.Amount)
+			debt.FromReward.Sub(was.Reward, now.Reward)
+			for j := range was.Undelegations {
+				debt.FromUndelegation.Add(debt.FromUndelegation, new(big.Int).Sub(
+					was.Undelegations[j].Amount, now.Undelegations[j].Amount,
+				))
+			}
+			for j := range was.Redelegations {
+				debt.FromRedelegation.Add(debt.FromRedelegation, new(big.Int).Sub(
+					was.Redelegations[j].Amount, now.Redelegations[j].Amount,
+				))
+			}
+		}
+		debt.Unpaid = new(big.Int).Sub(debt.Debt, debt.FromDelegation)
+		debt.Unpaid.Sub(debt.Unpaid, debt.FromUndelegation)
+		debt.Unpaid.Sub(debt.Unpaid, debt.FromRedelegation)
+		debt.Unpaid.Sub(debt.Unpaid, debt.FromReward)
+		result.Delegators = append(result.Delegators, debt)
+	}
+	return result, nil
+}
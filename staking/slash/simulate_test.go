@@ -0,0 +1,45 @@
+package slash
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/harmony-one/harmony/numeric"
+)
+
+func TestSimulate(t *testing.T) {
+	s := defaultFundingScenario()
+	s.snapshot, s.current = s.defaultValidatorPair(s.defaultDelegationPair())
+	stateHandle := defaultStateWithAccountsApplied()
+	if err := stateHandle.UpdateValidatorWrapper(
+		offenderAddr, s.current,
+	); err != nil {
+		t.Fatalf("creation of validator failed %s", err.Error())
+	}
+
+	result, err := Simulate(
+		s.snapshot, stateHandle, numeric.MustNewDecFromStr("0.02"),
+		big.NewInt(doubleSignEpoch), &randoDel,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Delegators) != 1 {
+		t.Fatalf("expected only the given delegator, got %s", result)
+	}
+	debt := result.Delegators[0]
+	// 2% of the 30k snapshot, all of it taken from the 5k delegation
+	want := new(big.Int).Mul(big.NewInt(600), big.NewInt(1e18))
+	if debt.Debt.Cmp(want) != 0 || debt.FromDelegation.Cmp(want) != 0 ||
+		debt.FromUndelegation.Sign() != 0 || debt.Unpaid.Sign() != 0 {
+		t.Errorf("unexpected debt %s", result)
+	}
+
+	wrapper, err := stateHandle.ValidatorWrapper(offenderAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapper.Delegations[1].Amount.Cmp(fiveKOnes) != 0 {
+		t.Errorf("simulating the slash changed the state")
+	}
+}
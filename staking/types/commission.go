@@ -24,3 +24,28 @@ type (
 		MaxChangeRate numeric.Dec `json:"max-change-rate"`
 	}
 )
+
+// CommissionChangeDelay is the number of epochs after the epoch of a
+// commission rate edit at which the new rate takes effect, giving the
+// delegators a full epoch to react to the change
+const CommissionChangeDelay = 2
+
+// PendingCommission is a commission rate edit waiting for its epoch
+type PendingCommission struct {
+	Rate  numeric.Dec `json:"rate"`
+	Epoch *big.Int    `json:"epoch"`
+}
+
+// ApplyPendingCommission sets the pending commission rate once epoch reaches
+// the epoch of the change, returning whether the rate was set
+func (w *ValidatorWrapper) ApplyPendingCommission(epoch, blockNum *big.Int) bool {
+	if w.PendingCommission == nil || w.PendingCommission.Epoch.Cmp(epoch) > 0 {
+		return false
+	}
+	if !w.Rate.Equal(w.PendingCommission.Rate) {
+		w.Rate = w.PendingCommission.Rate
+		w.UpdateHeight = blockNum
+	}
+	w.PendingCommission = nil
+	return true
+}
@@ -3,17 +3,21 @@ package types
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"math/big"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/harmony/crypto/hash"
 	common2 "github.com/harmony-one/harmony/internal/common"
 )
 
 var (
-	errInsufficientBalance = errors.New("insufficient balance to undelegate")
-	errInvalidAmount       = errors.New("invalid amount, must be positive")
+	errInsufficientBalance             = errors.New("insufficient balance to undelegate")
+	errInvalidAmount                   = errors.New("invalid amount, must be positive")
+	errInsufficientBalanceToRedelegate = errors.New("insufficient balance to redelegate")
+	errTooManyDelegationFields         = errors.New("too many fields in delegation encoding")
 )
 
 const (
@@ -29,6 +33,77 @@ type Delegation struct {
 	Amount           *big.Int
 	Reward           *big.Int
 	Undelegations    Undelegations
+	// Redelegations keeps the tokens moved to other validators slashable
+	// for this validator during the lock period
+	Redelegations Redelegations
+	// AutoCompound adds the reward to the amount at each epoch boundary
+	AutoCompound bool
+}
+
+// delegationRLP is the encoding of a delegation, with the fields added
+// after staking launch encoded in order only when set, so that existing
+// delegations keep their encoding
+type delegationRLP struct {
+	DelegatorAddress common.Address
+	Amount           *big.Int
+	Reward           *big.Int
+	Undelegations    Undelegations
+	Optional         []rlp.RawValue `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder
+func (d Delegation) EncodeRLP(w io.Writer) error {
+	enc := delegationRLP{
+		DelegatorAddress: d.DelegatorAddress,
+		Amount:           d.Amount,
+		Reward:           d.Reward,
+		Undelegations:    d.Undelegations,
+	}
+	optional := []interface{}{}
+	if d.AutoCompound {
+		optional = append(optional, d.Redelegations, d.AutoCompound)
+	} else if len(d.Redelegations) > 0 {
+		optional = append(optional, d.Redelegations)
+	}
+	for _, field := range optional {
+		raw, err := rlp.EncodeToBytes(field)
+		if err != nil {
+			return err
+		}
+		enc.Optional = append(enc.Optional, raw)
+	}
+	return rlp.Encode(w, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder
+func (d *Delegation) DecodeRLP(s *rlp.Stream) error {
+	dec := delegationRLP{}
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.Optional) > 2 {
+		return errTooManyDelegationFields
+	}
+	*d = Delegation{
+		DelegatorAddress: dec.DelegatorAddress,
+		Amount:           dec.Amount,
+		Reward:           dec.Reward,
+		Undelegations:    dec.Undelegations,
+	}
+	if len(dec.Optional) > 0 {
+		if err := rlp.DecodeBytes(dec.Optional[0], &d.Redelegations); err != nil {
+			return err
+		}
+		if len(d.Redelegations) == 0 {
+			d.Redelegations = nil
+		}
+	}
+	if len(dec.Optional) > 1 {
+		if err := rlp.DecodeBytes(dec.Optional[1], &d.AutoCompound); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Delegations ..
@@ -47,8 +122,10 @@ func (d Delegation) MarshalJSON() ([]byte, error) {
 		Amount           *big.Int      `json:"amount"`
 		Reward           *big.Int      `json:"reward"`
 		Undelegations    Undelegations `json:"undelegations"`
+		Redelegations    Redelegations `json:"redelegations,omitempty"`
+		AutoCompound     bool          `json:"auto-compound"`
 	}{common2.MustAddressToBech32(d.DelegatorAddress), d.Amount,
-		d.Reward, d.Undelegations,
+		d.Reward, d.Undelegations, d.Redelegations, d.AutoCompound,
 	})
 }
 
@@ -97,6 +174,22 @@ func (u Undelegations) String() string {
 	return string(s)
 }
 
+// Redelegation represents tokens moved to another validator at some epoch
+type Redelegation struct {
+	ValidatorAddress common.Address `json:"validator-address"`
+	Amount           *big.Int       `json:"amount"`
+	Epoch            *big.Int       `json:"epoch"`
+}
+
+// Redelegations ..
+type Redelegations []Redelegation
+
+// String ..
+func (r Redelegations) String() string {
+	s, _ := json.Marshal(r)
+	return string(s)
+}
+
 // DelegationIndexes is a slice of DelegationIndex
 type DelegationIndexes []DelegationIndex
 
@@ -192,3 +285,65 @@ func (d *Delegation) RemoveUnlockedUndelegations(
 	d.Undelegations = d.Undelegations[count:]
 	return totalWithdraw
 }
+
+// Redelegate moves amt out of the delegation to the validator at toValidator,
+// keeping a record of it for slashing during the lock period
+func (d *Delegation) Redelegate(toValidator common.Address, epoch, amt *big.Int) error {
+	if amt.Sign() <= 0 {
+		return errInvalidAmount
+	}
+	if d.Amount.Cmp(amt) < 0 {
+		return errInsufficientBalanceToRedelegate
+	}
+	d.Amount.Sub(d.Amount, amt)
+
+	for i := range d.Redelegations {
+		entry := &d.Redelegations[i]
+		if entry.ValidatorAddress == toValidator && entry.Epoch.Cmp(epoch) == 0 {
+			entry.Amount.Add(entry.Amount, amt)
+			return nil
+		}
+	}
+	d.Redelegations = append(d.Redelegations, Redelegation{
+		ValidatorAddress: toValidator,
+		Amount:           new(big.Int).Set(amt),
+		Epoch:            new(big.Int).Set(epoch),
+	})
+	return nil
+}
+
+// CompoundReward moves as much of the reward into the amount as allowed by
+// room, returning how much was moved
+func (d *Delegation) CompoundReward(room *big.Int) *big.Int {
+	compounded := big.NewInt(0)
+	if !d.AutoCompound || d.Reward == nil || room.Sign() <= 0 {
+		return compounded
+	}
+	compounded.Set(d.Reward)
+	if compounded.Cmp(room) > 0 {
+		compounded.Set(room)
+	}
+	d.Reward.Sub(d.Reward, compounded)
+	d.Amount.Add(d.Amount, compounded)
+	return compounded
+}
+
+// RemoveExpiredRedelegations forgets the redelegations past the lock period,
+// which are no longer slashable for this validator. Like undelegations they
+// also expire when the validator has been out of committee for the lock period.
+func (d *Delegation) RemoveExpiredRedelegations(
+	curEpoch, lastEpochInCommittee *big.Int,
+) {
+	kept := Redelegations{}
+	for _, entry := range d.Redelegations {
+		if big.NewInt(0).Sub(curEpoch, entry.Epoch).Int64() > LockPeriodInEpoch ||
+			big.NewInt(0).Sub(curEpoch, lastEpochInCommittee).Int64() > LockPeriodInEpoch {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	if len(kept) == 0 {
+		kept = nil
+	}
+	d.Redelegations = kept
+}
@@ -1,10 +1,12 @@
 package types
 
 import (
+	"bytes"
 	"math/big"
 	"testing"
 
 	common "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	common2 "github.com/harmony-one/harmony/internal/common"
 )
 
@@ -80,3 +82,95 @@ func TestRemoveUnlockUndelegations(t *testing.T) {
 		t.Errorf("removing an unlocked undelegation fails")
 	}
 }
+
+func TestRedelegate(t *testing.T) {
+	toValidator := common.BigToAddress(big.NewInt(1))
+	d := NewDelegation(delegatorAddr, big.NewInt(5000))
+
+	if err := d.Redelegate(toValidator, big.NewInt(10), big.NewInt(6000)); err == nil {
+		t.Errorf("redelegating more than the delegation should fail")
+	}
+	d.Redelegate(toValidator, big.NewInt(10), big.NewInt(1000))
+	d.Redelegate(toValidator, big.NewInt(10), big.NewInt(500))
+	d.Redelegate(toValidator, big.NewInt(12), big.NewInt(500))
+
+	if d.Amount.Cmp(big.NewInt(3000)) != 0 {
+		t.Errorf("redelegate failed, amount left should be 3000")
+	}
+	if len(d.Redelegations) != 2 {
+		t.Fatalf("redelegations in the same epoch should have been merged")
+	}
+	if d.Redelegations[0].Amount.Cmp(big.NewInt(1500)) != 0 {
+		t.Errorf("redelegate failed, merged amount does not match")
+	}
+
+	d.RemoveExpiredRedelegations(big.NewInt(18), big.NewInt(18))
+	if len(d.Redelegations) != 1 || d.Redelegations[0].Epoch.Cmp(big.NewInt(12)) != 0 {
+		t.Errorf("only the redelegation past the lock period should be removed")
+	}
+	// the validator out of committee for the lock period
+	d.RemoveExpiredRedelegations(big.NewInt(18), big.NewInt(10))
+	if len(d.Redelegations) != 0 {
+		t.Errorf("redelegation of a validator out of committee should be removed")
+	}
+}
+
+func TestRedelegationsKeepEncoding(t *testing.T) {
+	d := NewDelegation(delegatorAddr, big.NewInt(5000))
+	d.Reward = big.NewInt(0)
+	legacy := struct {
+		DelegatorAddress common.Address
+		Amount           *big.Int
+		Reward           *big.Int
+		Undelegations    Undelegations
+	}{d.DelegatorAddress, d.Amount, d.Reward, d.Undelegations}
+
+	got, _ := rlp.EncodeToBytes(d)
+	want, _ := rlp.EncodeToBytes(legacy)
+	if !bytes.Equal(got, want) {
+		t.Errorf("delegation without redelegations should keep its encoding")
+	}
+	decoded := Delegation{}
+	if err := rlp.DecodeBytes(want, &decoded); err != nil {
+		t.Errorf("cannot decode legacy delegation: %v", err)
+	}
+}
+
+func TestCompoundReward(t *testing.T) {
+	d := NewDelegation(delegatorAddr, big.NewInt(5000))
+	d.Reward = big.NewInt(300)
+
+	if moved := d.CompoundReward(big.NewInt(1000)); moved.Sign() != 0 {
+		t.Errorf("delegation not opted in should not compound")
+	}
+	d.AutoCompound = true
+	if moved := d.CompoundReward(big.NewInt(100)); moved.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("compounding should be capped by the room left, got %v", moved)
+	}
+	if d.Amount.Cmp(big.NewInt(5100)) != 0 || d.Reward.Cmp(big.NewInt(200)) != 0 {
+		t.Errorf("compounding failed, amount %v reward %v", d.Amount, d.Reward)
+	}
+	d.CompoundReward(big.NewInt(1000))
+	if d.Amount.Cmp(big.NewInt(5300)) != 0 || d.Reward.Sign() != 0 {
+		t.Errorf("compounding failed, amount %v reward %v", d.Amount, d.Reward)
+	}
+}
+
+func TestDelegationOptionalFieldsRoundTrip(t *testing.T) {
+	d := NewDelegation(delegatorAddr, big.NewInt(5000))
+	d.Reward = big.NewInt(7)
+	d.AutoCompound = true
+
+	encoded, err := rlp.EncodeToBytes(d)
+	if err != nil {
+		t.Fatalf("cannot encode delegation: %v", err)
+	}
+	decoded := Delegation{}
+	if err := rlp.DecodeBytes(encoded, &decoded); err != nil {
+		t.Fatalf("cannot decode delegation: %v", err)
+	}
+	if !decoded.AutoCompound || decoded.Redelegations != nil ||
+		decoded.Amount.Cmp(d.Amount) != 0 {
+		t.Errorf("delegation does not round trip: %v", decoded)
+	}
+}
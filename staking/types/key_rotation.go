@@ -0,0 +1,123 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/harmony-one/harmony/shard"
+	"github.com/harmony-one/harmony/staking/effective"
+	"github.com/pkg/errors"
+)
+
+var (
+	errKeyRotationPending = errors.New("slot key already has a pending rotation")
+	errKeyRotationSameKey = errors.New("slot key to add must differ from the key it replaces")
+)
+
+// KeyRotation is a slot key replaced by another key from some epoch on
+type KeyRotation struct {
+	SlotKeyToRemove shard.BLSPublicKey `json:"slot-key-to-remove"`
+	SlotKeyToAdd    shard.BLSPublicKey `json:"slot-key-to-add"`
+	Epoch           *big.Int           `json:"epoch"`
+}
+
+// KeyRotations ..
+type KeyRotations []KeyRotation
+
+// String ..
+func (r KeyRotations) String() string {
+	s, _ := json.Marshal(r)
+	return string(s)
+}
+
+// ScheduleKeyRotation records the rotation of the key message, which takes
+// effect at the epoch of the message
+func (w *ValidatorWrapper) ScheduleKeyRotation(msg *RotateKey, epoch *big.Int) error {
+	if w.Status == effective.Banned {
+		return errCannotChangeBannedTaint
+	}
+	if msg.SlotKeyToRemove == msg.SlotKeyToAdd {
+		return errKeyRotationSameKey
+	}
+	found := false
+	for _, key := range w.SlotPubKeys {
+		if key == msg.SlotKeyToAdd {
+			return errSlotKeyToAddExists
+		}
+		if key == msg.SlotKeyToRemove {
+			found = true
+		}
+	}
+	if !found {
+		return errSlotKeyToRemoveNotFound
+	}
+	for _, rotation := range w.KeyRotations {
+		if rotation.SlotKeyToRemove == msg.SlotKeyToRemove ||
+			rotation.SlotKeyToAdd == msg.SlotKeyToAdd {
+			return errKeyRotationPending
+		}
+	}
+	instance := shard.Schedule.InstanceForEpoch(epoch)
+	if err := matchesHarmonyBLSKey(
+		&msg.SlotKeyToAdd, instance.HmyAccounts(), epoch,
+	); err != nil {
+		return err
+	}
+	if err := VerifyBLSKey(&msg.SlotKeyToAdd, &msg.SlotKeyToAddSig); err != nil {
+		return err
+	}
+	w.KeyRotations = append(w.KeyRotations, KeyRotation{
+		SlotKeyToRemove: msg.SlotKeyToRemove,
+		SlotKeyToAdd:    msg.SlotKeyToAdd,
+		Epoch:           new(big.Int).Set(msg.Epoch),
+	})
+	return nil
+}
+
+// IsRotatingKey tells whether the key is replaced or added by a rotation
+// not applied yet
+func (w *ValidatorWrapper) IsRotatingKey(key shard.BLSPublicKey) bool {
+	for _, rotation := range w.KeyRotations {
+		if rotation.SlotKeyToRemove == key || rotation.SlotKeyToAdd == key {
+			return true
+		}
+	}
+	return false
+}
+
+// SlotPubKeysAt returns the slot keys of the validator at epoch,
+// with the rotations effective by then applied
+func (w *ValidatorWrapper) SlotPubKeysAt(epoch *big.Int) []shard.BLSPublicKey {
+	keys := append([]shard.BLSPublicKey{}, w.SlotPubKeys...)
+	for _, rotation := range w.KeyRotations {
+		if rotation.Epoch.Cmp(epoch) > 0 {
+			continue
+		}
+		for i := range keys {
+			if keys[i] == rotation.SlotKeyToRemove {
+				keys[i] = rotation.SlotKeyToAdd
+			}
+		}
+	}
+	return keys
+}
+
+// ApplyKeyRotations replaces the slot keys rotated by epoch and forgets
+// those rotations, returning whether any key was replaced
+func (w *ValidatorWrapper) ApplyKeyRotations(epoch *big.Int) bool {
+	pending := KeyRotations{}
+	for _, rotation := range w.KeyRotations {
+		if rotation.Epoch.Cmp(epoch) > 0 {
+			pending = append(pending, rotation)
+		}
+	}
+	if len(pending) == len(w.KeyRotations) {
+		return false
+	}
+	w.SlotPubKeys = w.SlotPubKeysAt(epoch)
+	if len(pending) == 0 {
+		pending = nil
+	}
+	w.KeyRotations = pending
+	return true
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/harmony-one/harmony/shard"
+)
+
+func TestApplyKeyRotations(t *testing.T) {
+	oldKey, newKey := shard.BLSPublicKey{1}, shard.BLSPublicKey{2}
+	w := ValidatorWrapper{
+		Validator: Validator{SlotPubKeys: []shard.BLSPublicKey{oldKey}},
+		KeyRotations: KeyRotations{
+			{SlotKeyToRemove: oldKey, SlotKeyToAdd: newKey, Epoch: big.NewInt(5)},
+		},
+	}
+
+	if keys := w.SlotPubKeysAt(big.NewInt(4)); keys[0] != oldKey {
+		t.Errorf("key rotated before its epoch")
+	}
+	if keys := w.SlotPubKeysAt(big.NewInt(5)); keys[0] != newKey {
+		t.Errorf("key not rotated at its epoch")
+	}
+	if w.SlotPubKeys[0] != oldKey {
+		t.Errorf("looking up the keys of an epoch changed the slot keys")
+	}
+
+	if w.ApplyKeyRotations(big.NewInt(4)) {
+		t.Errorf("rotation applied before its epoch")
+	}
+	if !w.ApplyKeyRotations(big.NewInt(5)) {
+		t.Fatalf("rotation not applied at its epoch")
+	}
+	if w.SlotPubKeys[0] != newKey || len(w.KeyRotations) != 0 {
+		t.Errorf("expected only %s as slot key and no pending rotation, got %v and %s",
+			newKey.Hex(), w.SlotPubKeys, w.KeyRotations)
+	}
+}
+
+func TestKeyRotationsKeepEncoding(t *testing.T) {
+	w := createNewValidatorWrapper(createNewValidator())
+	w.BlockReward = big.NewInt(0)
+	before, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w.KeyRotations = KeyRotations{}
+	empty, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, empty) {
+		t.Errorf("a wrapper without key rotations encodes differently")
+	}
+
+	w.KeyRotations = KeyRotations{
+		{SlotKeyToRemove: slotPubKeys[0], SlotKeyToAdd: shard.BLSPublicKey{2}, Epoch: big.NewInt(7)},
+	}
+	encoded, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := ValidatorWrapper{}
+	if err := rlp.DecodeBytes(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.KeyRotations) != 1 ||
+		decoded.KeyRotations[0].Epoch.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("key rotations lost when decoding, got %s", decoded.KeyRotations)
+	}
+}
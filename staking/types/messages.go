@@ -31,6 +31,14 @@ const (
 	DirectiveUndelegate
 	// DirectiveCollectRewards ...
 	DirectiveCollectRewards
+	// DirectiveRedelegate ...
+	DirectiveRedelegate
+	// DirectiveSetAutoCompound ...
+	DirectiveSetAutoCompound
+	// DirectiveBatch ...
+	DirectiveBatch
+	// DirectiveRotateKey ...
+	DirectiveRotateKey
 )
 
 var (
@@ -40,6 +48,10 @@ var (
 		DirectiveDelegate:        "Delegate",
 		DirectiveUndelegate:      "Undelegate",
 		DirectiveCollectRewards:  "CollectRewards",
+		DirectiveRedelegate:      "Redelegate",
+		DirectiveSetAutoCompound: "SetAutoCompound",
+		DirectiveBatch:           "Batch",
+		DirectiveRotateKey:       "RotateKey",
 	}
 	// ErrInvalidStakingKind given when caller gives bad staking message kind
 	ErrInvalidStakingKind = errors.New("bad staking kind")
@@ -96,6 +108,49 @@ type CollectRewards struct {
 	DelegatorAddress common.Address `json:"delegator_address"`
 }
 
+// Redelegate - type for moving delegated tokens from one validator to another
+type Redelegate struct {
+	DelegatorAddress     common.Address `json:"delegator_address"`
+	FromValidatorAddress common.Address `json:"from_validator_address"`
+	ToValidatorAddress   common.Address `json:"to_validator_address"`
+	Amount               *big.Int       `json:"amount"`
+}
+
+// SetAutoCompound - type for opting a delegation in or out of having its
+// rewards added to the delegated amount at each epoch boundary
+type SetAutoCompound struct {
+	DelegatorAddress common.Address `json:"delegator_address"`
+	ValidatorAddress common.Address `json:"validator_address"`
+	Enabled          bool           `json:"enabled"`
+}
+
+// RotateKey - type for replacing a slot key of a validator by another,
+// taking effect at the given epoch
+type RotateKey struct {
+	ValidatorAddress common.Address     `json:"validator-address"`
+	SlotKeyToRemove  shard.BLSPublicKey `json:"slot-key-to-remove"`
+	SlotKeyToAdd     shard.BLSPublicKey `json:"slot-key-to-add"`
+	SlotKeyToAddSig  shard.BLSSignature `json:"slot-key-to-add-sig"`
+	Epoch            *big.Int           `json:"epoch"`
+}
+
+// MaxBatchOperations is the largest number of operations in a batch
+const MaxBatchOperations = 32
+
+// BatchOperation - one delegate or undelegate of a batch
+type BatchOperation struct {
+	Directive        Directive      `json:"directive"`
+	ValidatorAddress common.Address `json:"validator_address"`
+	Amount           *big.Int       `json:"amount"`
+}
+
+// Batch - type for delegating to and undelegating from several validators
+// at once, with all of the operations applied or none
+type Batch struct {
+	DelegatorAddress common.Address   `json:"delegator_address"`
+	Operations       []BatchOperation `json:"operations"`
+}
+
 // Type of CreateValidator
 func (v CreateValidator) Type() Directive {
 	return DirectiveCreateValidator
@@ -121,6 +176,26 @@ func (v CollectRewards) Type() Directive {
 	return DirectiveCollectRewards
 }
 
+// Type of Redelegate
+func (v Redelegate) Type() Directive {
+	return DirectiveRedelegate
+}
+
+// Type of SetAutoCompound
+func (v SetAutoCompound) Type() Directive {
+	return DirectiveSetAutoCompound
+}
+
+// Type of Batch
+func (v Batch) Type() Directive {
+	return DirectiveBatch
+}
+
+// Type of RotateKey
+func (v RotateKey) Type() Directive {
+	return DirectiveRotateKey
+}
+
 // Copy deep copy of the interface
 func (v CreateValidator) Copy() StakeMsg {
 	v1 := v
@@ -152,3 +227,37 @@ func (v CollectRewards) Copy() StakeMsg {
 	v1 := v
 	return v1
 }
+
+// Copy deep copy of the interface
+func (v Redelegate) Copy() StakeMsg {
+	v1 := v
+	return v1
+}
+
+// Copy deep copy of the interface
+func (v SetAutoCompound) Copy() StakeMsg {
+	v1 := v
+	return v1
+}
+
+// Copy deep copy of the interface
+func (v Batch) Copy() StakeMsg {
+	v1 := v
+	v1.Operations = make([]BatchOperation, len(v.Operations))
+	for i, op := range v.Operations {
+		v1.Operations[i] = op
+		if op.Amount != nil {
+			v1.Operations[i].Amount = new(big.Int).Set(op.Amount)
+		}
+	}
+	return v1
+}
+
+// Copy deep copy of the interface
+func (v RotateKey) Copy() StakeMsg {
+	v1 := v
+	if v.Epoch != nil {
+		v1.Epoch = new(big.Int).Set(v.Epoch)
+	}
+	return v1
+}
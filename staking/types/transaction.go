@@ -299,6 +299,14 @@ func RLPDecodeStakeMsg(payload []byte, d Directive) (interface{}, error) {
 			ds = &Undelegate{}
 		case DirectiveCollectRewards:
 			ds = &CollectRewards{}
+		case DirectiveRedelegate:
+			ds = &Redelegate{}
+		case DirectiveSetAutoCompound:
+			ds = &SetAutoCompound{}
+		case DirectiveBatch:
+			ds = &Batch{}
+		case DirectiveRotateKey:
+			ds = &RotateKey{}
 		default:
 			return nil, nil
 		}
@@ -2,6 +2,7 @@ package types
 
 import (
 	"encoding/json"
+	"io"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -58,6 +59,7 @@ var (
 	errNilMaxTotalDelegation   = errors.New("MaxTotalDelegation can not be nil")
 	errSlotKeyToRemoveNotFound = errors.New("slot key to remove not found")
 	errSlotKeyToAddExists      = errors.New("slot key to add already exists")
+	errTooManyValidatorFields  = errors.New("too many fields in validator encoding")
 	errDuplicateSlotKeys       = errors.New("slot keys can not have duplicates")
 	// ErrExcessiveBLSKeys ..
 	ErrExcessiveBLSKeys        = errors.New("more slot keys provided than allowed")
@@ -89,6 +91,77 @@ type ValidatorWrapper struct {
 	Counters counters `json:"-"`
 	// All the rewarded accumulated so far
 	BlockReward *big.Int `json:"-"`
+	// KeyRotations are the slot keys to replace at some later epoch
+	KeyRotations KeyRotations `json:"-"`
+	// PendingCommission is the commission rate to charge from some later epoch
+	PendingCommission *PendingCommission `json:"-"`
+}
+
+// validatorWrapperRLP is the encoding of a validator wrapper, with the
+// fields added after staking launch encoded in order only when set,
+// so that existing validators keep their encoding
+type validatorWrapperRLP struct {
+	Validator   Validator
+	Delegations Delegations
+	Counters    counters
+	BlockReward *big.Int
+	Optional    []rlp.RawValue `rlp:"tail"`
+}
+
+// EncodeRLP implements rlp.Encoder
+func (w ValidatorWrapper) EncodeRLP(wr io.Writer) error {
+	enc := validatorWrapperRLP{
+		Validator:   w.Validator,
+		Delegations: w.Delegations,
+		Counters:    w.Counters,
+		BlockReward: w.BlockReward,
+	}
+	optional := []interface{}{}
+	if w.PendingCommission != nil {
+		optional = append(optional, w.KeyRotations, w.PendingCommission)
+	} else if len(w.KeyRotations) > 0 {
+		optional = append(optional, w.KeyRotations)
+	}
+	for _, field := range optional {
+		raw, err := rlp.EncodeToBytes(field)
+		if err != nil {
+			return err
+		}
+		enc.Optional = append(enc.Optional, raw)
+	}
+	return rlp.Encode(wr, &enc)
+}
+
+// DecodeRLP implements rlp.Decoder
+func (w *ValidatorWrapper) DecodeRLP(s *rlp.Stream) error {
+	dec := validatorWrapperRLP{}
+	if err := s.Decode(&dec); err != nil {
+		return err
+	}
+	if len(dec.Optional) > 2 {
+		return errTooManyValidatorFields
+	}
+	*w = ValidatorWrapper{
+		Validator:   dec.Validator,
+		Delegations: dec.Delegations,
+		Counters:    dec.Counters,
+		BlockReward: dec.BlockReward,
+	}
+	if len(dec.Optional) > 0 {
+		if err := rlp.DecodeBytes(dec.Optional[0], &w.KeyRotations); err != nil {
+			return err
+		}
+		if len(w.KeyRotations) == 0 {
+			w.KeyRotations = nil
+		}
+	}
+	if len(dec.Optional) > 1 {
+		w.PendingCommission = &PendingCommission{}
+		if err := rlp.DecodeBytes(dec.Optional[1], w.PendingCommission); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Computed represents current epoch
@@ -160,12 +233,16 @@ func (w ValidatorWrapper) String() string {
 func (w ValidatorWrapper) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
 		Validator
-		Address     string      `json:"address"`
-		Delegations Delegations `json:"delegations"`
+		Address           string             `json:"address"`
+		Delegations       Delegations        `json:"delegations"`
+		KeyRotations      KeyRotations       `json:"key-rotations,omitempty"`
+		PendingCommission *PendingCommission `json:"pending-commission,omitempty"`
 	}{
 		w.Validator,
 		common2.MustAddressToBech32(w.Address),
 		w.Delegations,
+		w.KeyRotations,
+		w.PendingCommission,
 	})
 }
 
@@ -192,6 +269,58 @@ func (s ValidatorStats) String() string {
 	return string(str)
 }
 
+// EpochAvailability is how a validator signed during one epoch
+type EpochAvailability struct {
+	Epoch           *big.Int
+	NumBlocksSigned *big.Int
+	NumBlocksToSign *big.Int
+	Percentage      numeric.Dec
+	// Elected is whether the validator was in the committee of the epoch
+	Elected bool
+	// BootedStatus is why the validator is not in the next committee, if so
+	BootedStatus effective.BootedStatus
+}
+
+// MarshalJSON ..
+func (a EpochAvailability) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Epoch           *big.Int    `json:"epoch"`
+		NumBlocksSigned *big.Int    `json:"signed"`
+		NumBlocksToSign *big.Int    `json:"to-sign"`
+		Percentage      numeric.Dec `json:"percentage"`
+		Elected         bool        `json:"elected"`
+		BootedStatus    string      `json:"booted-status"`
+	}{
+		a.Epoch, a.NumBlocksSigned, a.NumBlocksToSign,
+		a.Percentage, a.Elected, a.BootedStatus.String(),
+	})
+}
+
+// AvailabilityHistory is the availability of a validator in each epoch
+// since staking, oldest first
+type AvailabilityHistory []EpochAvailability
+
+// String ..
+func (h AvailabilityHistory) String() string {
+	str, _ := json.Marshal(h)
+	return string(str)
+}
+
+// EpochAPR is the APR of a validator computed at the end of an epoch
+type EpochAPR struct {
+	Epoch *big.Int    `json:"epoch"`
+	Value numeric.Dec `json:"apr"`
+}
+
+// APRHistory is the APR of a validator at the end of each epoch, oldest first
+type APRHistory []EpochAPR
+
+// String ..
+func (h APRHistory) String() string {
+	str, _ := json.Marshal(h)
+	return string(str)
+}
+
 // Validator - data fields for a validator
 type Validator struct {
 	// ECDSA address of the validator
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	common "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/harmony-one/bls/ffi/go/bls"
 	"github.com/harmony-one/harmony/crypto/hash"
 	common2 "github.com/harmony-one/harmony/internal/common"
@@ -370,3 +371,35 @@ func TestString(t *testing.T) {
 	// print out the string
 	fmt.Println(validator.String())
 }
+
+func TestPendingCommissionKeepsEncoding(t *testing.T) {
+	w := createNewValidatorWrapper(createNewValidator())
+	w.BlockReward = big.NewInt(0)
+	w.PendingCommission = &PendingCommission{
+		Rate: numeric.NewDecWithPrec(2, 1), Epoch: big.NewInt(9),
+	}
+	encoded, err := rlp.EncodeToBytes(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := ValidatorWrapper{}
+	if err := rlp.DecodeBytes(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.PendingCommission == nil || decoded.KeyRotations != nil ||
+		!decoded.PendingCommission.Rate.Equal(w.PendingCommission.Rate) {
+		t.Fatalf("pending commission lost when decoding, got %s", decoded)
+	}
+
+	if decoded.ApplyPendingCommission(big.NewInt(8), big.NewInt(100)) {
+		t.Errorf("commission set before its epoch")
+	}
+	if !decoded.ApplyPendingCommission(big.NewInt(9), big.NewInt(100)) {
+		t.Fatalf("commission not set at its epoch")
+	}
+	if !decoded.Rate.Equal(w.PendingCommission.Rate) ||
+		decoded.PendingCommission != nil ||
+		decoded.UpdateHeight.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("unexpected validator after setting the commission %s", decoded)
+	}
+}